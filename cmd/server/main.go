@@ -56,6 +56,11 @@ func main() {
 		return
 	}
 
+	// 启动后台任务（生成任务工作池）
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	httpHandler.StartBackgroundWorkers(workerCtx)
+
 	// 设置Gin模式
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
@@ -75,24 +80,59 @@ func main() {
 	authGroup.POST("/login", httpHandler.Login)
 	authGroup.GET("/me", httpHandler.AuthMiddleware(), httpHandler.Me)
 
+	// 服务商异步任务完成回调（通过回调地址中的令牌校验，无需登录）
+	apiGroup.POST("/callbacks/:driver", httpHandler.HandleProviderCallback)
+
 	protected := apiGroup.Group("")
 	protected.Use(httpHandler.AuthMiddleware())
+	// 提交生成任务的接口按用户限流
+	rateLimit := httpHandler.RateLimitMiddleware()
 	protected.GET("/llm/providers", httpHandler.ListProviders)
 	protected.GET("/llm/events", httpHandler.StreamGenerationEvents)
-	protected.POST("/llm", httpHandler.GenerateContent)
+	protected.POST("/llm", rateLimit, httpHandler.GenerateContent)
+	protected.POST("/llm/batch", rateLimit, httpHandler.GenerateBatch)
+	protected.GET("/llm/batch/:id", httpHandler.GetGenerationBatch)
+	protected.GET("/llm/queue", httpHandler.GetGenerationQueue)
 	protected.GET("/usage-records", httpHandler.ListUsageRecords)
 	protected.GET("/usage-records/:id", httpHandler.GetUsageRecord)
 	protected.DELETE("/usage-records/:id", httpHandler.DeleteUsageRecord)
+	protected.GET("/usage-records/:id/queue", httpHandler.GetUsageRecordQueuePosition)
+	protected.POST("/usage-records/:id/cancel", httpHandler.CancelUsageRecord)
+	protected.POST("/usage-records/:id/rerun", rateLimit, httpHandler.RerunUsageRecord)
+	protected.POST("/usage-records/:id/remix", rateLimit, httpHandler.RemixUsageRecord)
 	protected.PUT("/usage-records/:id/tags", httpHandler.UpdateUsageRecordTags)
 
 	protected.GET("/tags", httpHandler.ListTags)
 
+	protected.GET("/webhooks", httpHandler.ListWebhookEndpoints)
+	protected.POST("/webhooks", httpHandler.CreateWebhookEndpoint)
+	protected.PATCH("/webhooks/:id", httpHandler.UpdateWebhookEndpoint)
+	protected.DELETE("/webhooks/:id", httpHandler.DeleteWebhookEndpoint)
+	protected.GET("/webhooks/:id/deliveries", httpHandler.ListWebhookDeliveries)
+
+	protected.GET("/prompt-templates", httpHandler.ListPromptTemplates)
+	protected.POST("/prompt-templates", httpHandler.CreatePromptTemplate)
+	protected.GET("/prompt-templates/:id", httpHandler.GetPromptTemplate)
+	protected.PATCH("/prompt-templates/:id", httpHandler.UpdatePromptTemplate)
+	protected.DELETE("/prompt-templates/:id", httpHandler.DeletePromptTemplate)
+
+	protected.GET("/pipelines", httpHandler.ListPipelines)
+	protected.POST("/pipelines", httpHandler.CreatePipeline)
+	protected.GET("/pipelines/:id", httpHandler.GetPipeline)
+	protected.PATCH("/pipelines/:id", httpHandler.UpdatePipeline)
+	protected.DELETE("/pipelines/:id", httpHandler.DeletePipeline)
+	protected.POST("/pipelines/:id/runs", rateLimit, httpHandler.RunPipeline)
+	protected.GET("/pipeline-runs/:id", httpHandler.GetPipelineRun)
+	protected.POST("/pipeline-runs/:id/cancel", httpHandler.CancelPipelineRun)
+
 	userAdmin := protected.Group("/users")
 	userAdmin.Use(httpHandler.RequireAdmin())
 	userAdmin.GET("", httpHandler.ListUsers)
 	userAdmin.POST("", httpHandler.CreateUser)
 	userAdmin.PATCH(":id", httpHandler.UpdateUser)
 	userAdmin.DELETE(":id", httpHandler.DeleteUser)
+	userAdmin.GET(":id/credits", httpHandler.GetUserCredits)
+	userAdmin.POST(":id/credits", httpHandler.GrantUserCredits)
 
 	providerAdmin := protected.Group("/providers")
 	providerAdmin.Use(httpHandler.RequireAdmin())
@@ -101,6 +141,9 @@ func main() {
 	providerAdmin.GET("/:id", httpHandler.GetProviderDetail)
 	providerAdmin.PATCH("/:id", httpHandler.UpdateProvider)
 	providerAdmin.DELETE("/:id", httpHandler.DeleteProvider)
+	providerAdmin.POST("/:id/test", httpHandler.TestProvider)
+	providerAdmin.GET("/:id/catalog", httpHandler.PreviewProviderCatalog)
+	providerAdmin.POST("/:id/catalog/sync", httpHandler.SyncProviderCatalog)
 
 	modelAdmin := providerAdmin.Group("/:id/models")
 	modelAdmin.GET("", httpHandler.ListProviderModels)
@@ -114,6 +157,20 @@ func main() {
 	tagAdmin.PATCH("/:id", httpHandler.UpdateTag)
 	tagAdmin.DELETE("/:id", httpHandler.DeleteTag)
 
+	failoverAdmin := protected.Group("/failover-chains")
+	failoverAdmin.Use(httpHandler.RequireAdmin())
+	failoverAdmin.GET("", httpHandler.ListFailoverChains)
+	failoverAdmin.POST("", httpHandler.CreateFailoverChain)
+	failoverAdmin.PATCH("/:id", httpHandler.UpdateFailoverChain)
+	failoverAdmin.DELETE("/:id", httpHandler.DeleteFailoverChain)
+
+	creditAdmin := protected.Group("/credit-quotas")
+	creditAdmin.Use(httpHandler.RequireAdmin())
+	creditAdmin.GET("", httpHandler.ListCreditQuotas)
+	creditAdmin.POST("", httpHandler.CreateCreditQuota)
+	creditAdmin.PATCH("/:id", httpHandler.UpdateCreditQuota)
+	creditAdmin.DELETE("/:id", httpHandler.DeleteCreditQuota)
+
 	if localProvider, ok := store.(storage.LocalBaseDirProvider); ok {
 		publicPrefix := strings.TrimSpace(cfg.StoragePublicBaseURL)
 		if publicPrefix == "" {
@@ -158,7 +215,7 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Idempotency-Key")
 		c.Header("Access-Control-Allow-Credentials", "true")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
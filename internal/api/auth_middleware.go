@@ -24,6 +24,7 @@ type RequestUser struct {
 	Email       string
 	DisplayName string
 	Role        string
+	Settings    entity.JSONMap
 }
 
 // IsAdmin 判断用户是否具有管理员权限
@@ -120,6 +121,7 @@ func (h *HTTPHandler) AuthMiddleware() gin.HandlerFunc {
 			Email:       user.Email,
 			DisplayName: user.DisplayName,
 			Role:        user.Role,
+			Settings:    user.Settings,
 		}
 
 		c.Set(currentUserContextKey, requestUser)
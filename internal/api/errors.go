@@ -32,12 +32,31 @@ const (
 	ErrCodeTagNotFound        = "ERR_TAG_NOT_FOUND"
 	ErrCodeRecordNotFound     = "ERR_RECORD_NOT_FOUND"
 	ErrCodeUserNotFound       = "ERR_USER_NOT_FOUND"
+	ErrCodeFailoverChainNotFound = "ERR_FAILOVER_CHAIN_NOT_FOUND"
+	ErrCodeBatchNotFound      = "ERR_BATCH_NOT_FOUND"
+	ErrCodeWebhookNotFound    = "ERR_WEBHOOK_NOT_FOUND"
+	ErrCodePromptTemplateNotFound = "ERR_PROMPT_TEMPLATE_NOT_FOUND"
+	ErrCodePipelineNotFound   = "ERR_PIPELINE_NOT_FOUND"
+	ErrCodePipelineRunNotFound = "ERR_PIPELINE_RUN_NOT_FOUND"
+	ErrCodeCreditQuotaNotFound = "ERR_CREDIT_QUOTA_NOT_FOUND"
 
 	// 业务逻辑错误码 (4xxx)
 	ErrCodeMissingField       = "ERR_MISSING_FIELD"
 	ErrCodeInvalidTag         = "ERR_INVALID_TAG"
 	ErrCodeCannotDeleteSelf   = "ERR_CANNOT_DELETE_SELF"
 	ErrCodeGenerationFailed   = "ERR_GENERATION_FAILED"
+	ErrCodeCancelNotSupported = "ERR_CANCEL_NOT_SUPPORTED"
+	ErrCodeNotCancellable     = "ERR_NOT_CANCELLABLE"
+	ErrCodeTooManyOutputs     = "ERR_TOO_MANY_OUTPUTS"
+	ErrCodeUnsupportedSize    = "ERR_UNSUPPORTED_SIZE"
+	ErrCodeUnsupportedDuration = "ERR_UNSUPPORTED_DURATION"
+	ErrCodeTooManyImages      = "ERR_TOO_MANY_IMAGES"
+	ErrCodeUnsupportedModality = "ERR_UNSUPPORTED_MODALITY"
+	ErrCodeIdempotencyInProgress = "ERR_IDEMPOTENCY_IN_PROGRESS"
+	ErrCodeRecordNotFinished  = "ERR_RECORD_NOT_FINISHED"
+	ErrCodeQuotaExceeded      = "ERR_QUOTA_EXCEEDED"
+	ErrCodeRateLimited        = "ERR_RATE_LIMITED"
+	ErrCodeCatalogUnsupported = "ERR_CATALOG_UNSUPPORTED"
 )
 
 // APIError 统一的 API 错误响应结构
@@ -86,6 +105,11 @@ func NotFound(c *gin.Context, code string, message string) {
 	ErrorResponse(c, http.StatusNotFound, code, message)
 }
 
+// Conflict 409 资源状态冲突
+func Conflict(c *gin.Context, code string, message string) {
+	ErrorResponse(c, http.StatusConflict, code, message)
+}
+
 // InternalError 500 服务器内部错误
 func InternalError(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusInternalServerError, ErrCodeInternalError, message)
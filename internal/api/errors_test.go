@@ -151,6 +151,17 @@ func TestShortcutFunctions(t *testing.T) {
 		}
 	})
 
+	t.Run("Conflict", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		Conflict(c, ErrCodeNotCancellable, "状态冲突")
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected status %d, got %d", http.StatusConflict, w.Code)
+		}
+	})
+
 	t.Run("InternalError", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
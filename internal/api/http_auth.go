@@ -196,6 +196,7 @@ func makeUserSummary(user *entity.DbUser) entity.UserSummary {
 		DisplayName: user.DisplayName,
 		Role:        user.Role,
 		IsActive:    user.IsActive,
+		Settings:    user.Settings,
 		CreatedAt:   user.CreatedAt,
 		UpdatedAt:   user.UpdatedAt,
 	}
@@ -0,0 +1,301 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxBatchItems 单个批次最多包含的子生成数（提示词数 × 模型数）
+	maxBatchItems = 200
+	// defaultBatchConcurrency 未指定时同一批次同时执行的子生成数
+	defaultBatchConcurrency = 3
+	// maxBatchConcurrency 单个批次允许的最大并发
+	maxBatchConcurrency = 10
+)
+
+// GenerateBatch 提交批量生成请求：每个提示词在每个服务商/模型上各生成一次
+func (h *HTTPHandler) GenerateBatch(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+
+	var request entity.GenerateBatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		InvalidPayload(c)
+		return
+	}
+
+	if h.repo == nil {
+		InternalError(c, "服务商仓储未配置")
+		return
+	}
+
+	prompts, err := expandBatchPrompts(request)
+	if err != nil {
+		BadRequest(c, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	if len(prompts) == 0 {
+		MissingField(c, "prompts")
+		return
+	}
+
+	targets := normaliseBatchTargets(request.Targets)
+	if len(targets) == 0 {
+		MissingField(c, "targets")
+		return
+	}
+
+	total := len(prompts) * len(targets)
+	if total > maxBatchItems {
+		BadRequest(c, ErrCodeInvalidRequest, fmt.Sprintf("批量生成数量 %d 超过上限 %d", total, maxBatchItems))
+		return
+	}
+
+	targetModels := make([]entity.DbModel, 0, len(targets))
+	for _, target := range targets {
+		dbModel, service, ok := h.validateGenerationTarget(c, target.ProviderID, target.ModelID)
+		if !ok {
+			return
+		}
+		targetModels = append(targetModels, *dbModel)
+		if !validateNumOutputs(c, request.Output.NumOutputs, *dbModel) {
+			return
+		}
+		// 各子任务仅提示词不同，使用第一个提示词按模型能力校验
+		sample := entity.GenerateContentRequest{
+			ProviderID: target.ProviderID,
+			ModelID:    target.ModelID,
+			Prompt:     prompts[0],
+			InputMedia: request.InputMedia,
+			Output:     request.Output,
+		}
+		if !validateCapabilities(c, service, sample, *dbModel) {
+			return
+		}
+	}
+
+	if !validateAdvancedParams(c, &request.Advanced) {
+		return
+	}
+
+	tagIDs := deduplicatePositiveIDs(request.TagIDs)
+	if !h.validateTagIDs(c, tagIDs) {
+		return
+	}
+
+	concurrency := request.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	if concurrency > maxBatchConcurrency {
+		concurrency = maxBatchConcurrency
+	}
+
+	clientID := strings.TrimSpace(request.ClientID)
+	size := strings.TrimSpace(request.Output.Size)
+	output := request.Output
+	output.Size = size
+
+	records := make([]entity.DbUsageRecord, 0, total)
+	requests := make([]entity.GenerateContentRequest, 0, total)
+	estimates := make([]float64, 0, total)
+	var required float64
+	for _, prompt := range prompts {
+		for i, target := range targets {
+			records = append(records, entity.DbUsageRecord{
+				UserID:     requestUser.ID,
+				ProviderID: target.ProviderID,
+				ModelID:    target.ModelID,
+				Prompt:     prompt,
+				Size:       size,
+				NumOutputs: max(output.NumOutputs, 1),
+				Status:     entity.UsageRecordStatusQueued,
+			})
+			requests = append(requests, entity.GenerateContentRequest{
+				ClientID:   clientID,
+				ProviderID: target.ProviderID,
+				ModelID:    target.ModelID,
+				Prompt:     prompt,
+				InputMedia: request.InputMedia,
+				Output:     output,
+				Advanced:   request.Advanced,
+				TagIDs:     tagIDs,
+			})
+			estimate := service.EstimateGenerationCost(targetModels[i], requests[len(requests)-1])
+			estimates = append(estimates, estimate)
+			required += estimate
+		}
+	}
+
+	batch := &entity.DbGenerationBatch{
+		UserID:         requestUser.ID,
+		ClientID:       clientID,
+		Name:           strings.TrimSpace(request.Name),
+		MaxConcurrency: concurrency,
+		Status:         entity.GenerationBatchStatusRunning,
+	}
+
+	createCtx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	if err := h.generationService.SubmitBatch(createCtx, batch, records, requests, estimates, tagIDs); err != nil {
+		if respondCreditReservationError(c, err, required) {
+			return
+		}
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"user_id": requestUser.ID,
+			"total":   total,
+		}).Error("failed to create generation batch")
+		InternalError(c, "创建批量生成任务失败")
+		return
+	}
+
+	recordIDs := make([]uint, 0, len(records))
+	for _, record := range records {
+		recordIDs = append(recordIDs, record.ID)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"batch_id":    batch.ID,
+		"user_id":     requestUser.ID,
+		"prompts":     len(prompts),
+		"targets":     len(targets),
+		"concurrency": concurrency,
+	}).Info("queued generation batch")
+
+	c.JSON(http.StatusAccepted, entity.GenerateBatchResponse{
+		BatchID:   batch.ID,
+		Status:    batch.Status,
+		Total:     batch.Total,
+		RecordIDs: recordIDs,
+	})
+}
+
+// GetGenerationBatch 查询批量生成的状态与进度
+func (h *HTTPHandler) GetGenerationBatch(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+
+	if h.repo == nil {
+		ServiceUnavailable(c, "批量生成服务不可用")
+		return
+	}
+
+	rawID := strings.TrimSpace(c.Param("id"))
+	batchID, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil || batchID == 0 {
+		BadRequest(c, ErrCodeInvalidRequest, "无效的批次 ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	batch, err := h.repo.GetGenerationBatch(ctx, uint(batchID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodeBatchNotFound, "批次不存在")
+			return
+		}
+		logrus.WithError(err).WithField("batch_id", batchID).Error("failed to load generation batch")
+		InternalError(c, "加载批次失败")
+		return
+	}
+
+	if !requestUser.IsAdmin() && batch.UserID != requestUser.ID {
+		Forbidden(c, "无权访问此批次")
+		return
+	}
+
+	progress, err := h.generationService.BatchProgress(ctx, *batch)
+	if err != nil {
+		logrus.WithError(err).WithField("batch_id", batchID).Error("failed to count generation batch records")
+		InternalError(c, "加载批次进度失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.GenerationBatchDetailResponse{Batch: makeGenerationBatch(*batch, progress)})
+}
+
+// expandBatchPrompts 展开批次的提示词：直接给出的提示词列表，或模板与每组变量渲染的结果
+func expandBatchPrompts(request entity.GenerateBatchRequest) ([]string, error) {
+	prompts := make([]string, 0, len(request.Prompts)+len(request.Variables))
+	for _, prompt := range request.Prompts {
+		if trimmed := strings.TrimSpace(prompt); trimmed != "" {
+			prompts = append(prompts, trimmed)
+		}
+	}
+
+	template := strings.TrimSpace(request.PromptTemplate)
+	if template == "" {
+		if len(request.Variables) > 0 {
+			return nil, errors.New("提供变量时必须指定 prompt_template")
+		}
+		return prompts, nil
+	}
+	if len(request.Variables) == 0 {
+		return nil, errors.New("prompt_template 需要至少一组变量")
+	}
+
+	for idx, variables := range request.Variables {
+		rendered, err := service.RenderPromptTemplate(template, variables)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 组变量: %v", idx+1, err)
+		}
+		if rendered == "" {
+			return nil, fmt.Errorf("第 %d 组变量渲染后的提示词为空", idx+1)
+		}
+		prompts = append(prompts, rendered)
+	}
+	return prompts, nil
+}
+
+// normaliseBatchTargets 去除空白与重复的服务商/模型组合
+func normaliseBatchTargets(targets []entity.BatchTarget) []entity.BatchTarget {
+	result := make([]entity.BatchTarget, 0, len(targets))
+	seen := make(map[string]struct{}, len(targets))
+	for _, target := range targets {
+		providerID := strings.TrimSpace(target.ProviderID)
+		modelID := strings.TrimSpace(target.ModelID)
+		if providerID == "" || modelID == "" {
+			continue
+		}
+		key := providerID + "/" + modelID
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		result = append(result, entity.BatchTarget{ProviderID: providerID, ModelID: modelID})
+	}
+	return result
+}
+
+func makeGenerationBatch(batch entity.DbGenerationBatch, progress entity.GenerationBatchProgress) entity.GenerationBatch {
+	return entity.GenerationBatch{
+		ID:             batch.ID,
+		Name:           batch.Name,
+		Status:         batch.Status,
+		MaxConcurrency: batch.MaxConcurrency,
+		Progress:       progress,
+		CreatedAt:      batch.CreatedAt,
+		FinishedAt:     batch.FinishedAt,
+	}
+}
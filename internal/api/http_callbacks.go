@@ -0,0 +1,63 @@
+package api
+
+import (
+	"clothing/internal/llm"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// maxCallbackBodyBytes 回调请求体大小上限
+const maxCallbackBodyBytes = 1 << 20
+
+// HandleProviderCallback 接收服务商推送的异步任务完成回调，无需登录，通过回调地址中的驱动令牌校验来源
+func (h *HTTPHandler) HandleProviderCallback(c *gin.Context) {
+	driver := strings.ToLower(strings.TrimSpace(c.Param("driver")))
+	if !llm.VerifyTaskCallbackToken(h.cfg.ProviderCallbackSecret, driver, c.Query("token")) {
+		Unauthorized(c, "回调令牌无效")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCallbackBodyBytes))
+	if err != nil {
+		InvalidPayload(c)
+		return
+	}
+
+	callback, err := llm.ParseTaskCallback(driver, body)
+	if err != nil {
+		logrus.WithError(err).WithField("driver", driver).Warn("invalid provider callback")
+		BadRequest(c, ErrCodeInvalidRequest, "回调内容无效")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	record, err := h.generationService.HandleTaskCallback(ctx, callback)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodeRecordNotFound, "任务对应的使用记录不存在")
+			return
+		}
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"driver":  driver,
+			"task_id": callback.TaskID,
+		}).Error("failed to handle provider callback")
+		InternalError(c, "处理回调失败")
+		return
+	}
+
+	payload := gin.H{"received": true}
+	if record != nil {
+		payload["record_id"] = record.ID
+	}
+	c.JSON(http.StatusOK, payload)
+}
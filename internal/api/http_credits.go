@@ -0,0 +1,371 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/entity/converter"
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// GetUserCredits 查看用户的额度余额与最近的流水
+func (h *HTTPHandler) GetUserCredits(c *gin.Context) {
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	limit := 50
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > 200 {
+			BadRequest(c, ErrCodeInvalidRequest, "limit 必须在 1-200 之间")
+			return
+		}
+		limit = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	user, ok := h.loadCreditUser(ctx, c)
+	if !ok {
+		return
+	}
+
+	account, err := h.repo.GetCreditAccount(ctx, user.ID)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("failed to load credit account")
+		InternalError(c, "加载额度失败")
+		return
+	}
+	entries, err := h.repo.ListCreditLedger(ctx, user.ID, limit)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("failed to list credit ledger")
+		InternalError(c, "加载额度流水失败")
+		return
+	}
+
+	items := make([]entity.CreditLedgerEntry, 0, len(entries))
+	for i := range entries {
+		items = append(items, converter.CreditLedgerEntryToDTO(&entries[i]))
+	}
+	c.JSON(http.StatusOK, entity.CreditAccountResponse{
+		Account: converter.CreditAccountToDTO(account),
+		Entries: items,
+	})
+}
+
+// GrantUserCredits 为用户发放额度，金额为负数时扣减额度
+func (h *HTTPHandler) GrantUserCredits(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	var payload entity.GrantCreditsRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		InvalidPayload(c)
+		return
+	}
+	if payload.Amount == 0 || math.IsNaN(payload.Amount) || math.IsInf(payload.Amount, 0) {
+		BadRequest(c, ErrCodeInvalidRequest, "amount 必须是非零数值")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	user, ok := h.loadCreditUser(ctx, c)
+	if !ok {
+		return
+	}
+
+	grantedBy := requestUser.ID
+	entry := &entity.DbCreditLedgerEntry{
+		UserID:    user.ID,
+		Amount:    payload.Amount,
+		Note:      strings.TrimSpace(payload.Note),
+		CreatedBy: &grantedBy,
+	}
+	if err := h.repo.GrantCredits(ctx, entry); err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("failed to grant credits")
+		InternalError(c, "发放额度失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, entity.GrantCreditsResponse{
+		Account: entity.CreditAccount{UserID: user.ID, Balance: entry.BalanceAfter},
+		Entry:   converter.CreditLedgerEntryToDTO(entry),
+	})
+}
+
+// ListCreditQuotas 列出全部额度配额
+func (h *HTTPHandler) ListCreditQuotas(c *gin.Context) {
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	quotas, err := h.repo.ListCreditQuotas(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("failed to list credit quotas")
+		InternalError(c, "加载配额失败")
+		return
+	}
+
+	items := make([]entity.CreditQuota, 0, len(quotas))
+	for i := range quotas {
+		items = append(items, converter.CreditQuotaToDTO(&quotas[i]))
+	}
+	c.JSON(http.StatusOK, entity.CreditQuotaListResponse{Quotas: items})
+}
+
+// CreateCreditQuota 创建额度配额，user_id 为 0 时对每个用户分别生效
+func (h *HTTPHandler) CreateCreditQuota(c *gin.Context) {
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	var payload entity.CreateCreditQuotaRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		InvalidPayload(c)
+		return
+	}
+
+	period, err := normaliseQuotaPeriod(payload.Period)
+	if err != nil {
+		BadRequest(c, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	if !validQuotaLimit(payload.Limit) {
+		BadRequest(c, ErrCodeInvalidRequest, "limit 不能为负数")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if payload.UserID > 0 {
+		if _, err := h.repo.GetUserByID(ctx, payload.UserID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				NotFound(c, ErrCodeUserNotFound, "用户不存在")
+				return
+			}
+			logrus.WithError(err).WithField("user_id", payload.UserID).Error("failed to load user for credit quota")
+			InternalError(c, "创建配额失败")
+			return
+		}
+	}
+
+	quota := &entity.DbCreditQuota{
+		UserID:     payload.UserID,
+		ProviderID: strings.TrimSpace(payload.ProviderID),
+		ModelID:    strings.TrimSpace(payload.ModelID),
+		Period:     period,
+		Limit:      payload.Limit,
+	}
+	if err := h.repo.CreateCreditQuota(ctx, quota); err != nil {
+		logrus.WithError(err).Error("failed to create credit quota")
+		InternalError(c, "创建配额失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, entity.CreditQuotaDetailResponse{Quota: converter.CreditQuotaToDTO(quota)})
+}
+
+// UpdateCreditQuota 更新配额的周期或上限
+func (h *HTTPHandler) UpdateCreditQuota(c *gin.Context) {
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	quotaID, ok := parseCreditQuotaID(c)
+	if !ok {
+		return
+	}
+
+	var payload entity.UpdateCreditQuotaRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		InvalidPayload(c)
+		return
+	}
+
+	var updates entity.CreditQuotaUpdates
+	if payload.Period != nil {
+		period, err := normaliseQuotaPeriod(*payload.Period)
+		if err != nil {
+			BadRequest(c, ErrCodeInvalidRequest, err.Error())
+			return
+		}
+		updates.Period = &period
+	}
+	if payload.Limit != nil {
+		if !validQuotaLimit(*payload.Limit) {
+			BadRequest(c, ErrCodeInvalidRequest, "limit 不能为负数")
+			return
+		}
+		updates.Limit = payload.Limit
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if !updates.IsEmpty() {
+		if err := h.repo.UpdateCreditQuota(ctx, quotaID, updates); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				NotFound(c, ErrCodeCreditQuotaNotFound, "配额不存在")
+				return
+			}
+			logrus.WithError(err).WithField("quota_id", quotaID).Error("failed to update credit quota")
+			InternalError(c, "更新配额失败")
+			return
+		}
+	}
+
+	quota, err := h.repo.GetCreditQuota(ctx, quotaID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodeCreditQuotaNotFound, "配额不存在")
+			return
+		}
+		logrus.WithError(err).WithField("quota_id", quotaID).Error("failed to reload credit quota")
+		InternalError(c, "加载配额失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.CreditQuotaDetailResponse{Quota: converter.CreditQuotaToDTO(quota)})
+}
+
+// DeleteCreditQuota 删除配额
+func (h *HTTPHandler) DeleteCreditQuota(c *gin.Context) {
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	quotaID, ok := parseCreditQuotaID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.repo.DeleteCreditQuota(ctx, quotaID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodeCreditQuotaNotFound, "配额不存在")
+			return
+		}
+		logrus.WithError(err).WithField("quota_id", quotaID).Error("failed to delete credit quota")
+		InternalError(c, "删除配额失败")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondCreditReservationError 余额不足或超出配额时写入错误响应并返回 true；
+// required 为本次需要预留的额度，未知时传 0，响应中省略
+func respondCreditReservationError(c *gin.Context, err error, required float64) bool {
+	var details gin.H
+	var quotaErr *entity.QuotaExceededError
+	switch {
+	case errors.Is(err, entity.ErrInsufficientCredits):
+		details = gin.H{"reason": "insufficient_credits"}
+	case errors.As(err, &quotaErr):
+		details = gin.H{
+			"reason":   "quota_exceeded",
+			"quota_id": quotaErr.Quota.ID,
+			"period":   quotaErr.Quota.Period,
+			"limit":    quotaErr.Quota.Limit,
+			"spent":    quotaErr.Spent,
+		}
+	default:
+		return false
+	}
+	if required > 0 {
+		details["required"] = required
+	}
+
+	if quotaErr != nil {
+		ErrorResponseWithDetails(c, http.StatusTooManyRequests, ErrCodeQuotaExceeded, "已超出"+quotaPeriodLabel(quotaErr.Quota.Period)+"配额", details)
+	} else {
+		ErrorResponseWithDetails(c, http.StatusPaymentRequired, ErrCodeQuotaExceeded, "额度不足", details)
+	}
+	return true
+}
+
+// loadCreditUser 加载路径参数指定的用户
+func (h *HTTPHandler) loadCreditUser(ctx context.Context, c *gin.Context) (*entity.DbUser, bool) {
+	rawID := strings.TrimSpace(c.Param("id"))
+	userID, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil || userID == 0 {
+		BadRequest(c, ErrCodeInvalidRequest, "无效的用户 ID")
+		return nil, false
+	}
+
+	user, err := h.repo.GetUserByID(ctx, uint(userID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodeUserNotFound, "用户不存在")
+			return nil, false
+		}
+		logrus.WithError(err).WithField("user_id", userID).Error("failed to load user")
+		InternalError(c, "加载用户失败")
+		return nil, false
+	}
+	return user, true
+}
+
+// parseCreditQuotaID 解析路径参数中的配额 ID
+func parseCreditQuotaID(c *gin.Context) (uint, bool) {
+	rawID := strings.TrimSpace(c.Param("id"))
+	quotaID, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil || quotaID == 0 {
+		BadRequest(c, ErrCodeInvalidRequest, "无效的配额 ID")
+		return 0, false
+	}
+	return uint(quotaID), true
+}
+
+// normaliseQuotaPeriod 校验配额周期
+func normaliseQuotaPeriod(raw string) (string, error) {
+	switch period := strings.ToLower(strings.TrimSpace(raw)); period {
+	case entity.CreditQuotaPeriodDaily, entity.CreditQuotaPeriodMonthly:
+		return period, nil
+	default:
+		return "", errors.New("period 必须是 daily 或 monthly")
+	}
+}
+
+// validQuotaLimit 配额上限必须是非负数值
+func validQuotaLimit(limit float64) bool {
+	return limit >= 0 && !math.IsInf(limit, 0)
+}
+
+// quotaPeriodLabel 配额周期的中文名称
+func quotaPeriodLabel(period string) string {
+	if period == entity.CreditQuotaPeriodMonthly {
+		return "每月"
+	}
+	return "每日"
+}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+func (h *HTTPHandler) ListFailoverChains(c *gin.Context) {
+	if h.repo == nil {
+		InternalError(c, "服务商仓储未配置")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	chains, err := h.repo.ListFailoverChains(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("failed to list failover chains")
+		InternalError(c, "加载故障转移链失败")
+		return
+	}
+
+	items := make([]entity.FailoverChain, 0, len(chains))
+	for _, chain := range chains {
+		items = append(items, makeFailoverChain(chain))
+	}
+	c.JSON(http.StatusOK, entity.FailoverChainListResponse{Chains: items})
+}
+
+func (h *HTTPHandler) CreateFailoverChain(c *gin.Context) {
+	if h.repo == nil {
+		InternalError(c, "服务商仓储未配置")
+		return
+	}
+
+	var payload entity.CreateFailoverChainRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		InvalidPayload(c)
+		return
+	}
+
+	providerID, err := normaliseProviderID(payload.ProviderID)
+	if err != nil {
+		BadRequest(c, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	modelID := strings.TrimSpace(payload.ModelID)
+	if modelID == "" {
+		MissingField(c, "model_id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := h.repo.GetModel(ctx, providerID, modelID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodeModelNotFound, "模型不存在: "+modelID)
+			return
+		}
+		logrus.WithError(err).Error("failed to load primary model for failover chain")
+		InternalError(c, "创建故障转移链失败")
+		return
+	}
+
+	fallbacks, err := h.validateFailoverSteps(ctx, providerID, modelID, payload.Fallbacks)
+	if err != nil {
+		BadRequest(c, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	isActive := true
+	if payload.IsActive != nil {
+		isActive = *payload.IsActive
+	}
+
+	chain := &entity.DbFailoverChain{
+		ProviderID:  providerID,
+		ModelID:     modelID,
+		Fallbacks:   fallbacks,
+		Description: strings.TrimSpace(payload.Description),
+		IsActive:    isActive,
+	}
+	if err := h.repo.CreateFailoverChain(ctx, chain); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"provider_id": providerID,
+			"model_id":    modelID,
+		}).Error("failed to create failover chain")
+		InternalError(c, "创建故障转移链失败: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, entity.FailoverChainDetailResponse{Chain: makeFailoverChain(*chain)})
+}
+
+func (h *HTTPHandler) UpdateFailoverChain(c *gin.Context) {
+	if h.repo == nil {
+		InternalError(c, "服务商仓储未配置")
+		return
+	}
+
+	chainID, ok := parseFailoverChainID(c)
+	if !ok {
+		return
+	}
+
+	var payload entity.UpdateFailoverChainRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		InvalidPayload(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	chain, err := h.repo.GetFailoverChain(ctx, chainID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodeFailoverChainNotFound, "故障转移链不存在")
+			return
+		}
+		logrus.WithError(err).WithField("chain_id", chainID).Error("failed to load failover chain")
+		InternalError(c, "更新故障转移链失败")
+		return
+	}
+
+	var updates entity.FailoverChainUpdates
+	if payload.Fallbacks != nil {
+		fallbacks, err := h.validateFailoverSteps(ctx, chain.ProviderID, chain.ModelID, *payload.Fallbacks)
+		if err != nil {
+			BadRequest(c, ErrCodeInvalidRequest, err.Error())
+			return
+		}
+		updates.Fallbacks = &fallbacks
+	}
+	if payload.Description != nil {
+		description := strings.TrimSpace(*payload.Description)
+		updates.Description = &description
+	}
+	if payload.IsActive != nil {
+		updates.IsActive = payload.IsActive
+	}
+
+	if !updates.IsEmpty() {
+		if err := h.repo.UpdateFailoverChain(ctx, chainID, updates); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				NotFound(c, ErrCodeFailoverChainNotFound, "故障转移链不存在")
+				return
+			}
+			logrus.WithError(err).WithField("chain_id", chainID).Error("failed to update failover chain")
+			InternalError(c, "更新故障转移链失败")
+			return
+		}
+	}
+
+	updated, err := h.repo.GetFailoverChain(ctx, chainID)
+	if err != nil {
+		logrus.WithError(err).WithField("chain_id", chainID).Error("failed to reload failover chain")
+		InternalError(c, "加载故障转移链失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.FailoverChainDetailResponse{Chain: makeFailoverChain(*updated)})
+}
+
+func (h *HTTPHandler) DeleteFailoverChain(c *gin.Context) {
+	if h.repo == nil {
+		InternalError(c, "服务商仓储未配置")
+		return
+	}
+
+	chainID, ok := parseFailoverChainID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.repo.DeleteFailoverChain(ctx, chainID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodeFailoverChainNotFound, "故障转移链不存在")
+			return
+		}
+		logrus.WithError(err).WithField("chain_id", chainID).Error("failed to delete failover chain")
+		InternalError(c, "删除故障转移链失败")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func parseFailoverChainID(c *gin.Context) (uint, bool) {
+	rawID := strings.TrimSpace(c.Param("id"))
+	chainID, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil || chainID == 0 {
+		BadRequest(c, ErrCodeInvalidRequest, "无效的故障转移链 ID")
+		return 0, false
+	}
+	return uint(chainID), true
+}
+
+// validateFailoverSteps 校验备用列表：去重、不得包含链首，且每个模型必须存在
+func (h *HTTPHandler) validateFailoverSteps(ctx context.Context, providerID, modelID string, steps []entity.FailoverChainStep) (entity.FailoverSteps, error) {
+	result := make(entity.FailoverSteps, 0, len(steps))
+	seen := make(map[string]struct{}, len(steps))
+	for idx, step := range steps {
+		stepProvider, err := normaliseProviderID(step.ProviderID)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个备用服务商无效: %v", idx+1, err)
+		}
+		stepModel := strings.TrimSpace(step.ModelID)
+		if stepModel == "" {
+			return nil, fmt.Errorf("第 %d 个备用模型不能为空", idx+1)
+		}
+		if stepProvider == providerID && stepModel == modelID {
+			return nil, fmt.Errorf("备用列表不能包含主模型 %s/%s", providerID, modelID)
+		}
+
+		key := stepProvider + "/" + stepModel
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		if _, err := h.repo.GetModel(ctx, stepProvider, stepModel); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("备用模型不存在: %s", key)
+			}
+			return nil, fmt.Errorf("加载备用模型失败: %s", key)
+		}
+		result = append(result, entity.FailoverStep{ProviderID: stepProvider, ModelID: stepModel})
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("至少需要一个备用模型")
+	}
+	return result, nil
+}
+
+func makeFailoverChain(chain entity.DbFailoverChain) entity.FailoverChain {
+	fallbacks := make([]entity.FailoverChainStep, 0, len(chain.Fallbacks))
+	for _, step := range chain.Fallbacks {
+		fallbacks = append(fallbacks, entity.FailoverChainStep{
+			ProviderID: step.ProviderID,
+			ModelID:    step.ModelID,
+		})
+	}
+	return entity.FailoverChain{
+		ID:          chain.ID,
+		ProviderID:  chain.ProviderID,
+		ModelID:     chain.ModelID,
+		Fallbacks:   fallbacks,
+		Description: chain.Description,
+		IsActive:    chain.IsActive,
+		CreatedAt:   chain.CreatedAt,
+		UpdatedAt:   chain.UpdatedAt,
+	}
+}
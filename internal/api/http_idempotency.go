@@ -0,0 +1,146 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	idempotencyKeyTTL       = 24 * time.Hour
+	maxIdempotencyKeyLength = 255
+	// idempotencyPendingTTL 幂等键占用后超过该时长仍未关联使用记录，视为首个请求已中断，允许重新占用
+	idempotencyPendingTTL = time.Minute
+	// idempotencyPurgeInterval 清理过期幂等键的间隔
+	idempotencyPurgeInterval = time.Hour
+)
+
+// idempotencyKeyFromRequest 读取幂等键，请求头优先于请求体字段
+func idempotencyKeyFromRequest(c *gin.Context, bodyKey string) string {
+	if key := strings.TrimSpace(c.GetHeader(idempotencyKeyHeader)); key != "" {
+		return key
+	}
+	return strings.TrimSpace(bodyKey)
+}
+
+// claimIdempotencyKey 为用户占用幂等键。未携带幂等键时返回 (nil, true)；
+// 幂等键已被占用时写入原使用记录的响应并返回 false
+func (h *HTTPHandler) claimIdempotencyKey(ctx context.Context, c *gin.Context, userID uint, key string) (*entity.DbIdempotencyKey, bool) {
+	if key == "" {
+		return nil, true
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		BadRequest(c, ErrCodeInvalidRequest, fmt.Sprintf("幂等键长度不能超过 %d", maxIdempotencyKeyLength))
+		return nil, false
+	}
+
+	claim := &entity.DbIdempotencyKey{
+		UserID:    userID,
+		Key:       key,
+		ExpiresAt: time.Now().Add(idempotencyKeyTTL),
+	}
+	existing, claimed, err := h.repo.ClaimIdempotencyKey(ctx, claim, idempotencyPendingTTL)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Error("failed to claim idempotency key")
+		InternalError(c, "校验幂等键失败")
+		return nil, false
+	}
+	if claimed {
+		return existing, true
+	}
+
+	if existing.RecordID == 0 {
+		Conflict(c, ErrCodeIdempotencyInProgress, "相同幂等键的请求正在处理")
+		return nil, false
+	}
+
+	record, err := h.repo.GetUsageRecord(ctx, existing.RecordID)
+	if err != nil || record == nil {
+		NotFound(c, ErrCodeRecordNotFound, "幂等键对应的使用记录不存在")
+		return nil, false
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"record_id": record.ID,
+		"user_id":   userID,
+	}).Info("replayed idempotent generation request")
+
+	c.Header("Idempotent-Replayed", "true")
+	c.JSON(http.StatusOK, gin.H{
+		"record_id": record.ID,
+		"status":    record.Status,
+	})
+	return nil, false
+}
+
+// releaseIdempotencyKey 释放幂等键，使客户端可以用同一幂等键重试失败的请求。
+// 客户端断开或请求超时也需释放，因此不受 ctx 取消的影响
+func (h *HTTPHandler) releaseIdempotencyKey(ctx context.Context, claim *entity.DbIdempotencyKey) {
+	if claim == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := h.repo.DeleteIdempotencyKey(ctx, claim.ID); err != nil {
+		logrus.WithError(err).WithField("idempotency_key_id", claim.ID).Warn("failed to release idempotency key")
+	}
+}
+
+// bindIdempotencyKey 将幂等键关联到新建的使用记录。使用记录已创建，即使 ctx 已取消也需关联
+func (h *HTTPHandler) bindIdempotencyKey(ctx context.Context, claim *entity.DbIdempotencyKey, recordID uint) {
+	if claim == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := h.repo.BindIdempotencyKey(ctx, claim.ID, recordID); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"idempotency_key_id": claim.ID,
+			"record_id":          recordID,
+		}).Warn("failed to bind idempotency key")
+	}
+}
+
+// startIdempotencyKeyPurge 启动时及之后每隔 idempotencyPurgeInterval 删除过期的幂等键，随 ctx 取消而停止
+func (h *HTTPHandler) startIdempotencyKeyPurge(ctx context.Context) {
+	if h.repo == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(idempotencyPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			h.purgeExpiredIdempotencyKeys(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// purgeExpiredIdempotencyKeys 删除已过期的幂等键
+func (h *HTTPHandler) purgeExpiredIdempotencyKeys(ctx context.Context) {
+	purgeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	deleted, err := h.repo.DeleteExpiredIdempotencyKeys(purgeCtx, time.Now())
+	if err != nil {
+		logrus.WithError(err).Error("failed to purge expired idempotency keys")
+		return
+	}
+	if deleted > 0 {
+		logrus.WithField("deleted", deleted).Debug("purged expired idempotency keys")
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClaimIdempotencyKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	h := &HTTPHandler{repo: repo}
+
+	claim := func(t *testing.T, key string) (*entity.DbIdempotencyKey, bool, *httptest.ResponseRecorder) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/llm/generate", nil)
+		claimed, ok := h.claimIdempotencyKey(ctx, c, 1, key)
+		return claimed, ok, w
+	}
+
+	t.Run("未携带幂等键", func(t *testing.T) {
+		claimed, ok, _ := claim(t, "")
+		if !ok || claimed != nil {
+			t.Fatalf("expected no claim, got %+v, %v", claimed, ok)
+		}
+	})
+
+	t.Run("首次占用", func(t *testing.T) {
+		claimed, ok, _ := claim(t, "first")
+		if !ok || claimed == nil || claimed.ID == 0 {
+			t.Fatalf("expected key to be claimed, got %+v, %v", claimed, ok)
+		}
+	})
+
+	t.Run("处理中的请求返回冲突", func(t *testing.T) {
+		if _, ok, _ := claim(t, "pending"); !ok {
+			t.Fatal("expected first claim to succeed")
+		}
+		claimed, ok, w := claim(t, "pending")
+		if ok || claimed != nil {
+			t.Fatalf("expected duplicate claim to be rejected, got %+v", claimed)
+		}
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected status %d, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("已完成的请求重放原使用记录", func(t *testing.T) {
+		first, ok, _ := claim(t, "replay")
+		if !ok || first == nil {
+			t.Fatal("expected first claim to succeed")
+		}
+		record := &entity.DbUsageRecord{UserID: 1, ProviderID: "fal", ModelID: "fal-ai/flux", Status: entity.UsageRecordStatusQueued}
+		if err := repo.CreateUsageRecord(ctx, record); err != nil {
+			t.Fatalf("create usage record: %v", err)
+		}
+		h.bindIdempotencyKey(ctx, first, record.ID)
+
+		claimed, ok, w := claim(t, "replay")
+		if ok || claimed != nil {
+			t.Fatalf("expected duplicate claim to be replayed, got %+v", claimed)
+		}
+		if w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "true" {
+			t.Errorf("expected replayed response, got %d %q: %s", w.Code, w.Header().Get("Idempotent-Replayed"), w.Body.String())
+		}
+	})
+
+	t.Run("释放后可重新占用", func(t *testing.T) {
+		first, ok, _ := claim(t, "released")
+		if !ok || first == nil {
+			t.Fatal("expected first claim to succeed")
+		}
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		h.releaseIdempotencyKey(cancelled, first)
+
+		if claimed, ok, w := claim(t, "released"); !ok || claimed == nil {
+			t.Fatalf("expected released key to be claimed again, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("中断请求的占用过期后可重新占用", func(t *testing.T) {
+		stale := &entity.DbIdempotencyKey{
+			UserID:    1,
+			Key:       "interrupted",
+			CreatedAt: time.Now().Add(-2 * idempotencyPendingTTL),
+			ExpiresAt: time.Now().Add(idempotencyKeyTTL),
+		}
+		if _, ok, err := repo.ClaimIdempotencyKey(ctx, stale, idempotencyPendingTTL); err != nil || !ok {
+			t.Fatalf("seed stale claim: %v", err)
+		}
+
+		claimed, ok, w := claim(t, "interrupted")
+		if !ok || claimed == nil || claimed.ID == stale.ID {
+			t.Fatalf("expected stale claim to be replaced, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestPurgeExpiredIdempotencyKeys(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	h := &HTTPHandler{repo: repo}
+
+	seed := func(t *testing.T, key string, expiresAt time.Time) {
+		t.Helper()
+		claim := &entity.DbIdempotencyKey{UserID: 1, Key: key, RecordID: 1, ExpiresAt: expiresAt}
+		if _, ok, err := repo.ClaimIdempotencyKey(ctx, claim, idempotencyPendingTTL); err != nil || !ok {
+			t.Fatalf("seed key %q: %v", key, err)
+		}
+	}
+	seed(t, "expired", time.Now().Add(-time.Hour))
+	seed(t, "live", time.Now().Add(idempotencyKeyTTL))
+
+	h.purgeExpiredIdempotencyKeys(ctx)
+
+	if deleted, err := repo.DeleteExpiredIdempotencyKeys(ctx, time.Now()); err != nil || deleted != 0 {
+		t.Errorf("expected expired keys to be purged, %d left: %v", deleted, err)
+	}
+	if _, claimed, err := repo.ClaimIdempotencyKey(ctx, &entity.DbIdempotencyKey{UserID: 1, Key: "live", ExpiresAt: time.Now().Add(idempotencyKeyTTL)}, idempotencyPendingTTL); err != nil || claimed {
+		t.Errorf("expected unexpired key to be kept, claimed=%v: %v", claimed, err)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"clothing/internal/llm"
 	"clothing/internal/service"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
@@ -47,9 +49,9 @@ func (h *HTTPHandler) StreamGenerationEvents(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	events := make(chan sseMessage, 8)
-	h.registerSSEClient(clientID, events)
-	defer h.unregisterSSEClient(clientID, events)
+	client := newSSEClient()
+	h.registerSSEClient(clientID, client)
+	defer h.unregisterSSEClient(clientID, client)
 
 	c.Writer.Header().Set("Content-Type", "text/event-stream")
 	c.Writer.Header().Set("Cache-Control", "no-cache")
@@ -76,10 +78,13 @@ func (h *HTTPHandler) StreamGenerationEvents(c *gin.Context) {
 				"client_id": clientID,
 			}).Info("generation sse disconnected")
 			return false
+		case <-client.done:
+			// 连接长时间未读取，结束事件无法放入缓冲，已被断开
+			return false
 		case <-heartbeatTicker.C:
 			c.SSEvent("ping", gin.H{"ts": time.Now().UnixMilli()})
 			return true
-		case msg, ok := <-events:
+		case msg, ok := <-client.events:
 			if !ok {
 				return false
 			}
@@ -104,7 +109,12 @@ func (h *HTTPHandler) GenerateContent(c *gin.Context) {
 	}
 
 	request.Prompt = strings.TrimSpace(request.Prompt)
-	if request.Prompt == "" {
+	if request.TemplateID != nil {
+		if request.Prompt != "" {
+			BadRequest(c, ErrCodeInvalidRequest, "prompt 与 template_id 不能同时提供")
+			return
+		}
+	} else if request.Prompt == "" {
 		MissingField(c, "prompt")
 		return
 	}
@@ -131,61 +141,34 @@ func (h *HTTPHandler) GenerateContent(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
-	// 加载并验证服务商
-	dbProvider, err := h.repo.GetProvider(ctx, providerID)
-	if err != nil {
-		logrus.WithError(err).WithFields(logrus.Fields{
-			"provider": providerID,
-		}).Error("failed to load provider from database")
-		NotFound(c, ErrCodeProviderNotFound, "服务商不存在: "+request.ProviderID)
-		return
+	// 使用模板时在服务端渲染最终提示词
+	var promptVariables entity.JSONMap
+	if request.TemplateID != nil {
+		variables, ok := h.renderRequestPromptTemplate(ctx, c, requestUser, &request)
+		if !ok {
+			return
+		}
+		promptVariables = variables
 	}
-	if dbProvider == nil || !dbProvider.IsActive {
-		ErrorResponse(c, http.StatusBadRequest, ErrCodeProviderDisabled, "服务商已禁用: "+request.ProviderID)
+
+	dbModel, service, ok := h.validateGenerationTarget(c, providerID, request.ModelID)
+	if !ok {
 		return
 	}
-
-	// 加载并验证模型
-	dbModel, err := h.repo.GetModel(ctx, providerID, request.ModelID)
-	if err != nil {
-		logrus.WithError(err).WithFields(logrus.Fields{
-			"provider": providerID,
-			"model":    request.ModelID,
-		}).Error("failed to load model from database")
-		NotFound(c, ErrCodeModelNotFound, "模型不存在: "+request.ModelID)
+	if !validateNumOutputs(c, request.Output.NumOutputs, *dbModel) {
 		return
 	}
-	if dbModel == nil || !dbModel.IsActive {
-		ErrorResponse(c, http.StatusBadRequest, ErrCodeModelDisabled, "模型已禁用: "+request.ModelID)
+	if !validateCapabilities(c, service, request, *dbModel) {
 		return
 	}
-
-	// 初始化 LLM 服务
-	llmService, err := llm.GetFactory().Get(dbProvider)
-	if err != nil {
-		logrus.WithError(err).WithFields(logrus.Fields{
-			"provider": providerID,
-		}).Error("failed to initialise provider service")
-		ErrorResponse(c, http.StatusBadRequest, ErrCodeProviderUnavailable, "服务商暂时不可用: "+request.ProviderID)
+	if !validateAdvancedParams(c, &request.Advanced) {
 		return
 	}
 
 	// 验证标签
 	tagIDs := deduplicatePositiveIDs(request.TagIDs)
-	if len(tagIDs) > 0 {
-		validateCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
-		defer cancel()
-
-		tags, err := h.repo.FindTagsByIDs(validateCtx, tagIDs)
-		if err != nil {
-			logrus.WithError(err).Error("failed to validate tags")
-			InternalError(c, "验证标签失败")
-			return
-		}
-		if len(tags) != len(tagIDs) {
-			BadRequest(c, ErrCodeInvalidTag, "部分标签不存在")
-			return
-		}
+	if !h.validateTagIDs(c, tagIDs) {
+		return
 	}
 
 	// 创建使用记录
@@ -193,55 +176,209 @@ func (h *HTTPHandler) GenerateContent(c *gin.Context) {
 	createCtx, cancelCreate := context.WithTimeout(ctx, 5*time.Second)
 	defer cancelCreate()
 
+	// 幂等键已被占用时直接返回原使用记录，不再重复生成
+	idempotencyClaim, ok := h.claimIdempotencyKey(createCtx, c, userID, idempotencyKeyFromRequest(c, request.IdempotencyKey))
+	if !ok {
+		return
+	}
+
 	record := entity.DbUsageRecord{
 		UserID:     userID,
 		ProviderID: providerID,
 		ModelID:    request.ModelID,
 		Prompt:     request.Prompt,
 		Size:       request.Output.Size,
-	}
+		NumOutputs: request.GetNumOutputs(),
+		Status:     entity.UsageRecordStatusQueued,
 
-	if err := h.repo.CreateUsageRecord(createCtx, &record); err != nil {
-		logrus.WithError(err).WithFields(logrus.Fields{
-			"provider": providerID,
-			"model":    request.ModelID,
-			"user_id":  userID,
-		}).Error("failed to create usage record")
-		InternalError(c, "创建使用记录失败")
-		return
+		PromptTemplateID: request.TemplateID,
+		PromptVariables:  promptVariables,
 	}
 
-	// 关联标签
-	if len(tagIDs) > 0 {
-		if err := h.repo.SetUsageRecordTags(createCtx, record.ID, tagIDs); err != nil {
-			logrus.WithError(err).WithFields(logrus.Fields{
-				"record_id": record.ID,
-			}).Warn("failed to set tags for usage record")
+	h.queueGeneration(createCtx, c, &record, *dbModel, request, tagIDs, idempotencyClaim)
+}
+
+// queueGeneration 在同一事务中创建使用记录、预留额度、关联标签并写入生成任务队列，成功时返回 202，失败时写入错误响应
+func (h *HTTPHandler) queueGeneration(ctx context.Context, c *gin.Context, record *entity.DbUsageRecord, dbModel entity.DbModel, request entity.GenerateContentRequest, tagIDs []uint, idempotencyClaim *entity.DbIdempotencyKey) {
+	// 按预估费用预留额度，余额不足或超出配额时不创建使用记录
+	estimate := service.EstimateGenerationCost(dbModel, request)
+	if err := h.generationService.EnqueueGeneration(ctx, record, request, generationPriority(CurrentUser(c)), estimate, tagIDs); err != nil {
+		h.releaseIdempotencyKey(ctx, idempotencyClaim)
+		if respondCreditReservationError(c, err, estimate) {
+			return
 		}
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"provider": record.ProviderID,
+			"model":    record.ModelID,
+			"user_id":  record.UserID,
+		}).Error("failed to queue generation")
+		InternalError(c, "创建生成任务失败")
+		return
 	}
+	h.bindIdempotencyKey(ctx, idempotencyClaim, record.ID)
 
 	logrus.WithFields(logrus.Fields{
 		"record_id": record.ID,
-		"provider":  providerID,
-		"model":     request.ModelID,
-		"user_id":   userID,
+		"provider":  record.ProviderID,
+		"model":     record.ModelID,
+		"user_id":   record.UserID,
 	}).Info("queued generation task")
 
-	// 使用 Service 层异步处理生成任务
-	h.generationService.GenerateContentAsync(service.GenerateContentRequest{
-		Record:   record,
-		Request:  request,
-		Model:    *dbModel,
-		Service:  llmService,
-		ClientID: request.ClientID,
-	})
-
 	c.JSON(http.StatusAccepted, gin.H{
 		"record_id": record.ID,
-		"status":    "processing",
+		"status":    record.Status,
 	})
 }
 
+// validateGenerationTarget 校验服务商与模型存在且可用，失败时写入错误响应。
+// 服务商不可用但配置了故障转移链时返回的 service 为 nil。
+func (h *HTTPHandler) validateGenerationTarget(c *gin.Context, providerID, modelID string) (*entity.DbModel, llm.AIService, bool) {
+	ctx := c.Request.Context()
+
+	// 加载并验证服务商
+	dbProvider, err := h.repo.GetProvider(ctx, providerID)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"provider": providerID,
+		}).Error("failed to load provider from database")
+		NotFound(c, ErrCodeProviderNotFound, "服务商不存在: "+providerID)
+		return nil, nil, false
+	}
+	if dbProvider == nil || !dbProvider.IsActive {
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeProviderDisabled, "服务商已禁用: "+providerID)
+		return nil, nil, false
+	}
+
+	// 加载并验证模型
+	dbModel, err := h.repo.GetModel(ctx, providerID, modelID)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"provider": providerID,
+			"model":    modelID,
+		}).Error("failed to load model from database")
+		NotFound(c, ErrCodeModelNotFound, "模型不存在: "+modelID)
+		return nil, nil, false
+	}
+	if dbModel == nil || !dbModel.IsActive {
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeModelDisabled, "模型已禁用: "+modelID)
+		return nil, nil, false
+	}
+
+	// 提前校验服务商可用，避免无效任务入队；配置了故障转移链时交由备用模型处理
+	service, err := llm.GetFactory().Get(dbProvider)
+	if err != nil {
+		if _, chainErr := h.repo.FindActiveFailoverChain(ctx, providerID, modelID); chainErr != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"provider": providerID,
+			}).Error("failed to initialise provider service")
+			ErrorResponse(c, http.StatusBadRequest, ErrCodeProviderUnavailable, "服务商暂时不可用: "+providerID)
+			return nil, nil, false
+		}
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"provider": providerID,
+			"model":    modelID,
+		}).Warn("provider unavailable, generation will use failover chain")
+	}
+	return dbModel, service, true
+}
+
+// validateNumOutputs 校验请求的输出数量不超过模型上限，失败时写入错误响应
+func validateNumOutputs(c *gin.Context, numOutputs int, dbModel entity.DbModel) bool {
+	if numOutputs < 0 {
+		BadRequest(c, ErrCodeInvalidRequest, "num_outputs 不能为负数")
+		return false
+	}
+	if limit := llm.MaxOutputs(dbModel); numOutputs > limit {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, ErrCodeTooManyOutputs,
+			fmt.Sprintf("模型 %s 单次最多生成 %d 个输出", dbModel.ModelID, limit),
+			gin.H{"requested": numOutputs, "max": limit})
+		return false
+	}
+	return true
+}
+
+// validateAdvancedParams 规范化并校验高级参数的取值范围，失败时写入错误响应。
+// 模型不支持的参数不在此报错，由生成时剔除。
+func validateAdvancedParams(c *gin.Context, params *entity.AdvancedParams) bool {
+	params.NegativePrompt = strings.TrimSpace(params.NegativePrompt)
+	if params.Seed != nil && *params.Seed < 0 {
+		BadRequest(c, ErrCodeInvalidRequest, "seed 不能为负数")
+		return false
+	}
+	if params.GuidanceScale != nil && (*params.GuidanceScale < 0 || *params.GuidanceScale > 30) {
+		BadRequest(c, ErrCodeInvalidRequest, "guidance_scale 必须在 0-30 之间")
+		return false
+	}
+	if params.Steps != nil && (*params.Steps < 1 || *params.Steps > 150) {
+		BadRequest(c, ErrCodeInvalidRequest, "steps 必须在 1-150 之间")
+		return false
+	}
+	if params.Strength != nil && (*params.Strength < 0 || *params.Strength > 1) {
+		BadRequest(c, ErrCodeInvalidRequest, "strength 必须在 0-1 之间")
+		return false
+	}
+	return true
+}
+
+// capabilityErrorCodes 能力校验失败类型对应的错误码
+var capabilityErrorCodes = map[string]string{
+	llm.CapabilityUnsupportedSize:     ErrCodeUnsupportedSize,
+	llm.CapabilityUnsupportedDuration: ErrCodeUnsupportedDuration,
+	llm.CapabilityTooManyImages:       ErrCodeTooManyImages,
+	llm.CapabilityUnsupportedModality: ErrCodeUnsupportedModality,
+}
+
+// capabilityErrorMessages 能力校验失败类型对应的错误信息
+var capabilityErrorMessages = map[string]string{
+	llm.CapabilityUnsupportedSize:     "模型不支持该尺寸",
+	llm.CapabilityUnsupportedDuration: "模型不支持该时长",
+	llm.CapabilityTooManyImages:       "输入图片数量超过模型上限",
+	llm.CapabilityUnsupportedModality: "模型不支持该输入类型",
+}
+
+// validateCapabilities 在创建使用记录前按模型能力校验请求，失败时写入错误响应
+func validateCapabilities(c *gin.Context, service llm.AIService, request entity.GenerateContentRequest, dbModel entity.DbModel) bool {
+	err := llm.CheckCapabilities(request, llm.ServiceCapabilities(service, dbModel))
+
+	var capErr *llm.CapabilityError
+	if errors.As(err, &capErr) {
+		ErrorResponseWithDetails(c, http.StatusBadRequest, capabilityErrorCodes[capErr.Violation],
+			fmt.Sprintf("%s: %v", capabilityErrorMessages[capErr.Violation], capErr.Requested),
+			gin.H{"model": dbModel.ModelID, "requested": capErr.Requested, "allowed": capErr.Allowed})
+		return false
+	}
+
+	if service != nil {
+		if err := service.Validate(request, dbModel); err != nil {
+			BadRequest(c, ErrCodeInvalidRequest, err.Error())
+			return false
+		}
+	}
+	return true
+}
+
+// validateTagIDs 校验标签均存在，失败时写入错误响应
+func (h *HTTPHandler) validateTagIDs(c *gin.Context, tagIDs []uint) bool {
+	if len(tagIDs) == 0 {
+		return true
+	}
+
+	validateCtx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+
+	tags, err := h.repo.FindTagsByIDs(validateCtx, tagIDs)
+	if err != nil {
+		logrus.WithError(err).Error("failed to validate tags")
+		InternalError(c, "验证标签失败")
+		return false
+	}
+	if len(tags) != len(tagIDs) {
+		BadRequest(c, ErrCodeInvalidTag, "部分标签不存在")
+		return false
+	}
+	return true
+}
+
 // deduplicatePositiveIDs 去重正整数 ID 列表
 func deduplicatePositiveIDs(values []uint) []uint {
 	if len(values) == 0 {
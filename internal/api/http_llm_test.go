@@ -0,0 +1,65 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateCapabilities(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	model := entity.DbModel{
+		ModelID:            "wan2.5-t2v",
+		InputModalities:    []string{"text", "image"},
+		SupportedSizes:     []string{"1280x720"},
+		SupportedDurations: []int{5, 10},
+	}
+
+	tests := []struct {
+		name         string
+		output       entity.OutputConfig
+		expectedOK   bool
+		expectedCode string
+	}{
+		{name: "通过校验", output: entity.OutputConfig{Size: "1280x720", Duration: 5}, expectedOK: true},
+		{name: "尺寸不支持", output: entity.OutputConfig{Size: "1920x1080"}, expectedCode: ErrCodeUnsupportedSize},
+		{name: "时长不支持", output: entity.OutputConfig{Duration: 8}, expectedCode: ErrCodeUnsupportedDuration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			request := entity.GenerateContentRequest{Prompt: "a red dress", Output: tt.output}
+			if ok := validateCapabilities(c, nil, request, model); ok != tt.expectedOK {
+				t.Fatalf("expected ok %v, got %v", tt.expectedOK, ok)
+			}
+			if tt.expectedOK {
+				return
+			}
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+			var resp struct {
+				Code    string         `json:"code"`
+				Details map[string]any `json:"details"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp.Code != tt.expectedCode {
+				t.Errorf("expected code %s, got %s", tt.expectedCode, resp.Code)
+			}
+			if _, ok := resp.Details["allowed"]; !ok {
+				t.Errorf("expected allowed values in details, got %v", resp.Details)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/llm"
+	"clothing/internal/service"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// modelCatalogTimeout 拉取上游目录可能需要分页请求，超时比普通管理接口更长
+const modelCatalogTimeout = 2 * time.Minute
+
+// PreviewProviderCatalog 拉取服务商上游模型目录，返回与已保存模型的差异（新建、更新、停用），不做修改。
+// fields 查询参数（可重复或逗号分隔）指定更新比较的字段，与同步时的 fields 一致
+func (h *HTTPHandler) PreviewProviderCatalog(c *gin.Context) {
+	provider, ok := h.loadAdminProvider(c)
+	if !ok {
+		return
+	}
+
+	fields := make([]string, 0)
+	for _, value := range c.QueryArray("fields") {
+		fields = append(fields, strings.Split(value, ",")...)
+	}
+	if !validateCatalogFields(c, fields) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), modelCatalogTimeout)
+	defer cancel()
+
+	diff, err := h.modelCatalog.Preview(ctx, provider, fields)
+	if err != nil {
+		respondCatalogError(c, provider.ID, err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// SyncProviderCatalog 重新拉取服务商上游模型目录并应用所选差异，默认只新建与更新，停用需显式指定
+func (h *HTTPHandler) SyncProviderCatalog(c *gin.Context) {
+	provider, ok := h.loadAdminProvider(c)
+	if !ok {
+		return
+	}
+
+	var payload entity.ModelCatalogSyncRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			InvalidPayload(c)
+			return
+		}
+	}
+	for _, action := range payload.Actions {
+		if !service.IsCatalogAction(strings.TrimSpace(action)) {
+			BadRequest(c, ErrCodeInvalidRequest, "无效的同步动作: "+action)
+			return
+		}
+	}
+	if !validateCatalogFields(c, payload.Fields) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), modelCatalogTimeout)
+	defer cancel()
+
+	result, err := h.modelCatalog.Apply(ctx, provider, payload.Actions, payload.ModelIDs, payload.Fields)
+	if err != nil {
+		respondCatalogError(c, provider.ID, err)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"provider_id": provider.ID,
+		"applied":     len(result.Applied),
+		"failed":      len(result.Failed),
+	}).Info("provider model catalog synced")
+	c.JSON(http.StatusOK, result)
+}
+
+// validateCatalogFields 校验目录更新字段，空值忽略
+func validateCatalogFields(c *gin.Context, fields []string) bool {
+	for _, field := range fields {
+		if trimmed := strings.TrimSpace(field); trimmed != "" && !service.IsCatalogField(trimmed) {
+			BadRequest(c, ErrCodeInvalidRequest, "无效的更新字段: "+field)
+			return false
+		}
+	}
+	return true
+}
+
+// respondCatalogError 将目录同步错误转换为响应：驱动不支持为 400，上游请求失败为 502
+func respondCatalogError(c *gin.Context, providerID string, err error) {
+	switch {
+	case errors.Is(err, llm.ErrCatalogUnsupported):
+		BadRequest(c, ErrCodeCatalogUnsupported, "该服务商驱动不支持同步模型目录")
+	case errors.Is(err, service.ErrCatalogFetchFailed):
+		logrus.WithError(err).WithField("provider_id", providerID).Warn("failed to fetch provider model catalog")
+		ErrorResponseWithDetails(c, http.StatusBadGateway, ErrCodeProviderUnavailable, "拉取模型目录失败", gin.H{"error": err.Error()})
+	default:
+		logrus.WithError(err).WithField("provider_id", providerID).Error("failed to sync provider model catalog")
+		InternalError(c, "同步模型目录失败")
+	}
+}
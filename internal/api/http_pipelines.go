@@ -0,0 +1,445 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/entity/converter"
+	"clothing/internal/service"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ListPipelines 列出当前用户的流水线（管理员可查看全部）
+func (h *HTTPHandler) ListPipelines(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	pipelines, err := h.repo.ListPipelines(ctx, requestUser.ID, requestUser.IsAdmin())
+	if err != nil {
+		logrus.WithError(err).Error("failed to list pipelines")
+		InternalError(c, "加载流水线失败")
+		return
+	}
+
+	items := make([]entity.Pipeline, 0, len(pipelines))
+	for i := range pipelines {
+		items = append(items, converter.PipelineToDTO(&pipelines[i]))
+	}
+	c.JSON(http.StatusOK, entity.PipelineListResponse{Pipelines: items})
+}
+
+// GetPipeline 查看流水线
+func (h *HTTPHandler) GetPipeline(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	pipeline, ok := h.loadOwnedPipeline(ctx, c, requestUser)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, entity.PipelineDetailResponse{Pipeline: converter.PipelineToDTO(pipeline)})
+}
+
+// CreatePipeline 创建流水线
+func (h *HTTPHandler) CreatePipeline(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	var payload entity.CreatePipelineRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		InvalidPayload(c)
+		return
+	}
+
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		MissingField(c, "name")
+		return
+	}
+	steps, ok := h.validatePipelineSteps(c, payload.Steps)
+	if !ok {
+		return
+	}
+
+	pipeline := &entity.DbPipeline{
+		UserID:      requestUser.ID,
+		Name:        name,
+		Description: strings.TrimSpace(payload.Description),
+		Steps:       steps,
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.repo.CreatePipeline(ctx, pipeline); err != nil {
+		logrus.WithError(err).WithField("user_id", requestUser.ID).Error("failed to create pipeline")
+		InternalError(c, "创建流水线失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, entity.PipelineDetailResponse{Pipeline: converter.PipelineToDTO(pipeline)})
+}
+
+// UpdatePipeline 更新流水线，已开始的运行不受影响
+func (h *HTTPHandler) UpdatePipeline(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	var payload entity.UpdatePipelineRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		InvalidPayload(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	pipeline, ok := h.loadOwnedPipeline(ctx, c, requestUser)
+	if !ok {
+		return
+	}
+
+	var updates entity.PipelineUpdates
+	if payload.Name != nil {
+		name := strings.TrimSpace(*payload.Name)
+		if name == "" {
+			MissingField(c, "name")
+			return
+		}
+		updates.Name = &name
+	}
+	if payload.Description != nil {
+		description := strings.TrimSpace(*payload.Description)
+		updates.Description = &description
+	}
+	if payload.Steps != nil {
+		steps, ok := h.validatePipelineSteps(c, *payload.Steps)
+		if !ok {
+			return
+		}
+		updates.Steps = &steps
+	}
+
+	if !updates.IsEmpty() {
+		if err := h.repo.UpdatePipeline(ctx, pipeline.ID, updates); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				NotFound(c, ErrCodePipelineNotFound, "流水线不存在")
+				return
+			}
+			logrus.WithError(err).WithField("pipeline_id", pipeline.ID).Error("failed to update pipeline")
+			InternalError(c, "更新流水线失败")
+			return
+		}
+	}
+
+	updated, err := h.repo.GetPipeline(ctx, pipeline.ID)
+	if err != nil {
+		logrus.WithError(err).WithField("pipeline_id", pipeline.ID).Error("failed to reload pipeline")
+		InternalError(c, "加载流水线失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.PipelineDetailResponse{Pipeline: converter.PipelineToDTO(updated)})
+}
+
+// DeletePipeline 删除流水线，已有运行及其使用记录保留
+func (h *HTTPHandler) DeletePipeline(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	pipeline, ok := h.loadOwnedPipeline(ctx, c, requestUser)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeletePipeline(ctx, pipeline.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodePipelineNotFound, "流水线不存在")
+			return
+		}
+		logrus.WithError(err).WithField("pipeline_id", pipeline.ID).Error("failed to delete pipeline")
+		InternalError(c, "删除流水线失败")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RunPipeline 启动流水线运行，各步骤依次通过生成任务队列执行
+func (h *HTTPHandler) RunPipeline(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	var payload entity.RunPipelineRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		InvalidPayload(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	pipeline, ok := h.loadOwnedPipeline(ctx, c, requestUser)
+	if !ok {
+		return
+	}
+
+	variables := make(map[string]string, len(payload.Variables))
+	for name, value := range payload.Variables {
+		variables[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	inputs := make([]entity.MediaInput, 0, len(payload.InputMedia))
+	for _, media := range payload.InputMedia {
+		if strings.TrimSpace(media.Content) != "" {
+			inputs = append(inputs, media)
+		}
+	}
+	if err := service.ValidatePipelineRun(pipeline.Steps, variables, inputs); err != nil {
+		BadRequest(c, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	run, err := h.generationService.StartPipelineRun(ctx, *pipeline, requestUser.ID, strings.TrimSpace(payload.ClientID), variables, inputs)
+	if err != nil {
+		// 第一步的预估费用由服务计算，响应中不含所需额度
+		if respondCreditReservationError(c, err, 0) {
+			return
+		}
+		logrus.WithError(err).WithField("pipeline_id", pipeline.ID).Error("failed to start pipeline run")
+		ErrorResponse(c, http.StatusBadRequest, ErrCodeGenerationFailed, "启动流水线失败: "+err.Error())
+		return
+	}
+
+	records, err := h.repo.ListPipelineRunRecords(ctx, run.ID)
+	if err != nil {
+		logrus.WithError(err).WithField("run_id", run.ID).Warn("failed to load pipeline run records")
+	}
+	c.JSON(http.StatusAccepted, entity.PipelineRunDetailResponse{Run: h.makePipelineRun(*run, records)})
+}
+
+// GetPipelineRun 查看流水线运行及各步骤的使用记录
+func (h *HTTPHandler) GetPipelineRun(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	run, ok := h.loadOwnedPipelineRun(ctx, c, requestUser)
+	if !ok {
+		return
+	}
+
+	records, err := h.repo.ListPipelineRunRecords(ctx, run.ID)
+	if err != nil {
+		logrus.WithError(err).WithField("run_id", run.ID).Error("failed to load pipeline run records")
+		InternalError(c, "加载流水线运行失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.PipelineRunDetailResponse{Run: h.makePipelineRun(*run, records)})
+}
+
+// CancelPipelineRun 取消流水线运行：不再启动后续步骤，并尝试取消当前步骤的生成
+func (h *HTTPHandler) CancelPipelineRun(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	run, ok := h.loadOwnedPipelineRun(ctx, c, requestUser)
+	if !ok {
+		return
+	}
+	if run.Status != entity.PipelineRunStatusRunning || !h.generationService.CancelPipelineRun(run.ID) {
+		Conflict(c, ErrCodeNotCancellable, "流水线运行已结束，无法取消")
+		return
+	}
+
+	records, err := h.repo.ListPipelineRunRecords(ctx, run.ID)
+	if err != nil {
+		logrus.WithError(err).WithField("run_id", run.ID).Warn("failed to load pipeline run records for cancellation")
+	}
+	for i := range records {
+		record := &records[i]
+		if record.Status != entity.UsageRecordStatusQueued && record.Status != entity.UsageRecordStatusRunning {
+			continue
+		}
+		// 模型不支持取消执行中的生成时，当前步骤会继续完成，但不再进入下一步
+		if _, err := h.generationService.CancelGeneration(ctx, record.ID, h.supportsCancel(ctx, record)); err != nil &&
+			!errors.Is(err, service.ErrCancelNotSupported) && !errors.Is(err, service.ErrGenerationNotActive) {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"run_id":    run.ID,
+				"record_id": record.ID,
+			}).Warn("failed to cancel pipeline step")
+		}
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"run_id": run.ID,
+		"status": entity.PipelineRunStatusCancelled,
+	})
+}
+
+// validatePipelineSteps 校验步骤结构及每一步的服务商/模型，失败时写入错误响应
+func (h *HTTPHandler) validatePipelineSteps(c *gin.Context, values []entity.PipelineStepConfig) (entity.PipelineSteps, bool) {
+	steps, err := service.NormalisePipelineSteps(converter.PipelineStepsFromDTOs(values))
+	if err != nil {
+		BadRequest(c, ErrCodeInvalidRequest, err.Error())
+		return nil, false
+	}
+	for _, step := range steps {
+		dbModel, _, ok := h.validateGenerationTarget(c, step.ProviderID, step.ModelID)
+		if !ok {
+			return nil, false
+		}
+		if !validateNumOutputs(c, step.NumOutputs, *dbModel) {
+			return nil, false
+		}
+	}
+	return steps, true
+}
+
+// loadOwnedPipeline 加载路径参数指定的流水线，非管理员只能访问自己的流水线
+func (h *HTTPHandler) loadOwnedPipeline(ctx context.Context, c *gin.Context, requestUser *RequestUser) (*entity.DbPipeline, bool) {
+	rawID := strings.TrimSpace(c.Param("id"))
+	pipelineID, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil || pipelineID == 0 {
+		BadRequest(c, ErrCodeInvalidRequest, "无效的流水线 ID")
+		return nil, false
+	}
+
+	pipeline, err := h.repo.GetPipeline(ctx, uint(pipelineID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodePipelineNotFound, "流水线不存在")
+			return nil, false
+		}
+		logrus.WithError(err).WithField("pipeline_id", pipelineID).Error("failed to load pipeline")
+		InternalError(c, "加载流水线失败")
+		return nil, false
+	}
+	if !requestUser.IsAdmin() && pipeline.UserID != requestUser.ID {
+		NotFound(c, ErrCodePipelineNotFound, "流水线不存在")
+		return nil, false
+	}
+	return pipeline, true
+}
+
+// loadOwnedPipelineRun 加载路径参数指定的流水线运行，非管理员只能访问自己的运行
+func (h *HTTPHandler) loadOwnedPipelineRun(ctx context.Context, c *gin.Context, requestUser *RequestUser) (*entity.DbPipelineRun, bool) {
+	rawID := strings.TrimSpace(c.Param("id"))
+	runID, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil || runID == 0 {
+		BadRequest(c, ErrCodeInvalidRequest, "无效的流水线运行 ID")
+		return nil, false
+	}
+
+	run, err := h.repo.GetPipelineRun(ctx, uint(runID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodePipelineRunNotFound, "流水线运行不存在")
+			return nil, false
+		}
+		logrus.WithError(err).WithField("run_id", runID).Error("failed to load pipeline run")
+		InternalError(c, "加载流水线运行失败")
+		return nil, false
+	}
+	if !requestUser.IsAdmin() && run.UserID != requestUser.ID {
+		NotFound(c, ErrCodePipelineRunNotFound, "流水线运行不存在")
+		return nil, false
+	}
+	return run, true
+}
+
+// makePipelineRun 构造流水线运行响应
+func (h *HTTPHandler) makePipelineRun(run entity.DbPipelineRun, records []entity.DbUsageRecord) entity.PipelineRun {
+	items := make([]entity.UsageRecordItem, 0, len(records))
+	for _, record := range records {
+		items = append(items, h.makeUsageRecordItem(record))
+	}
+	return entity.PipelineRun{
+		ID:           run.ID,
+		PipelineID:   run.PipelineID,
+		Status:       run.Status,
+		CurrentStep:  run.CurrentStep,
+		TotalSteps:   len(run.Steps),
+		ErrorMessage: run.ErrorMessage,
+		Records:      items,
+		CreatedAt:    run.CreatedAt,
+		FinishedAt:   run.FinishedAt,
+	}
+}
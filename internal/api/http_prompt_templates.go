@@ -0,0 +1,332 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/entity/converter"
+	"clothing/internal/service"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ListPromptTemplates 列出当前用户可用的提示词模板（自己的与共享的，管理员可查看全部）
+func (h *HTTPHandler) ListPromptTemplates(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	templates, err := h.repo.ListPromptTemplates(ctx, requestUser.ID, requestUser.IsAdmin())
+	if err != nil {
+		logrus.WithError(err).Error("failed to list prompt templates")
+		InternalError(c, "加载提示词模板失败")
+		return
+	}
+
+	items := make([]entity.PromptTemplate, 0, len(templates))
+	for i := range templates {
+		items = append(items, converter.PromptTemplateToDTO(&templates[i]))
+	}
+	c.JSON(http.StatusOK, entity.PromptTemplateListResponse{Templates: items})
+}
+
+// GetPromptTemplate 查看提示词模板
+func (h *HTTPHandler) GetPromptTemplate(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	template, ok := h.loadPromptTemplateParam(ctx, c, requestUser, false)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, entity.PromptTemplateDetailResponse{Template: converter.PromptTemplateToDTO(template)})
+}
+
+// CreatePromptTemplate 创建提示词模板
+func (h *HTTPHandler) CreatePromptTemplate(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	var payload entity.CreatePromptTemplateRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		InvalidPayload(c)
+		return
+	}
+
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		MissingField(c, "name")
+		return
+	}
+	text := strings.TrimSpace(payload.Template)
+	if text == "" {
+		MissingField(c, "template")
+		return
+	}
+	variables, err := service.NormalisePromptTemplateVariables(text, promptTemplateVariablesFromDTO(payload.Variables))
+	if err != nil {
+		BadRequest(c, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	template := &entity.DbPromptTemplate{
+		UserID:      requestUser.ID,
+		Name:        name,
+		Description: strings.TrimSpace(payload.Description),
+		Template:    text,
+		Variables:   variables,
+		Shared:      payload.Shared,
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.repo.CreatePromptTemplate(ctx, template); err != nil {
+		logrus.WithError(err).WithField("user_id", requestUser.ID).Error("failed to create prompt template")
+		InternalError(c, "创建提示词模板失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, entity.PromptTemplateDetailResponse{Template: converter.PromptTemplateToDTO(template)})
+}
+
+// UpdatePromptTemplate 更新提示词模板，仅创建者与管理员可修改
+func (h *HTTPHandler) UpdatePromptTemplate(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	var payload entity.UpdatePromptTemplateRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		InvalidPayload(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	template, ok := h.loadPromptTemplateParam(ctx, c, requestUser, true)
+	if !ok {
+		return
+	}
+
+	var updates entity.PromptTemplateUpdates
+	if payload.Name != nil {
+		name := strings.TrimSpace(*payload.Name)
+		if name == "" {
+			MissingField(c, "name")
+			return
+		}
+		updates.Name = &name
+	}
+	if payload.Description != nil {
+		description := strings.TrimSpace(*payload.Description)
+		updates.Description = &description
+	}
+	if payload.Shared != nil {
+		updates.Shared = payload.Shared
+	}
+
+	// 模板正文或变量定义变化时需要重新校验两者
+	if payload.Template != nil || payload.Variables != nil {
+		text := template.Template
+		if payload.Template != nil {
+			text = strings.TrimSpace(*payload.Template)
+			if text == "" {
+				MissingField(c, "template")
+				return
+			}
+		}
+		variables := template.Variables
+		if payload.Variables != nil {
+			variables = promptTemplateVariablesFromDTO(*payload.Variables)
+		}
+		normalised, err := service.NormalisePromptTemplateVariables(text, variables)
+		if err != nil {
+			BadRequest(c, ErrCodeInvalidRequest, err.Error())
+			return
+		}
+		updates.Template = &text
+		updates.Variables = &normalised
+	}
+
+	if !updates.IsEmpty() {
+		if err := h.repo.UpdatePromptTemplate(ctx, template.ID, updates); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				NotFound(c, ErrCodePromptTemplateNotFound, "提示词模板不存在")
+				return
+			}
+			logrus.WithError(err).WithField("template_id", template.ID).Error("failed to update prompt template")
+			InternalError(c, "更新提示词模板失败")
+			return
+		}
+	}
+
+	updated, err := h.repo.GetPromptTemplate(ctx, template.ID)
+	if err != nil {
+		logrus.WithError(err).WithField("template_id", template.ID).Error("failed to reload prompt template")
+		InternalError(c, "加载提示词模板失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.PromptTemplateDetailResponse{Template: converter.PromptTemplateToDTO(updated)})
+}
+
+// DeletePromptTemplate 删除提示词模板，已有使用记录保留渲染后的提示词
+func (h *HTTPHandler) DeletePromptTemplate(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	template, ok := h.loadPromptTemplateParam(ctx, c, requestUser, true)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeletePromptTemplate(ctx, template.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodePromptTemplateNotFound, "提示词模板不存在")
+			return
+		}
+		logrus.WithError(err).WithField("template_id", template.ID).Error("failed to delete prompt template")
+		InternalError(c, "删除提示词模板失败")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// loadPromptTemplateParam 加载路径参数指定的模板
+func (h *HTTPHandler) loadPromptTemplateParam(ctx context.Context, c *gin.Context, requestUser *RequestUser, modify bool) (*entity.DbPromptTemplate, bool) {
+	rawID := strings.TrimSpace(c.Param("id"))
+	templateID, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil || templateID == 0 {
+		BadRequest(c, ErrCodeInvalidRequest, "无效的提示词模板 ID")
+		return nil, false
+	}
+	return h.loadPromptTemplate(ctx, c, requestUser, uint(templateID), modify)
+}
+
+// loadPromptTemplate 加载模板并检查权限：共享模板所有用户可用，修改仅限创建者与管理员
+func (h *HTTPHandler) loadPromptTemplate(ctx context.Context, c *gin.Context, requestUser *RequestUser, templateID uint, modify bool) (*entity.DbPromptTemplate, bool) {
+	template, err := h.repo.GetPromptTemplate(ctx, templateID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodePromptTemplateNotFound, "提示词模板不存在")
+			return nil, false
+		}
+		logrus.WithError(err).WithField("template_id", templateID).Error("failed to load prompt template")
+		InternalError(c, "加载提示词模板失败")
+		return nil, false
+	}
+
+	owned := requestUser.IsAdmin() || template.UserID == requestUser.ID
+	if !owned && !template.Shared {
+		NotFound(c, ErrCodePromptTemplateNotFound, "提示词模板不存在")
+		return nil, false
+	}
+	if modify && !owned {
+		Forbidden(c, "只能修改自己创建的提示词模板")
+		return nil, false
+	}
+	return template, true
+}
+
+// renderRequestPromptTemplate 使用请求指定的模板与变量渲染提示词，返回实际使用的变量
+func (h *HTTPHandler) renderRequestPromptTemplate(ctx context.Context, c *gin.Context, requestUser *RequestUser, request *entity.GenerateContentRequest) (entity.JSONMap, bool) {
+	if *request.TemplateID == 0 {
+		BadRequest(c, ErrCodeInvalidRequest, "无效的提示词模板 ID")
+		return nil, false
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	template, ok := h.loadPromptTemplate(loadCtx, c, requestUser, *request.TemplateID, false)
+	if !ok {
+		return nil, false
+	}
+
+	values := make(map[string]string, len(request.Variables))
+	for name, value := range request.Variables {
+		values[strings.TrimSpace(name)] = value
+	}
+	prompt, resolved, err := service.ResolvePromptTemplate(template, values)
+	if err != nil {
+		BadRequest(c, ErrCodeInvalidRequest, err.Error())
+		return nil, false
+	}
+	if prompt == "" {
+		BadRequest(c, ErrCodeInvalidRequest, "模板渲染后的提示词为空")
+		return nil, false
+	}
+
+	request.Prompt = prompt
+	variables := make(entity.JSONMap, len(resolved))
+	for name, value := range resolved {
+		variables[name] = value
+	}
+	return variables, true
+}
+
+// promptTemplateVariablesFromDTO 将请求中的变量定义转换为存储格式
+func promptTemplateVariablesFromDTO(values []entity.PromptVariable) entity.PromptTemplateVariables {
+	variables := make(entity.PromptTemplateVariables, 0, len(values))
+	for _, v := range values {
+		variables = append(variables, entity.PromptTemplateVariable{
+			Name:        v.Name,
+			Type:        v.Type,
+			Description: v.Description,
+			Default:     v.Default,
+			Choices:     v.Choices,
+		})
+	}
+	return variables
+}
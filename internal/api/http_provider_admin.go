@@ -2,6 +2,8 @@ package api
 
 import (
 	"clothing/internal/entity"
+	"clothing/internal/entity/converter"
+	"clothing/internal/llm"
 	"errors"
 	"net/http"
 	"regexp"
@@ -67,7 +69,9 @@ func (h *HTTPHandler) AdminListProviders(c *gin.Context) {
 
 	views := make([]entity.ProviderAdminView, 0, len(providers))
 	for _, provider := range providers {
-		views = append(views, entity.ProviderToAdminView(provider, true))
+		view := entity.ProviderToAdminView(provider, true)
+		h.attachProviderHealth(ctx, &view)
+		views = append(views, view)
 	}
 	c.JSON(http.StatusOK, gin.H{"providers": views})
 }
@@ -126,6 +130,8 @@ func (h *HTTPHandler) CreateProvider(c *gin.Context) {
 		InternalError(c, "创建服务商失败: "+err.Error())
 		return
 	}
+	// 同 ID 的服务商可能刚被删除，丢弃残留的缓存实例
+	llm.GetFactory().Invalidate(id)
 
 	c.JSON(http.StatusCreated, gin.H{"provider": entity.ProviderToAdminView(*provider, false)})
 }
@@ -154,7 +160,9 @@ func (h *HTTPHandler) GetProviderDetail(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"provider": entity.ProviderToAdminView(*provider, true)})
+	view := entity.ProviderToAdminView(*provider, true)
+	h.attachProviderHealth(ctx, &view)
+	c.JSON(http.StatusOK, gin.H{"provider": view})
 }
 
 func (h *HTTPHandler) UpdateProvider(c *gin.Context) {
@@ -224,6 +232,8 @@ func (h *HTTPHandler) UpdateProvider(c *gin.Context) {
 		InternalError(c, "更新服务商失败: "+err.Error())
 		return
 	}
+	// 本实例立即丢弃旧客户端；其他实例在下次加载服务商时通过 config_version 发现变化
+	llm.GetFactory().Invalidate(id)
 
 	provider, err := h.repo.GetProvider(ctx, id)
 	if err != nil {
@@ -257,6 +267,7 @@ func (h *HTTPHandler) DeleteProvider(c *gin.Context) {
 		InternalError(c, "删除服务商失败")
 		return
 	}
+	llm.GetFactory().Invalidate(id)
 
 	c.Status(http.StatusNoContent)
 }
@@ -322,6 +333,10 @@ func (h *HTTPHandler) CreateProviderModel(c *gin.Context) {
 		return
 	}
 
+	if !validateModelPricing(c, payload.Pricing) {
+		return
+	}
+
 	isActive := true
 	if payload.IsActive != nil {
 		isActive = *payload.IsActive
@@ -333,6 +348,7 @@ func (h *HTTPHandler) CreateProviderModel(c *gin.Context) {
 		Name:               name,
 		Description:        strings.TrimSpace(payload.Description),
 		Price:              strings.TrimSpace(payload.Price),
+		Pricing:            converter.ModelPricingFromDTO(payload.Pricing),
 		MaxImages:          0,
 		InputModalities:    entity.StringArray(normaliseStringSlice(payload.InputModalities)),
 		OutputModalities:   entity.StringArray(normaliseStringSlice(payload.OutputModalities)),
@@ -427,6 +443,13 @@ func (h *HTTPHandler) UpdateProviderModel(c *gin.Context) {
 		price := strings.TrimSpace(*payload.Price)
 		updates.Price = &price
 	}
+	if payload.Pricing != nil {
+		if !validateModelPricing(c, payload.Pricing) {
+			return
+		}
+		pricing := converter.ModelPricingFromDTO(payload.Pricing)
+		updates.Pricing = &pricing
+	}
 	if payload.MaxImages != nil {
 		updates.MaxImages = payload.MaxImages
 	}
@@ -511,6 +534,30 @@ func (h *HTTPHandler) UpdateProviderModel(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"model": entity.ModelToAdminView(*model)})
 }
 
+// validateModelPricing 校验计费规则，各项单价不能为负数
+func validateModelPricing(c *gin.Context, pricing *entity.ModelPricingConfig) bool {
+	if pricing == nil {
+		return true
+	}
+	rates := []struct {
+		name string
+		rate float64
+	}{
+		{"per_image", pricing.PerImage},
+		{"per_video_second", pricing.PerVideoSecond},
+		{"per_megapixel", pricing.PerMegapixel},
+		{"per_1k_input_tokens", pricing.PerThousandInputTokens},
+		{"per_1k_output_tokens", pricing.PerThousandOutputTokens},
+	}
+	for _, item := range rates {
+		if item.rate < 0 {
+			BadRequest(c, ErrCodeInvalidRequest, "pricing."+item.name+" 不能为负数")
+			return false
+		}
+	}
+	return true
+}
+
 func (h *HTTPHandler) DeleteProviderModel(c *gin.Context) {
 	if h.repo == nil {
 		InternalError(c, "服务商仓储未配置")
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/entity/converter"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// providerHealthHistoryLimit 服务商列表中返回的健康检查历史条数
+const providerHealthHistoryLimit = 20
+
+// TestProvider 使用服务商当前保存的配置执行一次低成本凭证检查，返回耗时与错误详情，并记入健康检查历史
+func (h *HTTPHandler) TestProvider(c *gin.Context) {
+	provider, ok := h.loadAdminProvider(c)
+	if !ok {
+		return
+	}
+	id := provider.ID
+
+	timeout := time.Duration(h.cfg.ProviderHealthTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	result, err := h.providerHealth.Check(ctx, provider, entity.ProviderHealthSourceManual)
+	if err != nil {
+		// 检查本身已完成，记录失败不影响返回结果
+		logrus.WithError(err).WithField("provider_id", id).Warn("failed to record provider health check")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"provider_id": id,
+		"check":       converter.ProviderHealthCheckToDTO(result),
+	})
+}
+
+// loadAdminProvider 按路径参数加载服务商，失败时写入错误响应
+func (h *HTTPHandler) loadAdminProvider(c *gin.Context) (*entity.DbProvider, bool) {
+	if h.repo == nil {
+		InternalError(c, "服务商仓储未配置")
+		return nil, false
+	}
+
+	id, err := normaliseProviderID(c.Param("id"))
+	if err != nil {
+		BadRequest(c, ErrCodeInvalidRequest, err.Error())
+		return nil, false
+	}
+
+	provider, err := h.repo.GetProvider(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodeProviderNotFound, "服务商不存在")
+			return nil, false
+		}
+		logrus.WithError(err).WithField("provider_id", id).Error("failed to load provider")
+		InternalError(c, "加载服务商失败")
+		return nil, false
+	}
+	return provider, true
+}
+
+// attachProviderHealth 为服务商视图附加最近的健康检查结果；加载失败时仅记录日志
+func (h *HTTPHandler) attachProviderHealth(ctx context.Context, view *entity.ProviderAdminView) {
+	checks, err := h.repo.ListProviderHealthChecks(ctx, view.ID, providerHealthHistoryLimit)
+	if err != nil {
+		logrus.WithError(err).WithField("provider_id", view.ID).Warn("failed to load provider health checks")
+		return
+	}
+	if len(checks) == 0 {
+		return
+	}
+
+	view.HealthHistory = make([]entity.ProviderHealthCheck, 0, len(checks))
+	for i := range checks {
+		view.HealthHistory = append(view.HealthHistory, converter.ProviderHealthCheckToDTO(&checks[i]))
+	}
+	view.Health = &view.HealthHistory[0]
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// GetGenerationQueue 返回生成任务队列各优先级通道与服务商的深度，以及当前用户自己的任务数
+func (h *HTTPHandler) GetGenerationQueue(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		ServiceUnavailable(c, "生成队列不可用")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	status, err := h.generationService.QueueStatus(ctx, requestUser.ID)
+	if err != nil {
+		logrus.WithError(err).Error("failed to load generation queue status")
+		InternalError(c, "加载生成队列失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// GetUsageRecordQueuePosition 返回使用记录在生成任务队列中的位置
+func (h *HTTPHandler) GetUsageRecordQueuePosition(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		ServiceUnavailable(c, "使用记录服务不可用")
+		return
+	}
+
+	idValue := strings.TrimSpace(c.Param("id"))
+	id, err := strconv.ParseUint(idValue, 10, 64)
+	if err != nil || id == 0 {
+		BadRequest(c, ErrCodeInvalidRequest, "无效的使用记录 ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	record, err := h.repo.GetUsageRecord(ctx, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodeRecordNotFound, "使用记录不存在")
+			return
+		}
+		logrus.WithError(err).WithField("id", id).Error("failed to load usage record for queue position")
+		InternalError(c, "加载队列位置失败")
+		return
+	}
+	if !requestUser.IsAdmin() && record.UserID != requestUser.ID {
+		Forbidden(c, "无权访问此记录")
+		return
+	}
+
+	position, err := h.generationService.QueuePosition(ctx, record.ID)
+	if err != nil {
+		logrus.WithError(err).WithField("record_id", record.ID).Error("failed to load generation queue position")
+		InternalError(c, "加载队列位置失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, position)
+}
+
+// generationPriority 直接提交的生成所在的优先级通道：管理员优先于普通用户
+func generationPriority(user *RequestUser) int {
+	if user.IsAdmin() {
+		return entity.GenerationJobPriorityAdmin
+	}
+	return entity.GenerationJobPriorityInteractive
+}
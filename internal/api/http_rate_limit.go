@@ -0,0 +1,120 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/llm"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userRateLimiter 为每个用户维护提交请求的令牌桶
+type userRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[uint]*userBucket
+}
+
+type userBucket struct {
+	limit  llm.RateLimit
+	bucket *llm.TokenBucket
+}
+
+func newUserRateLimiter() *userRateLimiter {
+	return &userRateLimiter{buckets: make(map[uint]*userBucket)}
+}
+
+// take 从用户的令牌桶取出一个令牌；限流配置变化时重建令牌桶
+func (l *userRateLimiter) take(userID uint, limit llm.RateLimit, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	entry, ok := l.buckets[userID]
+	if !ok || entry.limit != limit {
+		entry = &userBucket{limit: limit, bucket: llm.NewTokenBucket(limit.RequestsPerMinute, limit.Burst)}
+		l.buckets[userID] = entry
+	}
+	l.mu.Unlock()
+
+	return entry.bucket.Take(now)
+}
+
+// RateLimitMiddleware 按用户限制提交生成请求的频率，需放在 AuthMiddleware 之后。
+// 超出限制时返回 429 与 Retry-After 头。
+func (h *HTTPHandler) RateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		if user == nil {
+			c.Next()
+			return
+		}
+
+		limit := h.userRateLimit(user)
+		if limit.RequestsPerMinute <= 0 {
+			c.Next()
+			return
+		}
+
+		ok, wait := h.userLimiter.take(user.ID, limit, time.Now())
+		if !ok {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, APIError{
+				Code:    ErrCodeRateLimited,
+				Message: "请求过于频繁，请稍后重试",
+				Details: gin.H{
+					"retry_after":         retryAfter,
+					"requests_per_minute": limit.RequestsPerMinute,
+					"burst":               limit.Burst,
+				},
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// userRateLimit 返回用户的限流配置：按角色取默认值，用户 settings.rate_limit 中的字段覆盖默认值
+func (h *HTTPHandler) userRateLimit(user *RequestUser) llm.RateLimit {
+	limit := llm.RateLimit{
+		RequestsPerMinute: float64(h.cfg.RateLimitUserRPM),
+		Burst:             h.cfg.RateLimitUserBurst,
+	}
+	if user.IsAdmin() {
+		limit = llm.RateLimit{
+			RequestsPerMinute: float64(h.cfg.RateLimitAdminRPM),
+			Burst:             h.cfg.RateLimitAdminBurst,
+		}
+	}
+	return llm.ParseRateLimit(user.Settings["rate_limit"], limit)
+}
+
+// validateUserSettings 校验用户设置中的限流配置，失败时写入错误响应
+func validateUserSettings(c *gin.Context, settings entity.JSONMap) bool {
+	raw, ok := settings["rate_limit"]
+	if !ok || raw == nil {
+		return true
+	}
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		BadRequest(c, ErrCodeInvalidRequest, "settings.rate_limit 必须是对象")
+		return false
+	}
+	for _, field := range []string{"requests_per_minute", "burst"} {
+		value, present := values[field]
+		if !present {
+			continue
+		}
+		number, ok := value.(float64)
+		if !ok || number < 0 || math.IsInf(number, 0) {
+			BadRequest(c, ErrCodeInvalidRequest, fmt.Sprintf("settings.rate_limit.%s 必须是非负数", field))
+			return false
+		}
+	}
+	return true
+}
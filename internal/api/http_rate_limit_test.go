@@ -0,0 +1,96 @@
+package api
+
+import (
+	"clothing/internal/config"
+	"clothing/internal/entity"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := &HTTPHandler{
+		cfg:         config.Config{RateLimitUserRPM: 1, RateLimitUserBurst: 2},
+		userLimiter: newUserRateLimiter(),
+	}
+
+	tests := []struct {
+		name  string
+		user  *RequestUser
+		codes []int
+	}{
+		{
+			name:  "超过突发上限后限流",
+			user:  &RequestUser{ID: 1, Role: entity.UserRoleUser},
+			codes: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:  "管理员默认不限流",
+			user:  &RequestUser{ID: 2, Role: entity.UserRoleAdmin},
+			codes: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+		{
+			name: "用户设置覆盖默认值",
+			user: &RequestUser{ID: 3, Role: entity.UserRoleUser, Settings: entity.JSONMap{
+				"rate_limit": map[string]interface{}{"burst": float64(1)},
+			}},
+			codes: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.POST("/api/llm", func(c *gin.Context) {
+				c.Set(currentUserContextKey, tt.user)
+				c.Next()
+			}, h.RateLimitMiddleware(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			for i, code := range tt.codes {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/llm", nil))
+				if w.Code != code {
+					t.Fatalf("request %d: expected status %d, got %d", i+1, code, w.Code)
+				}
+				if code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+					t.Errorf("request %d: expected Retry-After header", i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateUserSettings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		settings entity.JSONMap
+		valid    bool
+	}{
+		{name: "未配置限流", settings: entity.JSONMap{"theme": "dark"}, valid: true},
+		{name: "有效限流", settings: entity.JSONMap{"rate_limit": map[string]interface{}{"requests_per_minute": float64(60), "burst": float64(0)}}, valid: true},
+		{name: "限流不是对象", settings: entity.JSONMap{"rate_limit": float64(60)}},
+		{name: "负数", settings: entity.JSONMap{"rate_limit": map[string]interface{}{"burst": float64(-1)}}},
+		{name: "非数值", settings: entity.JSONMap{"rate_limit": map[string]interface{}{"requests_per_minute": "fast"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			if got := validateUserSettings(c, tt.settings); got != tt.valid {
+				t.Fatalf("expected %v, got %v", tt.valid, got)
+			}
+			if !tt.valid && w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
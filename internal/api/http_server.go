@@ -3,9 +3,12 @@ package api
 import (
 	"clothing/internal/auth"
 	"clothing/internal/config"
+	"clothing/internal/entity"
+	"clothing/internal/llm"
 	"clothing/internal/model"
 	"clothing/internal/service"
 	"clothing/internal/storage"
+	"context"
 	"strings"
 	"sync"
 	"time"
@@ -23,9 +26,15 @@ type HTTPHandler struct {
 
 	// 服务层
 	generationService *service.GenerationService
+	webhookService    *service.WebhookService
+	providerHealth    *service.ProviderHealthService
+	modelCatalog      *service.ModelCatalogService
+
+	// 按用户的提交限流
+	userLimiter *userRateLimiter
 
 	// SSE 客户端管理
-	sseClients map[string][]chan sseMessage
+	sseClients map[string][]*sseClient
 	sseMu      sync.Mutex
 }
 
@@ -47,15 +56,66 @@ func NewHTTPHandler(cfg config.Config, repo model.Repository, store storage.Stor
 		storagePublicBase: normalisePublicBase(cfg.StoragePublicBaseURL),
 		authManager:       authManager,
 		generationService: generationSvc,
-		sseClients:        make(map[string][]chan sseMessage),
+		providerHealth:    service.NewProviderHealthService(repo),
+		modelCatalog:      service.NewModelCatalogService(repo),
+		userLimiter:       newUserRateLimiter(),
+		sseClients:        make(map[string][]*sseClient),
 	}
 
+	// 生成结束时投递 Webhook 事件
+	handler.webhookService = service.NewWebhookService(repo, func(path string) entity.UsageImage {
+		return entity.UsageImage{Path: path, URL: handler.publicURL(path)}
+	})
+	generationSvc.SetWebhookService(handler.webhookService)
+
+	// 配置公开回调地址后，服务商在异步任务完成时回调，轮询仅作兜底
+	generationSvc.SetTaskCallbacks(llm.TaskCallbackConfig{
+		BaseURL: cfg.ProviderCallbackBaseURL,
+		Secret:  cfg.ProviderCallbackSecret,
+	})
+
+	// 批量生成与流水线步骤由服务预留额度
+	generationSvc.SetCreditsEnforced(cfg.CreditsEnforced)
+
 	// 设置 SSE 通知回调
 	generationSvc.SetNotifyFunc(handler.notifyGenerationComplete)
+	generationSvc.SetProgressFunc(handler.notifyGenerationProgress)
+	generationSvc.SetDeltaFunc(handler.notifyGenerationDelta)
+	generationSvc.SetBatchNotifyFunc(handler.notifyBatchComplete)
+	generationSvc.SetPipelineNotifyFunc(handler.notifyPipelineComplete)
+
+	// 流水线步骤引用上一步输出时需要可访问的媒体地址
+	generationSvc.SetMediaURLBuilder(handler.publicURL)
 
 	return handler, nil
 }
 
+// StartBackgroundWorkers 启动后台任务（生成任务工作池等），随 ctx 取消而停止
+func (h *HTTPHandler) StartBackgroundWorkers(ctx context.Context) {
+	h.generationService.StartWorkers(ctx, service.WorkerConfig{
+		WorkerID:      h.cfg.GenerationWorkerID,
+		Workers:       h.cfg.GenerationWorkers,
+		PollInterval:  time.Duration(h.cfg.GenerationPollIntervalSeconds) * time.Second,
+		LeaseDuration: time.Duration(h.cfg.GenerationLeaseSeconds) * time.Second,
+		MaxAttempts:   h.cfg.GenerationMaxAttempts,
+	})
+	h.generationService.StartRecovery(ctx, service.RecoveryConfig{
+		Interval:    time.Duration(h.cfg.GenerationRecoveryIntervalSeconds) * time.Second,
+		Concurrency: h.cfg.GenerationWorkers,
+	})
+	h.webhookService.Start(ctx, service.WebhookConfig{
+		PollInterval: time.Duration(h.cfg.WebhookPollIntervalSeconds) * time.Second,
+		Timeout:      time.Duration(h.cfg.WebhookTimeoutSeconds) * time.Second,
+		MaxAttempts:  h.cfg.WebhookMaxAttempts,
+	})
+	h.providerHealth.Start(ctx, service.ProviderHealthConfig{
+		Interval:  time.Duration(h.cfg.ProviderHealthIntervalSeconds) * time.Second,
+		Timeout:   time.Duration(h.cfg.ProviderHealthTimeoutSeconds) * time.Second,
+		Retention: time.Duration(h.cfg.ProviderHealthRetentionHours) * time.Hour,
+	})
+	h.startIdempotencyKeyPurge(ctx)
+}
+
 // normalisePublicBase 规范化公共 URL 基础路径
 func normalisePublicBase(value string) string {
 	trimmed := strings.TrimSpace(value)
@@ -84,7 +144,92 @@ func (h *HTTPHandler) notifyGenerationComplete(clientID string, recordID uint, s
 		payload["error"] = trimmed
 	}
 	h.publishSSEMessage(clientID, sseMessage{
-		event: "generation_completed",
+		event:    "generation_completed",
+		terminal: true,
+		data:     payload,
+	})
+}
+
+// notifyBatchComplete 通知批量生成完成（用于 SSE 推送）
+func (h *HTTPHandler) notifyBatchComplete(clientID string, batch entity.DbGenerationBatch, progress entity.GenerationBatchProgress) {
+	if strings.TrimSpace(clientID) == "" {
+		return
+	}
+	h.publishSSEMessage(clientID, sseMessage{
+		event:    "batch_completed",
+		terminal: true,
+		data: gin.H{
+			"batch_id": batch.ID,
+			"status":   batch.Status,
+			"progress": progress,
+		},
+	})
+}
+
+// notifyPipelineComplete 通知流水线运行结束（用于 SSE 推送）
+func (h *HTTPHandler) notifyPipelineComplete(clientID string, run entity.DbPipelineRun) {
+	if strings.TrimSpace(clientID) == "" {
+		return
+	}
+	payload := gin.H{
+		"run_id":      run.ID,
+		"pipeline_id": run.PipelineID,
+		"status":      run.Status,
+	}
+	if trimmed := strings.TrimSpace(run.ErrorMessage); trimmed != "" {
+		payload["error"] = trimmed
+	}
+	h.publishSSEMessage(clientID, sseMessage{
+		event:    "pipeline_completed",
+		terminal: true,
+		data:     payload,
+	})
+}
+
+// notifyGenerationProgress 推送异步任务进度（用于 SSE 推送）
+func (h *HTTPHandler) notifyGenerationProgress(clientID string, recordID uint, progress llm.TaskProgress) {
+	if strings.TrimSpace(clientID) == "" {
+		return
+	}
+	payload := gin.H{
+		"record_id": recordID,
+		"status":    string(progress.Status),
+	}
+	if progress.Percent != nil {
+		payload["percent"] = *progress.Percent
+	}
+	if progress.QueuePosition != nil {
+		payload["queue_position"] = *progress.QueuePosition
+	}
+	if progress.TaskID != "" {
+		payload["task_id"] = progress.TaskID
+	}
+	h.publishSSEMessage(clientID, sseMessage{
+		event: "generation_progress",
+		data:  payload,
+	})
+}
+
+// notifyGenerationDelta 推送流式输出的文本片段与图片地址（用于 SSE 推送）。
+// slot 为输出序号，attempt 为尝试序号，同一输出出现更大的 attempt 时之前的增量已失效
+func (h *HTTPHandler) notifyGenerationDelta(clientID string, recordID uint, seq int, delta llm.GenerationDelta) {
+	if strings.TrimSpace(clientID) == "" {
+		return
+	}
+	payload := gin.H{
+		"record_id": recordID,
+		"seq":       seq,
+		"slot":      delta.Slot,
+		"attempt":   delta.Attempt,
+	}
+	if delta.Text != "" {
+		payload["text"] = delta.Text
+	}
+	if delta.Image != "" {
+		payload["image"] = delta.Image
+	}
+	h.publishSSEMessage(clientID, sseMessage{
+		event: "generation_delta",
 		data:  payload,
 	})
 }
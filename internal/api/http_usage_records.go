@@ -2,6 +2,9 @@ package api
 
 import (
 	"clothing/internal/entity"
+	"clothing/internal/entity/converter"
+	"clothing/internal/llm"
+	"clothing/internal/service"
 	"context"
 	"errors"
 	"net/http"
@@ -47,6 +50,10 @@ func (h *HTTPHandler) ListUsageRecords(c *gin.Context) {
 	if params.Result == "" {
 		params.Result = "success"
 	}
+	if _, ok := entity.UsageRecordStatusesForResult(params.Result); !ok {
+		BadRequest(c, ErrCodeInvalidRequest, "无效的 result 筛选值")
+		return
+	}
 
 	if params.Page <= 0 {
 		params.Page = 1
@@ -81,6 +88,14 @@ func (h *HTTPHandler) ListUsageRecords(c *gin.Context) {
 		return
 	}
 
+	// 费用合计覆盖全部匹配的记录，而非仅当前页
+	costSummary, err := h.repo.SummariseUsageRecordCost(ctx, &params)
+	if err != nil {
+		logrus.WithError(err).Error("failed to summarise usage record cost")
+		InternalError(c, "加载使用记录失败")
+		return
+	}
+
 	items := make([]entity.UsageRecordItem, 0, len(records))
 	for _, record := range records {
 		items = append(items, h.makeUsageRecordItem(record))
@@ -90,7 +105,7 @@ func (h *HTTPHandler) ListUsageRecords(c *gin.Context) {
 		meta = &entity.Meta{Page: int64(params.Page), PageSize: int64(params.PageSize), Total: int64(len(items))}
 	}
 
-	c.JSON(http.StatusOK, entity.UsageRecordListResponse{Records: items, Meta: meta})
+	c.JSON(http.StatusOK, entity.UsageRecordListResponse{Records: items, Meta: meta, Cost: costSummary})
 }
 
 func (h *HTTPHandler) makeUsageImages(paths []string) []entity.UsageImage {
@@ -114,6 +129,40 @@ func (h *HTTPHandler) makeUsageImages(paths []string) []entity.UsageImage {
 	return items
 }
 
+func makeUsageAttempts(attempts entity.GenerationAttempts) []entity.UsageAttempt {
+	items := make([]entity.UsageAttempt, 0, len(attempts))
+	for _, attempt := range attempts {
+		items = append(items, entity.UsageAttempt{
+			Attempt:    attempt.Attempt,
+			ProviderID: attempt.ProviderID,
+			ModelID:    attempt.ModelID,
+			StartedAt:  attempt.StartedAt,
+			FinishedAt: attempt.FinishedAt,
+			Error:      attempt.Error,
+			StatusCode: attempt.StatusCode,
+			Retryable:  attempt.Retryable,
+			BackoffMs:  attempt.BackoffMs,
+		})
+	}
+	return items
+}
+
+func (h *HTTPHandler) makeUsageOutputs(outputs entity.GenerationOutputs) []entity.UsageOutput {
+	items := make([]entity.UsageOutput, 0, len(outputs))
+	for _, output := range outputs {
+		item := entity.UsageOutput{
+			Index:  output.Index,
+			Status: output.Status,
+			Error:  output.Error,
+		}
+		if path := strings.TrimSpace(output.Path); path != "" {
+			item.Image = &entity.UsageImage{Path: path, URL: h.publicURL(path)}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
 func (h *HTTPHandler) makeTags(tags []entity.DbTag) []entity.Tag {
 	if len(tags) == 0 {
 		return []entity.Tag{}
@@ -133,18 +182,35 @@ func (h *HTTPHandler) makeTags(tags []entity.DbTag) []entity.Tag {
 
 func (h *HTTPHandler) makeUsageRecordItem(record entity.DbUsageRecord) entity.UsageRecordItem {
 	return entity.UsageRecordItem{
-		ID:           record.ID,
-		ProviderID:   record.ProviderID,
-		ModelID:      record.ModelID,
-		Prompt:       record.Prompt,
-		Size:         record.Size,
-		OutputText:   record.OutputText,
-		ErrorMessage: record.ErrorMessage,
-		CreatedAt:    record.CreatedAt,
-		InputImages:  h.makeUsageImages(record.InputImages.ToSlice()),
-		OutputImages: h.makeUsageImages(record.OutputImages.ToSlice()),
-		User:         makeUserSummary(record.User),
-		Tags:         h.makeTags(record.Tags),
+		ID:               record.ID,
+		BatchID:          record.BatchID,
+		PipelineRunID:    record.PipelineRunID,
+		PipelineStep:     record.PipelineStep,
+		ParentRecordID:   record.ParentRecordID,
+		ProviderID:       record.ProviderID,
+		ModelID:          record.ModelID,
+		ServedProviderID: record.ServedProviderID,
+		ServedModelID:    record.ServedModelID,
+		Prompt:           record.Prompt,
+		PromptTemplateID: record.PromptTemplateID,
+		PromptVariables:  record.PromptVariables,
+		Size:             record.Size,
+		AdvancedParams:   converter.AdvancedParamsToDTO(record.AdvancedParams),
+		Cost:             record.Cost,
+		CostSource:       record.CostSource,
+		OutputText:       record.OutputText,
+		ErrorMessage:     record.ErrorMessage,
+		Status:           record.Status,
+		CreatedAt:        record.CreatedAt,
+		StartedAt:        record.StartedAt,
+		FinishedAt:       record.FinishedAt,
+		Attempts:         makeUsageAttempts(record.Attempts),
+		InputImages:      h.makeUsageImages(record.InputImages.ToSlice()),
+		OutputImages:     h.makeUsageImages(record.OutputImages.ToSlice()),
+		NumOutputs:       record.NumOutputs,
+		Outputs:          h.makeUsageOutputs(record.Outputs),
+		User:             makeUserSummary(record.User),
+		Tags:             h.makeTags(record.Tags),
 	}
 }
 
@@ -240,6 +306,84 @@ func (h *HTTPHandler) DeleteUsageRecord(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// CancelUsageRecord 取消排队中或执行中的生成
+func (h *HTTPHandler) CancelUsageRecord(c *gin.Context) {
+	if h.repo == nil {
+		ServiceUnavailable(c, "使用记录服务不可用")
+		return
+	}
+
+	idValue := strings.TrimSpace(c.Param("id"))
+	id, err := strconv.ParseUint(idValue, 10, 64)
+	if err != nil || id == 0 {
+		BadRequest(c, ErrCodeInvalidRequest, "无效的使用记录 ID")
+		return
+	}
+
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	record, err := h.repo.GetUsageRecord(ctx, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodeRecordNotFound, "使用记录不存在")
+			return
+		}
+		logrus.WithError(err).WithField("id", id).Error("failed to load usage record for cancellation")
+		InternalError(c, "取消生成失败")
+		return
+	}
+
+	if !requestUser.IsAdmin() && record.UserID != requestUser.ID {
+		Forbidden(c, "无权访问此记录")
+		return
+	}
+
+	if record.Status != entity.UsageRecordStatusQueued && record.Status != entity.UsageRecordStatusRunning {
+		Conflict(c, ErrCodeNotCancellable, "生成已结束，无法取消")
+		return
+	}
+
+	status, err := h.generationService.CancelGeneration(ctx, record.ID, h.supportsCancel(ctx, record))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrCancelNotSupported):
+			Conflict(c, ErrCodeCancelNotSupported, "该模型不支持取消执行中的生成")
+		case errors.Is(err, service.ErrGenerationNotActive):
+			Conflict(c, ErrCodeNotCancellable, "生成已结束，无法取消")
+		default:
+			logrus.WithError(err).WithField("id", id).Error("failed to cancel generation")
+			InternalError(c, "取消生成失败")
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"record_id": record.ID,
+		"status":    status,
+	})
+}
+
+// supportsCancel 判断记录对应的模型是否支持取消执行中的生成
+func (h *HTTPHandler) supportsCancel(ctx context.Context, record *entity.DbUsageRecord) bool {
+	dbModel, err := h.repo.GetModel(ctx, record.ProviderID, record.ModelID)
+	if err != nil {
+		return false
+	}
+	if dbProvider, err := h.repo.GetProvider(ctx, record.ProviderID); err == nil {
+		if llmService, err := llm.GetFactory().Get(dbProvider); err == nil {
+			return llmService.Capabilities(*dbModel).SupportsCancel
+		}
+	}
+	return dbModel.SupportsCancel
+}
+
 func parseUintListParam(values []string, fallbacks ...string) []uint {
 	items := make([]string, 0, len(values)+1)
 	for _, val := range values {
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"clothing/internal/config"
+	"clothing/internal/entity"
+	"clothing/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRepository 创建基于临时 SQLite 文件的仓库
+func newTestRepository(t *testing.T) model.Repository {
+	t.Helper()
+
+	repo, err := model.InitRepository(&config.Config{
+		DBType: model.DBTypeSQLite,
+		DBPath: filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatalf("init repository: %v", err)
+	}
+	return repo
+}
+
+func TestListUsageRecordsResultFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := &HTTPHandler{repo: newTestRepository(t)}
+	r := gin.New()
+	r.GET("/api/usage-records", func(c *gin.Context) {
+		c.Set(currentUserContextKey, &RequestUser{ID: 1, Role: entity.UserRoleUser})
+		c.Next()
+	}, h.ListUsageRecords)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{name: "默认筛选", query: "", status: http.StatusOK},
+		{name: "兼容旧值", query: "?result=failure", status: http.StatusOK},
+		{name: "状态值", query: "?result=Cancelled", status: http.StatusOK},
+		{name: "全部", query: "?result=all", status: http.StatusOK},
+		{name: "未知值", query: "?result=typo", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/usage-records"+tt.query, nil))
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/entity/converter"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// RerunUsageRecord 按原记录的提示词、尺寸、输入图片与模型重新生成，新记录关联来源记录
+func (h *HTTPHandler) RerunUsageRecord(c *gin.Context) {
+	var payload entity.RerunUsageRecordRequest
+	// 请求体可选
+	if err := c.ShouldBindJSON(&payload); err != nil && !errors.Is(err, io.EOF) {
+		InvalidPayload(c)
+		return
+	}
+	h.rerunUsageRecord(c, entity.RemixUsageRecordRequest{RerunUsageRecordRequest: payload})
+}
+
+// RemixUsageRecord 以原记录为基础、按请求覆盖模型、提示词、尺寸等参数重新生成
+func (h *HTTPHandler) RemixUsageRecord(c *gin.Context) {
+	var payload entity.RemixUsageRecordRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		InvalidPayload(c)
+		return
+	}
+	h.rerunUsageRecord(c, payload)
+}
+
+// rerunUsageRecord 重建生成请求、应用覆盖参数并入队，与直接生成走相同的校验
+func (h *HTTPHandler) rerunUsageRecord(c *gin.Context, payload entity.RemixUsageRecordRequest) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "服务商仓储未配置")
+		return
+	}
+
+	rawID := strings.TrimSpace(c.Param("id"))
+	parentID, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil || parentID == 0 {
+		BadRequest(c, ErrCodeInvalidRequest, "无效的使用记录 ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	parent, err := h.repo.GetUsageRecord(ctx, uint(parentID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodeRecordNotFound, "使用记录不存在")
+			return
+		}
+		logrus.WithError(err).WithField("id", parentID).Error("failed to load usage record")
+		InternalError(c, "加载使用记录失败")
+		return
+	}
+	if !requestUser.IsAdmin() && parent.UserID != requestUser.ID {
+		Forbidden(c, "无权访问此记录")
+		return
+	}
+	// 输入图片在生成开始后才写入存储，未结束的记录无法完整重建
+	if parent.Status == entity.UsageRecordStatusQueued || parent.Status == entity.UsageRecordStatusRunning {
+		Conflict(c, ErrCodeRecordNotFinished, "生成尚未结束，无法重新生成")
+		return
+	}
+
+	request, ok := h.rebuildGenerationRequest(ctx, c, parent)
+	if !ok {
+		return
+	}
+	request.ClientID = strings.TrimSpace(payload.ClientID)
+	request.TagIDs = payload.TagIDs
+
+	promptTemplateID := parent.PromptTemplateID
+	promptVariables := parent.PromptVariables
+
+	if payload.ProviderID != nil {
+		request.ProviderID = strings.TrimSpace(*payload.ProviderID)
+		if request.ProviderID == "" {
+			MissingField(c, "provider")
+			return
+		}
+	}
+	if payload.ModelID != nil {
+		request.ModelID = strings.TrimSpace(*payload.ModelID)
+		if request.ModelID == "" {
+			MissingField(c, "model")
+			return
+		}
+	}
+	if payload.Prompt != nil {
+		request.Prompt = strings.TrimSpace(*payload.Prompt)
+		if request.Prompt == "" {
+			MissingField(c, "prompt")
+			return
+		}
+		// 修改后的提示词不再对应模板渲染结果
+		if request.Prompt != parent.Prompt {
+			promptTemplateID = nil
+			promptVariables = nil
+		}
+	}
+	if payload.Size != nil {
+		request.Output.Size = strings.TrimSpace(*payload.Size)
+	}
+	if payload.Duration != nil {
+		request.Output.Duration = *payload.Duration
+	}
+	if payload.NumOutputs != nil {
+		request.Output.NumOutputs = *payload.NumOutputs
+	}
+	if payload.InputMedia != nil {
+		request.InputMedia = *payload.InputMedia
+	}
+	if payload.Advanced != nil {
+		request.Advanced = *payload.Advanced
+	}
+
+	dbModel, service, ok := h.validateGenerationTarget(c, request.ProviderID, request.ModelID)
+	if !ok {
+		return
+	}
+	if !validateNumOutputs(c, request.Output.NumOutputs, *dbModel) {
+		return
+	}
+	if !validateCapabilities(c, service, request, *dbModel) {
+		return
+	}
+	if !validateAdvancedParams(c, &request.Advanced) {
+		return
+	}
+
+	tagIDs := deduplicatePositiveIDs(request.TagIDs)
+	if !h.validateTagIDs(c, tagIDs) {
+		return
+	}
+
+	userID := requestUser.ID
+	idempotencyClaim, ok := h.claimIdempotencyKey(ctx, c, userID, idempotencyKeyFromRequest(c, payload.IdempotencyKey))
+	if !ok {
+		return
+	}
+
+	record := entity.DbUsageRecord{
+		UserID:         userID,
+		ParentRecordID: &parent.ID,
+		ProviderID:     request.ProviderID,
+		ModelID:        request.ModelID,
+		Prompt:         request.Prompt,
+		Size:           request.Output.Size,
+		NumOutputs:     request.GetNumOutputs(),
+		Status:         entity.UsageRecordStatusQueued,
+
+		PromptTemplateID: promptTemplateID,
+		PromptVariables:  promptVariables,
+	}
+
+	h.queueGeneration(ctx, c, &record, *dbModel, request, tagIDs, idempotencyClaim)
+}
+
+// rebuildGenerationRequest 由使用记录重建生成请求，已保存的输入图片转换为服务商可读取的内容；
+// 输入媒体的类型、角色（如首尾帧）与输出时长取自入队时保存的原始请求
+func (h *HTTPHandler) rebuildGenerationRequest(ctx context.Context, c *gin.Context, record *entity.DbUsageRecord) (entity.GenerateContentRequest, bool) {
+	request := entity.GenerateContentRequest{
+		ProviderID: record.ProviderID,
+		ModelID:    record.ModelID,
+		Prompt:     record.Prompt,
+		Output: entity.OutputConfig{
+			Size:       record.Size,
+			NumOutputs: record.NumOutputs,
+		},
+	}
+	// 记录中保存的是实际生效的高级参数，重新生成时沿用以复现结果
+	if params := converter.AdvancedParamsToDTO(record.AdvancedParams); params != nil {
+		request.Advanced = *params
+	}
+
+	// 早于任务队列创建的记录没有原始请求，输入按图片处理
+	var originalInputs []entity.MediaInput
+	original, err := h.generationService.QueuedGenerationRequest(ctx, record.ID)
+	switch {
+	case err == nil:
+		request.Output.Duration = original.Output.Duration
+		for _, input := range original.InputMedia {
+			if strings.TrimSpace(input.Content) != "" {
+				originalInputs = append(originalInputs, input)
+			}
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		logrus.WithError(err).WithField("record_id", record.ID).Error("failed to load queued generation request")
+		InternalError(c, "加载原生成请求失败")
+		return request, false
+	}
+
+	// 部分输入保存失败时已保存的路径无法与原始输入一一对应，直接沿用原始输入
+	if len(originalInputs) > 0 && len(originalInputs) != len(record.InputImages) {
+		request.InputMedia = originalInputs
+		return request, true
+	}
+
+	for idx, path := range record.InputImages {
+		content, err := h.generationService.StoredMediaContent(ctx, path)
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"record_id": record.ID,
+				"path":      path,
+			}).Error("failed to load stored input image")
+			InternalError(c, "加载原输入图片失败")
+			return request, false
+		}
+		input := entity.MediaInput{Type: "image", Content: content}
+		if idx < len(originalInputs) {
+			input.Type = originalInputs[idx].Type
+			input.Role = originalInputs[idx].Role
+		}
+		request.InputMedia = append(request.InputMedia, input)
+	}
+	return request, true
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/service"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRebuildGenerationRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	generationService := service.NewGenerationService(repo, nil)
+	generationService.SetMediaURLBuilder(func(path string) string {
+		return "https://cdn.example.com/" + path
+	})
+	h := &HTTPHandler{repo: repo, generationService: generationService}
+
+	rebuild := func(t *testing.T, record *entity.DbUsageRecord) entity.GenerateContentRequest {
+		t.Helper()
+		parent, err := repo.GetUsageRecord(ctx, record.ID)
+		if err != nil {
+			t.Fatalf("load record: %v", err)
+		}
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/usage-records/1/rerun", nil)
+		request, ok := h.rebuildGenerationRequest(ctx, c, parent)
+		if !ok {
+			t.Fatalf("expected request to be rebuilt, got %d: %s", w.Code, w.Body.String())
+		}
+		return request
+	}
+
+	enqueue := func(t *testing.T, request entity.GenerateContentRequest, inputImages []string) *entity.DbUsageRecord {
+		t.Helper()
+		record := &entity.DbUsageRecord{
+			UserID:     1,
+			ProviderID: request.ProviderID,
+			ModelID:    request.ModelID,
+			Prompt:     request.Prompt,
+			Size:       request.Output.Size,
+			NumOutputs: request.GetNumOutputs(),
+			Status:     entity.UsageRecordStatusQueued,
+		}
+		if err := generationService.EnqueueGeneration(ctx, record, request, entity.GenerationJobPriorityInteractive, 0, nil); err != nil {
+			t.Fatalf("enqueue generation: %v", err)
+		}
+		images := entity.StringArray(inputImages)
+		status := entity.UsageRecordStatusSucceeded
+		if err := repo.UpdateUsageRecord(ctx, record.ID, entity.UsageRecordUpdates{InputImages: &images, Status: &status}); err != nil {
+			t.Fatalf("update record: %v", err)
+		}
+		return record
+	}
+
+	videoRequest := entity.GenerateContentRequest{
+		ProviderID: "volcengine",
+		ModelID:    "seedance",
+		Prompt:     "模特转身",
+		InputMedia: []entity.MediaInput{
+			{Type: "image", Content: "https://origin.example.com/first.png", Role: "first_frame"},
+			{Type: "image", Content: "https://origin.example.com/last.png", Role: "last_frame"},
+		},
+		Output: entity.OutputConfig{Size: "1280x720", Duration: 5},
+	}
+
+	t.Run("视频首尾帧保留角色与时长", func(t *testing.T) {
+		record := enqueue(t, videoRequest, []string{"inputs/first.png", "inputs/last.png"})
+
+		request := rebuild(t, record)
+		if request.Output.Duration != 5 {
+			t.Errorf("expected duration %d, got %d", 5, request.Output.Duration)
+		}
+		if len(request.InputMedia) != 2 {
+			t.Fatalf("expected 2 inputs, got %+v", request.InputMedia)
+		}
+		expected := []entity.MediaInput{
+			{Type: "image", Content: "https://cdn.example.com/inputs/first.png", Role: "first_frame"},
+			{Type: "image", Content: "https://cdn.example.com/inputs/last.png", Role: "last_frame"},
+		}
+		for idx, input := range request.InputMedia {
+			if input != expected[idx] {
+				t.Errorf("input %d: expected %+v, got %+v", idx, expected[idx], input)
+			}
+		}
+	})
+
+	t.Run("部分输入未保存时沿用原始输入", func(t *testing.T) {
+		record := enqueue(t, videoRequest, []string{"inputs/first.png"})
+
+		request := rebuild(t, record)
+		if len(request.InputMedia) != 2 {
+			t.Fatalf("expected 2 inputs, got %+v", request.InputMedia)
+		}
+		for idx, input := range request.InputMedia {
+			if input != videoRequest.InputMedia[idx] {
+				t.Errorf("input %d: expected %+v, got %+v", idx, videoRequest.InputMedia[idx], input)
+			}
+		}
+	})
+
+	t.Run("没有任务的旧记录按图片输入重建", func(t *testing.T) {
+		record := &entity.DbUsageRecord{
+			UserID:      1,
+			ProviderID:  "openai",
+			ModelID:     "gpt-image-1",
+			Prompt:      "白色T恤",
+			InputImages: entity.StringArray{"inputs/shirt.png"},
+			Status:      entity.UsageRecordStatusSucceeded,
+		}
+		if err := repo.CreateUsageRecord(ctx, record); err != nil {
+			t.Fatalf("create record: %v", err)
+		}
+
+		request := rebuild(t, record)
+		if request.Output.Duration != 0 {
+			t.Errorf("expected no duration, got %d", request.Output.Duration)
+		}
+		expected := entity.MediaInput{Type: "image", Content: "https://cdn.example.com/inputs/shirt.png"}
+		if len(request.InputMedia) != 1 || request.InputMedia[0] != expected {
+			t.Errorf("expected inputs %+v, got %+v", expected, request.InputMedia)
+		}
+	})
+}
@@ -108,12 +108,17 @@ func (h *HTTPHandler) CreateUser(c *gin.Context) {
 		isActive = *req.IsActive
 	}
 
+	if !validateUserSettings(c, req.Settings) {
+		return
+	}
+
 	user := &entity.DbUser{
 		Email:        email,
 		DisplayName:  strings.TrimSpace(req.DisplayName),
 		PasswordHash: hash,
 		Role:         role,
 		IsActive:     isActive,
+		Settings:     entity.JSONMap(req.Settings),
 	}
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
@@ -218,6 +223,14 @@ func (h *HTTPHandler) UpdateUser(c *gin.Context) {
 		updates.IsActive = req.IsActive
 	}
 
+	if req.Settings != nil {
+		if !validateUserSettings(c, *req.Settings) {
+			return
+		}
+		settings := entity.JSONMap(*req.Settings)
+		updates.Settings = &settings
+	}
+
 	if updates.IsEmpty() {
 		c.JSON(http.StatusOK, makeUserSummary(dbUser))
 		return
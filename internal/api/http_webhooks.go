@@ -0,0 +1,358 @@
+package api
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/entity/converter"
+	"clothing/internal/service"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// webhookEvents 可订阅的事件类型
+var webhookEvents = map[string]struct{}{
+	entity.WebhookEventGenerationCompleted: {},
+	entity.WebhookEventGenerationFailed:    {},
+}
+
+// ListWebhookEndpoints 列出当前用户的 Webhook 端点（管理员可查看全部）
+func (h *HTTPHandler) ListWebhookEndpoints(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	endpoints, err := h.repo.ListWebhookEndpoints(ctx, requestUser.ID, requestUser.IsAdmin())
+	if err != nil {
+		logrus.WithError(err).Error("failed to list webhook endpoints")
+		InternalError(c, "加载 Webhook 端点失败")
+		return
+	}
+
+	items := make([]entity.WebhookEndpoint, 0, len(endpoints))
+	for i := range endpoints {
+		items = append(items, converter.WebhookEndpointToDTO(&endpoints[i]))
+	}
+	c.JSON(http.StatusOK, entity.WebhookEndpointListResponse{Endpoints: items})
+}
+
+// CreateWebhookEndpoint 注册 Webhook 端点，签名密钥仅在创建时返回
+func (h *HTTPHandler) CreateWebhookEndpoint(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	var payload entity.CreateWebhookEndpointRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		InvalidPayload(c)
+		return
+	}
+
+	endpointURL, err := normaliseWebhookURL(payload.URL)
+	if err != nil {
+		BadRequest(c, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	events, err := normaliseWebhookEvents(payload.Events)
+	if err != nil {
+		BadRequest(c, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	if payload.AllUsers && !requestUser.IsAdmin() {
+		Forbidden(c, "仅管理员可以订阅所有用户的事件")
+		return
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate webhook secret")
+		InternalError(c, "创建 Webhook 端点失败")
+		return
+	}
+
+	isActive := true
+	if payload.IsActive != nil {
+		isActive = *payload.IsActive
+	}
+
+	endpoint := &entity.DbWebhookEndpoint{
+		UserID:      requestUser.ID,
+		URL:         endpointURL,
+		Secret:      secret,
+		Events:      events,
+		Description: strings.TrimSpace(payload.Description),
+		AllUsers:    payload.AllUsers,
+		IsActive:    isActive,
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.repo.CreateWebhookEndpoint(ctx, endpoint); err != nil {
+		logrus.WithError(err).WithField("user_id", requestUser.ID).Error("failed to create webhook endpoint")
+		InternalError(c, "创建 Webhook 端点失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, entity.WebhookEndpointDetailResponse{
+		Endpoint: converter.WebhookEndpointToDTO(endpoint),
+		Secret:   secret,
+	})
+}
+
+// UpdateWebhookEndpoint 更新 Webhook 端点
+func (h *HTTPHandler) UpdateWebhookEndpoint(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	var payload entity.UpdateWebhookEndpointRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		InvalidPayload(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	endpoint, ok := h.loadOwnedWebhookEndpoint(ctx, c, requestUser)
+	if !ok {
+		return
+	}
+
+	var updates entity.WebhookEndpointUpdates
+	if payload.URL != nil {
+		endpointURL, err := normaliseWebhookURL(*payload.URL)
+		if err != nil {
+			BadRequest(c, ErrCodeInvalidRequest, err.Error())
+			return
+		}
+		updates.URL = &endpointURL
+	}
+	if payload.Events != nil {
+		events, err := normaliseWebhookEvents(*payload.Events)
+		if err != nil {
+			BadRequest(c, ErrCodeInvalidRequest, err.Error())
+			return
+		}
+		updates.Events = &events
+	}
+	if payload.Description != nil {
+		description := strings.TrimSpace(*payload.Description)
+		updates.Description = &description
+	}
+	if payload.AllUsers != nil {
+		if *payload.AllUsers && !requestUser.IsAdmin() {
+			Forbidden(c, "仅管理员可以订阅所有用户的事件")
+			return
+		}
+		updates.AllUsers = payload.AllUsers
+	}
+	if payload.IsActive != nil {
+		updates.IsActive = payload.IsActive
+	}
+
+	if !updates.IsEmpty() {
+		if err := h.repo.UpdateWebhookEndpoint(ctx, endpoint.ID, updates); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				NotFound(c, ErrCodeWebhookNotFound, "Webhook 端点不存在")
+				return
+			}
+			logrus.WithError(err).WithField("endpoint_id", endpoint.ID).Error("failed to update webhook endpoint")
+			InternalError(c, "更新 Webhook 端点失败")
+			return
+		}
+	}
+
+	updated, err := h.repo.GetWebhookEndpoint(ctx, endpoint.ID)
+	if err != nil {
+		logrus.WithError(err).WithField("endpoint_id", endpoint.ID).Error("failed to reload webhook endpoint")
+		InternalError(c, "加载 Webhook 端点失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.WebhookEndpointDetailResponse{Endpoint: converter.WebhookEndpointToDTO(updated)})
+}
+
+// DeleteWebhookEndpoint 删除 Webhook 端点及其投递日志
+func (h *HTTPHandler) DeleteWebhookEndpoint(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	endpoint, ok := h.loadOwnedWebhookEndpoint(ctx, c, requestUser)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteWebhookEndpoint(ctx, endpoint.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodeWebhookNotFound, "Webhook 端点不存在")
+			return
+		}
+		logrus.WithError(err).WithField("endpoint_id", endpoint.ID).Error("failed to delete webhook endpoint")
+		InternalError(c, "删除 Webhook 端点失败")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries 查看 Webhook 端点的投递日志
+func (h *HTTPHandler) ListWebhookDeliveries(c *gin.Context) {
+	requestUser := CurrentUser(c)
+	if requestUser == nil {
+		Unauthorized(c, "需要登录")
+		return
+	}
+	if h.repo == nil {
+		InternalError(c, "仓储未配置")
+		return
+	}
+
+	limit := 50
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > 200 {
+			BadRequest(c, ErrCodeInvalidRequest, "limit 必须在 1-200 之间")
+			return
+		}
+		limit = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	endpoint, ok := h.loadOwnedWebhookEndpoint(ctx, c, requestUser)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.repo.ListWebhookDeliveries(ctx, endpoint.ID, limit)
+	if err != nil {
+		logrus.WithError(err).WithField("endpoint_id", endpoint.ID).Error("failed to list webhook deliveries")
+		InternalError(c, "加载投递日志失败")
+		return
+	}
+
+	items := make([]entity.WebhookDelivery, 0, len(deliveries))
+	for i := range deliveries {
+		items = append(items, converter.WebhookDeliveryToDTO(&deliveries[i]))
+	}
+	c.JSON(http.StatusOK, entity.WebhookDeliveryListResponse{Deliveries: items})
+}
+
+// loadOwnedWebhookEndpoint 加载路径参数指定的端点，非管理员只能访问自己的端点
+func (h *HTTPHandler) loadOwnedWebhookEndpoint(ctx context.Context, c *gin.Context, requestUser *RequestUser) (*entity.DbWebhookEndpoint, bool) {
+	rawID := strings.TrimSpace(c.Param("id"))
+	endpointID, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil || endpointID == 0 {
+		BadRequest(c, ErrCodeInvalidRequest, "无效的 Webhook 端点 ID")
+		return nil, false
+	}
+
+	endpoint, err := h.repo.GetWebhookEndpoint(ctx, uint(endpointID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, ErrCodeWebhookNotFound, "Webhook 端点不存在")
+			return nil, false
+		}
+		logrus.WithError(err).WithField("endpoint_id", endpointID).Error("failed to load webhook endpoint")
+		InternalError(c, "加载 Webhook 端点失败")
+		return nil, false
+	}
+	if !requestUser.IsAdmin() && endpoint.UserID != requestUser.ID {
+		NotFound(c, ErrCodeWebhookNotFound, "Webhook 端点不存在")
+		return nil, false
+	}
+	return endpoint, true
+}
+
+// normaliseWebhookURL 校验端点地址为 http(s) 绝对地址，且不指向本机或内网。
+// 域名解析到的地址在投递建立连接时再次校验
+func normaliseWebhookURL(raw string) (string, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return "", errors.New("url 不能为空")
+	}
+	parsed, err := url.Parse(trimmed)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", errors.New("url 必须是 http 或 https 地址")
+	}
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return "", errors.New("url 不能指向本机或内网地址")
+	}
+	if ip := net.ParseIP(host); ip != nil && !service.WebhookAddressAllowed(ip) {
+		return "", errors.New("url 不能指向本机或内网地址")
+	}
+	return trimmed, nil
+}
+
+// normaliseWebhookEvents 去重并校验事件类型，为空表示订阅全部事件
+func normaliseWebhookEvents(values []string) (entity.StringArray, error) {
+	events := make(entity.StringArray, 0, len(values))
+	seen := make(map[string]struct{}, len(values))
+	for _, value := range normaliseStringSlice(values) {
+		event := strings.ToLower(value)
+		if _, ok := webhookEvents[event]; !ok {
+			return nil, fmt.Errorf("不支持的事件类型: %s", value)
+		}
+		if _, ok := seen[event]; ok {
+			continue
+		}
+		seen[event] = struct{}{}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// generateWebhookSecret 生成端点的签名密钥
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
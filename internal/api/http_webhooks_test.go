@@ -0,0 +1,30 @@
+package api
+
+import "testing"
+
+func TestNormaliseWebhookURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{name: "公网域名", raw: " https://hooks.example.com/events ", wantErr: false},
+		{name: "公网地址", raw: "http://93.184.216.34:8080/hook", wantErr: false},
+		{name: "空地址", raw: "", wantErr: true},
+		{name: "非 http 协议", raw: "ftp://example.com", wantErr: true},
+		{name: "本机域名", raw: "http://localhost:8080/hook", wantErr: true},
+		{name: "回环地址", raw: "http://127.0.0.1/hook", wantErr: true},
+		{name: "内网地址", raw: "http://192.168.1.10/hook", wantErr: true},
+		{name: "元数据地址", raw: "http://169.254.169.254/latest/meta-data", wantErr: true},
+		{name: "IPv6 回环", raw: "http://[::1]:9000/hook", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := normaliseWebhookURL(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
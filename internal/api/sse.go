@@ -2,25 +2,43 @@ package api
 
 import "github.com/sirupsen/logrus"
 
+// sseClientBuffer 每个 SSE 连接的消息缓冲，流式增量事件较密集，留出足够缓冲
+const sseClientBuffer = 128
+
 type sseMessage struct {
 	event string
 	data  interface{}
+	// terminal 表示生成、批量或流水线结束的事件，客户端依赖其更新最终状态，缓冲已满时也不丢弃
+	terminal bool
+}
+
+// sseClient 一个 SSE 连接的消息队列，连接断开时关闭 done
+type sseClient struct {
+	events chan sseMessage
+	done   chan struct{}
+}
+
+func newSSEClient() *sseClient {
+	return &sseClient{
+		events: make(chan sseMessage, sseClientBuffer),
+		done:   make(chan struct{}),
+	}
 }
 
-func (h *HTTPHandler) registerSSEClient(clientID string, ch chan sseMessage) {
-	if h == nil || ch == nil || clientID == "" {
+func (h *HTTPHandler) registerSSEClient(clientID string, client *sseClient) {
+	if h == nil || client == nil || clientID == "" {
 		return
 	}
 	h.sseMu.Lock()
 	defer h.sseMu.Unlock()
 
 	if h.sseClients == nil {
-		h.sseClients = make(map[string][]chan sseMessage)
+		h.sseClients = make(map[string][]*sseClient)
 	}
-	h.sseClients[clientID] = append(h.sseClients[clientID], ch)
+	h.sseClients[clientID] = append(h.sseClients[clientID], client)
 }
 
-func (h *HTTPHandler) unregisterSSEClient(clientID string, target chan sseMessage) {
+func (h *HTTPHandler) unregisterSSEClient(clientID string, target *sseClient) {
 	if h == nil || target == nil || clientID == "" {
 		return
 	}
@@ -33,11 +51,13 @@ func (h *HTTPHandler) unregisterSSEClient(clientID string, target chan sseMessag
 	}
 
 	remaining := current[:0]
-	for _, ch := range current {
-		if ch == target {
+	for _, client := range current {
+		if client == target {
+			// 唤醒等待投递结束事件的发布方
+			close(client.done)
 			continue
 		}
-		remaining = append(remaining, ch)
+		remaining = append(remaining, client)
 	}
 
 	if len(remaining) == 0 {
@@ -48,18 +68,31 @@ func (h *HTTPHandler) unregisterSSEClient(clientID string, target chan sseMessag
 	h.sseClients[clientID] = remaining
 }
 
+// publishSSEMessage 向客户端的所有连接推送消息，不会阻塞调用方（任务队列工作协程、取消接口等）。
+// 普通事件在缓冲已满时丢弃；结束事件挤出排队中的普通事件腾出空间，缓冲中只剩结束事件时断开该连接
 func (h *HTTPHandler) publishSSEMessage(clientID string, msg sseMessage) {
 	if h == nil || clientID == "" {
 		return
 	}
 
 	h.sseMu.Lock()
-	channels := append([]chan sseMessage(nil), h.sseClients[clientID]...)
+	clients := append([]*sseClient(nil), h.sseClients[clientID]...)
 	h.sseMu.Unlock()
 
-	for _, ch := range channels {
+	for _, client := range clients {
+		if msg.terminal {
+			if !client.enqueueTerminal(msg) {
+				logrus.WithFields(logrus.Fields{
+					"client_id": clientID,
+					"event":     msg.event,
+				}).Warn("disconnecting sse client that stopped reading")
+				h.unregisterSSEClient(clientID, client)
+			}
+			continue
+		}
+
 		select {
-		case ch <- msg:
+		case client.events <- msg:
 		default:
 			logrus.WithFields(logrus.Fields{
 				"client_id": clientID,
@@ -68,3 +101,29 @@ func (h *HTTPHandler) publishSSEMessage(clientID string, msg sseMessage) {
 		}
 	}
 }
+
+// enqueueTerminal 将结束事件放入缓冲，缓冲已满时移出排队中的普通事件；移出的结束事件重新排到队尾。
+// 缓冲被结束事件占满、无法腾出空间时返回 false，此时仍未送达的结束事件由客户端重连后重新加载状态
+func (client *sseClient) enqueueTerminal(msg sseMessage) bool {
+	pending := []sseMessage{msg}
+	for attempt := 0; attempt < 2*sseClientBuffer && len(pending) > 0; attempt++ {
+		select {
+		case client.events <- pending[0]:
+			pending = pending[1:]
+			continue
+		case <-client.done:
+			return true
+		default:
+		}
+
+		select {
+		case queued := <-client.events:
+			if queued.terminal {
+				pending = append(pending, queued)
+			}
+		default:
+			// 连接刚好读走了消息，下一轮重试放入
+		}
+	}
+	return len(pending) == 0
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPublishSSEMessage(t *testing.T) {
+	t.Run("缓冲已满时丢弃普通事件", func(t *testing.T) {
+		h := &HTTPHandler{}
+		client := newSSEClient()
+		h.registerSSEClient("c1", client)
+		defer h.unregisterSSEClient("c1", client)
+
+		for i := 0; i < sseClientBuffer+10; i++ {
+			h.publishSSEMessage("c1", sseMessage{event: "generation_delta"})
+		}
+		if len(client.events) != sseClientBuffer {
+			t.Errorf("expected %d buffered events, got %d", sseClientBuffer, len(client.events))
+		}
+	})
+
+	t.Run("缓冲已满且连接不读取时结束事件挤出普通事件", func(t *testing.T) {
+		h := &HTTPHandler{}
+		client := newSSEClient()
+		h.registerSSEClient("c2", client)
+		defer h.unregisterSSEClient("c2", client)
+
+		for i := 0; i < sseClientBuffer; i++ {
+			h.publishSSEMessage("c2", sseMessage{event: "generation_delta"})
+		}
+		published := make(chan struct{})
+		go func() {
+			h.publishSSEMessage("c2", sseMessage{event: "generation_completed", terminal: true})
+			close(published)
+		}()
+		select {
+		case <-published:
+		case <-time.After(time.Second):
+			t.Fatal("publisher blocked on a client that never reads")
+		}
+
+		if len(client.events) != sseClientBuffer {
+			t.Fatalf("expected %d buffered events, got %d", sseClientBuffer, len(client.events))
+		}
+		var last sseMessage
+		for i := 0; i < sseClientBuffer; i++ {
+			last = <-client.events
+		}
+		if last.event != "generation_completed" {
+			t.Errorf("expected terminal event last, got %s", last.event)
+		}
+	})
+
+	t.Run("缓冲被结束事件占满时断开连接", func(t *testing.T) {
+		h := &HTTPHandler{}
+		client := newSSEClient()
+		h.registerSSEClient("c3", client)
+		for i := 0; i < sseClientBuffer; i++ {
+			h.publishSSEMessage("c3", sseMessage{event: "generation_completed", terminal: true})
+		}
+
+		published := make(chan struct{})
+		go func() {
+			h.publishSSEMessage("c3", sseMessage{event: "generation_completed", terminal: true})
+			close(published)
+		}()
+		select {
+		case <-published:
+		case <-time.After(time.Second):
+			t.Fatal("publisher blocked on a client that never reads")
+		}
+
+		select {
+		case <-client.done:
+		default:
+			t.Error("expected the stalled client to be disconnected")
+		}
+		h.sseMu.Lock()
+		remaining := len(h.sseClients["c3"])
+		h.sseMu.Unlock()
+		if remaining != 0 {
+			t.Errorf("expected client to be unregistered, %d remaining", remaining)
+		}
+	})
+}
@@ -60,6 +60,37 @@ type Config struct {
 	VolcengineAPIKey string `env:"VOLCENGINE_API_KEY" envDefault:""`
 	FalAPIKey        string `env:"FAL_KEY" envDefault:""`
 
+	// 生成任务队列配置
+	GenerationWorkerID                string `env:"GENERATION_WORKER_ID" envDefault:""`
+	GenerationWorkers                 int    `env:"GENERATION_WORKERS" envDefault:"4"`
+	GenerationPollIntervalSeconds     int    `env:"GENERATION_POLL_INTERVAL_SECONDS" envDefault:"2"`
+	GenerationLeaseSeconds            int    `env:"GENERATION_LEASE_SECONDS" envDefault:"60"`
+	GenerationMaxAttempts             int    `env:"GENERATION_MAX_ATTEMPTS" envDefault:"3"`
+	GenerationRecoveryIntervalSeconds int    `env:"GENERATION_RECOVERY_INTERVAL_SECONDS" envDefault:"60"`
+
+	// Webhook 投递配置
+	WebhookPollIntervalSeconds int `env:"WEBHOOK_POLL_INTERVAL_SECONDS" envDefault:"5"`
+	WebhookTimeoutSeconds      int `env:"WEBHOOK_TIMEOUT_SECONDS" envDefault:"10"`
+	WebhookMaxAttempts         int `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"6"`
+
+	// 服务商健康检查配置（间隔为 0 时关闭后台检查，连通性测试接口不受影响）
+	ProviderHealthIntervalSeconds int `env:"PROVIDER_HEALTH_INTERVAL_SECONDS" envDefault:"300"`
+	ProviderHealthTimeoutSeconds  int `env:"PROVIDER_HEALTH_TIMEOUT_SECONDS" envDefault:"15"`
+	ProviderHealthRetentionHours  int `env:"PROVIDER_HEALTH_RETENTION_HOURS" envDefault:"168"`
+
+	// 服务商异步任务完成回调配置（回调地址形如 https://example.com/api/callbacks），未配置时回退为轮询
+	ProviderCallbackBaseURL string `env:"PROVIDER_CALLBACK_BASE_URL" envDefault:""`
+	ProviderCallbackSecret  string `env:"PROVIDER_CALLBACK_SECRET" envDefault:""`
+
+	// 额度配置：开启后余额不足以预留预估费用时拒绝生成；关闭时仍记录预留与结算流水，配额始终生效
+	CreditsEnforced bool `env:"CREDITS_ENFORCED" envDefault:"false"`
+
+	// 按角色的提交限流（令牌桶，每分钟请求数为 0 表示不限制），可在用户 settings.rate_limit 中单独覆盖
+	RateLimitUserRPM    int `env:"RATE_LIMIT_USER_RPM" envDefault:"30"`
+	RateLimitUserBurst  int `env:"RATE_LIMIT_USER_BURST" envDefault:"10"`
+	RateLimitAdminRPM   int `env:"RATE_LIMIT_ADMIN_RPM" envDefault:"0"`
+	RateLimitAdminBurst int `env:"RATE_LIMIT_ADMIN_BURST" envDefault:"10"`
+
 	JWTSecret            string `env:"JWT_SECRET" envDefault:"dev-secret-change-me"`
 	JWTIssuer            string `env:"JWT_ISSUER" envDefault:"clothing-app"`
 	JWTExpirationMinutes int    `env:"JWT_EXPIRATION_MINUTES" envDefault:"1440"`
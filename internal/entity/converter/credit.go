@@ -0,0 +1,44 @@
+package converter
+
+import (
+	"clothing/internal/entity/db"
+	"clothing/internal/entity/dto"
+)
+
+// CreditAccountToDTO converts db.CreditAccount to dto.CreditAccount.
+func CreditAccountToDTO(a *db.CreditAccount) dto.CreditAccount {
+	return dto.CreditAccount{
+		UserID:  a.UserID,
+		Balance: a.Balance,
+	}
+}
+
+// CreditLedgerEntryToDTO converts db.CreditLedgerEntry to dto.CreditLedgerEntry.
+func CreditLedgerEntryToDTO(e *db.CreditLedgerEntry) dto.CreditLedgerEntry {
+	return dto.CreditLedgerEntry{
+		ID:           e.ID,
+		Type:         e.Type,
+		Amount:       e.Amount,
+		BalanceAfter: e.BalanceAfter,
+		RecordID:     e.RecordID,
+		ProviderID:   e.ProviderID,
+		ModelID:      e.ModelID,
+		Note:         e.Note,
+		CreatedBy:    e.CreatedBy,
+		CreatedAt:    e.CreatedAt,
+	}
+}
+
+// CreditQuotaToDTO converts db.CreditQuota to dto.CreditQuota.
+func CreditQuotaToDTO(q *db.CreditQuota) dto.CreditQuota {
+	return dto.CreditQuota{
+		ID:         q.ID,
+		UserID:     q.UserID,
+		ProviderID: q.ProviderID,
+		ModelID:    q.ModelID,
+		Period:     q.Period,
+		Limit:      q.Limit,
+		CreatedAt:  q.CreatedAt,
+		UpdatedAt:  q.UpdatedAt,
+	}
+}
@@ -0,0 +1,75 @@
+package converter
+
+import (
+	"clothing/internal/entity/db"
+	"clothing/internal/entity/dto"
+)
+
+// PipelineStepsToDTOs converts stored pipeline steps to dto.PipelineStep.
+func PipelineStepsToDTOs(steps db.PipelineSteps) []dto.PipelineStep {
+	items := make([]dto.PipelineStep, 0, len(steps))
+	for _, step := range steps {
+		inputs := make([]dto.PipelineStepInput, 0, len(step.Inputs))
+		for _, input := range step.Inputs {
+			inputs = append(inputs, dto.PipelineStepInput{
+				FromStep: input.FromStep,
+				Output:   input.Output,
+				Type:     input.Type,
+				Role:     input.Role,
+			})
+		}
+		items = append(items, dto.PipelineStep{
+			Name:       step.Name,
+			ProviderID: step.ProviderID,
+			ModelID:    step.ModelID,
+			Prompt:     step.Prompt,
+			Inputs:     inputs,
+			Output: dto.OutputConfig{
+				Size:       step.Size,
+				Duration:   step.Duration,
+				NumOutputs: step.NumOutputs,
+			},
+		})
+	}
+	return items
+}
+
+// PipelineStepsFromDTOs converts requested pipeline steps to their stored form.
+func PipelineStepsFromDTOs(steps []dto.PipelineStep) db.PipelineSteps {
+	items := make(db.PipelineSteps, 0, len(steps))
+	for _, step := range steps {
+		inputs := make([]db.PipelineStepInput, 0, len(step.Inputs))
+		for _, input := range step.Inputs {
+			inputs = append(inputs, db.PipelineStepInput{
+				FromStep: input.FromStep,
+				Output:   input.Output,
+				Type:     input.Type,
+				Role:     input.Role,
+			})
+		}
+		items = append(items, db.PipelineStep{
+			Name:       step.Name,
+			ProviderID: step.ProviderID,
+			ModelID:    step.ModelID,
+			Prompt:     step.Prompt,
+			Inputs:     inputs,
+			Size:       step.Output.Size,
+			Duration:   step.Output.Duration,
+			NumOutputs: step.Output.NumOutputs,
+		})
+	}
+	return items
+}
+
+// PipelineToDTO converts a db.Pipeline to dto.Pipeline.
+func PipelineToDTO(p *db.Pipeline) dto.Pipeline {
+	return dto.Pipeline{
+		ID:          p.ID,
+		UserID:      p.UserID,
+		Name:        p.Name,
+		Description: p.Description,
+		Steps:       PipelineStepsToDTOs(p.Steps),
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
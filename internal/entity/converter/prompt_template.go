@@ -0,0 +1,31 @@
+package converter
+
+import (
+	"clothing/internal/entity/db"
+	"clothing/internal/entity/dto"
+)
+
+// PromptTemplateToDTO converts a db.PromptTemplate to dto.PromptTemplate.
+func PromptTemplateToDTO(t *db.PromptTemplate) dto.PromptTemplate {
+	variables := make([]dto.PromptTemplateVariable, 0, len(t.Variables))
+	for _, v := range t.Variables {
+		variables = append(variables, dto.PromptTemplateVariable{
+			Name:        v.Name,
+			Type:        v.Type,
+			Description: v.Description,
+			Default:     v.Default,
+			Choices:     v.Choices,
+		})
+	}
+	return dto.PromptTemplate{
+		ID:          t.ID,
+		UserID:      t.UserID,
+		Name:        t.Name,
+		Description: t.Description,
+		Template:    t.Template,
+		Variables:   variables,
+		Shared:      t.Shared,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+}
@@ -23,6 +23,8 @@ func ProviderToAdminView(p *db.Provider, includeModels bool) dto.ProviderAdminVi
 		HasAPIKey: hasAPIKey,
 		CreatedAt: p.CreatedAt,
 		UpdatedAt: p.UpdatedAt,
+
+		ConfigVersion: p.ConfigVersion,
 	}
 	if strings.TrimSpace(p.Description) != "" {
 		view.Description = p.Description
@@ -37,6 +39,18 @@ func ProviderToAdminView(p *db.Provider, includeModels bool) dto.ProviderAdminVi
 	return view
 }
 
+// ProviderHealthCheckToDTO converts db.ProviderHealthCheck to dto.ProviderHealthCheck.
+func ProviderHealthCheckToDTO(c *db.ProviderHealthCheck) dto.ProviderHealthCheck {
+	return dto.ProviderHealthCheck{
+		Status:     c.Status,
+		Source:     c.Source,
+		LatencyMs:  c.LatencyMs,
+		StatusCode: c.StatusCode,
+		Error:      c.Error,
+		CheckedAt:  c.CreatedAt,
+	}
+}
+
 // ProvidersToAdminViews converts a slice of db.Provider to dto.ProviderAdminView.
 func ProvidersToAdminViews(providers []db.Provider, includeModels bool) []dto.ProviderAdminView {
 	views := make([]dto.ProviderAdminView, len(providers))
@@ -73,6 +87,7 @@ func ModelToSummary(m *db.Model) dto.ProviderModelSummary {
 		Name:               m.Name,
 		Description:        m.Description,
 		Price:              m.Price,
+		Pricing:            ModelPricingToDTO(m.Pricing),
 		MaxImages:          m.MaxImages,
 		InputModalities:    inputModalities,
 		OutputModalities:   outputModalities,
@@ -99,3 +114,31 @@ func ModelsToSummaries(models []db.Model) []dto.ProviderModelSummary {
 	}
 	return summaries
 }
+
+// ModelPricingToDTO converts db.ModelPricing to dto.ModelPricing, returning nil when no rate is set.
+func ModelPricingToDTO(p db.ModelPricing) *dto.ModelPricing {
+	if p.IsEmpty() {
+		return nil
+	}
+	return &dto.ModelPricing{
+		PerImage:                p.PerImage,
+		PerVideoSecond:          p.PerVideoSecond,
+		PerMegapixel:            p.PerMegapixel,
+		PerThousandInputTokens:  p.PerThousandInputTokens,
+		PerThousandOutputTokens: p.PerThousandOutputTokens,
+	}
+}
+
+// ModelPricingFromDTO converts dto.ModelPricing to db.ModelPricing.
+func ModelPricingFromDTO(p *dto.ModelPricing) db.ModelPricing {
+	if p == nil {
+		return db.ModelPricing{}
+	}
+	return db.ModelPricing{
+		PerImage:                p.PerImage,
+		PerVideoSecond:          p.PerVideoSecond,
+		PerMegapixel:            p.PerMegapixel,
+		PerThousandInputTokens:  p.PerThousandInputTokens,
+		PerThousandOutputTokens: p.PerThousandOutputTokens,
+	}
+}
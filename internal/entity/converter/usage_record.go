@@ -57,18 +57,35 @@ func UsageRecordToItem(r *db.UsageRecord, imageURLBuilder func(path string) dto.
 	}
 
 	return dto.UsageRecordItem{
-		ID:           r.ID,
-		ProviderID:   r.ProviderID,
-		ModelID:      r.ModelID,
-		Prompt:       r.Prompt,
-		Size:         r.Size,
-		OutputText:   r.OutputText,
-		ErrorMessage: r.ErrorMessage,
-		CreatedAt:    r.CreatedAt,
-		InputImages:  inputImages,
-		OutputImages: outputImages,
-		User:         user,
-		Tags:         tags,
+		ID:               r.ID,
+		BatchID:          r.BatchID,
+		PipelineRunID:    r.PipelineRunID,
+		PipelineStep:     r.PipelineStep,
+		ParentRecordID:   r.ParentRecordID,
+		ProviderID:       r.ProviderID,
+		ModelID:          r.ModelID,
+		ServedProviderID: r.ServedProviderID,
+		ServedModelID:    r.ServedModelID,
+		Prompt:           r.Prompt,
+		PromptTemplateID: r.PromptTemplateID,
+		PromptVariables:  r.PromptVariables,
+		Size:             r.Size,
+		AdvancedParams:   AdvancedParamsToDTO(r.AdvancedParams),
+		Cost:             r.Cost,
+		CostSource:       r.CostSource,
+		OutputText:       r.OutputText,
+		ErrorMessage:     r.ErrorMessage,
+		Status:           r.Status,
+		CreatedAt:        r.CreatedAt,
+		StartedAt:        r.StartedAt,
+		FinishedAt:       r.FinishedAt,
+		Attempts:         AttemptsToDTOs(r.Attempts),
+		InputImages:      inputImages,
+		OutputImages:     outputImages,
+		NumOutputs:       r.NumOutputs,
+		Outputs:          OutputsToDTOs(r.Outputs, imageURLBuilder),
+		User:             user,
+		Tags:             tags,
 	}
 }
 
@@ -80,3 +97,65 @@ func UsageRecordsToItems(records []db.UsageRecord, imageURLBuilder func(path str
 	}
 	return items
 }
+
+// OutputsToDTOs converts per-output results to dto.UsageOutput.
+func OutputsToDTOs(outputs db.GenerationOutputs, imageURLBuilder func(path string) dto.UsageImage) []dto.UsageOutput {
+	items := make([]dto.UsageOutput, 0, len(outputs))
+	for _, o := range outputs {
+		item := dto.UsageOutput{
+			Index:  o.Index,
+			Status: o.Status,
+			Error:  o.Error,
+		}
+		if o.Path != "" {
+			image := imageURLBuilder(o.Path)
+			item.Image = &image
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// AttemptsToDTOs converts recorded provider attempts to dto.UsageAttempt.
+func AttemptsToDTOs(attempts db.GenerationAttempts) []dto.UsageAttempt {
+	items := make([]dto.UsageAttempt, 0, len(attempts))
+	for _, a := range attempts {
+		items = append(items, dto.UsageAttempt{
+			Attempt:    a.Attempt,
+			ProviderID: a.ProviderID,
+			ModelID:    a.ModelID,
+			StartedAt:  a.StartedAt,
+			FinishedAt: a.FinishedAt,
+			Error:      a.Error,
+			StatusCode: a.StatusCode,
+			Retryable:  a.Retryable,
+			BackoffMs:  a.BackoffMs,
+		})
+	}
+	return items
+}
+
+// AdvancedParamsToDTO converts stored generation parameters to dto.AdvancedParams, nil when none were applied.
+func AdvancedParamsToDTO(p db.GenerationParams) *dto.AdvancedParams {
+	if p.IsEmpty() {
+		return nil
+	}
+	return &dto.AdvancedParams{
+		Seed:           p.Seed,
+		NegativePrompt: p.NegativePrompt,
+		GuidanceScale:  p.GuidanceScale,
+		Steps:          p.Steps,
+		Strength:       p.Strength,
+	}
+}
+
+// AdvancedParamsFromDTO converts requested advanced parameters to their stored form.
+func AdvancedParamsFromDTO(p dto.AdvancedParams) db.GenerationParams {
+	return db.GenerationParams{
+		Seed:           p.Seed,
+		NegativePrompt: p.NegativePrompt,
+		GuidanceScale:  p.GuidanceScale,
+		Steps:          p.Steps,
+		Strength:       p.Strength,
+	}
+}
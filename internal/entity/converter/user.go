@@ -16,6 +16,7 @@ func UserToSummary(u *db.User) dto.UserSummary {
 		DisplayName: u.DisplayName,
 		Role:        u.Role,
 		IsActive:    u.IsActive,
+		Settings:    u.Settings,
 		CreatedAt:   u.CreatedAt,
 		UpdatedAt:   u.UpdatedAt,
 	}
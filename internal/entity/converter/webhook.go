@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"clothing/internal/entity/db"
+	"clothing/internal/entity/dto"
+	"strings"
+)
+
+// UsageRecordToWebhookData converts a finished usage record to the data of a webhook event.
+func UsageRecordToWebhookData(r *db.UsageRecord, imageURLBuilder func(path string) dto.UsageImage) dto.WebhookGenerationData {
+	data := dto.WebhookGenerationData{
+		RecordID:         r.ID,
+		BatchID:          r.BatchID,
+		UserID:           r.UserID,
+		Status:           r.Status,
+		ProviderID:       r.ProviderID,
+		ModelID:          r.ModelID,
+		ServedProviderID: r.ServedProviderID,
+		ServedModelID:    r.ServedModelID,
+		Prompt:           r.Prompt,
+		OutputURLs:       make([]string, 0, len(r.OutputImages)),
+		OutputText:       r.OutputText,
+		Error:            r.ErrorMessage,
+		Tags:             TagsToDTOs(r.Tags),
+		CreatedAt:        r.CreatedAt,
+		StartedAt:        r.StartedAt,
+		FinishedAt:       r.FinishedAt,
+	}
+	for _, path := range r.OutputImages {
+		if trimmed := strings.TrimSpace(path); trimmed != "" {
+			data.OutputURLs = append(data.OutputURLs, imageURLBuilder(trimmed).URL)
+		}
+	}
+	if len(r.Outputs) > 0 {
+		data.Outputs = OutputsToDTOs(r.Outputs, imageURLBuilder)
+	}
+	if r.StartedAt != nil && r.FinishedAt != nil {
+		data.DurationMs = r.FinishedAt.Sub(*r.StartedAt).Milliseconds()
+	}
+	return data
+}
+
+// WebhookEndpointToDTO converts a db.WebhookEndpoint to dto.WebhookEndpoint.
+func WebhookEndpointToDTO(e *db.WebhookEndpoint) dto.WebhookEndpoint {
+	events := []string(e.Events)
+	if events == nil {
+		events = []string{}
+	}
+	return dto.WebhookEndpoint{
+		ID:          e.ID,
+		UserID:      e.UserID,
+		URL:         e.URL,
+		Events:      events,
+		Description: e.Description,
+		AllUsers:    e.AllUsers,
+		IsActive:    e.IsActive,
+		CreatedAt:   e.CreatedAt,
+		UpdatedAt:   e.UpdatedAt,
+	}
+}
+
+// WebhookDeliveryToDTO converts a db.WebhookDelivery to dto.WebhookDelivery.
+func WebhookDeliveryToDTO(d *db.WebhookDelivery) dto.WebhookDelivery {
+	item := dto.WebhookDelivery{
+		ID:             d.ID,
+		RecordID:       d.RecordID,
+		Event:          d.Event,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+	}
+	if d.Status == db.WebhookDeliveryStatusPending {
+		next := d.NextAttemptAt
+		item.NextAttemptAt = &next
+	}
+	return item
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 额度流水类型
+const (
+	CreditEntryTypeGrant   = "grant"   // 管理员发放或扣减额度
+	CreditEntryTypeReserve = "reserve" // 提交生成时按预估费用预留
+	CreditEntryTypeSettle  = "settle"  // 生成结束后结算，退还预留与实际费用的差额
+)
+
+// 配额统计周期
+const (
+	CreditQuotaPeriodDaily   = "daily"
+	CreditQuotaPeriodMonthly = "monthly"
+)
+
+// ErrInsufficientCredits 余额不足以预留本次生成的预估费用
+var ErrInsufficientCredits = errors.New("insufficient credits")
+
+// CreditAccount 用户的额度余额，单位与使用记录的费用相同（美元）。
+type CreditAccount struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Balance   float64   `gorm:"column:balance;not null;default:0" json:"balance"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (CreditAccount) TableName() string {
+	return "credit_accounts"
+}
+
+// CreditLedgerEntry 额度流水，只追加不修改。Amount 为对余额的变动（预留为负），BalanceAfter 为变动后的余额。
+type CreditLedgerEntry struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index:idx_credit_ledger_user_created,priority:2" json:"created_at"`
+
+	UserID uint   `gorm:"column:user_id;not null;index:idx_credit_ledger_user_created,priority:1" json:"user_id"`
+	Type   string `gorm:"column:type;type:varchar(32);not null;uniqueIndex:idx_credit_ledger_record_type,priority:2" json:"type"`
+
+	Amount       float64 `gorm:"column:amount;not null" json:"amount"`
+	BalanceAfter float64 `gorm:"column:balance_after;not null" json:"balance_after"`
+
+	// RecordID 关联的使用记录，每条记录至多一次预留与一次结算
+	RecordID *uint `gorm:"column:record_id;uniqueIndex:idx_credit_ledger_record_type,priority:1" json:"record_id"`
+	// ProviderID/ModelID 为生成请求的服务商与模型，用于按服务商/模型统计配额
+	ProviderID string `gorm:"column:provider_id;type:varchar(64)" json:"provider_id"`
+	ModelID    string `gorm:"column:model_id;type:varchar(255)" json:"model_id"`
+
+	Note string `gorm:"column:note;type:text" json:"note"`
+	// CreatedBy 发放额度的管理员
+	CreatedBy *uint `gorm:"column:created_by" json:"created_by"`
+}
+
+// TableName 指定表名
+func (CreditLedgerEntry) TableName() string {
+	return "credit_ledger_entries"
+}
+
+// CreditQuota 限制用户每个周期在服务商/模型上的花费。
+// UserID 为 0 时对每个用户分别生效；ProviderID、ModelID 为空表示不限服务商、模型。
+type CreditQuota struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID     uint    `gorm:"column:user_id;not null;default:0;index" json:"user_id"`
+	ProviderID string  `gorm:"column:provider_id;type:varchar(64)" json:"provider_id"`
+	ModelID    string  `gorm:"column:model_id;type:varchar(255)" json:"model_id"`
+	Period     string  `gorm:"column:period;type:varchar(16);not null" json:"period"`
+	Limit      float64 `gorm:"column:limit_amount;not null" json:"limit"`
+}
+
+// TableName 指定表名
+func (CreditQuota) TableName() string {
+	return "credit_quotas"
+}
+
+// PeriodStart 返回 now 所在统计周期的开始时间（按 now 的时区计算自然日、自然月）
+func (q CreditQuota) PeriodStart(now time.Time) time.Time {
+	year, month, day := now.Date()
+	if q.Period == CreditQuotaPeriodMonthly {
+		return time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+}
+
+// QuotaExceededError 预留会使周期内花费超出配额时返回
+type QuotaExceededError struct {
+	Quota CreditQuota
+	// Spent 本周期内已花费（含未结算的预留）
+	Spent float64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s quota %d exceeded: spent %.4f of %.4f", e.Quota.Period, e.Quota.ID, e.Spent, e.Quota.Limit)
+}
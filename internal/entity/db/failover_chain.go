@@ -0,0 +1,73 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// FailoverChain 逻辑模型的故障转移链：主服务商/模型不可用时，按顺序尝试备用的服务商/模型。
+type FailoverChain struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// ProviderID/ModelID 为链首（用户请求的）服务商与模型，每个组合最多一条链
+	ProviderID  string        `gorm:"column:provider_id;type:varchar(64);uniqueIndex:idx_failover_primary,priority:1;not null" json:"provider_id"`
+	ModelID     string        `gorm:"column:model_id;type:varchar(255);uniqueIndex:idx_failover_primary,priority:2;not null" json:"model_id"`
+	Fallbacks   FailoverSteps `gorm:"column:fallbacks;type:json" json:"fallbacks"`
+	Description string        `gorm:"column:description;type:text" json:"description"`
+	IsActive    bool          `gorm:"column:is_active;default:true" json:"is_active"`
+}
+
+// TableName 指定表名
+func (FailoverChain) TableName() string {
+	return "failover_chains"
+}
+
+// FailoverStep 故障转移链中的一个备用服务商/模型
+type FailoverStep struct {
+	ProviderID string `json:"provider_id"`
+	ModelID    string `json:"model_id"`
+}
+
+// FailoverSteps 以 JSON 格式存储有序的备用列表。
+type FailoverSteps []FailoverStep
+
+// Value 实现 driver.Valuer 接口。
+func (s FailoverSteps) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return "[]", nil
+	}
+	raw, err := json.Marshal([]FailoverStep(s))
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan 实现 sql.Scanner 接口。
+func (s *FailoverSteps) Scan(value interface{}) error {
+	if value == nil {
+		*s = nil
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		if len(v) == 0 {
+			*s = FailoverSteps{}
+			return nil
+		}
+		return json.Unmarshal(v, (*[]FailoverStep)(s))
+	case string:
+		if v == "" {
+			*s = FailoverSteps{}
+			return nil
+		}
+		return json.Unmarshal([]byte(v), (*[]FailoverStep)(s))
+	default:
+		return fmt.Errorf("unsupported type for FailoverSteps: %T", value)
+	}
+}
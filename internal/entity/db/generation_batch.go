@@ -0,0 +1,30 @@
+package db
+
+import "time"
+
+const (
+	GenerationBatchStatusRunning   = "running"
+	GenerationBatchStatusCompleted = "completed"
+)
+
+// GenerationBatch 批量生成任务：多个提示词 × 多个服务商/模型，每个组合对应一条子使用记录。
+// 子任务通过生成任务队列执行，同一批次同时执行的任务数不超过 MaxConcurrency。
+type GenerationBatch struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID   uint   `gorm:"column:user_id;index" json:"user_id"`
+	ClientID string `gorm:"column:client_id;type:varchar(255)" json:"client_id"`
+	Name     string `gorm:"column:name;type:varchar(255)" json:"name"`
+
+	Total          int        `gorm:"column:total;not null;default:0" json:"total"`
+	MaxConcurrency int        `gorm:"column:max_concurrency;not null;default:1" json:"max_concurrency"`
+	Status         string     `gorm:"column:status;type:varchar(32);index;not null" json:"status"`
+	FinishedAt     *time.Time `gorm:"column:finished_at" json:"finished_at"`
+}
+
+// TableName 指定表名
+func (GenerationBatch) TableName() string {
+	return "generation_batches"
+}
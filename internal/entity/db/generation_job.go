@@ -0,0 +1,104 @@
+package db
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	GenerationJobStatusPending   = "pending"
+	GenerationJobStatusRunning   = "running"
+	GenerationJobStatusSucceeded = "succeeded"
+	GenerationJobStatusFailed    = "failed"
+	GenerationJobStatusCancelled = "cancelled"
+)
+
+// 生成任务优先级通道，数值越大越先被认领；同一通道内按用户轮转认领
+const (
+	GenerationJobPriorityBatch       = 0  // 批量生成与流水线步骤
+	GenerationJobPriorityInteractive = 10 // 用户直接提交的生成
+	GenerationJobPriorityAdmin       = 20 // 管理员直接提交的生成
+)
+
+// GenerationJob 持久化的生成任务队列条目，每条使用记录对应一个任务。
+// 任务通过租约（lease）被某个工作实例认领，租约过期未续期的任务会被重新认领。
+type GenerationJob struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	RecordID   uint   `gorm:"column:record_id;uniqueIndex" json:"record_id"`
+	UserID     uint   `gorm:"column:user_id;index" json:"user_id"`
+	ProviderID string `gorm:"column:provider_id;type:varchar(64)" json:"provider_id"`
+	ModelID    string `gorm:"column:model_id;type:varchar(255)" json:"model_id"`
+	ClientID   string `gorm:"column:client_id;type:varchar(255)" json:"client_id"`
+	// Priority 优先级通道，见 GenerationJobPriority*
+	Priority int `gorm:"column:priority;not null;default:0" json:"priority"`
+	// BatchID 所属批量生成任务，认领时据此限制批次并发
+	BatchID *uint `gorm:"column:batch_id;index" json:"batch_id"`
+	// PipelineRunID 所属流水线运行，任务结束后据此推进到下一步骤
+	PipelineRunID *uint `gorm:"column:pipeline_run_id;index" json:"pipeline_run_id"`
+
+	// Payload 是 JSON 编码的生成请求，可能包含 base64 输入图片，因此不限定列类型，
+	// 由各数据库方言选择足够大的文本类型（MySQL longtext / PostgreSQL text / SQLite text）。
+	Payload string `gorm:"column:payload" json:"-"`
+
+	Status         string     `gorm:"column:status;type:varchar(32);index:idx_generation_job_claim,priority:1;not null" json:"status"`
+	LeaseExpiresAt *time.Time `gorm:"column:lease_expires_at;index:idx_generation_job_claim,priority:2" json:"lease_expires_at"`
+	LeaseOwner     string     `gorm:"column:lease_owner;type:varchar(128)" json:"lease_owner"`
+	Attempts       int        `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	LastError      string     `gorm:"column:last_error;type:text" json:"last_error"`
+	// CancelRequested 由取消接口设置，持有租约的实例在续约时感知并中止执行。
+	CancelRequested bool       `gorm:"column:cancel_requested;not null;default:false" json:"cancel_requested"`
+	FinishedAt      *time.Time `gorm:"column:finished_at" json:"finished_at"`
+}
+
+// TableName 指定表名
+func (GenerationJob) TableName() string {
+	return "generation_jobs"
+}
+
+// GenerationJobLaneName 返回优先级通道的名称
+func GenerationJobLaneName(priority int) string {
+	switch {
+	case priority >= GenerationJobPriorityAdmin:
+		return "admin"
+	case priority >= GenerationJobPriorityInteractive:
+		return "interactive"
+	default:
+		return "batch"
+	}
+}
+
+// ScheduleGenerationJobs 返回待认领任务的认领顺序：优先级高的通道优先；同一通道内按用户轮转，
+// 正在执行任务较少的用户先轮到，同一用户的任务按提交顺序。running 为各用户正在执行的任务数。
+func ScheduleGenerationJobs(pending []GenerationJob, running map[uint]int) []GenerationJob {
+	ordered := make([]GenerationJob, len(pending))
+	copy(ordered, pending)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].ID < ordered[j].ID })
+
+	type laneUser struct {
+		priority int
+		userID   uint
+	}
+	// turn 为任务在轮转中的轮次：用户已执行的任务数加上该用户在同一通道中更早提交的任务数
+	turns := make(map[uint]int, len(ordered))
+	queued := make(map[laneUser]int)
+	for _, job := range ordered {
+		key := laneUser{priority: job.Priority, userID: job.UserID}
+		turns[job.ID] = running[job.UserID] + queued[key]
+		queued[key]++
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if turns[a.ID] != turns[b.ID] {
+			return turns[a.ID] < turns[b.ID]
+		}
+		return a.ID < b.ID
+	})
+	return ordered
+}
@@ -0,0 +1,20 @@
+package db
+
+import "time"
+
+// IdempotencyKey 记录用户提交生成请求时携带的幂等键，有效期内重复提交返回同一条使用记录。
+// RecordID 为 0 表示首个请求仍在创建使用记录。
+type IdempotencyKey struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID    uint      `gorm:"column:user_id;uniqueIndex:idx_idempotency_user_key,priority:1;not null" json:"user_id"`
+	Key       string    `gorm:"column:idempotency_key;type:varchar(255);uniqueIndex:idx_idempotency_user_key,priority:2;not null" json:"key"`
+	RecordID  uint      `gorm:"column:record_id;not null;default:0" json:"record_id"`
+	ExpiresAt time.Time `gorm:"column:expires_at;index;not null" json:"expires_at"`
+}
+
+// TableName 指定表名
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"clothing/internal/entity/common"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 流水线运行状态
+const (
+	PipelineRunStatusRunning   = "running"
+	PipelineRunStatusSucceeded = "succeeded"
+	PipelineRunStatusFailed    = "failed"
+	PipelineRunStatusCancelled = "cancelled"
+)
+
+// Pipeline 多步生成流水线定义：按顺序执行的服务商/模型步骤，后续步骤可将之前步骤的输出作为输入媒体。
+type Pipeline struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID      uint          `gorm:"column:user_id;index;not null" json:"user_id"`
+	Name        string        `gorm:"column:name;type:varchar(128);not null" json:"name"`
+	Description string        `gorm:"column:description;type:text" json:"description"`
+	Steps       PipelineSteps `gorm:"column:steps;type:json" json:"steps"`
+}
+
+// TableName 指定表名
+func (Pipeline) TableName() string {
+	return "pipelines"
+}
+
+// PipelineStep 流水线中的一个生成步骤
+type PipelineStep struct {
+	Name       string `json:"name,omitempty"`
+	ProviderID string `json:"provider_id"`
+	ModelID    string `json:"model_id"`
+	// Prompt 可包含 {{variable}} 占位符，由运行时提交的变量渲染
+	Prompt     string              `json:"prompt"`
+	Inputs     []PipelineStepInput `json:"inputs,omitempty"`
+	Size       string              `json:"size,omitempty"`
+	Duration   int                 `json:"duration,omitempty"`
+	NumOutputs int                 `json:"num_outputs,omitempty"`
+}
+
+// PipelineStepInput 将之前步骤的输出（或运行时提交的输入媒体）映射为当前步骤的输入媒体
+type PipelineStepInput struct {
+	// FromStep 取第几步（从 1 开始）的输出，0 表示运行时提交的输入媒体
+	FromStep int `json:"from_step"`
+	// Output 取第几个输出（从 0 开始），为空时取全部输出
+	Output *int   `json:"output,omitempty"`
+	Type   string `json:"type"`           // image, video
+	Role   string `json:"role,omitempty"` // reference, first_frame, last_frame
+}
+
+// PipelineSteps 以 JSON 格式存储有序的步骤列表。
+type PipelineSteps []PipelineStep
+
+// Value 实现 driver.Valuer 接口。
+func (s PipelineSteps) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return "[]", nil
+	}
+	raw, err := json.Marshal([]PipelineStep(s))
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan 实现 sql.Scanner 接口。
+func (s *PipelineSteps) Scan(value interface{}) error {
+	if value == nil {
+		*s = nil
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		if len(v) == 0 {
+			*s = PipelineSteps{}
+			return nil
+		}
+		return json.Unmarshal(v, (*[]PipelineStep)(s))
+	case string:
+		if v == "" {
+			*s = PipelineSteps{}
+			return nil
+		}
+		return json.Unmarshal([]byte(v), (*[]PipelineStep)(s))
+	default:
+		return fmt.Errorf("unsupported type for PipelineSteps: %T", value)
+	}
+}
+
+// PipelineRun 流水线的一次运行，每个步骤对应一条使用记录（UsageRecord.PipelineRunID）。
+// 步骤依次执行：当前步骤的使用记录结束后才创建下一步骤的记录与生成任务。
+type PipelineRun struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	PipelineID uint   `gorm:"column:pipeline_id;index" json:"pipeline_id"`
+	UserID     uint   `gorm:"column:user_id;index" json:"user_id"`
+	ClientID   string `gorm:"column:client_id;type:varchar(255)" json:"client_id"`
+
+	// Steps 运行开始时的步骤快照，运行期间修改流水线不影响本次运行
+	Steps     PipelineSteps  `gorm:"column:steps;type:json" json:"steps"`
+	Variables common.JSONMap `gorm:"column:variables;type:json" json:"variables"`
+	// InputMedia 是 JSON 编码的运行输入媒体，可能包含 base64 内容，因此不限定列类型
+	InputMedia string `gorm:"column:input_media" json:"-"`
+
+	// CurrentStep 正在执行的步骤（从 1 开始）
+	CurrentStep  int        `gorm:"column:current_step;not null;default:0" json:"current_step"`
+	Status       string     `gorm:"column:status;type:varchar(32);index;not null" json:"status"`
+	ErrorMessage string     `gorm:"column:error_message;type:text" json:"error_message"`
+	FinishedAt   *time.Time `gorm:"column:finished_at" json:"finished_at"`
+}
+
+// TableName 指定表名
+func (PipelineRun) TableName() string {
+	return "pipeline_runs"
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 提示词模板变量类型
+const (
+	PromptVariableTypeText = "text"
+	PromptVariableTypeEnum = "enum"
+)
+
+// PromptTemplate 带 {{variable}} 占位符的可复用提示词模板。
+type PromptTemplate struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID      uint                    `gorm:"column:user_id;index;not null" json:"user_id"`
+	Name        string                  `gorm:"column:name;type:varchar(128);not null" json:"name"`
+	Description string                  `gorm:"column:description;type:text" json:"description"`
+	Template    string                  `gorm:"column:template;type:text;not null" json:"template"`
+	Variables   PromptTemplateVariables `gorm:"column:variables;type:json" json:"variables"`
+	// Shared 共享给所有用户使用，否则仅创建者可用
+	Shared bool `gorm:"column:shared;default:false" json:"shared"`
+}
+
+// TableName 指定表名
+func (PromptTemplate) TableName() string {
+	return "prompt_templates"
+}
+
+// PromptTemplateVariable 模板中一个占位符的定义
+type PromptTemplateVariable struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // text, enum
+	Description string `json:"description,omitempty"`
+	// Default 未传入变量时使用的值，为空表示必填
+	Default *string  `json:"default,omitempty"`
+	Choices []string `json:"choices,omitempty"` // enum 类型的可选值
+}
+
+// PromptTemplateVariables 以 JSON 格式存储模板变量定义。
+type PromptTemplateVariables []PromptTemplateVariable
+
+// Value 实现 driver.Valuer 接口。
+func (v PromptTemplateVariables) Value() (driver.Value, error) {
+	if len(v) == 0 {
+		return "[]", nil
+	}
+	raw, err := json.Marshal([]PromptTemplateVariable(v))
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan 实现 sql.Scanner 接口。
+func (v *PromptTemplateVariables) Scan(value interface{}) error {
+	if value == nil {
+		*v = nil
+		return nil
+	}
+
+	switch raw := value.(type) {
+	case []byte:
+		if len(raw) == 0 {
+			*v = PromptTemplateVariables{}
+			return nil
+		}
+		return json.Unmarshal(raw, (*[]PromptTemplateVariable)(v))
+	case string:
+		if raw == "" {
+			*v = PromptTemplateVariables{}
+			return nil
+		}
+		return json.Unmarshal([]byte(raw), (*[]PromptTemplateVariable)(v))
+	default:
+		return fmt.Errorf("unsupported type for PromptTemplateVariables: %T", value)
+	}
+}
@@ -2,6 +2,9 @@ package db
 
 import (
 	"clothing/internal/entity/common"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -29,6 +32,12 @@ type Provider struct {
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 
+	// ConfigVersion 每次更新服务商时递增，各实例据此发现配置变化并重建缓存的服务商客户端
+	ConfigVersion uint64 `gorm:"column:config_version;not null;default:1" json:"config_version"`
+
+	// HealthCheckLeaseUntil 后台健康检查的租约，多个实例中仅认领到租约的实例检查该服务商
+	HealthCheckLeaseUntil *time.Time `gorm:"column:health_check_lease_until" json:"-"`
+
 	Models []Model `gorm:"foreignKey:ProviderID" json:"models,omitempty"`
 }
 
@@ -37,6 +46,26 @@ func (Provider) TableName() string {
 	return "llm_providers"
 }
 
+// MaxConcurrentJobs 返回 Config["max_concurrent_jobs"] 配置的同时执行的生成任务数上限，0 表示不限制。
+func (p *Provider) MaxConcurrentJobs() int {
+	if p == nil {
+		return 0
+	}
+	var limit int
+	switch v := p.Config["max_concurrent_jobs"].(type) {
+	case float64:
+		limit = int(v)
+	case int:
+		limit = v
+	case string:
+		limit, _ = strconv.Atoi(strings.TrimSpace(v))
+	}
+	if limit < 0 {
+		return 0
+	}
+	return limit
+}
+
 // Model 存储服务商特定的模型配置。
 type Model struct {
 	ID uint `gorm:"primarykey" json:"id"`
@@ -47,6 +76,8 @@ type Model struct {
 	Name        string `gorm:"type:varchar(255);not null" json:"name"`
 	Description string `gorm:"type:text" json:"description"`
 	Price       string `gorm:"type:varchar(64)" json:"price"`
+	// Pricing 结构化计费规则，用于计算每次生成的费用；Price 仅用于展示
+	Pricing ModelPricing `gorm:"column:pricing;type:json" json:"pricing"`
 
 	MaxImages int `gorm:"column:max_images" json:"max_images"`
 	// Modalities 保持兼容旧字段，迁移后请使用 InputModalities/OutputModalities。
@@ -79,6 +110,55 @@ func (Model) TableName() string {
 	return "llm_models"
 }
 
+// ModelPricing 模型的计费规则（美元），各项按实际产出累加。
+type ModelPricing struct {
+	PerImage                float64 `json:"per_image,omitempty"`            // 每张图片
+	PerVideoSecond          float64 `json:"per_video_second,omitempty"`     // 每秒视频
+	PerMegapixel            float64 `json:"per_megapixel,omitempty"`        // 每百万像素（按输出尺寸计算）
+	PerThousandInputTokens  float64 `json:"per_1k_input_tokens,omitempty"`  // 每千输入 token
+	PerThousandOutputTokens float64 `json:"per_1k_output_tokens,omitempty"` // 每千输出 token
+}
+
+// IsEmpty 判断是否没有配置任何计费项。
+func (p ModelPricing) IsEmpty() bool {
+	return p.PerImage == 0 && p.PerVideoSecond == 0 && p.PerMegapixel == 0 &&
+		p.PerThousandInputTokens == 0 && p.PerThousandOutputTokens == 0
+}
+
+// Value 实现 driver.Valuer 接口。
+func (p ModelPricing) Value() (driver.Value, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan 实现 sql.Scanner 接口。
+func (p *ModelPricing) Scan(value interface{}) error {
+	if value == nil {
+		*p = ModelPricing{}
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		if len(v) == 0 {
+			*p = ModelPricing{}
+			return nil
+		}
+		return json.Unmarshal(v, p)
+	case string:
+		if v == "" {
+			*p = ModelPricing{}
+			return nil
+		}
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("unsupported type for ModelPricing: %T", value)
+	}
+}
+
 // IsVideoModel 检查此模型是否输出视频。
 func (m *Model) IsVideoModel() bool {
 	return m.OutputModalities.Contains("video")
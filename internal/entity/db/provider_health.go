@@ -0,0 +1,41 @@
+package db
+
+import "time"
+
+// 服务商健康检查结果
+const (
+	ProviderHealthStatusOK    = "ok"
+	ProviderHealthStatusError = "error"
+)
+
+// 服务商健康检查来源
+const (
+	ProviderHealthSourceManual    = "manual"    // 管理员通过连通性测试接口触发
+	ProviderHealthSourceScheduled = "scheduled" // 后台健康检查定期执行
+)
+
+// ProviderHealthCheck 服务商连通性检查的历史记录，只追加，超过保留期后由后台健康检查清理。
+type ProviderHealthCheck struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index:idx_provider_health_provider_created,priority:2" json:"created_at"`
+
+	ProviderID string `gorm:"column:provider_id;type:varchar(64);not null;index:idx_provider_health_provider_created,priority:1" json:"provider_id"`
+	Status     string `gorm:"column:status;type:varchar(16);not null" json:"status"`
+	Source     string `gorm:"column:source;type:varchar(16);not null" json:"source"`
+
+	// LatencyMs 凭证检查请求的耗时（毫秒）
+	LatencyMs int64 `gorm:"column:latency_ms" json:"latency_ms"`
+	// StatusCode 服务商返回的 HTTP 状态码，请求未到达服务商时为 0
+	StatusCode int    `gorm:"column:status_code" json:"status_code"`
+	Error      string `gorm:"column:error;type:text" json:"error"`
+}
+
+// TableName 指定表名
+func (ProviderHealthCheck) TableName() string {
+	return "provider_health_checks"
+}
+
+// IsHealthy 检查是否成功
+func (c *ProviderHealthCheck) IsHealthy() bool {
+	return c != nil && c.Status == ProviderHealthStatusOK
+}
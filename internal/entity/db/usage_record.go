@@ -2,31 +2,111 @@ package db
 
 import (
 	"clothing/internal/entity/common"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
+// 使用记录生命周期状态
+const (
+	UsageRecordStatusQueued             = "queued"
+	UsageRecordStatusRunning            = "running"
+	UsageRecordStatusSucceeded          = "succeeded"
+	UsageRecordStatusFailed             = "failed"
+	UsageRecordStatusCancelled          = "cancelled"
+	UsageRecordStatusPartiallySucceeded = "partially_succeeded" // 生成成功，但部分结果未能保存
+)
+
+// UsageRecordStatusesForResult 将列表接口的 result 筛选值映射为记录状态。
+// "success"、"failure" 为兼容旧接口保留，也可直接传入状态值；空值与 "all" 不筛选（返回 nil）。
+// 无法识别的值返回 ok=false。
+func UsageRecordStatusesForResult(result string) (statuses []string, ok bool) {
+	switch trimmed := strings.ToLower(strings.TrimSpace(result)); trimmed {
+	case "", "all":
+		return nil, true
+	case "success":
+		return []string{UsageRecordStatusSucceeded, UsageRecordStatusPartiallySucceeded}, true
+	case "failure":
+		return []string{UsageRecordStatusFailed}, true
+	case "active":
+		return []string{UsageRecordStatusQueued, UsageRecordStatusRunning}, true
+	case UsageRecordStatusQueued,
+		UsageRecordStatusRunning,
+		UsageRecordStatusSucceeded,
+		UsageRecordStatusFailed,
+		UsageRecordStatusCancelled,
+		UsageRecordStatusPartiallySucceeded:
+		return []string{trimmed}, true
+	default:
+		return nil, false
+	}
+}
+
+// 费用来源
+const (
+	UsageCostSourcePricing  = "pricing"  // 按模型计费规则计算
+	UsageCostSourceProvider = "provider" // 服务商报告的实际费用
+)
+
 // UsageRecord stores a generation usage record.
 type UsageRecord struct {
 	ID        uint      `gorm:"primarykey" json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
-	UserID uint  `gorm:"column:user_id;index" json:"user_id"`
+	UserID uint  `gorm:"column:user_id;index;index:idx_usage_record_user_status,priority:1" json:"user_id"`
 	User   *User `gorm:"foreignKey:UserID" json:"-"`
 
+	// BatchID 所属批量生成任务，单次生成为空
+	BatchID *uint `gorm:"column:batch_id;index" json:"batch_id"`
+
+	// PipelineRunID 所属流水线运行，PipelineStep 为对应的步骤（从 1 开始）
+	PipelineRunID *uint `gorm:"column:pipeline_run_id;index" json:"pipeline_run_id"`
+	PipelineStep  int   `gorm:"column:pipeline_step;not null;default:0" json:"pipeline_step"`
+
+	// ParentRecordID 重新生成或改编时的来源记录，用于追溯生成谱系
+	ParentRecordID *uint `gorm:"column:parent_record_id;index" json:"parent_record_id"`
+
 	ProviderID string `gorm:"column:provider_id;type:varchar(255);index" json:"provider_id"`
 	ModelID    string `gorm:"column:model_id;type:varchar(255);index" json:"model_id"`
 	Prompt     string `gorm:"column:prompt;type:text" json:"prompt"`
 	Size       string `gorm:"column:size;type:varchar(64)" json:"size"`
 
+	// PromptTemplateID 渲染提示词所用的模板，PromptVariables 为渲染时实际使用的变量（含默认值）
+	PromptTemplateID *uint          `gorm:"column:prompt_template_id;index" json:"prompt_template_id"`
+	PromptVariables  common.JSONMap `gorm:"column:prompt_variables;type:json" json:"prompt_variables"`
+
 	InputImages  common.StringArray `gorm:"column:input_images;type:json" json:"input_images"`
 	OutputImages common.StringArray `gorm:"column:output_images;type:json" json:"output_images"`
 
+	// AdvancedParams 实际发送给服务商的高级参数（模型不支持的参数已剔除），用于复现生成结果
+	AdvancedParams GenerationParams `gorm:"column:advanced_params;type:json" json:"advanced_params"`
+
+	// NumOutputs 请求的输出数量，Outputs 记录每个输出的状态
+	NumOutputs int               `gorm:"column:num_outputs;not null;default:1" json:"num_outputs"`
+	Outputs    GenerationOutputs `gorm:"column:outputs;type:json" json:"outputs"`
+
+	// Cost 本次生成的费用（美元），优先使用服务商报告的费用，否则按实际服务模型的计费规则计算；未配置计费时为空
+	Cost       *float64 `gorm:"column:cost" json:"cost"`
+	CostSource string   `gorm:"column:cost_source;type:varchar(32)" json:"cost_source"`
+
 	OutputText   string `gorm:"column:output_text;type:text" json:"output_text"`
 	ErrorMessage string `gorm:"column:error_message;type:text" json:"error_message"`
 
-	ExternalTaskCode string `gorm:"column:external_task_code;type:varchar(255)" json:"external_task_code"` // 外部（第三方）任务code，或者任务ID
-	RequestID        string `gorm:"column:request_id;type:varchar(255)" json:"request_id"`                 // 请求ID
+	Status     string     `gorm:"column:status;type:varchar(32);index;index:idx_usage_record_user_status,priority:2" json:"status"`
+	StartedAt  *time.Time `gorm:"column:started_at" json:"started_at"`
+	FinishedAt *time.Time `gorm:"column:finished_at" json:"finished_at"`
+
+	Attempts GenerationAttempts `gorm:"column:attempts;type:json" json:"attempts"` // 服务商调用尝试记录（含重试）
+
+	// 实际完成生成的服务商与模型（发生故障转移时与 ProviderID/ModelID 不同）
+	ServedProviderID string `gorm:"column:served_provider_id;type:varchar(255)" json:"served_provider_id"`
+	ServedModelID    string `gorm:"column:served_model_id;type:varchar(255)" json:"served_model_id"`
+
+	ExternalTaskCode string `gorm:"column:external_task_code;type:varchar(255);index" json:"external_task_code"` // 外部（第三方）任务code，或者任务ID
+	RequestID        string `gorm:"column:request_id;type:varchar(255)" json:"request_id"`                       // 请求ID
 
 	Tags []Tag `gorm:"many2many:usage_record_tags;foreignKey:ID;joinForeignKey:UsageRecordID;references:ID;joinReferences:TagID" json:"tags"`
 }
@@ -35,3 +115,158 @@ type UsageRecord struct {
 func (UsageRecord) TableName() string {
 	return "usage_records"
 }
+
+// GenerationAttempt 记录一次服务商调用尝试。
+type GenerationAttempt struct {
+	Attempt    int       `json:"attempt"`
+	ProviderID string    `json:"provider_id,omitempty"`
+	ModelID    string    `json:"model_id,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      string    `json:"error,omitempty"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Retryable  bool      `json:"retryable"`
+	BackoffMs  int64     `json:"backoff_ms,omitempty"` // 距下一次尝试的等待时间
+}
+
+// GenerationAttempts 以 JSON 格式存储调用尝试列表。
+type GenerationAttempts []GenerationAttempt
+
+// Value 实现 driver.Valuer 接口。
+func (a GenerationAttempts) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return "[]", nil
+	}
+	raw, err := json.Marshal([]GenerationAttempt(a))
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan 实现 sql.Scanner 接口。
+func (a *GenerationAttempts) Scan(value interface{}) error {
+	if value == nil {
+		*a = nil
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		if len(v) == 0 {
+			*a = GenerationAttempts{}
+			return nil
+		}
+		return json.Unmarshal(v, (*[]GenerationAttempt)(a))
+	case string:
+		if v == "" {
+			*a = GenerationAttempts{}
+			return nil
+		}
+		return json.Unmarshal([]byte(v), (*[]GenerationAttempt)(a))
+	default:
+		return fmt.Errorf("unsupported type for GenerationAttempts: %T", value)
+	}
+}
+
+// 单个输出的生成状态
+const (
+	GenerationOutputStatusSucceeded = "succeeded"
+	GenerationOutputStatusFailed    = "failed"
+)
+
+// GenerationOutput 记录一次生成中单个输出（按 NumOutputs 请求的第 Index 个）的结果。
+type GenerationOutput struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	Path   string `json:"path,omitempty"` // 存储中的相对路径，成功且已保存时有值
+	Error  string `json:"error,omitempty"`
+}
+
+// GenerationOutputs 以 JSON 格式存储输出列表。
+type GenerationOutputs []GenerationOutput
+
+// Value 实现 driver.Valuer 接口。
+func (o GenerationOutputs) Value() (driver.Value, error) {
+	if len(o) == 0 {
+		return "[]", nil
+	}
+	raw, err := json.Marshal([]GenerationOutput(o))
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan 实现 sql.Scanner 接口。
+func (o *GenerationOutputs) Scan(value interface{}) error {
+	if value == nil {
+		*o = nil
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		if len(v) == 0 {
+			*o = GenerationOutputs{}
+			return nil
+		}
+		return json.Unmarshal(v, (*[]GenerationOutput)(o))
+	case string:
+		if v == "" {
+			*o = GenerationOutputs{}
+			return nil
+		}
+		return json.Unmarshal([]byte(v), (*[]GenerationOutput)(o))
+	default:
+		return fmt.Errorf("unsupported type for GenerationOutputs: %T", value)
+	}
+}
+
+// GenerationParams 以 JSON 格式存储高级生成参数。
+type GenerationParams struct {
+	Seed           *int64   `json:"seed,omitempty"`
+	NegativePrompt string   `json:"negative_prompt,omitempty"`
+	GuidanceScale  *float64 `json:"guidance_scale,omitempty"`
+	Steps          *int     `json:"steps,omitempty"`
+	Strength       *float64 `json:"strength,omitempty"`
+}
+
+// IsEmpty 判断是否没有设置任何参数。
+func (p GenerationParams) IsEmpty() bool {
+	return p.Seed == nil && p.NegativePrompt == "" && p.GuidanceScale == nil && p.Steps == nil && p.Strength == nil
+}
+
+// Value 实现 driver.Valuer 接口。
+func (p GenerationParams) Value() (driver.Value, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan 实现 sql.Scanner 接口。
+func (p *GenerationParams) Scan(value interface{}) error {
+	if value == nil {
+		*p = GenerationParams{}
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		if len(v) == 0 {
+			*p = GenerationParams{}
+			return nil
+		}
+		return json.Unmarshal(v, p)
+	case string:
+		if v == "" {
+			*p = GenerationParams{}
+			return nil
+		}
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("unsupported type for GenerationParams: %T", value)
+	}
+}
@@ -0,0 +1,25 @@
+package db
+
+import "time"
+
+// UsageRecordTask 使用记录提交到服务商的异步任务。多输出分多次调用时，每个输出（Slot）各对应一个远程任务；
+// 取消、重启后恢复轮询以及按任务编号匹配完成回调都依据该表，而不只是记录上的 ExternalTaskCode。
+type UsageRecordTask struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	RecordID uint `gorm:"column:record_id;not null;uniqueIndex:idx_usage_record_task_slot,priority:1" json:"record_id"`
+	// Slot 输出序号（从 0 开始），单次调用生成全部输出时为 0
+	Slot int `gorm:"column:slot;not null;default:0;uniqueIndex:idx_usage_record_task_slot,priority:2" json:"slot"`
+
+	// ProviderID/ModelID 实际提交任务的服务商与模型（发生故障转移时为备用模型）
+	ProviderID string `gorm:"column:provider_id;type:varchar(255);not null;index:idx_usage_record_task_code,priority:1" json:"provider_id"`
+	ModelID    string `gorm:"column:model_id;type:varchar(255);not null" json:"model_id"`
+	TaskCode   string `gorm:"column:task_code;type:varchar(255);not null;index:idx_usage_record_task_code,priority:2" json:"task_code"`
+}
+
+// TableName 指定表名
+func (UsageRecordTask) TableName() string {
+	return "usage_record_tasks"
+}
@@ -1,6 +1,9 @@
 package db
 
-import "time"
+import (
+	"clothing/internal/entity/common"
+	"time"
+)
 
 const (
 	UserRoleSuperAdmin = "super_admin"
@@ -18,6 +21,8 @@ type User struct {
 	DisplayName  string    `gorm:"column:display_name;type:varchar(255)" json:"display_name"`
 	Role         string    `gorm:"column:role;type:varchar(50);index;not null" json:"role"`
 	IsActive     bool      `gorm:"column:is_active;not null;default:true" json:"is_active"`
+	// Settings 用户级设置，如 rate_limit 覆盖按角色的默认限流
+	Settings common.JSONMap `gorm:"column:settings;type:json" json:"settings"`
 }
 
 // TableName 指定表名。
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"clothing/internal/entity/common"
+	"time"
+)
+
+// Webhook 事件类型
+const (
+	WebhookEventGenerationCompleted = "generation.completed"
+	WebhookEventGenerationFailed    = "generation.failed"
+)
+
+// Webhook 投递状态
+const (
+	WebhookDeliveryStatusPending   = "pending"
+	WebhookDeliveryStatusSucceeded = "succeeded"
+	WebhookDeliveryStatusFailed    = "failed"
+)
+
+// WebhookEndpoint 用户或管理员注册的回调地址，生成结束时推送带 HMAC 签名的事件。
+type WebhookEndpoint struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID uint   `gorm:"column:user_id;index;not null" json:"user_id"`
+	URL    string `gorm:"column:url;type:varchar(1024);not null" json:"url"`
+	Secret string `gorm:"column:secret;type:varchar(255);not null" json:"-"`
+	// Events 订阅的事件类型，为空表示订阅全部事件
+	Events      common.StringArray `gorm:"column:events;type:json" json:"events"`
+	Description string             `gorm:"column:description;type:text" json:"description"`
+	// AllUsers 接收所有用户的生成事件（仅管理员可设置），否则只接收创建者自己的事件
+	AllUsers bool `gorm:"column:all_users;default:false" json:"all_users"`
+	IsActive bool `gorm:"column:is_active;default:true" json:"is_active"`
+}
+
+// TableName 指定表名
+func (WebhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+// Subscribes 判断是否订阅了指定事件
+func (e *WebhookEndpoint) Subscribes(event string) bool {
+	return len(e.Events) == 0 || e.Events.Contains(event)
+}
+
+// WebhookDelivery 一次事件投递及其重试记录（投递日志）。
+type WebhookDelivery struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	EndpointID uint   `gorm:"column:endpoint_id;index;not null" json:"endpoint_id"`
+	RecordID   uint   `gorm:"column:record_id;index" json:"record_id"`
+	Event      string `gorm:"column:event;type:varchar(64);not null" json:"event"`
+	Payload    string `gorm:"column:payload;type:text" json:"payload"`
+
+	Status         string     `gorm:"column:status;type:varchar(32);index:idx_webhook_delivery_due,priority:1;not null" json:"status"`
+	Attempts       int        `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"column:next_attempt_at;index:idx_webhook_delivery_due,priority:2" json:"next_attempt_at"`
+	LastStatusCode int        `gorm:"column:last_status_code" json:"last_status_code"`
+	LastError      string     `gorm:"column:last_error;type:text" json:"last_error"`
+	DeliveredAt    *time.Time `gorm:"column:delivered_at" json:"delivered_at"`
+}
+
+// TableName 指定表名
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
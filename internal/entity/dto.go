@@ -26,22 +26,99 @@ type CreateModelRequest = dto.CreateModelRequest
 type UpdateModelRequest = dto.UpdateModelRequest
 type ProviderAdminView = dto.ProviderAdminView
 type ProviderModelSummary = dto.ProviderModelSummary
+type ProviderHealthCheck = dto.ProviderHealthCheck
+type ModelPricingConfig = dto.ModelPricing
+type ModelCatalogChange = dto.ModelCatalogChange
+type ModelCatalogDiff = dto.ModelCatalogDiff
+type ModelCatalogSyncRequest = dto.ModelCatalogSyncRequest
+type ModelCatalogSyncResponse = dto.ModelCatalogSyncResponse
 
 // 内容生成相关 DTO
 type MediaInput = dto.MediaInput
 type OutputConfig = dto.OutputConfig
+type AdvancedParams = dto.AdvancedParams
 type MediaOutput = dto.MediaOutput
+type ProviderUsage = dto.ProviderUsage
 type GenerateContentRequest = dto.GenerateContentRequest
 type GenerateContentResponse = dto.GenerateContentResponse
 
+// 批量生成相关 DTO
+type BatchTarget = dto.BatchTarget
+type GenerateBatchRequest = dto.GenerateBatchRequest
+type GenerateBatchResponse = dto.GenerateBatchResponse
+type GenerationBatchProgress = dto.GenerationBatchProgress
+type GenerationBatch = dto.GenerationBatch
+type GenerationBatchDetailResponse = dto.GenerationBatchDetailResponse
+
+// 生成任务队列相关 DTO
+type GenerationQueueLane = dto.GenerationQueueLane
+type GenerationQueueProvider = dto.GenerationQueueProvider
+type GenerationQueueStatus = dto.GenerationQueueStatus
+type GenerationQueuePosition = dto.GenerationQueuePosition
+
 // 使用记录相关 DTO
 type UsageRecordQuery = dto.UsageRecordQuery
 type UsageImage = dto.UsageImage
+type UsageAttempt = dto.UsageAttempt
+type UsageOutput = dto.UsageOutput
 type UsageRecordItem = dto.UsageRecordItem
 type UsageRecordListResponse = dto.UsageRecordListResponse
+type UsageCostSummary = dto.UsageCostSummary
 type UsageRecordDetailResponse = dto.UsageRecordDetailResponse
+type RerunUsageRecordRequest = dto.RerunUsageRecordRequest
+type RemixUsageRecordRequest = dto.RemixUsageRecordRequest
 
 // 标签相关 DTO
 type Tag = dto.Tag
 type TagListResponse = dto.TagListResponse
 type TagDetailResponse = dto.TagDetailResponse
+
+// 故障转移链相关 DTO
+type FailoverChain = dto.FailoverChain
+type FailoverChainStep = dto.FailoverStep
+type CreateFailoverChainRequest = dto.CreateFailoverChainRequest
+type UpdateFailoverChainRequest = dto.UpdateFailoverChainRequest
+type FailoverChainListResponse = dto.FailoverChainListResponse
+type FailoverChainDetailResponse = dto.FailoverChainDetailResponse
+
+// Webhook 相关 DTO
+type WebhookEndpoint = dto.WebhookEndpoint
+type CreateWebhookEndpointRequest = dto.CreateWebhookEndpointRequest
+type UpdateWebhookEndpointRequest = dto.UpdateWebhookEndpointRequest
+type WebhookEndpointListResponse = dto.WebhookEndpointListResponse
+type WebhookEndpointDetailResponse = dto.WebhookEndpointDetailResponse
+type WebhookDelivery = dto.WebhookDelivery
+type WebhookDeliveryListResponse = dto.WebhookDeliveryListResponse
+type WebhookEvent = dto.WebhookEvent
+type WebhookGenerationData = dto.WebhookGenerationData
+
+// 提示词模板相关 DTO
+type PromptTemplate = dto.PromptTemplate
+type PromptVariable = dto.PromptTemplateVariable
+type CreatePromptTemplateRequest = dto.CreatePromptTemplateRequest
+type UpdatePromptTemplateRequest = dto.UpdatePromptTemplateRequest
+type PromptTemplateListResponse = dto.PromptTemplateListResponse
+type PromptTemplateDetailResponse = dto.PromptTemplateDetailResponse
+
+// 流水线相关 DTO
+type Pipeline = dto.Pipeline
+type PipelineStepConfig = dto.PipelineStep
+type CreatePipelineRequest = dto.CreatePipelineRequest
+type UpdatePipelineRequest = dto.UpdatePipelineRequest
+type PipelineListResponse = dto.PipelineListResponse
+type PipelineDetailResponse = dto.PipelineDetailResponse
+type RunPipelineRequest = dto.RunPipelineRequest
+type PipelineRun = dto.PipelineRun
+type PipelineRunDetailResponse = dto.PipelineRunDetailResponse
+
+// 额度与配额相关 DTO
+type CreditAccount = dto.CreditAccount
+type CreditLedgerEntry = dto.CreditLedgerEntry
+type CreditAccountResponse = dto.CreditAccountResponse
+type GrantCreditsRequest = dto.GrantCreditsRequest
+type GrantCreditsResponse = dto.GrantCreditsResponse
+type CreditQuota = dto.CreditQuota
+type CreateCreditQuotaRequest = dto.CreateCreditQuotaRequest
+type UpdateCreditQuotaRequest = dto.UpdateCreditQuotaRequest
+type CreditQuotaListResponse = dto.CreditQuotaListResponse
+type CreditQuotaDetailResponse = dto.CreditQuotaDetailResponse
@@ -0,0 +1,67 @@
+package dto
+
+import "time"
+
+// BatchTarget is one provider/model pair a batch runs every prompt against.
+type BatchTarget struct {
+	ProviderID string `json:"provider_id"`
+	ModelID    string `json:"model_id"`
+}
+
+// GenerateBatchRequest is the request payload for batch generation.
+// Either Prompts, or PromptTemplate with one variable set per prompt, must be provided.
+type GenerateBatchRequest struct {
+	ClientID string `json:"client_id,omitempty"`
+	Name     string `json:"name,omitempty"`
+
+	Prompts        []string            `json:"prompts,omitempty"`
+	PromptTemplate string              `json:"prompt_template,omitempty"` // e.g. "a {{color}} {{garment}}"
+	Variables      []map[string]string `json:"variables,omitempty"`
+
+	Targets []BatchTarget `json:"targets" binding:"required"`
+
+	InputMedia []MediaInput   `json:"input_media,omitempty"`
+	Output     OutputConfig   `json:"output,omitempty"`
+	Advanced   AdvancedParams `json:"advanced,omitempty"`
+	TagIDs     []uint         `json:"tag_ids,omitempty"`
+
+	// MaxConcurrency bounds how many child generations of the batch run at the same time.
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+}
+
+// GenerateBatchResponse is returned once a batch has been queued.
+type GenerateBatchResponse struct {
+	BatchID   uint   `json:"batch_id"`
+	Status    string `json:"status"`
+	Total     int    `json:"total"`
+	RecordIDs []uint `json:"record_ids"`
+}
+
+// GenerationBatchProgress counts the child records of a batch per lifecycle status.
+type GenerationBatchProgress struct {
+	Total              int64 `json:"total"`
+	Queued             int64 `json:"queued"`
+	Running            int64 `json:"running"`
+	Succeeded          int64 `json:"succeeded"`
+	PartiallySucceeded int64 `json:"partially_succeeded"`
+	Failed             int64 `json:"failed"`
+	Cancelled          int64 `json:"cancelled"`
+	// Finished is the number of children in a terminal status.
+	Finished int64 `json:"finished"`
+}
+
+// GenerationBatch is the response representation of a batch.
+type GenerationBatch struct {
+	ID             uint                    `json:"id"`
+	Name           string                  `json:"name"`
+	Status         string                  `json:"status"`
+	MaxConcurrency int                     `json:"max_concurrency"`
+	Progress       GenerationBatchProgress `json:"progress"`
+	CreatedAt      time.Time               `json:"created_at"`
+	FinishedAt     *time.Time              `json:"finished_at"`
+}
+
+// GenerationBatchDetailResponse is the response for a single batch.
+type GenerationBatchDetailResponse struct {
+	Batch GenerationBatch `json:"batch"`
+}
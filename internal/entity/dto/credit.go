@@ -0,0 +1,78 @@
+package dto
+
+import "time"
+
+// CreditAccount is a user's credit balance, in the same unit as usage record costs (USD).
+type CreditAccount struct {
+	UserID  uint    `json:"user_id"`
+	Balance float64 `json:"balance"`
+}
+
+// CreditLedgerEntry is one entry of a user's append-only credit ledger.
+type CreditLedgerEntry struct {
+	ID           uint      `json:"id"`
+	Type         string    `json:"type"`
+	Amount       float64   `json:"amount"`
+	BalanceAfter float64   `json:"balance_after"`
+	RecordID     *uint     `json:"record_id,omitempty"`
+	ProviderID   string    `json:"provider_id,omitempty"`
+	ModelID      string    `json:"model_id,omitempty"`
+	Note         string    `json:"note,omitempty"`
+	CreatedBy    *uint     `json:"created_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// CreditAccountResponse is the response for a user's balance and recent ledger entries.
+type CreditAccountResponse struct {
+	Account CreditAccount       `json:"account"`
+	Entries []CreditLedgerEntry `json:"entries"`
+}
+
+// GrantCreditsRequest is the payload for granting credits; a negative amount deducts credits.
+type GrantCreditsRequest struct {
+	Amount float64 `json:"amount"`
+	Note   string  `json:"note"`
+}
+
+// GrantCreditsResponse is the response for a credit grant.
+type GrantCreditsResponse struct {
+	Account CreditAccount     `json:"account"`
+	Entry   CreditLedgerEntry `json:"entry"`
+}
+
+// CreditQuota caps what a user may spend per period on a provider or model.
+type CreditQuota struct {
+	ID         uint      `json:"id"`
+	UserID     uint      `json:"user_id"` // 0 applies the quota to every user
+	ProviderID string    `json:"provider_id,omitempty"`
+	ModelID    string    `json:"model_id,omitempty"`
+	Period     string    `json:"period"`
+	Limit      float64   `json:"limit"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CreateCreditQuotaRequest is the payload for creating a quota.
+type CreateCreditQuotaRequest struct {
+	UserID     uint    `json:"user_id"`
+	ProviderID string  `json:"provider_id"`
+	ModelID    string  `json:"model_id"`
+	Period     string  `json:"period" binding:"required"`
+	Limit      float64 `json:"limit"`
+}
+
+// UpdateCreditQuotaRequest is the payload for updating a quota.
+type UpdateCreditQuotaRequest struct {
+	Period *string  `json:"period"`
+	Limit  *float64 `json:"limit"`
+}
+
+// CreditQuotaListResponse is the response for listing quotas.
+type CreditQuotaListResponse struct {
+	Quotas []CreditQuota `json:"quotas"`
+}
+
+// CreditQuotaDetailResponse is the response for a single quota.
+type CreditQuotaDetailResponse struct {
+	Quota CreditQuota `json:"quota"`
+}
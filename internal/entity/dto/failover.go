@@ -0,0 +1,47 @@
+package dto
+
+import "time"
+
+// FailoverStep is one fallback provider/model pair in a failover chain.
+type FailoverStep struct {
+	ProviderID string `json:"provider_id"`
+	ModelID    string `json:"model_id"`
+}
+
+// FailoverChain is the DTO representation of a failover chain.
+type FailoverChain struct {
+	ID          uint           `json:"id"`
+	ProviderID  string         `json:"provider_id"`
+	ModelID     string         `json:"model_id"`
+	Fallbacks   []FailoverStep `json:"fallbacks"`
+	Description string         `json:"description,omitempty"`
+	IsActive    bool           `json:"is_active"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// CreateFailoverChainRequest is the payload for creating a failover chain.
+type CreateFailoverChainRequest struct {
+	ProviderID  string         `json:"provider_id" binding:"required"`
+	ModelID     string         `json:"model_id" binding:"required"`
+	Fallbacks   []FailoverStep `json:"fallbacks" binding:"required"`
+	Description string         `json:"description"`
+	IsActive    *bool          `json:"is_active"`
+}
+
+// UpdateFailoverChainRequest is the payload for updating a failover chain.
+type UpdateFailoverChainRequest struct {
+	Fallbacks   *[]FailoverStep `json:"fallbacks"`
+	Description *string         `json:"description"`
+	IsActive    *bool           `json:"is_active"`
+}
+
+// FailoverChainListResponse is the response for listing failover chains.
+type FailoverChainListResponse struct {
+	Chains []FailoverChain `json:"chains"`
+}
+
+// FailoverChainDetailResponse is the response for a single failover chain.
+type FailoverChainDetailResponse struct {
+	Chain FailoverChain `json:"chain"`
+}
@@ -16,6 +16,20 @@ type OutputConfig struct {
 	NumOutputs int    `json:"num_outputs,omitempty"`
 }
 
+// AdvancedParams are optional sampling parameters shared by image and video models.
+type AdvancedParams struct {
+	Seed           *int64   `json:"seed,omitempty"`
+	NegativePrompt string   `json:"negative_prompt,omitempty"`
+	GuidanceScale  *float64 `json:"guidance_scale,omitempty"`
+	Steps          *int     `json:"steps,omitempty"`    // number of inference steps
+	Strength       *float64 `json:"strength,omitempty"` // image-to-image strength in [0, 1]
+}
+
+// IsEmpty reports whether no advanced parameter is set.
+func (p AdvancedParams) IsEmpty() bool {
+	return p.Seed == nil && strings.TrimSpace(p.NegativePrompt) == "" && p.GuidanceScale == nil && p.Steps == nil && p.Strength == nil
+}
+
 // MediaOutput represents a unified media output.
 type MediaOutput struct {
 	Type     string `json:"type"`               // image, video
@@ -29,7 +43,12 @@ type GenerateContentRequest struct {
 	ProviderID string `json:"provider_id" binding:"required"` // 供应商ID
 	ModelID    string `json:"model_id" binding:"required"`    // 模型ID
 
-	Prompt string `json:"prompt" binding:"required"`
+	// Prompt is required unless TemplateID is given, in which case it is rendered from the template.
+	Prompt string `json:"prompt"`
+
+	// TemplateID selects a prompt template; Variables fill its {{variable}} placeholders.
+	TemplateID *uint             `json:"template_id,omitempty"`
+	Variables  map[string]string `json:"variables,omitempty"`
 
 	// New unified input format
 	InputMedia []MediaInput `json:"input_media,omitempty"`
@@ -37,7 +56,13 @@ type GenerateContentRequest struct {
 	// New output configuration
 	Output OutputConfig `json:"output,omitempty"`
 
+	// Advanced holds optional sampling parameters; drivers drop the ones the model does not accept.
+	Advanced AdvancedParams `json:"advanced,omitempty"`
+
 	TagIDs []uint `json:"tag_ids,omitempty"`
+
+	// IdempotencyKey deduplicates client retries; the Idempotency-Key header takes precedence.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 // GetImages returns all image inputs from InputMedia.
@@ -75,6 +100,14 @@ func (r *GenerateContentRequest) GetSize() string {
 	return r.Output.Size
 }
 
+// GetNumOutputs returns the requested number of outputs, at least 1.
+func (r *GenerateContentRequest) GetNumOutputs() int {
+	if r.Output.NumOutputs < 1 {
+		return 1
+	}
+	return r.Output.NumOutputs
+}
+
 // GetDuration returns the output duration.
 func (r *GenerateContentRequest) GetDuration() int {
 	return r.Output.Duration
@@ -89,4 +122,28 @@ type GenerateContentResponse struct {
 	// Task identification
 	TaskID    string `json:"task_id,omitempty"`     // Unified task ID
 	RequestID string `json:"request_id,omitempty"`
+
+	// Usage is the token usage and cost reported by the provider, when available.
+	Usage *ProviderUsage `json:"usage,omitempty"`
+}
+
+// ProviderUsage is the usage block reported by providers such as OpenRouter.
+type ProviderUsage struct {
+	PromptTokens     int `json:"prompt_tokens,omitempty"`
+	CompletionTokens int `json:"completion_tokens,omitempty"`
+	// Cost is the amount charged for the request in USD, nil when the provider does not report it.
+	Cost *float64 `json:"cost,omitempty"`
+}
+
+// Add accumulates other into u, e.g. when outputs were generated by several calls.
+// The cost stays nil unless every call reported one.
+func (u *ProviderUsage) Add(other ProviderUsage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	if u.Cost != nil && other.Cost != nil {
+		total := *u.Cost + *other.Cost
+		u.Cost = &total
+	} else {
+		u.Cost = nil
+	}
 }
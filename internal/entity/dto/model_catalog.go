@@ -0,0 +1,43 @@
+package dto
+
+// ModelCatalogChange is one difference between a provider's upstream catalog and its stored models.
+type ModelCatalogChange struct {
+	// Action is create, update or deactivate.
+	Action  string `json:"action"`
+	ModelID string `json:"model_id"`
+	// Fields lists the fields an update changes.
+	Fields []string `json:"fields,omitempty"`
+	// Current is the stored model, absent for creates; Proposed is the model after the change.
+	Current  *ProviderModelSummary `json:"current,omitempty"`
+	Proposed *ProviderModelSummary `json:"proposed,omitempty"`
+	// Error is set when applying the change failed.
+	Error string `json:"error,omitempty"`
+}
+
+// ModelCatalogDiff is the preview of syncing a provider's models with its upstream catalog.
+type ModelCatalogDiff struct {
+	ProviderID string `json:"provider_id"`
+	// CatalogSize is the number of generation models listed upstream.
+	CatalogSize   int                  `json:"catalog_size"`
+	Creates       int                  `json:"creates"`
+	Updates       int                  `json:"updates"`
+	Deactivations int                  `json:"deactivations"`
+	Changes       []ModelCatalogChange `json:"changes"`
+}
+
+// ModelCatalogSyncRequest selects which catalog changes to apply.
+type ModelCatalogSyncRequest struct {
+	// Actions limits the sync to these actions; empty applies creates and updates.
+	Actions []string `json:"actions"`
+	// ModelIDs limits the sync to these models; empty applies every change of the selected actions.
+	ModelIDs []string `json:"model_ids"`
+	// Fields limits updates to these model fields; empty updates every field except name and description.
+	Fields []string `json:"fields"`
+}
+
+// ModelCatalogSyncResponse reports the catalog changes applied by a sync.
+type ModelCatalogSyncResponse struct {
+	ProviderID string               `json:"provider_id"`
+	Applied    []ModelCatalogChange `json:"applied"`
+	Failed     []ModelCatalogChange `json:"failed"`
+}
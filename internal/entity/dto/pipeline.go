@@ -0,0 +1,81 @@
+package dto
+
+import "time"
+
+// PipelineStepInput maps outputs of an earlier step (or the run's input media) into a step's InputMedia.
+type PipelineStepInput struct {
+	FromStep int    `json:"from_step"`        // 1-based step number; 0 selects the run's input media
+	Output   *int   `json:"output,omitempty"` // 0-based output index; all outputs when omitted
+	Type     string `json:"type,omitempty"`   // image (default), video
+	Role     string `json:"role,omitempty"`   // reference, first_frame, last_frame
+}
+
+// PipelineStep is one generation step of a pipeline.
+type PipelineStep struct {
+	Name       string              `json:"name,omitempty"`
+	ProviderID string              `json:"provider_id"`
+	ModelID    string              `json:"model_id"`
+	Prompt     string              `json:"prompt"` // may contain {{variable}} placeholders filled from the run variables
+	Inputs     []PipelineStepInput `json:"inputs,omitempty"`
+	Output     OutputConfig        `json:"output,omitempty"`
+}
+
+// Pipeline is the DTO representation of a pipeline definition.
+type Pipeline struct {
+	ID          uint           `json:"id"`
+	UserID      uint           `json:"user_id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Steps       []PipelineStep `json:"steps"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// CreatePipelineRequest is the payload for creating a pipeline.
+type CreatePipelineRequest struct {
+	Name        string         `json:"name" binding:"required"`
+	Description string         `json:"description"`
+	Steps       []PipelineStep `json:"steps" binding:"required"`
+}
+
+// UpdatePipelineRequest is the payload for updating a pipeline.
+type UpdatePipelineRequest struct {
+	Name        *string         `json:"name"`
+	Description *string         `json:"description"`
+	Steps       *[]PipelineStep `json:"steps"`
+}
+
+// PipelineListResponse is the response for listing pipelines.
+type PipelineListResponse struct {
+	Pipelines []Pipeline `json:"pipelines"`
+}
+
+// PipelineDetailResponse is the response for a single pipeline.
+type PipelineDetailResponse struct {
+	Pipeline Pipeline `json:"pipeline"`
+}
+
+// RunPipelineRequest is the payload for starting a pipeline run.
+type RunPipelineRequest struct {
+	ClientID   string            `json:"client_id,omitempty"`
+	Variables  map[string]string `json:"variables,omitempty"`
+	InputMedia []MediaInput      `json:"input_media,omitempty"` // referenced by step inputs with from_step 0
+}
+
+// PipelineRun is the response representation of a pipeline run.
+type PipelineRun struct {
+	ID           uint              `json:"id"`
+	PipelineID   uint              `json:"pipeline_id"`
+	Status       string            `json:"status"`
+	CurrentStep  int               `json:"current_step"`
+	TotalSteps   int               `json:"total_steps"`
+	ErrorMessage string            `json:"error_message,omitempty"`
+	Records      []UsageRecordItem `json:"records"` // usage records of the steps started so far, in step order
+	CreatedAt    time.Time         `json:"created_at"`
+	FinishedAt   *time.Time        `json:"finished_at"`
+}
+
+// PipelineRunDetailResponse is the response for a single pipeline run.
+type PipelineRunDetailResponse struct {
+	Run PipelineRun `json:"run"`
+}
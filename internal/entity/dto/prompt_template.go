@@ -0,0 +1,53 @@
+package dto
+
+import "time"
+
+// PromptTemplateVariable declares one {{variable}} placeholder of a prompt template.
+type PromptTemplateVariable struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type,omitempty"` // text (default), enum
+	Description string   `json:"description,omitempty"`
+	Default     *string  `json:"default,omitempty"` // variables without a default are required
+	Choices     []string `json:"choices,omitempty"` // allowed values of enum variables
+}
+
+// PromptTemplate is the DTO representation of a prompt template.
+type PromptTemplate struct {
+	ID          uint                     `json:"id"`
+	UserID      uint                     `json:"user_id"`
+	Name        string                   `json:"name"`
+	Description string                   `json:"description,omitempty"`
+	Template    string                   `json:"template"`
+	Variables   []PromptTemplateVariable `json:"variables"`
+	Shared      bool                     `json:"shared"`
+	CreatedAt   time.Time                `json:"created_at"`
+	UpdatedAt   time.Time                `json:"updated_at"`
+}
+
+// CreatePromptTemplateRequest is the payload for creating a prompt template.
+type CreatePromptTemplateRequest struct {
+	Name        string                   `json:"name" binding:"required"`
+	Description string                   `json:"description"`
+	Template    string                   `json:"template" binding:"required"` // e.g. "a {{color}} {{garment}} on a {{background}} background"
+	Variables   []PromptTemplateVariable `json:"variables"`
+	Shared      bool                     `json:"shared"`
+}
+
+// UpdatePromptTemplateRequest is the payload for updating a prompt template.
+type UpdatePromptTemplateRequest struct {
+	Name        *string                   `json:"name"`
+	Description *string                   `json:"description"`
+	Template    *string                   `json:"template"`
+	Variables   *[]PromptTemplateVariable `json:"variables"`
+	Shared      *bool                     `json:"shared"`
+}
+
+// PromptTemplateListResponse is the response for listing prompt templates.
+type PromptTemplateListResponse struct {
+	Templates []PromptTemplate `json:"templates"`
+}
+
+// PromptTemplateDetailResponse is the response for a single prompt template.
+type PromptTemplateDetailResponse struct {
+	Template PromptTemplate `json:"template"`
+}
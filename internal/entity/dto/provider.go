@@ -34,6 +34,7 @@ type CreateModelRequest struct {
 	Name               string                 `json:"name" binding:"required"`
 	Description        string                 `json:"description"`
 	Price              string                 `json:"price"`
+	Pricing            *ModelPricing          `json:"pricing"`
 	MaxImages          *int                   `json:"max_images"`
 	InputModalities    []string               `json:"input_modalities"`
 	OutputModalities   []string               `json:"output_modalities"`
@@ -50,11 +51,21 @@ type CreateModelRequest struct {
 	IsActive          *bool  `json:"is_active"`
 }
 
+// ModelPricing is the structured pricing of a model in USD; each rate applies to the matching output.
+type ModelPricing struct {
+	PerImage                float64 `json:"per_image,omitempty"`
+	PerVideoSecond          float64 `json:"per_video_second,omitempty"`
+	PerMegapixel            float64 `json:"per_megapixel,omitempty"`
+	PerThousandInputTokens  float64 `json:"per_1k_input_tokens,omitempty"`
+	PerThousandOutputTokens float64 `json:"per_1k_output_tokens,omitempty"`
+}
+
 // UpdateModelRequest defines payload for updating provider models.
 type UpdateModelRequest struct {
 	Name               *string                `json:"name"`
 	Description        *string                `json:"description"`
 	Price              *string                `json:"price"`
+	Pricing            *ModelPricing          `json:"pricing"`
 	MaxImages          *int                   `json:"max_images"`
 	InputModalities    *[]string              `json:"input_modalities"`
 	OutputModalities   *[]string              `json:"output_modalities"`
@@ -84,6 +95,24 @@ type ProviderAdminView struct {
 	CreatedAt   time.Time              `json:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at"`
 	Models      []ProviderModelSummary `json:"models,omitempty"`
+
+	// ConfigVersion increases on every update; instances rebuild cached provider clients when it changes.
+	ConfigVersion uint64 `json:"config_version"`
+
+	// Health is the latest connectivity check and HealthHistory the recent checks, newest first.
+	Health        *ProviderHealthCheck  `json:"health,omitempty"`
+	HealthHistory []ProviderHealthCheck `json:"health_history,omitempty"`
+}
+
+// ProviderHealthCheck is the result of a provider connectivity check.
+type ProviderHealthCheck struct {
+	Status string `json:"status"`
+	Source string `json:"source"`
+	// LatencyMs is how long the credential check took; StatusCode is the provider's HTTP status when it answered.
+	LatencyMs  int64     `json:"latency_ms"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
 }
 
 // ProviderModelSummary is the admin-facing model representation.
@@ -92,6 +121,7 @@ type ProviderModelSummary struct {
 	Name               string         `json:"name"`
 	Description        string         `json:"description,omitempty"`
 	Price              string         `json:"price,omitempty"`
+	Pricing            *ModelPricing  `json:"pricing,omitempty"`
 	MaxImages          int            `json:"max_images,omitempty"`
 	InputModalities    []string       `json:"input_modalities,omitempty"`
 	OutputModalities   []string       `json:"output_modalities,omitempty"`
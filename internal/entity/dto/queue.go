@@ -0,0 +1,42 @@
+package dto
+
+// GenerationQueueLane is the depth of one priority lane of the generation queue.
+type GenerationQueueLane struct {
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+	Pending  int    `json:"pending"`
+	Running  int    `json:"running"`
+}
+
+// GenerationQueueProvider is the depth of the generation queue for one provider.
+type GenerationQueueProvider struct {
+	ProviderID string `json:"provider_id"`
+	Pending    int    `json:"pending"`
+	Running    int    `json:"running"`
+	// MaxConcurrentJobs is the provider's cap on running jobs, 0 when unlimited.
+	MaxConcurrentJobs int `json:"max_concurrent_jobs"`
+}
+
+// GenerationQueueStatus is the response for the generation queue depth.
+type GenerationQueueStatus struct {
+	Pending int `json:"pending"`
+	Running int `json:"running"`
+	// UserPending and UserRunning count the requesting user's own jobs.
+	UserPending int                       `json:"user_pending"`
+	UserRunning int                       `json:"user_running"`
+	Lanes       []GenerationQueueLane     `json:"lanes"`
+	Providers   []GenerationQueueProvider `json:"providers"`
+}
+
+// GenerationQueuePosition is the queue position of a usage record's job.
+type GenerationQueuePosition struct {
+	RecordID uint `json:"record_id"`
+	// Status is the job status (pending or running); empty when the record is no longer queued.
+	Status   string `json:"status"`
+	Lane     string `json:"lane,omitempty"`
+	Priority int    `json:"priority"`
+	// Position is the 1-based place in claim order among pending jobs, 0 unless pending.
+	// It is an estimate: batch and provider concurrency caps may let later jobs start first.
+	Position int `json:"position"`
+	Pending  int `json:"pending"`
+}
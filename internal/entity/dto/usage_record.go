@@ -11,6 +11,8 @@ type UsageRecordQuery struct {
 	Provider        string `json:"provider" form:"provider" query:"provider"`
 	Model           string `json:"model" form:"model" query:"model"`
 	Result          string `json:"result" form:"result" query:"result"`
+	BatchID         uint   `json:"batch_id" form:"batch_id" query:"batch_id"`
+	ParentRecordID  uint   `json:"parent_record_id" form:"parent_record_id" query:"parent_record_id"`
 	UserID          uint   `json:"-" form:"-" query:"-"`
 	IncludeAll      bool   `json:"-" form:"-" query:"-"`
 	TagIDs          []uint `json:"-" form:"-" query:"-"`
@@ -25,27 +27,100 @@ type UsageImage struct {
 
 // UsageRecordItem is the response representation of a usage record.
 type UsageRecordItem struct {
-	ID           uint         `json:"id"`
-	ProviderID   string       `json:"provider_id"`
-	ModelID      string       `json:"model_id"`
-	Prompt       string       `json:"prompt"`
-	Size         string       `json:"size"`
-	OutputText   string       `json:"output_text"`
-	ErrorMessage string       `json:"error_message"`
-	CreatedAt    time.Time    `json:"created_at"`
-	InputImages  []UsageImage `json:"input_images"`
-	OutputImages []UsageImage `json:"output_images"`
-	User         UserSummary  `json:"user"`
-	Tags         []Tag        `json:"tags"`
+	ID               uint            `json:"id"`
+	BatchID          *uint           `json:"batch_id,omitempty"`
+	PipelineRunID    *uint           `json:"pipeline_run_id,omitempty"`
+	PipelineStep     int             `json:"pipeline_step,omitempty"`
+	ParentRecordID   *uint           `json:"parent_record_id,omitempty"`
+	ProviderID       string          `json:"provider_id"`
+	ModelID          string          `json:"model_id"`
+	ServedProviderID string          `json:"served_provider_id,omitempty"`
+	ServedModelID    string          `json:"served_model_id,omitempty"`
+	Prompt           string          `json:"prompt"`
+	PromptTemplateID *uint           `json:"prompt_template_id,omitempty"`
+	PromptVariables  map[string]any  `json:"prompt_variables,omitempty"`
+	Size             string          `json:"size"`
+	AdvancedParams   *AdvancedParams `json:"advanced_params,omitempty"`
+	Cost             *float64        `json:"cost"`
+	CostSource       string          `json:"cost_source,omitempty"`
+	OutputText       string          `json:"output_text"`
+	ErrorMessage     string          `json:"error_message"`
+	Status           string          `json:"status"`
+	CreatedAt        time.Time       `json:"created_at"`
+	StartedAt        *time.Time      `json:"started_at"`
+	FinishedAt       *time.Time      `json:"finished_at"`
+	Attempts         []UsageAttempt  `json:"attempts"`
+	InputImages      []UsageImage    `json:"input_images"`
+	OutputImages     []UsageImage    `json:"output_images"`
+	NumOutputs       int             `json:"num_outputs"`
+	Outputs          []UsageOutput   `json:"outputs"`
+	User             UserSummary     `json:"user"`
+	Tags             []Tag           `json:"tags"`
+}
+
+// UsageAttempt is one provider call made for a usage record.
+type UsageAttempt struct {
+	Attempt    int       `json:"attempt"`
+	ProviderID string    `json:"provider_id,omitempty"`
+	ModelID    string    `json:"model_id,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      string    `json:"error,omitempty"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Retryable  bool      `json:"retryable"`
+	BackoffMs  int64     `json:"backoff_ms,omitempty"`
+}
+
+// UsageOutput is the status of one requested output of a usage record.
+type UsageOutput struct {
+	Index  int         `json:"index"`
+	Status string      `json:"status"`
+	Image  *UsageImage `json:"image,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// UsageCostSummary totals the cost of all usage records matching a listing's filters.
+type UsageCostSummary struct {
+	TotalCost float64 `json:"total_cost"`
+	// PricedRecords is the number of matching records that have a cost; the rest ran on unpriced models.
+	PricedRecords int64 `json:"priced_records"`
 }
 
 // UsageRecordListResponse is the response for listing usage records.
 type UsageRecordListResponse struct {
 	Records []UsageRecordItem `json:"records"`
 	Meta    *common.Meta      `json:"meta"`
+	Cost    *UsageCostSummary `json:"cost,omitempty"`
 }
 
 // UsageRecordDetailResponse is the response for a single usage record.
 type UsageRecordDetailResponse struct {
 	Record UsageRecordItem `json:"record"`
 }
+
+// RerunUsageRecordRequest is the optional payload for re-running a usage record as-is.
+type RerunUsageRecordRequest struct {
+	ClientID string `json:"client_id,omitempty"`
+	TagIDs   []uint `json:"tag_ids,omitempty"`
+
+	// IdempotencyKey deduplicates client retries; the Idempotency-Key header takes precedence.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// RemixUsageRecordRequest re-runs a usage record with overrides; omitted fields keep the original values.
+type RemixUsageRecordRequest struct {
+	RerunUsageRecordRequest
+
+	ProviderID *string `json:"provider_id,omitempty"`
+	ModelID    *string `json:"model_id,omitempty"`
+	Prompt     *string `json:"prompt,omitempty"` // replaces the prompt; the prompt template link is dropped
+	Size       *string `json:"size,omitempty"`
+	Duration   *int    `json:"duration,omitempty"` // not stored on the original record, so only set by remixes
+	NumOutputs *int    `json:"num_outputs,omitempty"`
+
+	// Advanced replaces the advanced parameters stored on the original record when set.
+	Advanced *AdvancedParams `json:"advanced,omitempty"`
+
+	// InputMedia replaces the original input images when set; an empty list removes them.
+	InputMedia *[]MediaInput `json:"input_media,omitempty"`
+}
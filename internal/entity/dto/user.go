@@ -14,6 +14,9 @@ type UserSummary struct {
 	IsActive    bool      `json:"is_active"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Settings holds per-user settings such as the rate_limit override.
+	Settings common.JSONMap `json:"settings,omitempty"`
 }
 
 // UserQuery supports listing users with pagination.
@@ -25,11 +28,12 @@ type UserQuery struct {
 
 // UserCreateRequest is the payload for creating a user.
 type UserCreateRequest struct {
-	Email       string `json:"email" binding:"required,email"`
-	Password    string `json:"password" binding:"required,min=8"`
-	DisplayName string `json:"display_name"`
-	Role        string `json:"role" binding:"required"`
-	IsActive    *bool  `json:"is_active"`
+	Email       string         `json:"email" binding:"required,email"`
+	Password    string         `json:"password" binding:"required,min=8"`
+	DisplayName string         `json:"display_name"`
+	Role        string         `json:"role" binding:"required"`
+	IsActive    *bool          `json:"is_active"`
+	Settings    common.JSONMap `json:"settings"`
 }
 
 // UserUpdateRequest is the payload for updating a user.
@@ -38,6 +42,8 @@ type UserUpdateRequest struct {
 	Role        *string `json:"role,omitempty"`
 	Password    *string `json:"password,omitempty"`
 	IsActive    *bool   `json:"is_active,omitempty"`
+	// Settings replaces the user's settings, e.g. {"rate_limit": {"requests_per_minute": 60, "burst": 10}}.
+	Settings *common.JSONMap `json:"settings,omitempty"`
 }
 
 // UserListResponse is the response for listing users.
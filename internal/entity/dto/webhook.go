@@ -0,0 +1,94 @@
+package dto
+
+import "time"
+
+// WebhookEndpoint is the DTO representation of a webhook endpoint. The secret is never listed.
+type WebhookEndpoint struct {
+	ID          uint      `json:"id"`
+	UserID      uint      `json:"user_id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description,omitempty"`
+	AllUsers    bool      `json:"all_users"`
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateWebhookEndpointRequest is the payload for registering a webhook endpoint.
+type CreateWebhookEndpointRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+	AllUsers    bool     `json:"all_users"` // admin only
+	IsActive    *bool    `json:"is_active"`
+}
+
+// UpdateWebhookEndpointRequest is the payload for updating a webhook endpoint.
+type UpdateWebhookEndpointRequest struct {
+	URL         *string   `json:"url"`
+	Events      *[]string `json:"events"`
+	Description *string   `json:"description"`
+	AllUsers    *bool     `json:"all_users"` // admin only
+	IsActive    *bool     `json:"is_active"`
+}
+
+// WebhookEndpointListResponse is the response for listing webhook endpoints.
+type WebhookEndpointListResponse struct {
+	Endpoints []WebhookEndpoint `json:"endpoints"`
+}
+
+// WebhookEndpointDetailResponse is the response for a single webhook endpoint.
+// Secret is only returned when the endpoint is created.
+type WebhookEndpointDetailResponse struct {
+	Endpoint WebhookEndpoint `json:"endpoint"`
+	Secret   string          `json:"secret,omitempty"`
+}
+
+// WebhookDelivery is one entry of an endpoint's delivery log.
+type WebhookDelivery struct {
+	ID             uint       `json:"id"`
+	RecordID       uint       `json:"record_id"`
+	Event          string     `json:"event"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// WebhookDeliveryListResponse is the response for an endpoint's delivery log.
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+// WebhookEvent is the JSON body POSTed to webhook endpoints.
+type WebhookEvent struct {
+	Type      string                `json:"type"` // generation.completed, generation.failed
+	CreatedAt time.Time             `json:"created_at"`
+	Data      WebhookGenerationData `json:"data"`
+}
+
+// WebhookGenerationData describes the finished generation carried by a webhook event.
+type WebhookGenerationData struct {
+	RecordID         uint          `json:"record_id"`
+	BatchID          *uint         `json:"batch_id,omitempty"`
+	UserID           uint          `json:"user_id"`
+	Status           string        `json:"status"`
+	ProviderID       string        `json:"provider_id"`
+	ModelID          string        `json:"model_id"`
+	ServedProviderID string        `json:"served_provider_id,omitempty"`
+	ServedModelID    string        `json:"served_model_id,omitempty"`
+	Prompt           string        `json:"prompt"`
+	OutputURLs       []string      `json:"output_urls"`
+	Outputs          []UsageOutput `json:"outputs,omitempty"`
+	OutputText       string        `json:"output_text,omitempty"`
+	Error            string        `json:"error,omitempty"`
+	Tags             []Tag         `json:"tags"`
+	CreatedAt        time.Time     `json:"created_at"`
+	StartedAt        *time.Time    `json:"started_at"`
+	FinishedAt       *time.Time    `json:"finished_at"`
+	DurationMs       int64         `json:"duration_ms"`
+}
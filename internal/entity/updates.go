@@ -1,11 +1,14 @@
 package entity
 
+import "time"
+
 // UserUpdates 用户更新字段
 type UserUpdates struct {
 	DisplayName  *string
 	Role         *string
 	PasswordHash *string
 	IsActive     *bool
+	Settings     *JSONMap
 }
 
 // ToMap 转换为 GORM 更新 map（内部使用）
@@ -23,6 +26,9 @@ func (u UserUpdates) ToMap() map[string]interface{} {
 	if u.IsActive != nil {
 		updates["is_active"] = *u.IsActive
 	}
+	if u.Settings != nil {
+		updates["settings"] = *u.Settings
+	}
 	return updates
 }
 
@@ -79,6 +85,7 @@ type ModelUpdates struct {
 	Name               *string
 	Description        *string
 	Price              *string
+	Pricing            *ModelPricing
 	MaxImages          *int
 	InputModalities    *StringArray
 	OutputModalities   *StringArray
@@ -107,6 +114,9 @@ func (u ModelUpdates) ToMap() map[string]interface{} {
 	if u.Price != nil {
 		updates["price"] = *u.Price
 	}
+	if u.Pricing != nil {
+		updates["pricing"] = *u.Pricing
+	}
 	if u.MaxImages != nil {
 		updates["max_images"] = *u.MaxImages
 	}
@@ -162,6 +172,17 @@ type UsageRecordUpdates struct {
 	ErrorMessage *string
 	TaskID       *string
 	RequestID    *string
+	Status       *string
+	StartedAt    *time.Time
+	FinishedAt   *time.Time
+	Attempts     *GenerationAttempts
+
+	ServedProviderID *string
+	ServedModelID    *string
+	Outputs          *GenerationOutputs
+	AdvancedParams   *GenerationParams
+	Cost             *float64
+	CostSource       *string
 }
 
 // ToMap 转换为 GORM 更新 map（内部使用）
@@ -185,6 +206,36 @@ func (u UsageRecordUpdates) ToMap() map[string]interface{} {
 	if u.RequestID != nil {
 		updates["request_id"] = *u.RequestID
 	}
+	if u.Status != nil {
+		updates["status"] = *u.Status
+	}
+	if u.StartedAt != nil {
+		updates["started_at"] = *u.StartedAt
+	}
+	if u.FinishedAt != nil {
+		updates["finished_at"] = *u.FinishedAt
+	}
+	if u.Attempts != nil {
+		updates["attempts"] = *u.Attempts
+	}
+	if u.ServedProviderID != nil {
+		updates["served_provider_id"] = *u.ServedProviderID
+	}
+	if u.ServedModelID != nil {
+		updates["served_model_id"] = *u.ServedModelID
+	}
+	if u.Outputs != nil {
+		updates["outputs"] = *u.Outputs
+	}
+	if u.AdvancedParams != nil {
+		updates["advanced_params"] = *u.AdvancedParams
+	}
+	if u.Cost != nil {
+		updates["cost"] = *u.Cost
+	}
+	if u.CostSource != nil {
+		updates["cost_source"] = *u.CostSource
+	}
 	return updates
 }
 
@@ -211,3 +262,189 @@ func (u TagUpdates) ToMap() map[string]interface{} {
 func (u TagUpdates) IsEmpty() bool {
 	return len(u.ToMap()) == 0
 }
+
+// FailoverChainUpdates 故障转移链更新字段
+type FailoverChainUpdates struct {
+	Fallbacks   *FailoverSteps
+	Description *string
+	IsActive    *bool
+}
+
+// ToMap 转换为 GORM 更新 map（内部使用）
+func (u FailoverChainUpdates) ToMap() map[string]interface{} {
+	updates := make(map[string]interface{})
+	if u.Fallbacks != nil {
+		updates["fallbacks"] = *u.Fallbacks
+	}
+	if u.Description != nil {
+		updates["description"] = *u.Description
+	}
+	if u.IsActive != nil {
+		updates["is_active"] = *u.IsActive
+	}
+	return updates
+}
+
+// IsEmpty 检查是否没有任何更新字段
+func (u FailoverChainUpdates) IsEmpty() bool {
+	return len(u.ToMap()) == 0
+}
+
+// WebhookEndpointUpdates Webhook 端点更新字段
+type WebhookEndpointUpdates struct {
+	URL         *string
+	Events      *StringArray
+	Description *string
+	AllUsers    *bool
+	IsActive    *bool
+}
+
+// ToMap 转换为 GORM 更新 map（内部使用）
+func (u WebhookEndpointUpdates) ToMap() map[string]interface{} {
+	updates := make(map[string]interface{})
+	if u.URL != nil {
+		updates["url"] = *u.URL
+	}
+	if u.Events != nil {
+		updates["events"] = *u.Events
+	}
+	if u.Description != nil {
+		updates["description"] = *u.Description
+	}
+	if u.AllUsers != nil {
+		updates["all_users"] = *u.AllUsers
+	}
+	if u.IsActive != nil {
+		updates["is_active"] = *u.IsActive
+	}
+	return updates
+}
+
+// IsEmpty 检查是否没有任何更新字段
+func (u WebhookEndpointUpdates) IsEmpty() bool {
+	return len(u.ToMap()) == 0
+}
+
+// WebhookDeliveryUpdates Webhook 投递更新字段
+type WebhookDeliveryUpdates struct {
+	Status         *string
+	Attempts       *int
+	NextAttemptAt  *time.Time
+	LastStatusCode *int
+	LastError      *string
+	DeliveredAt    *time.Time
+}
+
+// ToMap 转换为 GORM 更新 map（内部使用）
+func (u WebhookDeliveryUpdates) ToMap() map[string]interface{} {
+	updates := make(map[string]interface{})
+	if u.Status != nil {
+		updates["status"] = *u.Status
+	}
+	if u.Attempts != nil {
+		updates["attempts"] = *u.Attempts
+	}
+	if u.NextAttemptAt != nil {
+		updates["next_attempt_at"] = *u.NextAttemptAt
+	}
+	if u.LastStatusCode != nil {
+		updates["last_status_code"] = *u.LastStatusCode
+	}
+	if u.LastError != nil {
+		updates["last_error"] = *u.LastError
+	}
+	if u.DeliveredAt != nil {
+		updates["delivered_at"] = *u.DeliveredAt
+	}
+	return updates
+}
+
+// IsEmpty 检查是否没有任何更新字段
+func (u WebhookDeliveryUpdates) IsEmpty() bool {
+	return len(u.ToMap()) == 0
+}
+
+// PromptTemplateUpdates 提示词模板更新字段
+type PromptTemplateUpdates struct {
+	Name        *string
+	Description *string
+	Template    *string
+	Variables   *PromptTemplateVariables
+	Shared      *bool
+}
+
+// ToMap 转换为 GORM 更新 map（内部使用）
+func (u PromptTemplateUpdates) ToMap() map[string]interface{} {
+	updates := make(map[string]interface{})
+	if u.Name != nil {
+		updates["name"] = *u.Name
+	}
+	if u.Description != nil {
+		updates["description"] = *u.Description
+	}
+	if u.Template != nil {
+		updates["template"] = *u.Template
+	}
+	if u.Variables != nil {
+		updates["variables"] = *u.Variables
+	}
+	if u.Shared != nil {
+		updates["shared"] = *u.Shared
+	}
+	return updates
+}
+
+// IsEmpty 检查是否没有任何更新字段
+func (u PromptTemplateUpdates) IsEmpty() bool {
+	return len(u.ToMap()) == 0
+}
+
+// PipelineUpdates 流水线更新字段
+type PipelineUpdates struct {
+	Name        *string
+	Description *string
+	Steps       *PipelineSteps
+}
+
+// ToMap 转换为 GORM 更新 map（内部使用）
+func (u PipelineUpdates) ToMap() map[string]interface{} {
+	updates := make(map[string]interface{})
+	if u.Name != nil {
+		updates["name"] = *u.Name
+	}
+	if u.Description != nil {
+		updates["description"] = *u.Description
+	}
+	if u.Steps != nil {
+		updates["steps"] = *u.Steps
+	}
+	return updates
+}
+
+// IsEmpty 检查是否没有任何更新字段
+func (u PipelineUpdates) IsEmpty() bool {
+	return len(u.ToMap()) == 0
+}
+
+// CreditQuotaUpdates 额度配额更新字段
+type CreditQuotaUpdates struct {
+	Period *string
+	Limit  *float64
+}
+
+// ToMap 转换为 GORM 更新 map（内部使用）
+func (u CreditQuotaUpdates) ToMap() map[string]interface{} {
+	updates := make(map[string]interface{})
+	if u.Period != nil {
+		updates["period"] = *u.Period
+	}
+	if u.Limit != nil {
+		updates["limit_amount"] = *u.Limit
+	}
+	return updates
+}
+
+// IsEmpty 检查是否没有任何更新字段
+func (u CreditQuotaUpdates) IsEmpty() bool {
+	return len(u.ToMap()) == 0
+}
@@ -13,6 +13,34 @@ type DbModel = db.Model
 type DbUsageRecord = db.UsageRecord
 type DbTag = db.Tag
 type DbUsageRecordTag = db.UsageRecordTag
+type DbUsageRecordTask = db.UsageRecordTask
+type DbGenerationJob = db.GenerationJob
+type GenerationAttempt = db.GenerationAttempt
+type GenerationAttempts = db.GenerationAttempts
+type DbFailoverChain = db.FailoverChain
+type DbGenerationBatch = db.GenerationBatch
+type DbIdempotencyKey = db.IdempotencyKey
+type DbWebhookEndpoint = db.WebhookEndpoint
+type DbWebhookDelivery = db.WebhookDelivery
+type DbPromptTemplate = db.PromptTemplate
+type PromptTemplateVariable = db.PromptTemplateVariable
+type PromptTemplateVariables = db.PromptTemplateVariables
+type DbPipeline = db.Pipeline
+type DbPipelineRun = db.PipelineRun
+type PipelineStep = db.PipelineStep
+type PipelineSteps = db.PipelineSteps
+type PipelineStepInput = db.PipelineStepInput
+type GenerationOutput = db.GenerationOutput
+type GenerationOutputs = db.GenerationOutputs
+type GenerationParams = db.GenerationParams
+type ModelPricing = db.ModelPricing
+type DbCreditAccount = db.CreditAccount
+type DbCreditLedgerEntry = db.CreditLedgerEntry
+type DbCreditQuota = db.CreditQuota
+type DbProviderHealthCheck = db.ProviderHealthCheck
+type QuotaExceededError = db.QuotaExceededError
+type FailoverStep = db.FailoverStep
+type FailoverSteps = db.FailoverSteps
 
 // User role constants
 const (
@@ -21,6 +49,99 @@ const (
 	UserRoleUser       = db.UserRoleUser
 )
 
+// Usage record status constants
+const (
+	UsageRecordStatusQueued             = db.UsageRecordStatusQueued
+	UsageRecordStatusRunning            = db.UsageRecordStatusRunning
+	UsageRecordStatusSucceeded          = db.UsageRecordStatusSucceeded
+	UsageRecordStatusFailed             = db.UsageRecordStatusFailed
+	UsageRecordStatusCancelled          = db.UsageRecordStatusCancelled
+	UsageRecordStatusPartiallySucceeded = db.UsageRecordStatusPartiallySucceeded
+)
+
+// Usage cost source constants
+const (
+	UsageCostSourcePricing  = db.UsageCostSourcePricing
+	UsageCostSourceProvider = db.UsageCostSourceProvider
+)
+
+// Credit ledger entry type constants
+const (
+	CreditEntryTypeGrant   = db.CreditEntryTypeGrant
+	CreditEntryTypeReserve = db.CreditEntryTypeReserve
+	CreditEntryTypeSettle  = db.CreditEntryTypeSettle
+)
+
+// Credit quota period constants
+const (
+	CreditQuotaPeriodDaily   = db.CreditQuotaPeriodDaily
+	CreditQuotaPeriodMonthly = db.CreditQuotaPeriodMonthly
+)
+
+// ErrInsufficientCredits is returned when a reservation exceeds the user's balance.
+var ErrInsufficientCredits = db.ErrInsufficientCredits
+
+// UsageRecordStatusesForResult maps the usage record list result filter to statuses.
+var UsageRecordStatusesForResult = db.UsageRecordStatusesForResult
+
+// Generation job scheduling helpers
+var (
+	ScheduleGenerationJobs = db.ScheduleGenerationJobs
+	GenerationJobLaneName  = db.GenerationJobLaneName
+)
+
+// Generation job status and priority constants
+const (
+	GenerationJobStatusPending   = db.GenerationJobStatusPending
+	GenerationJobStatusRunning   = db.GenerationJobStatusRunning
+	GenerationJobStatusSucceeded = db.GenerationJobStatusSucceeded
+	GenerationJobStatusFailed    = db.GenerationJobStatusFailed
+	GenerationJobStatusCancelled = db.GenerationJobStatusCancelled
+
+	GenerationJobPriorityBatch       = db.GenerationJobPriorityBatch
+	GenerationJobPriorityInteractive = db.GenerationJobPriorityInteractive
+	GenerationJobPriorityAdmin       = db.GenerationJobPriorityAdmin
+)
+
+// Generation output status constants
+const (
+	GenerationOutputStatusSucceeded = db.GenerationOutputStatusSucceeded
+	GenerationOutputStatusFailed    = db.GenerationOutputStatusFailed
+)
+
+// Generation batch status constants
+const (
+	GenerationBatchStatusRunning   = db.GenerationBatchStatusRunning
+	GenerationBatchStatusCompleted = db.GenerationBatchStatusCompleted
+)
+
+// Webhook event constants
+const (
+	WebhookEventGenerationCompleted = db.WebhookEventGenerationCompleted
+	WebhookEventGenerationFailed    = db.WebhookEventGenerationFailed
+)
+
+// Webhook delivery status constants
+const (
+	WebhookDeliveryStatusPending   = db.WebhookDeliveryStatusPending
+	WebhookDeliveryStatusSucceeded = db.WebhookDeliveryStatusSucceeded
+	WebhookDeliveryStatusFailed    = db.WebhookDeliveryStatusFailed
+)
+
+// Pipeline run status constants
+const (
+	PipelineRunStatusRunning   = db.PipelineRunStatusRunning
+	PipelineRunStatusSucceeded = db.PipelineRunStatusSucceeded
+	PipelineRunStatusFailed    = db.PipelineRunStatusFailed
+	PipelineRunStatusCancelled = db.PipelineRunStatusCancelled
+)
+
+// Prompt template variable type constants
+const (
+	PromptVariableTypeText = db.PromptVariableTypeText
+	PromptVariableTypeEnum = db.PromptVariableTypeEnum
+)
+
 // Provider driver constants
 const (
 	ProviderDriverOpenRouter = db.ProviderDriverOpenRouter
@@ -30,3 +151,12 @@ const (
 	ProviderDriverFal        = db.ProviderDriverFal
 	ProviderDriverVolcengine = db.ProviderDriverVolcengine
 )
+
+// Provider health check status and source constants
+const (
+	ProviderHealthStatusOK    = db.ProviderHealthStatusOK
+	ProviderHealthStatusError = db.ProviderHealthStatusError
+
+	ProviderHealthSourceManual    = db.ProviderHealthSourceManual
+	ProviderHealthSourceScheduled = db.ProviderHealthSourceScheduled
+)
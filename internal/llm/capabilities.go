@@ -0,0 +1,153 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"fmt"
+	"strings"
+)
+
+// Capability violations reported by CheckCapabilities.
+const (
+	CapabilityUnsupportedSize     = "unsupported_size"
+	CapabilityUnsupportedDuration = "unsupported_duration"
+	CapabilityTooManyImages       = "too_many_images"
+	CapabilityUnsupportedModality = "unsupported_modality"
+)
+
+// Advanced generation parameters a driver may forward, see ModelCapabilities.AdvancedParams.
+const (
+	AdvancedParamSeed           = "seed"
+	AdvancedParamNegativePrompt = "negative_prompt"
+	AdvancedParamGuidanceScale  = "guidance_scale"
+	AdvancedParamSteps          = "steps"
+	AdvancedParamStrength       = "strength"
+)
+
+// CapabilityError describes a request that asks a model for something it does not support.
+type CapabilityError struct {
+	Violation string
+	// Requested is the offending value from the request.
+	Requested interface{}
+	// Allowed lists the accepted values (or the limit) for the model.
+	Allowed interface{}
+}
+
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("%s: requested %v, allowed %v", e.Violation, e.Requested, e.Allowed)
+}
+
+// ServiceCapabilities returns the capabilities reported by service, falling back to the
+// model configuration when no service is available.
+func ServiceCapabilities(service AIService, model entity.DbModel) *ModelCapabilities {
+	if service != nil {
+		if caps := service.Capabilities(model); caps != nil {
+			return caps
+		}
+	}
+	return (&BaseProvider{}).Capabilities(model)
+}
+
+// CheckCapabilities verifies the request against the model capabilities before dispatch.
+// Empty capability lists and zero limits are treated as unrestricted.
+func CheckCapabilities(request entity.GenerateContentRequest, caps *ModelCapabilities) error {
+	if caps == nil {
+		return nil
+	}
+
+	if size := strings.TrimSpace(request.GetSize()); size != "" && len(caps.SupportedSizes) > 0 {
+		if !containsFold(caps.SupportedSizes, size) {
+			return &CapabilityError{Violation: CapabilityUnsupportedSize, Requested: size, Allowed: caps.SupportedSizes}
+		}
+	}
+
+	if duration := request.GetDuration(); duration > 0 && len(caps.SupportedDurations) > 0 {
+		supported := false
+		for _, allowed := range caps.SupportedDurations {
+			if allowed == duration {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return &CapabilityError{Violation: CapabilityUnsupportedDuration, Requested: duration, Allowed: caps.SupportedDurations}
+		}
+	}
+
+	if len(caps.InputModalities) > 0 {
+		for _, media := range request.InputMedia {
+			mediaType := strings.ToLower(strings.TrimSpace(media.Type))
+			if mediaType == "" || strings.TrimSpace(media.Content) == "" {
+				continue
+			}
+			if !containsFold(caps.InputModalities, mediaType) {
+				return &CapabilityError{Violation: CapabilityUnsupportedModality, Requested: mediaType, Allowed: caps.InputModalities}
+			}
+		}
+	}
+
+	if images := request.GetImages(); caps.MaxImages > 0 && len(images) > caps.MaxImages {
+		return &CapabilityError{Violation: CapabilityTooManyImages, Requested: len(images), Allowed: caps.MaxImages}
+	}
+
+	return nil
+}
+
+// ApplyAdvancedParams keeps the advanced parameters the model accepts and returns the names of the
+// dropped ones. The result is what the driver actually sends, so it is what a record should store.
+func ApplyAdvancedParams(params entity.AdvancedParams, caps *ModelCapabilities) (entity.AdvancedParams, []string) {
+	var supported []string
+	if caps != nil {
+		supported = caps.AdvancedParams
+	}
+	accepts := func(name string) bool {
+		return containsFold(supported, name)
+	}
+
+	var effective entity.AdvancedParams
+	var dropped []string
+	if params.Seed != nil {
+		if accepts(AdvancedParamSeed) {
+			effective.Seed = params.Seed
+		} else {
+			dropped = append(dropped, AdvancedParamSeed)
+		}
+	}
+	if negative := strings.TrimSpace(params.NegativePrompt); negative != "" {
+		if accepts(AdvancedParamNegativePrompt) {
+			effective.NegativePrompt = negative
+		} else {
+			dropped = append(dropped, AdvancedParamNegativePrompt)
+		}
+	}
+	if params.GuidanceScale != nil {
+		if accepts(AdvancedParamGuidanceScale) {
+			effective.GuidanceScale = params.GuidanceScale
+		} else {
+			dropped = append(dropped, AdvancedParamGuidanceScale)
+		}
+	}
+	if params.Steps != nil {
+		if accepts(AdvancedParamSteps) {
+			effective.Steps = params.Steps
+		} else {
+			dropped = append(dropped, AdvancedParamSteps)
+		}
+	}
+	if params.Strength != nil {
+		if accepts(AdvancedParamStrength) {
+			effective.Strength = params.Strength
+		} else {
+			dropped = append(dropped, AdvancedParamStrength)
+		}
+	}
+	return effective, dropped
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(strings.TrimSpace(value), target) {
+			return true
+		}
+	}
+	return false
+}
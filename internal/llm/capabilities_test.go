@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"errors"
+	"testing"
+)
+
+func TestCheckCapabilities(t *testing.T) {
+	caps := &ModelCapabilities{
+		InputModalities:    []string{"text", "image"},
+		MaxImages:          2,
+		SupportedSizes:     []string{"1024x1024", "1280x720"},
+		SupportedDurations: []int{5, 10},
+	}
+	image := entity.MediaInput{Type: "image", Content: "https://example.com/a.png"}
+
+	tests := []struct {
+		name      string
+		request   entity.GenerateContentRequest
+		violation string
+	}{
+		{name: "满足能力", request: entity.GenerateContentRequest{InputMedia: []entity.MediaInput{image}, Output: entity.OutputConfig{Size: "1024X1024", Duration: 5}}},
+		{name: "未指定尺寸与时长", request: entity.GenerateContentRequest{}},
+		{name: "不支持的尺寸", request: entity.GenerateContentRequest{Output: entity.OutputConfig{Size: "512x512"}}, violation: CapabilityUnsupportedSize},
+		{name: "不支持的时长", request: entity.GenerateContentRequest{Output: entity.OutputConfig{Duration: 7}}, violation: CapabilityUnsupportedDuration},
+		{name: "图片过多", request: entity.GenerateContentRequest{InputMedia: []entity.MediaInput{image, image, image}}, violation: CapabilityTooManyImages},
+		{name: "不支持视频输入", request: entity.GenerateContentRequest{InputMedia: []entity.MediaInput{{Type: "video", Content: "https://example.com/a.mp4"}}}, violation: CapabilityUnsupportedModality},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCapabilities(tt.request, caps)
+			if tt.violation == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var capErr *CapabilityError
+			if !errors.As(err, &capErr) {
+				t.Fatalf("expected capability error, got %v", err)
+			}
+			if capErr.Violation != tt.violation {
+				t.Errorf("expected violation %q, got %q", tt.violation, capErr.Violation)
+			}
+		})
+	}
+
+	t.Run("未配置能力时不限制", func(t *testing.T) {
+		request := entity.GenerateContentRequest{Output: entity.OutputConfig{Size: "any", Duration: 3}}
+		if err := CheckCapabilities(request, &ModelCapabilities{}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestApplyAdvancedParams(t *testing.T) {
+	seed := int64(7)
+	guidance := 3.5
+	steps := 30
+	strength := 0.6
+	params := entity.AdvancedParams{Seed: &seed, NegativePrompt: " blurry ", GuidanceScale: &guidance, Steps: &steps, Strength: &strength}
+
+	t.Run("保留支持的参数并剔除其余参数", func(t *testing.T) {
+		caps := &ModelCapabilities{AdvancedParams: []string{AdvancedParamSeed, AdvancedParamNegativePrompt}}
+		effective, dropped := ApplyAdvancedParams(params, caps)
+		if effective.Seed == nil || *effective.Seed != seed || effective.NegativePrompt != "blurry" {
+			t.Errorf("expected seed and negative prompt to be kept, got %+v", effective)
+		}
+		if effective.GuidanceScale != nil || effective.Steps != nil || effective.Strength != nil {
+			t.Errorf("expected unsupported params to be dropped, got %+v", effective)
+		}
+		want := []string{AdvancedParamGuidanceScale, AdvancedParamSteps, AdvancedParamStrength}
+		if len(dropped) != len(want) {
+			t.Fatalf("expected dropped %v, got %v", want, dropped)
+		}
+		for i := range want {
+			if dropped[i] != want[i] {
+				t.Errorf("expected dropped %v, got %v", want, dropped)
+			}
+		}
+	})
+
+	t.Run("模型不支持任何参数", func(t *testing.T) {
+		effective, dropped := ApplyAdvancedParams(params, &ModelCapabilities{})
+		if !effective.IsEmpty() {
+			t.Errorf("expected no effective params, got %+v", effective)
+		}
+		if len(dropped) != 5 {
+			t.Errorf("expected all 5 params dropped, got %v", dropped)
+		}
+	})
+
+	t.Run("未设置参数", func(t *testing.T) {
+		effective, dropped := ApplyAdvancedParams(entity.AdvancedParams{}, &ModelCapabilities{})
+		if !effective.IsEmpty() || len(dropped) != 0 {
+			t.Errorf("expected nothing, got %+v and %v", effective, dropped)
+		}
+	})
+}
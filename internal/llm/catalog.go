@@ -0,0 +1,376 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCatalogUnsupported is returned when a provider driver cannot list its upstream model catalog.
+var ErrCatalogUnsupported = errors.New("provider does not support catalog sync")
+
+// CatalogModel is a model listed in a provider's upstream catalog, with the fields that can be pre-filled
+// when importing it into llm_models.
+type CatalogModel struct {
+	ModelID          string
+	Name             string
+	Description      string
+	InputModalities  []string
+	OutputModalities []string
+	// GenerationMode is set when the catalog tells how the model is invoked (e.g. text_to_image).
+	GenerationMode string
+	// Pricing is empty when the catalog has no machine-readable prices.
+	Pricing entity.ModelPricing
+}
+
+// CatalogLister is implemented by providers that can list the generation models they offer upstream.
+type CatalogLister interface {
+	// ListCatalog returns the image and video generation models of the provider.
+	ListCatalog(ctx context.Context) ([]CatalogModel, error)
+}
+
+// FetchCatalog builds a fresh service from the provider config and lists its upstream model catalog.
+func (f *ProviderFactory) FetchCatalog(ctx context.Context, provider *entity.DbProvider) ([]CatalogModel, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("provider config is nil")
+	}
+
+	service, err := f.construct(provider)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := Unwrap(service).(CatalogLister)
+	if !ok {
+		return nil, ErrCatalogUnsupported
+	}
+	return lister.ListCatalog(ctx)
+}
+
+// catalogClient is used to download model catalogs, which can be several megabytes.
+var catalogClient = &http.Client{Timeout: 60 * time.Second}
+
+// getCatalogJSON sends an authenticated GET request and decodes the JSON response into out.
+func getCatalogJSON(ctx context.Context, provider, target string, header http.Header, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("%s catalog: %w", provider, err)
+	}
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := catalogClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s catalog: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxHealthCheckErrorBody))
+		return newHTTPStatusError(provider, resp, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s catalog decode: %w", provider, err)
+	}
+	return nil
+}
+
+// catalogModalities keeps the modalities the generation pipeline understands, in a stable order.
+func catalogModalities(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		seen[strings.ToLower(strings.TrimSpace(value))] = true
+	}
+	out := make([]string, 0, 3)
+	for _, modality := range []string{string(entity.ModText), string(entity.ModImage), string(entity.ModVideo)} {
+		if seen[modality] {
+			out = append(out, modality)
+		}
+	}
+	return out
+}
+
+// roundPrice removes floating point noise introduced when converting per-unit prices.
+func roundPrice(value float64) float64 {
+	return math.Round(value*1e9) / 1e9
+}
+
+// OpenRouter
+
+type openRouterCatalogResponse struct {
+	Data []openRouterCatalogModel `json:"data"`
+}
+
+type openRouterCatalogModel struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Architecture struct {
+		InputModalities  []string `json:"input_modalities"`
+		OutputModalities []string `json:"output_modalities"`
+	} `json:"architecture"`
+	// Pricing values are USD per token, encoded as strings.
+	Pricing struct {
+		Prompt     string `json:"prompt"`
+		Completion string `json:"completion"`
+	} `json:"pricing"`
+}
+
+// ListCatalog lists the models that can output images.
+func (o *OpenRouter) ListCatalog(ctx context.Context) ([]CatalogModel, error) {
+	var resp openRouterCatalogResponse
+	if err := getCatalogJSON(ctx, "openrouter", openaiCompatibleBase(o.endpoint)+"/models", bearerHeader(o.apiKey), &resp); err != nil {
+		return nil, err
+	}
+	return parseOpenRouterCatalog(resp.Data), nil
+}
+
+// parseOpenRouterCatalog keeps the models that can output images.
+func parseOpenRouterCatalog(models []openRouterCatalogModel) []CatalogModel {
+	out := make([]CatalogModel, 0)
+	for _, model := range models {
+		outputs := catalogModalities(model.Architecture.OutputModalities)
+		if strings.TrimSpace(model.ID) == "" || !containsString(outputs, string(entity.ModImage)) {
+			continue
+		}
+		out = append(out, CatalogModel{
+			ModelID:          strings.TrimSpace(model.ID),
+			Name:             strings.TrimSpace(model.Name),
+			Description:      strings.TrimSpace(model.Description),
+			InputModalities:  catalogModalities(model.Architecture.InputModalities),
+			OutputModalities: outputs,
+			Pricing: entity.ModelPricing{
+				PerThousandInputTokens:  perThousandTokens(model.Pricing.Prompt),
+				PerThousandOutputTokens: perThousandTokens(model.Pricing.Completion),
+			},
+		})
+	}
+	return out
+}
+
+// perThousandTokens converts a per-token price string to a price per thousand tokens.
+func perThousandTokens(raw string) float64 {
+	price, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil || price <= 0 {
+		return 0
+	}
+	return roundPrice(price * 1000)
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// fal
+
+// falCatalogCategories are the fal model categories imported, with the generation mode each maps to.
+// Only the modes FalAI.buildInputPayload supports are imported; video endpoints take model-specific
+// inputs and are added by hand.
+var falCatalogCategories = []struct {
+	category string
+	mode     falMode
+	inputs   []string
+	output   string
+}{
+	{category: "text-to-image", mode: falModeTextToImage, inputs: []string{"text"}, output: "image"},
+	{category: "image-to-image", mode: falModeImageToImage, inputs: []string{"text", "image"}, output: "image"},
+}
+
+// falCatalogMaxPages bounds the pages fetched per category; pricing is requested for
+// falPricingBatchSize endpoints at a time.
+const (
+	falCatalogPageSize  = 100
+	falCatalogMaxPages  = 10
+	falPricingBatchSize = 50
+)
+
+type falCatalogResponse struct {
+	Models []struct {
+		EndpointID string `json:"endpoint_id"`
+		Metadata   struct {
+			DisplayName string `json:"display_name"`
+			Description string `json:"description"`
+			Status      string `json:"status"`
+		} `json:"metadata"`
+	} `json:"models"`
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
+type falPricingResponse struct {
+	Prices []falPrice `json:"prices"`
+}
+
+type falPrice struct {
+	EndpointID string  `json:"endpoint_id"`
+	UnitPrice  float64 `json:"unit_price"`
+	Unit       string  `json:"unit"`
+	Currency   string  `json:"currency"`
+}
+
+// ListCatalog lists the active text-to-image and image-to-image models of the platform API, with unit prices where known.
+func (f *FalAI) ListCatalog(ctx context.Context) ([]CatalogModel, error) {
+	header := falAuthHeader(f.apiKey)
+	models := make([]CatalogModel, 0)
+	seen := make(map[string]bool)
+
+	for _, category := range falCatalogCategories {
+		cursor := ""
+		for page := 0; page < falCatalogMaxPages; page++ {
+			query := url.Values{}
+			query.Set("category", category.category)
+			query.Set("status", "active")
+			query.Set("limit", strconv.Itoa(falCatalogPageSize))
+			if cursor != "" {
+				query.Set("cursor", cursor)
+			}
+
+			var resp falCatalogResponse
+			if err := getCatalogJSON(ctx, "fal", falPlatformAPIBaseURL+"/models?"+query.Encode(), header, &resp); err != nil {
+				return nil, err
+			}
+			for _, item := range resp.Models {
+				endpointID := strings.TrimSpace(item.EndpointID)
+				status := strings.ToLower(strings.TrimSpace(item.Metadata.Status))
+				if endpointID == "" || seen[endpointID] || (status != "" && status != "active") {
+					continue
+				}
+				seen[endpointID] = true
+
+				name := strings.TrimSpace(item.Metadata.DisplayName)
+				if name == "" {
+					name = endpointID
+				}
+				models = append(models, CatalogModel{
+					ModelID:          endpointID,
+					Name:             name,
+					Description:      strings.TrimSpace(item.Metadata.Description),
+					InputModalities:  append([]string(nil), category.inputs...),
+					OutputModalities: []string{category.output},
+					GenerationMode:   string(category.mode),
+				})
+			}
+			if !resp.HasMore || resp.NextCursor == "" {
+				break
+			}
+			cursor = resp.NextCursor
+		}
+	}
+
+	// Prices are optional: the catalog is still useful without them.
+	prices, err := f.catalogPricing(ctx, header, models)
+	if err != nil {
+		logrus.WithError(err).WithField("provider_id", f.providerID).Warn("fal catalog pricing unavailable")
+	}
+	for i := range models {
+		models[i].Pricing = prices[models[i].ModelID]
+	}
+	return models, nil
+}
+
+// catalogPricing fetches unit prices for the models in batches of falPricingBatchSize endpoint IDs.
+func (f *FalAI) catalogPricing(ctx context.Context, header http.Header, models []CatalogModel) (map[string]entity.ModelPricing, error) {
+	prices := make(map[string]entity.ModelPricing, len(models))
+	for start := 0; start < len(models); start += falPricingBatchSize {
+		end := start + falPricingBatchSize
+		if end > len(models) {
+			end = len(models)
+		}
+		query := url.Values{}
+		for _, model := range models[start:end] {
+			query.Add("endpoint_id", model.ModelID)
+		}
+
+		var resp falPricingResponse
+		if err := getCatalogJSON(ctx, "fal", falPlatformAPIBaseURL+"/models/pricing?"+query.Encode(), header, &resp); err != nil {
+			return prices, err
+		}
+		for _, price := range resp.Prices {
+			if pricing := falPricing(price); !pricing.IsEmpty() {
+				prices[price.EndpointID] = pricing
+			}
+		}
+	}
+	return prices, nil
+}
+
+// falPricing maps a fal unit price to the model pricing field it bills; unknown units yield empty pricing.
+func falPricing(price falPrice) entity.ModelPricing {
+	if price.UnitPrice <= 0 || (price.Currency != "" && !strings.EqualFold(price.Currency, "USD")) {
+		return entity.ModelPricing{}
+	}
+	unit := strings.ToLower(strings.TrimSpace(price.Unit))
+	switch {
+	case strings.HasPrefix(unit, "image"):
+		return entity.ModelPricing{PerImage: price.UnitPrice}
+	case strings.HasPrefix(unit, "second"):
+		return entity.ModelPricing{PerVideoSecond: price.UnitPrice}
+	case strings.HasPrefix(unit, "megapixel"):
+		return entity.ModelPricing{PerMegapixel: price.UnitPrice}
+	}
+	return entity.ModelPricing{}
+}
+
+// DashScope
+
+type dashscopeCatalogResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// ListCatalog lists the image and video generation models of the OpenAI-compatible API on the configured host.
+func (p *Dashscope) ListCatalog(ctx context.Context) ([]CatalogModel, error) {
+	var resp dashscopeCatalogResponse
+	target := urlOrigin(p.endpoint, dashscopeDefaultHost) + dashscopeModelsPath
+	if err := getCatalogJSON(ctx, "dashscope", target, bearerHeader(p.apiKey), &resp); err != nil {
+		return nil, err
+	}
+
+	models := make([]CatalogModel, 0)
+	for _, item := range resp.Data {
+		if model, ok := classifyDashscopeModel(item.ID); ok {
+			models = append(models, model)
+		}
+	}
+	return models, nil
+}
+
+// classifyDashscopeModel infers modalities and generation mode from a DashScope model ID, whose model list
+// carries no capability metadata. Models that are not image or video generators are rejected.
+func classifyDashscopeModel(modelID string) (CatalogModel, bool) {
+	lower := strings.ToLower(strings.TrimSpace(modelID))
+	model := CatalogModel{ModelID: strings.TrimSpace(modelID), Name: strings.TrimSpace(modelID)}
+	switch {
+	case strings.Contains(lower, "i2v") || strings.Contains(lower, "kf2v"):
+		model.InputModalities, model.OutputModalities, model.GenerationMode = []string{"text", "image"}, []string{"video"}, "image_to_video"
+	case strings.Contains(lower, "t2v"):
+		model.InputModalities, model.OutputModalities, model.GenerationMode = []string{"text"}, []string{"video"}, "text_to_video"
+	case strings.Contains(lower, "image-edit") || strings.Contains(lower, "imageedit"):
+		model.InputModalities, model.OutputModalities, model.GenerationMode = []string{"text", "image"}, []string{"image"}, "image_to_image"
+	case strings.Contains(lower, "t2i") || strings.Contains(lower, "qwen-image") || strings.HasPrefix(lower, "wanx"):
+		model.InputModalities, model.OutputModalities, model.GenerationMode = []string{"text"}, []string{"image"}, "text_to_image"
+	default:
+		return CatalogModel{}, false
+	}
+	return model, true
+}
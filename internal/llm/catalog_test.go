@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestOpenRouterListCatalog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/models" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[
+			{"id":"google/gemini-2.5-flash-image","name":"Nano Banana","architecture":{"input_modalities":["image","text","file"],"output_modalities":["image","text"]},"pricing":{"prompt":"0.0000003","completion":"0.0000025"}},
+			{"id":"openai/gpt-4o","name":"GPT-4o","architecture":{"input_modalities":["text"],"output_modalities":["text"]},"pricing":{"prompt":"0.0000025","completion":"0.00001"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	models, err := GetFactory().FetchCatalog(context.Background(), &entity.DbProvider{
+		ID:      "openrouter",
+		Driver:  entity.ProviderDriverOpenRouter,
+		APIKey:  "key",
+		BaseURL: server.URL + "/api/v1/chat/completions",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []CatalogModel{{
+		ModelID:          "google/gemini-2.5-flash-image",
+		Name:             "Nano Banana",
+		InputModalities:  []string{"text", "image"},
+		OutputModalities: []string{"text", "image"},
+		Pricing:          entity.ModelPricing{PerThousandInputTokens: 0.0003, PerThousandOutputTokens: 0.0025},
+	}}
+	if !reflect.DeepEqual(models, want) {
+		t.Errorf("expected %+v, got %+v", want, models)
+	}
+}
+
+func TestFetchCatalogUnsupported(t *testing.T) {
+	_, err := GetFactory().FetchCatalog(context.Background(), &entity.DbProvider{ID: "gemini", Driver: entity.ProviderDriverGemini, APIKey: "key"})
+	if !errors.Is(err, ErrCatalogUnsupported) {
+		t.Fatalf("expected ErrCatalogUnsupported, got %v", err)
+	}
+}
+
+func TestFalPricing(t *testing.T) {
+	tests := []struct {
+		name  string
+		price falPrice
+		want  entity.ModelPricing
+	}{
+		{name: "按张计费", price: falPrice{UnitPrice: 0.025, Unit: "image", Currency: "USD"}, want: entity.ModelPricing{PerImage: 0.025}},
+		{name: "按秒计费", price: falPrice{UnitPrice: 0.1, Unit: "seconds"}, want: entity.ModelPricing{PerVideoSecond: 0.1}},
+		{name: "按百万像素计费", price: falPrice{UnitPrice: 0.03, Unit: "megapixels", Currency: "usd"}, want: entity.ModelPricing{PerMegapixel: 0.03}},
+		{name: "未知单位", price: falPrice{UnitPrice: 0.5, Unit: "request", Currency: "USD"}},
+		{name: "非美元", price: falPrice{UnitPrice: 0.5, Unit: "image", Currency: "EUR"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := falPricing(tt.price); got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFalCatalogCategoriesSupported(t *testing.T) {
+	request := entity.GenerateContentRequest{
+		Prompt:     "a red dress",
+		InputMedia: []entity.MediaInput{{Type: "image", Content: "https://example.com/dress.png"}},
+	}
+	for _, category := range falCatalogCategories {
+		t.Run(category.category, func(t *testing.T) {
+			if _, err := (&FalAI{}).buildInputPayload(category.mode, request); err != nil {
+				t.Errorf("imported mode %q is not supported: %v", category.mode, err)
+			}
+		})
+	}
+}
+
+func TestClassifyDashscopeModel(t *testing.T) {
+	tests := []struct {
+		modelID  string
+		wantMode string
+		wantOK   bool
+	}{
+		{modelID: "wan2.2-i2v-plus", wantMode: "image_to_video", wantOK: true},
+		{modelID: "wanx2.1-kf2v-plus", wantMode: "image_to_video", wantOK: true},
+		{modelID: "wan2.2-t2v-plus", wantMode: "text_to_video", wantOK: true},
+		{modelID: "qwen-image-edit", wantMode: "image_to_image", wantOK: true},
+		{modelID: "qwen-image", wantMode: "text_to_image", wantOK: true},
+		{modelID: "wan2.2-t2i-flash", wantMode: "text_to_image", wantOK: true},
+		{modelID: "qwen-plus"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.modelID, func(t *testing.T) {
+			got, ok := classifyDashscopeModel(tt.modelID)
+			if ok != tt.wantOK || got.GenerationMode != tt.wantMode {
+				t.Errorf("expected %q/%v, got %q/%v", tt.wantMode, tt.wantOK, got.GenerationMode, ok)
+			}
+		})
+	}
+}
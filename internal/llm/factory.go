@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 )
 
 // ProviderConstructor is a function that creates an AIService from a provider config.
 type ProviderConstructor func(provider *entity.DbProvider) (AIService, error)
 
 // ProviderFactory manages AIService instances with registration and caching.
+// Cached instances are rebuilt when the provider's ConfigVersion changes, so an update made
+// through any instance reaches all of them the next time they load the provider.
 type ProviderFactory struct {
 	registry map[string]ProviderConstructor
 	cache    sync.Map
@@ -74,8 +77,21 @@ func (f *ProviderFactory) Register(driver string, constructor ProviderConstructo
 	f.registry[strings.ToLower(driver)] = constructor
 }
 
+// cachedService is a cached AIService with the provider revision it was built from.
+type cachedService struct {
+	service   AIService
+	version   uint64
+	createdAt time.Time
+}
+
+// matches reports whether the cached instance was built from the provider's current config.
+// CreatedAt distinguishes a provider that was deleted and recreated with the same ID.
+func (c *cachedService) matches(provider *entity.DbProvider) bool {
+	return c.version == provider.ConfigVersion && c.createdAt.Equal(provider.CreatedAt)
+}
+
 // Get returns an AIService for the given provider.
-// It caches instances by provider ID for reuse.
+// It caches instances by provider ID for reuse until the provider's config version changes.
 func (f *ProviderFactory) Get(provider *entity.DbProvider) (AIService, error) {
 	if provider == nil {
 		return nil, fmt.Errorf("provider config is nil")
@@ -85,9 +101,32 @@ func (f *ProviderFactory) Get(provider *entity.DbProvider) (AIService, error) {
 
 	// Check cache first
 	if cached, ok := f.cache.Load(cacheKey); ok {
-		return cached.(AIService), nil
+		if entry := cached.(*cachedService); entry.matches(provider) {
+			return entry.service, nil
+		}
 	}
 
+	// Create new service
+	service, err := f.construct(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	// Enforce the outbound limits configured for the provider
+	service = withRateLimits(provider.ID, service, RateLimitsFromConfig(provider.Config))
+
+	// Store in cache
+	f.cache.Store(cacheKey, &cachedService{
+		service:   service,
+		version:   provider.ConfigVersion,
+		createdAt: provider.CreatedAt,
+	})
+
+	return service, nil
+}
+
+// construct creates an uncached AIService for the provider using the constructor registered for its driver.
+func (f *ProviderFactory) construct(provider *entity.DbProvider) (AIService, error) {
 	// Get driver
 	driver := strings.ToLower(strings.TrimSpace(provider.Driver))
 	if driver == "" {
@@ -102,17 +141,7 @@ func (f *ProviderFactory) Get(provider *entity.DbProvider) (AIService, error) {
 	if !ok {
 		return nil, fmt.Errorf("unsupported provider driver: %s", provider.Driver)
 	}
-
-	// Create new service
-	service, err := constructor(provider)
-	if err != nil {
-		return nil, err
-	}
-
-	// Store in cache
-	f.cache.Store(cacheKey, service)
-
-	return service, nil
+	return constructor(provider)
 }
 
 // Invalidate removes a cached provider instance.
@@ -0,0 +1,50 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"testing"
+	"time"
+)
+
+func TestProviderFactoryCacheFollowsConfigVersion(t *testing.T) {
+	built := 0
+	factory := &ProviderFactory{registry: make(map[string]ProviderConstructor)}
+	factory.Register("stub", func(provider *entity.DbProvider) (AIService, error) {
+		built++
+		return &stubAIService{}, nil
+	})
+
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	provider := &entity.DbProvider{ID: "stub", Driver: "stub", ConfigVersion: 1, CreatedAt: createdAt}
+
+	steps := []struct {
+		name      string
+		mutate    func()
+		wantBuilt int
+		wantReuse bool
+	}{
+		{name: "首次创建", mutate: func() {}, wantBuilt: 1},
+		{name: "版本未变时复用缓存", mutate: func() {}, wantBuilt: 1, wantReuse: true},
+		{name: "其他实例更新后重建", mutate: func() { provider.ConfigVersion = 2 }, wantBuilt: 2},
+		{name: "删除后以相同 ID 重建", mutate: func() { provider.ConfigVersion, provider.CreatedAt = 1, createdAt.Add(time.Hour) }, wantBuilt: 3},
+		{name: "本实例主动失效", mutate: func() { factory.Invalidate("stub") }, wantBuilt: 4},
+	}
+
+	var previous AIService
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			step.mutate()
+			service, err := factory.Get(provider)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if built != step.wantBuilt {
+				t.Fatalf("expected %d constructions, got %d", step.wantBuilt, built)
+			}
+			if reused := service == previous; reused != step.wantReuse {
+				t.Errorf("expected reuse %v, got %v", step.wantReuse, reused)
+			}
+			previous = service
+		})
+	}
+}
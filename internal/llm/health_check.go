@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrHealthCheckUnsupported is returned when a provider driver has no credential check.
+var ErrHealthCheckUnsupported = errors.New("provider does not support health checks")
+
+// HealthChecker is implemented by providers that can verify their endpoint and credentials with a
+// cheap authenticated call (listing models, reading account info) that does not generate anything.
+type HealthChecker interface {
+	// CheckHealth returns nil when the provider accepted the configured credentials.
+	CheckHealth(ctx context.Context) error
+}
+
+// CheckHealth builds a fresh service from the provider config and runs its health check, returning
+// how long the check took. The cache is bypassed so that the stored credentials are tested even when
+// a cached instance predates an update.
+func (f *ProviderFactory) CheckHealth(ctx context.Context, provider *entity.DbProvider) (time.Duration, error) {
+	if provider == nil {
+		return 0, fmt.Errorf("provider config is nil")
+	}
+
+	service, err := f.construct(provider)
+	if err != nil {
+		return 0, err
+	}
+	checker, ok := Unwrap(service).(HealthChecker)
+	if !ok {
+		return 0, ErrHealthCheckUnsupported
+	}
+
+	start := time.Now()
+	err = checker.CheckHealth(ctx)
+	return time.Since(start), err
+}
+
+// healthCheckClient is used for credential checks; callers bound each check with their context.
+var healthCheckClient = &http.Client{Timeout: 30 * time.Second}
+
+// maxHealthCheckErrorBody caps how much of an error response is kept.
+const maxHealthCheckErrorBody = 2048
+
+// checkHTTPEndpoint sends an authenticated GET request and treats any 2xx answer as healthy.
+func checkHTTPEndpoint(ctx context.Context, provider, target string, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("%s health check: %w", provider, err)
+	}
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := healthCheckClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s health check: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxHealthCheckErrorBody))
+		return newHTTPStatusError(provider, resp, strings.TrimSpace(string(body)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// bearerHeader returns the Authorization header used by OpenAI-compatible APIs.
+func bearerHeader(apiKey string) http.Header {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+apiKey)
+	return header
+}
+
+// openaiCompatibleBase strips the chat completions path from an OpenAI-compatible endpoint,
+// leaving the API base (e.g. https://openrouter.ai/api/v1).
+func openaiCompatibleBase(endpoint string) string {
+	base := strings.TrimRight(strings.TrimSpace(endpoint), "/")
+	if idx := strings.Index(strings.ToLower(base), "/chat/completions"); idx >= 0 {
+		base = base[:idx]
+	}
+	return base
+}
+
+// urlOrigin returns the scheme and host of raw, or fallback when raw is empty or not an absolute URL.
+func urlOrigin(raw, fallback string) string {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return fallback
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenRouterCheckHealth(t *testing.T) {
+	var gotPath, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		if gotAuth != "Bearer good-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"No auth credentials found"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"label":"test"}}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		apiKey     string
+		wantStatus int
+	}{
+		{name: "凭证有效", apiKey: "good-key"},
+		{name: "凭证无效", apiKey: "bad-key", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &entity.DbProvider{
+				ID:      "openrouter",
+				Driver:  entity.ProviderDriverOpenRouter,
+				APIKey:  tt.apiKey,
+				BaseURL: server.URL + "/api/v1/chat/completions",
+			}
+			_, err := GetFactory().CheckHealth(context.Background(), provider)
+			if gotPath != "/api/v1/key" {
+				t.Errorf("expected key endpoint, got %s", gotPath)
+			}
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("expected healthy provider, got %v", err)
+				}
+				return
+			}
+			var statusErr *HTTPStatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus {
+				t.Fatalf("expected http %d error, got %v", tt.wantStatus, err)
+			}
+		})
+	}
+}
+
+func TestCheckHealthConstructorError(t *testing.T) {
+	_, err := GetFactory().CheckHealth(context.Background(), &entity.DbProvider{ID: "fal", Driver: entity.ProviderDriverFal})
+	if err == nil {
+		t.Fatal("expected missing api key to fail the check")
+	}
+}
+
+func TestResolveGeminiModelsEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		want     string
+	}{
+		{name: "默认地址", endpoint: "", want: "https://generativelanguage.googleapis.com/v1beta/models?pageSize=1"},
+		{name: "基础地址", endpoint: "https://aihubmix.com/gemini/", want: "https://aihubmix.com/gemini/v1beta/models?pageSize=1"},
+		{
+			name:     "模板地址",
+			endpoint: "https://proxy.example.com/v1beta/models/%s:streamGenerateContent?alt=sse",
+			want:     "https://proxy.example.com/v1beta/models?pageSize=1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveGeminiModelsEndpoint(tt.endpoint); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestOpenaiCompatibleBase(t *testing.T) {
+	if got := openaiCompatibleBase("https://openrouter.ai/api/v1/chat/completions"); got != "https://openrouter.ai/api/v1" {
+		t.Errorf("expected chat completions path to be stripped, got %s", got)
+	}
+	if got := urlOrigin("https://dashscope-intl.aliyuncs.com/api/v1/services/aigc", "fallback"); got != "https://dashscope-intl.aliyuncs.com" {
+		t.Errorf("expected origin of endpoint, got %s", got)
+	}
+	if got := urlOrigin("", "fallback"); got != "fallback" {
+		t.Errorf("expected fallback for empty endpoint, got %s", got)
+	}
+}
@@ -17,6 +17,14 @@ type ModelCapabilities struct {
 	MaxImages          int
 	SupportedSizes     []string
 	SupportedDurations []int
+
+	// SupportsMultipleOutputs reports whether one call can return Output.NumOutputs results;
+	// otherwise the caller fans out one call per output.
+	SupportsMultipleOutputs bool
+
+	// AdvancedParams lists the AdvancedParam* names the driver forwards for the model;
+	// other advanced parameters are dropped before dispatch.
+	AdvancedParams []string
 }
 
 // AIService defines the interface for AI content generation services.
@@ -29,3 +29,14 @@ func buildMediaOutputs(urls []string, mediaType string) []entity.MediaOutput {
 	}
 	return outputs
 }
+
+// DefaultMaxOutputs caps Output.NumOutputs for models that do not configure MaxImages.
+const DefaultMaxOutputs = 4
+
+// MaxOutputs returns how many outputs a single request may ask the model for.
+func MaxOutputs(model entity.DbModel) int {
+	if model.MaxImages > 0 {
+		return model.MaxImages
+	}
+	return DefaultMaxOutputs
+}
@@ -16,6 +16,8 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+const dashscopeDefaultHost = "https://dashscope.aliyuncs.com"
+const dashscopeModelsPath = "/compatible-mode/v1/models"
 const defaultDashscopeGenerationURL = "https://dashscope.aliyuncs.com/api/v1/services/aigc/multimodal-generation/generation"
 const dashscopeImageToVideoURL = "https://dashscope.aliyuncs.com/api/v1/services/aigc/video-generation/video-synthesis"
 const dashscopeKeyframeToVideoURL = "https://dashscope.aliyuncs.com/api/v1/services/aigc/image2video/video-synthesis"
@@ -46,6 +48,8 @@ type dashscopeInput struct {
 type dashscopeParameters struct {
 	NegativePrompt string `json:"negative_prompt,omitempty"`
 	Watermark      *bool  `json:"watermark,omitempty"`
+	N              int    `json:"n,omitempty"` // number of images to generate
+	Seed           *int64 `json:"seed,omitempty"`
 }
 
 type dashscopeResponse struct {
@@ -74,19 +78,23 @@ type dashscopeVideoRequest struct {
 	Model      string                 `json:"model"`
 	Input      dashscopeVideoInput    `json:"input"`
 	Parameters dashscopeVideoParamers `json:"parameters,omitempty"`
+	// CallbackURL receives the task result once the async task finishes.
+	CallbackURL string `json:"callback_url,omitempty"`
 }
 
 type dashscopeVideoInput struct {
-	Prompt        string `json:"prompt"`
-	ImgURL        string `json:"img_url,omitempty"`
-	FirstFrameURL string `json:"first_frame_url,omitempty"`
-	LastFrameURL  string `json:"last_frame_url,omitempty"`
+	Prompt         string `json:"prompt"`
+	NegativePrompt string `json:"negative_prompt,omitempty"`
+	ImgURL         string `json:"img_url,omitempty"`
+	FirstFrameURL  string `json:"first_frame_url,omitempty"`
+	LastFrameURL   string `json:"last_frame_url,omitempty"`
 }
 
 type dashscopeVideoParamers struct {
 	Resolution   string `json:"resolution,omitempty"`
 	PromptExtend *bool  `json:"prompt_extend,omitempty"`
 	Duration     int    `json:"duration,omitempty"`
+	Seed         *int64 `json:"seed,omitempty"`
 }
 
 type dashscopeVideoResponse struct {
@@ -147,7 +155,7 @@ func (o dashscopeVideoOutput) collectAssets() []string {
 	return out
 }
 
-func GenerateImageByDashscopeProtocol(ctx context.Context, apiKey, endpoint string, model entity.DbModel, prompt, size string, duration int, base64Images, videos []string) (*entity.GenerateContentResponse, error) {
+func GenerateImageByDashscopeProtocol(ctx context.Context, apiKey, endpoint string, model entity.DbModel, prompt, size string, numOutputs int, base64Images, videos []string, params entity.AdvancedParams) (*entity.GenerateContentResponse, error) {
 	if strings.TrimSpace(apiKey) == "" {
 		return nil, errors.New("api key missing")
 	}
@@ -168,6 +176,7 @@ func GenerateImageByDashscopeProtocol(ctx context.Context, apiKey, endpoint stri
 		"image_count":     len(base64Images),
 		"video_count":     len(videos),
 		"reference_media": len(base64Images) + len(videos),
+		"num_outputs":     numOutputs,
 	}).Info("dashscope_generate_content_start")
 
 	messageContents := make([]dashscopeContent, 0, len(base64Images)+len(videos)+1)
@@ -227,9 +236,13 @@ func GenerateImageByDashscopeProtocol(ctx context.Context, apiKey, endpoint stri
 		},
 		Parameters: dashscopeParameters{
 			Watermark:      &watermark,
-			NegativePrompt: "",
+			NegativePrompt: strings.TrimSpace(params.NegativePrompt),
+			Seed:           params.Seed,
 		},
 	}
+	if numOutputs > 1 {
+		reqBody.Parameters.N = numOutputs
+	}
 
 	payload, err := json.Marshal(reqBody)
 	if err != nil {
@@ -259,7 +272,7 @@ func GenerateImageByDashscopeProtocol(ctx context.Context, apiKey, endpoint stri
 			"status": resp.StatusCode,
 			"body":   buf.String(),
 		}).Error("dashscope generate content http error")
-		return nil, fmt.Errorf("dashscope http %d: %s", resp.StatusCode, buf.String())
+		return nil, newHTTPStatusError("dashscope", resp, buf.String())
 	}
 
 	var result dashscopeResponse
@@ -327,7 +340,7 @@ func GenerateImageByDashscopeProtocol(ctx context.Context, apiKey, endpoint stri
 	}, nil
 }
 
-func GenerateDashscopeVideo(ctx context.Context, apiKey, endpoint string, model entity.DbModel, prompt, size string, duration int, images []string) (*entity.GenerateContentResponse, error) {
+func GenerateDashscopeVideo(ctx context.Context, apiKey, endpoint string, model entity.DbModel, prompt, size string, duration int, images []string, params entity.AdvancedParams) (*entity.GenerateContentResponse, error) {
 	cfg := videoConfigFromModel(model)
 	if len(images) == 0 {
 		return nil, errors.New("dashscope video model requires at least one reference image")
@@ -373,6 +386,7 @@ func GenerateDashscopeVideo(ctx context.Context, apiKey, endpoint string, model
 			LastFrameURL:  lastFrame,
 		}
 	}
+	input.NegativePrompt = strings.TrimSpace(params.NegativePrompt)
 
 	reqBody := dashscopeVideoRequest{
 		Model: model.ModelID,
@@ -381,7 +395,9 @@ func GenerateDashscopeVideo(ctx context.Context, apiKey, endpoint string, model
 			Resolution:   resolution,
 			PromptExtend: &promptExtend,
 			Duration:     durationValue,
+			Seed:         params.Seed,
 		},
+		CallbackURL: TaskCallbackURL(ctx, entity.ProviderDriverDashscope),
 	}
 
 	payload, err := json.Marshal(reqBody)
@@ -413,7 +429,7 @@ func GenerateDashscopeVideo(ctx context.Context, apiKey, endpoint string, model
 			"status": resp.StatusCode,
 			"body":   buf.String(),
 		}).Error("dashscope generate video http error")
-		return nil, fmt.Errorf("dashscope http %d: %s", resp.StatusCode, buf.String())
+		return nil, newHTTPStatusError("dashscope", resp, buf.String())
 	}
 
 	var result dashscopeVideoResponse
@@ -433,12 +449,13 @@ func GenerateDashscopeVideo(ctx context.Context, apiKey, endpoint string, model
 
 	output := result.Output
 	externalTaskCode := output.TaskID
+	ReportTaskSubmitted(ctx, externalTaskCode)
 
 	var assets []string
 	var assistantText string
 
 	if status := strings.ToUpper(strings.TrimSpace(output.TaskStatus)); status != "" && status != "SUCCEEDED" {
-		assets, err = waitForDashscopeVideo(ctx, apiKey, output.TaskID)
+		assets, err = waitForDashscopeVideo(ctx, apiKey, output.TaskID, reqBody.CallbackURL)
 		if err != nil {
 			return &entity.GenerateContentResponse{
 				TaskID:    externalTaskCode,
@@ -456,7 +473,7 @@ func GenerateDashscopeVideo(ctx context.Context, apiKey, endpoint string, model
 	assets = output.collectAssets()
 	if len(assets) == 0 {
 		if strings.TrimSpace(output.TaskID) != "" {
-			assets, err = waitForDashscopeVideo(ctx, apiKey, output.TaskID)
+			assets, err = waitForDashscopeVideo(ctx, apiKey, output.TaskID, reqBody.CallbackURL)
 			if err != nil {
 				return &entity.GenerateContentResponse{
 					TaskID:    externalTaskCode,
@@ -496,54 +513,69 @@ func resolveDashscopeVideoEndpoint(endpoint string, useKeyframe bool) string {
 	return dashscopeImageToVideoURL
 }
 
-func waitForDashscopeVideo(ctx context.Context, apiKey, taskID string) ([]string, error) {
+// waitForDashscopeVideo waits for the async video task to finish. With a callback url the task is
+// queried when its completion callback arrives and otherwise only by a sparse safety poll.
+func waitForDashscopeVideo(ctx context.Context, apiKey, taskID, callbackURL string) ([]string, error) {
 	taskID = strings.TrimSpace(taskID)
 	if taskID == "" {
 		return nil, errors.New("dashscope missing task id for async video")
 	}
 
 	maxWait := 5 * time.Minute
-	pollInterval := 3 * time.Second
+	pollInterval := taskPollInterval(callbackURL, 3*time.Second)
 	deadline := time.Now().Add(maxWait)
 
+	var callbacks <-chan TaskCallback
+	if callbackURL != "" {
+		var release func()
+		callbacks, release = SubscribeTaskCallback(entity.ProviderDriverDashscope, taskID)
+		defer release()
+	}
+
+	var pollErrors pollErrorBudget
 	for {
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
 		}
-		assets, status, err := fetchDashscopeTask(ctx, apiKey, taskID)
-		if err != nil {
+		assets, status, err := fetchDashscopeTask(ctx, http.DefaultClient, apiKey, taskID)
+		switch {
+		case pollErrors.retry(ctx, err):
+			logrus.WithError(err).WithField("task_id", taskID).Warn("dashscope video task query failed, retrying")
+		case err != nil:
 			return nil, err
-		}
-		if len(assets) > 0 {
+		case len(assets) > 0:
 			return assets, nil
+		default:
+			state := strings.ToUpper(strings.TrimSpace(status))
+			if state == "FAILED" || state == "CANCELLED" {
+				return nil, taskFailed(fmt.Errorf("dashscope task %s", state))
+			}
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("dashscope task timeout (last status: %s)", state)
+			}
+			logrus.WithFields(logrus.Fields{
+				"task_id": taskID,
+				"status":  state,
+			}).Info("dashscope video task still running")
+			ReportTaskProgress(ctx, TaskProgress{TaskID: taskID, Status: MapTaskStatus(state)})
 		}
-		state := strings.ToUpper(strings.TrimSpace(status))
-		if state == "FAILED" || state == "CANCELLED" {
-			return nil, fmt.Errorf("dashscope task %s", state)
-		}
-		if time.Now().After(deadline) {
-			return nil, fmt.Errorf("dashscope task timeout (last status: %s)", state)
-		}
-		logrus.WithFields(logrus.Fields{
-			"task_id": taskID,
-			"status":  state,
-		}).Info("dashscope video task still running")
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
+		case <-callbacks:
 		case <-time.After(pollInterval):
 		}
 	}
 }
 
-func fetchDashscopeTask(ctx context.Context, apiKey, taskID string) ([]string, string, error) {
+func fetchDashscopeTask(ctx context.Context, client *http.Client, apiKey, taskID string) ([]string, string, error) {
 	target := dashscopeTaskQueryURL + taskID
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
 	if err != nil {
 		return nil, "", fmt.Errorf("dashscope task request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+apiKey)
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, "", fmt.Errorf("dashscope task query: %w", err)
 	}
@@ -559,7 +591,7 @@ func fetchDashscopeTask(ctx context.Context, apiKey, taskID string) ([]string, s
 			"status": resp.StatusCode,
 			"body":   buf.String(),
 		}).Error("dashscope task http error")
-		return nil, "", fmt.Errorf("dashscope task http %d: %s", resp.StatusCode, buf.String())
+		return nil, "", newHTTPStatusError("dashscope task", resp, buf.String())
 	}
 
 	var result dashscopeVideoResponse
@@ -579,6 +611,67 @@ func fetchDashscopeTask(ctx context.Context, apiKey, taskID string) ([]string, s
 	return assets, output.TaskStatus, nil
 }
 
+// dashscopeTaskPoller polls a dashscope async video task by its task id.
+type dashscopeTaskPoller struct {
+	apiKey     string
+	providerID string
+	modelID    string
+	// httpClient overrides http.DefaultClient, e.g. in tests.
+	httpClient *http.Client
+}
+
+func (p *dashscopeTaskPoller) Poll(ctx context.Context, taskID string) (*AsyncTask, error) {
+	client := p.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	assets, status, err := fetchDashscopeTask(ctx, client, p.apiKey, strings.TrimSpace(taskID))
+	if err != nil {
+		return nil, err
+	}
+
+	task := &AsyncTask{ID: taskID, ProviderID: p.providerID, ModelID: p.modelID, Status: MapTaskStatus(status)}
+	state := strings.ToUpper(strings.TrimSpace(status))
+	switch {
+	case len(assets) > 0:
+		task.Status = TaskStatusSucceeded
+		task.Result = &entity.GenerateContentResponse{
+			Outputs: buildMediaOutputs(assets, "video"),
+			TaskID:  taskID,
+		}
+	case state == "SUCCEEDED":
+		task.Status = TaskStatusFailed
+		task.Error = errors.New("dashscope video response missing video url")
+	case state == "FAILED" || state == "UNKNOWN":
+		// UNKNOWN means the task no longer exists upstream (expired)
+		task.Status = TaskStatusFailed
+		task.Error = fmt.Errorf("dashscope task %s", state)
+	}
+	return task, nil
+}
+
+// cancelDashscopeTask cancels a dashscope async task; only tasks still pending can be cancelled.
+func cancelDashscopeTask(ctx context.Context, apiKey, taskID string) error {
+	target := dashscopeTaskQueryURL + strings.TrimSpace(taskID) + "/cancel"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, nil)
+	if err != nil {
+		return fmt.Errorf("dashscope cancel request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("dashscope cancel task: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		buf := new(bytes.Buffer)
+		_, _ = buf.ReadFrom(resp.Body)
+		return fmt.Errorf("dashscope cancel http %d: %s", resp.StatusCode, buf.String())
+	}
+	return nil
+}
+
 func inlineDashscopeImage(ctx context.Context, payload string) (string, error) {
 	trimmed := strings.TrimSpace(payload)
 	if trimmed == "" {
@@ -136,7 +136,7 @@ func GenerateContentByGeminiProtocol(ctx context.Context, apiKey, endpoint, mode
 			"status": resp.StatusCode,
 			"body":   buf.String(),
 		}).Error("gemini generate images http error")
-		return nil, fmt.Errorf("gemini http %d: %s", resp.StatusCode, buf.String())
+		return nil, newHTTPStatusError("gemini", resp, buf.String())
 	}
 
 	// Stream parsing: Gemini with ?alt=sse emits `data: { ... }` lines similar to OpenAI.
@@ -185,6 +185,7 @@ func GenerateContentByGeminiProtocol(ctx context.Context, apiKey, endpoint, mode
 			for _, part := range cand.Content.Parts {
 				if part.Text != "" {
 					assistantText = appendLine(assistantText, part.Text)
+					ReportDelta(ctx, GenerationDelta{Text: part.Text})
 				}
 				// InlineData returns base64 image payload; wrap it into a data URL so downstream
 				// consumers can persist or preview it without guessing MIME type.
@@ -194,6 +195,7 @@ func GenerateContentByGeminiProtocol(ctx context.Context, apiKey, endpoint, mode
 						if _, ok := seenImages[dataURL]; !ok {
 							seenImages[dataURL] = struct{}{}
 							imageDataURLs = append(imageDataURLs, dataURL)
+							ReportDelta(ctx, GenerationDelta{Image: dataURL})
 							logrus.WithFields(logrus.Fields{
 								"mime":        part.InlineData.MimeType,
 								"image_len":   len(part.InlineData.Data),
@@ -211,6 +213,7 @@ func GenerateContentByGeminiProtocol(ctx context.Context, apiKey, endpoint, mode
 					if _, ok := seenImages[url]; !ok {
 						seenImages[url] = struct{}{}
 						imageDataURLs = append(imageDataURLs, url)
+						ReportDelta(ctx, GenerationDelta{Image: url})
 						logrus.WithFields(logrus.Fields{
 							"file_uri":    url,
 							"mime":        part.FileData.MimeType,
@@ -404,3 +407,16 @@ func resolveGeminiEndpoint(endpoint, model string) string {
 	base = strings.TrimRight(base, "/")
 	return fmt.Sprintf("%s/v1beta/models/%s:streamGenerateContent?alt=sse", base, model)
 }
+
+// resolveGeminiModelsEndpoint builds the URL listing a single model for the same endpoint forms
+// accepted by resolveGeminiEndpoint; a template's path up to "/models/" is kept.
+func resolveGeminiModelsEndpoint(endpoint string) string {
+	base := strings.TrimSpace(endpoint)
+	if base == "" {
+		base = geminiStreamEndpoint
+	}
+	if idx := strings.Index(base, "/models/"); idx >= 0 {
+		return base[:idx] + "/models?pageSize=1"
+	}
+	return strings.TrimRight(base, "/") + "/v1beta/models?pageSize=1"
+}
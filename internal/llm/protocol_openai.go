@@ -10,7 +10,6 @@ import (
 	"context"
 
 	"errors"
-	"fmt"
 	"net/http"
 
 	"github.com/sirupsen/logrus"
@@ -66,8 +65,16 @@ type orChoice struct {
 	NativeFinishReason string  `json:"native_finish_reason"`
 	Index              int     `json:"index"`
 }
+
+// orUsage is the usage block OpenRouter sends in the last chunk when usage accounting is requested.
+type orUsage struct {
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	Cost             *float64 `json:"cost"`
+}
 type orStreamChunk struct {
 	Choices []orChoice `json:"choices"`
+	Usage   *orUsage   `json:"usage"`
 }
 
 type orMsgPart struct {
@@ -142,6 +149,8 @@ func GenerateContentByOpenaiProtocol(ctx context.Context, apiKey, baseURL, model
 		"messages":   []orMessage{makeUserMessage(trimmedPrompt, refs, videos)},
 		"modalities": modalities,
 		"stream":     true,
+		// 请求在最后一个分片中返回 token 用量与费用
+		"usage": map[string]any{"include": true},
 	}
 
 	bs, _ := json.Marshal(reqBody)
@@ -165,7 +174,7 @@ func GenerateContentByOpenaiProtocol(ctx context.Context, apiKey, baseURL, model
 			"status":  resp.StatusCode,
 			"body":    buf.String(),
 		}).Error("openai generate content failed")
-		return nil, fmt.Errorf("openai http %d: %s", resp.StatusCode, buf.String())
+		return nil, newHTTPStatusError("openai", resp, buf.String())
 	}
 	// 处理 SSE 流式响应
 	logrus.Info("openai stream response started")
@@ -175,6 +184,7 @@ func GenerateContentByOpenaiProtocol(ctx context.Context, apiKey, baseURL, model
 
 	var imageDataURLs []string
 	var assistantText string
+	var usage *entity.ProviderUsage
 	nativeFinishReasonText := ""
 	seenImages := make(map[string]struct{})
 	for sc.Scan() {
@@ -192,6 +202,13 @@ func GenerateContentByOpenaiProtocol(ctx context.Context, apiKey, baseURL, model
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			continue
 		}
+		if chunk.Usage != nil {
+			usage = &entity.ProviderUsage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				Cost:             chunk.Usage.Cost,
+			}
+		}
 		if len(chunk.Choices) == 0 {
 			continue
 		}
@@ -202,6 +219,7 @@ func GenerateContentByOpenaiProtocol(ctx context.Context, apiKey, baseURL, model
 			delta := choice.Delta
 			if text := delta.Text(); text != "" {
 				assistantText += text
+				ReportDelta(ctx, GenerationDelta{Text: text})
 			}
 
 			if choice.FinishReason != "" {
@@ -223,6 +241,7 @@ func GenerateContentByOpenaiProtocol(ctx context.Context, apiKey, baseURL, model
 					}
 					seenImages[url] = struct{}{}
 					imageDataURLs = append(imageDataURLs, url)
+					ReportDelta(ctx, GenerationDelta{Image: url})
 				}
 			}
 		}
@@ -243,7 +262,8 @@ func GenerateContentByOpenaiProtocol(ctx context.Context, apiKey, baseURL, model
 				"text_length":          len(assistantText),
 			}).Warn("openai stream returned text only without images")
 			return &entity.GenerateContentResponse{
-				Text: assistantText,
+				Text:  assistantText,
+				Usage: usage,
 			}, nil
 		}
 		if len(nativeFinishReasonText) > 0 {
@@ -254,5 +274,6 @@ func GenerateContentByOpenaiProtocol(ctx context.Context, apiKey, baseURL, model
 	return &entity.GenerateContentResponse{
 		Outputs: buildMediaOutputs(imageDataURLs, "image"),
 		Text:    assistantText,
+		Usage:   usage,
 	}, nil
 }
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
@@ -19,11 +20,18 @@ import (
 
 //文档:https://www.volcengine.com/docs/82379/1824121
 
-func GenerateContentByVolcengineProtocol(ctx context.Context, apiKey, model, prompt, size string, base64Images []string) (*entity.GenerateContentResponse, error) {
+// numOutputs 0 keeps the automatic sequential mode (up to 5 images), 1 disables it and N > 1 allows up to N images.
+func GenerateContentByVolcengineProtocol(ctx context.Context, apiKey, model, prompt, size string, numOutputs int, base64Images []string, params entity.AdvancedParams) (*entity.GenerateContentResponse, error) {
 	client := arkruntime.NewClientWithApiKey(apiKey)
 
 	var sequentialImageGeneration volcModel.SequentialImageGeneration = "auto" // allow multi-image sequences when supported
 	maxImages := 5
+	switch {
+	case numOutputs == 1:
+		sequentialImageGeneration = "disabled"
+	case numOutputs > 1:
+		maxImages = numOutputs
+	}
 	sizeValue := strings.TrimSpace(size)
 	if sizeValue == "" {
 		sizeValue = "4K"
@@ -39,6 +47,8 @@ func GenerateContentByVolcengineProtocol(ctx context.Context, apiKey, model, pro
 		SequentialImageGenerationOptions: &volcModel.SequentialImageGenerationOptions{
 			MaxImages: &maxImages,
 		}, //指定本次请求，最多可生成的图片数量。仅当sequential_image_generation为auto时生效。
+		Seed:          params.Seed,          //随机种子，相同种子与参数可复现结果
+		GuidanceScale: params.GuidanceScale, //提示词相关度，值越大越贴合提示词
 	}
 	stream, err := client.GenerateImagesStreaming(ctx, generateReq)
 	if err != nil {
@@ -98,13 +108,13 @@ func GenerateContentByVolcengineProtocol(ctx context.Context, apiKey, model, pro
 	}, nil
 }
 
-func GenerateVolcengineVideo(ctx context.Context, apiKey string, model entity.DbModel, prompt, size string, duration int, images []string) (*entity.GenerateContentResponse, error) {
+func GenerateVolcengineVideo(ctx context.Context, apiKey string, model entity.DbModel, prompt, size string, duration int, images []string, params entity.AdvancedParams) (*entity.GenerateContentResponse, error) {
 	if strings.TrimSpace(apiKey) == "" {
 		return nil, errors.New("api key missing")
 	}
 
 	client := arkruntime.NewClientWithApiKey(apiKey)
-	trimmedPrompt := buildVolcengineVideoPrompt(prompt, size, duration)
+	trimmedPrompt := buildVolcengineVideoPrompt(prompt, size, duration, params.Seed)
 
 	contentItems := make([]*volcModel.CreateContentGenerationContentItem, 0, len(images)+1)
 	if strings.TrimSpace(trimmedPrompt) != "" {
@@ -134,6 +144,10 @@ func GenerateVolcengineVideo(ctx context.Context, apiKey string, model entity.Db
 		Model:   model.ModelID,
 		Content: contentItems,
 	}
+	callbackURL := TaskCallbackURL(ctx, entity.ProviderDriverVolcengine)
+	if callbackURL != "" {
+		req.CallbackUrl = volcengine.String(callbackURL)
+	}
 
 	createResp, err := client.CreateContentGenerationTask(ctx, req)
 	if err != nil {
@@ -145,8 +159,9 @@ func GenerateVolcengineVideo(ctx context.Context, apiKey string, model entity.Db
 	if taskID == "" {
 		return &entity.GenerateContentResponse{RequestID: requestID}, errors.New("volcengine video task id is empty")
 	}
+	ReportTaskSubmitted(ctx, taskID)
 
-	assets, revisedPrompt, err := waitForVolcengineVideo(ctx, client, taskID)
+	assets, revisedPrompt, err := waitForVolcengineVideo(ctx, client, taskID, callbackURL)
 	if err != nil {
 		return &entity.GenerateContentResponse{
 			Text:      revisedPrompt,
@@ -159,7 +174,7 @@ func GenerateVolcengineVideo(ctx context.Context, apiKey string, model entity.Db
 			Text:      revisedPrompt,
 			TaskID:    taskID,
 			RequestID: requestID,
-		}, errors.New("volcengine video response missing video url")
+		}, taskFailed(errors.New("volcengine video response missing video url"))
 	}
 
 	return &entity.GenerateContentResponse{
@@ -267,11 +282,20 @@ func buildVolcengineVideoImages(ctx context.Context, modelID string, images []st
 	}, nil
 }
 
-func buildVolcengineVideoPrompt(prompt, size string, duration int) string {
+// volcengineSeedParam matches a "--seed N" parameter written into the prompt.
+var volcengineSeedParam = regexp.MustCompile(`(?i)\s*--seed\s+-?\d+`)
+
+// buildVolcengineVideoPrompt appends the size, duration and seed parameters to the prompt.
+// Size and duration already written into the prompt are kept, while a seed in the prompt is replaced
+// by the given one so the video is generated with the seed recorded for the request.
+func buildVolcengineVideoPrompt(prompt, size string, duration int, seed *int64) string {
 	trimmed := strings.TrimSpace(prompt)
 	if trimmed == "" {
 		return trimmed
 	}
+	if seed != nil {
+		trimmed = strings.TrimSpace(volcengineSeedParam.ReplaceAllString(trimmed, ""))
+	}
 
 	promptLower := strings.ToLower(trimmed)
 	if sizeValue := strings.TrimSpace(size); sizeValue != "" && !strings.Contains(promptLower, "--rs") {
@@ -280,6 +304,9 @@ func buildVolcengineVideoPrompt(prompt, size string, duration int) string {
 	if duration > 0 && !strings.Contains(promptLower, "--dur") {
 		trimmed += fmt.Sprintf(" --dur %d", duration)
 	}
+	if seed != nil {
+		trimmed += fmt.Sprintf(" --seed %d", *seed)
+	}
 	return trimmed
 }
 
@@ -298,19 +325,38 @@ func inlineVolcengineImage(ctx context.Context, payload string) (string, error)
 	return utils.EnsureDataURL(trimmed), nil
 }
 
-func waitForVolcengineVideo(ctx context.Context, client *arkruntime.Client, taskID string) ([]string, string, error) {
+// waitForVolcengineVideo polls the video task until it finishes; a completion callback
+// for the task triggers the next query immediately.
+func waitForVolcengineVideo(ctx context.Context, client *arkruntime.Client, taskID, callbackURL string) ([]string, string, error) {
 	if strings.TrimSpace(taskID) == "" {
 		return nil, "", errors.New("volcengine missing task id for video")
 	}
 
-	pollInterval := 5 * time.Second
+	pollInterval := taskPollInterval(callbackURL, 5*time.Second)
 
+	var callbacks <-chan TaskCallback
+	if callbackURL != "" {
+		var release func()
+		callbacks, release = SubscribeTaskCallback(entity.ProviderDriverVolcengine, taskID)
+		defer release()
+	}
+
+	var pollErrors pollErrorBudget
 	for {
 		if ctx.Err() != nil {
 			return nil, "", ctx.Err()
 		}
 
 		resp, err := client.GetContentGenerationTask(ctx, volcModel.GetContentGenerationTaskRequest{ID: taskID})
+		if pollErrors.retry(ctx, err) {
+			logrus.WithError(err).WithField("task_id", taskID).Warn("volcengine get video task failed, retrying")
+			select {
+			case <-ctx.Done():
+				return nil, "", ctx.Err()
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
 		if err != nil {
 			return nil, "", fmt.Errorf("volcengine get video task: %w", err)
 		}
@@ -326,28 +372,104 @@ func waitForVolcengineVideo(ctx context.Context, client *arkruntime.Client, task
 		}
 
 		if resp.Error != nil && resp.Error.Message != "" {
-			return nil, revisedPrompt, fmt.Errorf("volcengine task error: %s", resp.Error.Message)
+			return nil, revisedPrompt, taskFailed(fmt.Errorf("volcengine task error: %s", resp.Error.Message))
 		}
 
 		if status == strings.ToLower(volcModel.StatusFailed) ||
 			status == strings.ToLower(volcModel.StatusCancelled) ||
 			status == "expired" {
-			return nil, revisedPrompt, fmt.Errorf("volcengine task %s", status)
+			return nil, revisedPrompt, taskFailed(fmt.Errorf("volcengine task %s", status))
 		}
 
 		logrus.WithFields(logrus.Fields{
 			"task_id": taskID,
 			"status":  status,
 		}).Info("volcengine video task still running")
+		ReportTaskProgress(ctx, TaskProgress{TaskID: taskID, Status: MapTaskStatus(status)})
 
 		select {
 		case <-ctx.Done():
 			return nil, revisedPrompt, ctx.Err()
+		case <-callbacks:
 		case <-time.After(pollInterval):
 		}
 	}
 }
 
+// volcengineTaskPoller polls a content generation task by its task id.
+type volcengineTaskPoller struct {
+	apiKey     string
+	providerID string
+	modelID    string
+	// options are passed to the ark client, e.g. a base url in tests.
+	options []arkruntime.ConfigOption
+}
+
+func (p *volcengineTaskPoller) Poll(ctx context.Context, taskID string) (*AsyncTask, error) {
+	if strings.TrimSpace(p.apiKey) == "" {
+		return nil, errors.New("api key missing")
+	}
+	client := arkruntime.NewClientWithApiKey(p.apiKey, p.options...)
+	resp, err := client.GetContentGenerationTask(ctx, volcModel.GetContentGenerationTaskRequest{ID: strings.TrimSpace(taskID)})
+	if err != nil {
+		return nil, fmt.Errorf("volcengine get video task: %w", err)
+	}
+
+	status := strings.ToLower(strings.TrimSpace(resp.Status))
+	revisedPrompt := ""
+	if resp.RevisedPrompt != nil {
+		revisedPrompt = strings.TrimSpace(*resp.RevisedPrompt)
+	}
+
+	task := &AsyncTask{ID: taskID, ProviderID: p.providerID, ModelID: p.modelID, Status: MapTaskStatus(status)}
+	switch {
+	case status == strings.ToLower(volcModel.StatusSucceeded):
+		assets := collectVolcengineVideoAssets(resp.Content)
+		if len(assets) == 0 {
+			task.Status = TaskStatusFailed
+			task.Error = errors.New("volcengine video response missing video url")
+			break
+		}
+		task.Result = &entity.GenerateContentResponse{
+			Outputs: buildMediaOutputs(assets, "video"),
+			Text:    revisedPrompt,
+			TaskID:  taskID,
+		}
+	case resp.Error != nil && resp.Error.Message != "":
+		task.Status = TaskStatusFailed
+		task.Error = fmt.Errorf("volcengine task error: %s", resp.Error.Message)
+	case status == "expired":
+		task.Status = TaskStatusFailed
+		task.Error = fmt.Errorf("volcengine task %s", status)
+	}
+	return task, nil
+}
+
+// cancelVolcengineTask cancels a queued content generation task (running tasks cannot be stopped upstream).
+func cancelVolcengineTask(ctx context.Context, apiKey, taskID string) error {
+	if strings.TrimSpace(apiKey) == "" {
+		return errors.New("api key missing")
+	}
+	client := arkruntime.NewClientWithApiKey(apiKey)
+	if err := client.DeleteContentGenerationTask(ctx, volcModel.DeleteContentGenerationTaskRequest{ID: strings.TrimSpace(taskID)}); err != nil {
+		return fmt.Errorf("volcengine cancel video task: %w", err)
+	}
+	return nil
+}
+
+// checkVolcengineCredentials lists a single content generation task to verify the API key.
+func checkVolcengineCredentials(ctx context.Context, apiKey string) error {
+	if strings.TrimSpace(apiKey) == "" {
+		return errors.New("api key missing")
+	}
+	pageSize := 1
+	client := arkruntime.NewClientWithApiKey(apiKey)
+	if _, err := client.ListContentGenerationTasks(ctx, volcModel.ListContentGenerationTasksRequest{PageSize: &pageSize}); err != nil {
+		return fmt.Errorf("volcengine health check: %w", err)
+	}
+	return nil
+}
+
 func collectVolcengineVideoAssets(content volcModel.Content) []string {
 	assets := make([]string, 0, 2)
 	if url := strings.TrimSpace(content.VideoURL); url != "" {
@@ -8,11 +8,13 @@ import (
 )
 
 func TestBuildVolcengineVideoPrompt(t *testing.T) {
+	seed := int64(42)
 	tests := []struct {
 		name     string
 		prompt   string
 		size     string
 		duration int
+		seed     *int64
 		want     string
 	}{
 		{
@@ -50,10 +52,28 @@ func TestBuildVolcengineVideoPrompt(t *testing.T) {
 			duration: 6,
 			want:     "scene --RS 480P --DUR 5",
 		},
+		{
+			name:     "append seed",
+			prompt:   "hello world",
+			duration: 5,
+			seed:     &seed,
+			want:     "hello world --dur 5 --seed 42",
+		},
+		{
+			name:   "replace existing seed",
+			prompt: "scene --SEED 7 --dur 4",
+			seed:   &seed,
+			want:   "scene --dur 4 --seed 42",
+		},
+		{
+			name:   "keep existing seed without recorded seed",
+			prompt: "scene --seed 7",
+			want:   "scene --seed 7",
+		},
 	}
 
 	for _, tt := range tests {
-		got := buildVolcengineVideoPrompt(tt.prompt, tt.size, tt.duration)
+		got := buildVolcengineVideoPrompt(tt.prompt, tt.size, tt.duration, tt.seed)
 		if got != tt.want {
 			t.Fatalf("%s: expected %q, got %q", tt.name, tt.want, got)
 		}
@@ -89,3 +89,8 @@ func (p *AiHubMix) Validate(request entity.GenerateContentRequest, model entity.
 	}
 	return nil
 }
+
+// CheckHealth lists the models available to the API key.
+func (p *AiHubMix) CheckHealth(ctx context.Context) error {
+	return checkHTTPEndpoint(ctx, "aihubmix", openaiCompatibleBase(p.endpoint)+"/models", bearerHeader(p.apiKey))
+}
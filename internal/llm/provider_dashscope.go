@@ -40,9 +40,22 @@ func NewDashscope(provider *entity.DbProvider) (*Dashscope, error) {
 
 func (p *Dashscope) GenerateContent(ctx context.Context, request entity.GenerateContentRequest, dbModel entity.DbModel) (*entity.GenerateContentResponse, error) {
 	if dbModel.IsVideoModel() {
-		return GenerateDashscopeVideo(ctx, p.apiKey, p.endpoint, dbModel, request.Prompt, request.GetSize(), request.GetDuration(), request.GetImages())
+		return GenerateDashscopeVideo(ctx, p.apiKey, p.endpoint, dbModel, request.Prompt, request.GetSize(), request.GetDuration(), request.GetImages(), request.Advanced)
 	}
-	return GenerateImageByDashscopeProtocol(ctx, p.apiKey, p.endpoint, dbModel, request.Prompt, request.GetSize(), request.GetDuration(), request.GetImages(), request.GetVideos())
+	return GenerateImageByDashscopeProtocol(ctx, p.apiKey, p.endpoint, dbModel, request.Prompt, request.GetSize(), request.GetNumOutputs(), request.GetImages(), request.GetVideos(), request.Advanced)
+}
+
+// CancelTask cancels a pending dashscope async task.
+func (p *Dashscope) CancelTask(ctx context.Context, dbModel entity.DbModel, taskID string) error {
+	if strings.TrimSpace(taskID) == "" {
+		return errors.New("dashscope task id is required")
+	}
+	return cancelDashscopeTask(ctx, p.apiKey, taskID)
+}
+
+// TaskPoller returns a poller for async video tasks.
+func (p *Dashscope) TaskPoller(dbModel entity.DbModel) TaskPoller {
+	return &dashscopeTaskPoller{apiKey: p.apiKey, providerID: p.providerID, modelID: dbModel.ModelID}
 }
 
 // Capabilities returns the capabilities of the model.
@@ -56,6 +69,9 @@ func (p *Dashscope) Capabilities(model entity.DbModel) *ModelCapabilities {
 		SupportsStream:     model.SupportsStreaming,
 		SupportsCancel:     model.SupportsCancel,
 		SupportsAsync:      model.IsVideoModel(),
+		// parameters.n, image generation only
+		SupportsMultipleOutputs: !model.IsVideoModel(),
+		AdvancedParams:          []string{AdvancedParamSeed, AdvancedParamNegativePrompt},
 	}
 }
 
@@ -67,6 +83,12 @@ func (p *Dashscope) Validate(request entity.GenerateContentRequest, model entity
 	return nil
 }
 
+// CheckHealth lists the models of the OpenAI-compatible API on the configured host.
+func (p *Dashscope) CheckHealth(ctx context.Context) error {
+	target := urlOrigin(p.endpoint, dashscopeDefaultHost) + dashscopeModelsPath
+	return checkHTTPEndpoint(ctx, "dashscope", target, bearerHeader(p.apiKey))
+}
+
 func normalizeModelID(id string) string {
 	return strings.ToLower(strings.TrimSpace(id))
 }
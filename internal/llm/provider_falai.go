@@ -10,7 +10,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -18,6 +20,7 @@ import (
 
 const (
 	falDefaultAPIBaseURL         = "https://fal.run"
+	falQueueAPIBaseURL           = "https://queue.fal.run"
 	falModeTextToImage   falMode = "text_to_image"
 	falModeImageToImage  falMode = "image_to_image"
 
@@ -26,6 +29,12 @@ const (
 	falMaxPollAttempts  = 60
 )
 
+// Credential checks use the platform API, whose pricing endpoint requires a valid key.
+const (
+	falPlatformAPIBaseURL    = "https://api.fal.ai/v1"
+	falHealthCheckEndpointID = "fal-ai/flux/dev"
+)
+
 type falMode string
 
 type falModelConfig struct {
@@ -41,6 +50,9 @@ type FalAI struct {
 	apiBase string
 
 	httpClient *http.Client
+
+	// cancelURLs remembers the queue cancel url of in-flight requests, keyed by request id.
+	cancelURLs sync.Map
 }
 
 func NewFalAI(provider *entity.DbProvider) (*FalAI, error) {
@@ -87,16 +99,7 @@ func (f *FalAI) GenerateContent(ctx context.Context, request entity.GenerateCont
 		endpoint = "/" + endpoint
 	}
 
-	// Use new GenerationMode field first, fallback to inferring from ModelID
-	mode := falMode(strings.TrimSpace(dbModel.GenerationMode))
-	if mode == "" {
-		if strings.Contains(strings.ToLower(dbModel.ModelID), "image-to-image") ||
-			strings.Contains(strings.ToLower(dbModel.ModelID), "edit") {
-			mode = falModeImageToImage
-		} else {
-			mode = falModeTextToImage
-		}
-	}
+	mode := resolveFalMode(dbModel)
 
 	logrus.WithFields(logrus.Fields{
 		"model":               request.ModelID,
@@ -138,6 +141,19 @@ func (f *FalAI) GenerateContent(ctx context.Context, request entity.GenerateCont
 	}, nil
 }
 
+// resolveFalMode uses the GenerationMode field first, falling back to inferring it from ModelID.
+func resolveFalMode(dbModel entity.DbModel) falMode {
+	mode := falMode(strings.TrimSpace(dbModel.GenerationMode))
+	if mode != "" {
+		return mode
+	}
+	if strings.Contains(strings.ToLower(dbModel.ModelID), "image-to-image") ||
+		strings.Contains(strings.ToLower(dbModel.ModelID), "edit") {
+		return falModeImageToImage
+	}
+	return falModeTextToImage
+}
+
 func (f *FalAI) buildInputPayload(mode falMode, request entity.GenerateContentRequest) (map[string]any, error) {
 	prompt := strings.TrimSpace(request.Prompt)
 	if prompt == "" {
@@ -154,7 +170,7 @@ func (f *FalAI) buildInputPayload(mode falMode, request entity.GenerateContentRe
 	switch mode {
 	case falModeTextToImage:
 		input["image_size"] = size
-		input["num_images"] = 1
+		input["num_images"] = request.GetNumOutputs()
 	case falModeImageToImage:
 		imageURL, base64Payload := f.pickReferenceImage(request.GetImages())
 		if imageURL == "" && base64Payload == "" {
@@ -166,11 +182,28 @@ func (f *FalAI) buildInputPayload(mode falMode, request entity.GenerateContentRe
 			input["image_url"] = imageURL
 		}
 		input["image_size"] = size
-		input["num_images"] = 1
+		input["num_images"] = request.GetNumOutputs()
 	default:
 		return nil, fmt.Errorf("unsupported fal.ai mode %q", mode)
 	}
 
+	params := request.Advanced
+	if params.Seed != nil {
+		input["seed"] = *params.Seed
+	}
+	if negative := strings.TrimSpace(params.NegativePrompt); negative != "" {
+		input["negative_prompt"] = negative
+	}
+	if params.GuidanceScale != nil {
+		input["guidance_scale"] = *params.GuidanceScale
+	}
+	if params.Steps != nil {
+		input["num_inference_steps"] = *params.Steps
+	}
+	if params.Strength != nil && mode == falModeImageToImage {
+		input["strength"] = *params.Strength
+	}
+
 	return input, nil
 }
 
@@ -201,7 +234,14 @@ func (f *FalAI) submitAndWait(ctx context.Context, endpoint string, payload map[
 		return nil, fmt.Errorf("fal.ai marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.apiBase+endpoint, bytes.NewReader(bs))
+	// fal.ai posts the result to fal_webhook once the queued request finishes
+	submitURL := f.apiBase + endpoint
+	callbackURL := TaskCallbackURL(ctx, entity.ProviderDriverFal)
+	if callbackURL != "" {
+		submitURL = appendQueryParam(submitURL, "fal_webhook", callbackURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, submitURL, bytes.NewReader(bs))
 	if err != nil {
 		return nil, fmt.Errorf("fal.ai create request: %w", err)
 	}
@@ -220,7 +260,7 @@ func (f *FalAI) submitAndWait(ctx context.Context, endpoint string, payload map[
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("fal.ai http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		return nil, newHTTPStatusError("fal.ai", resp, strings.TrimSpace(string(body)))
 	}
 
 	var submission falSubmissionResponse
@@ -245,21 +285,180 @@ func (f *FalAI) submitAndWait(ctx context.Context, endpoint string, payload map[
 		return nil, errors.New("fal.ai response url missing")
 	}
 
-	return f.pollForCompletion(ctx, responseURL, submission.RequestID)
+	requestID := strings.TrimSpace(submission.RequestID)
+	if requestID != "" {
+		if cancelURL := submission.cancelURL(); cancelURL != "" {
+			f.cancelURLs.Store(requestID, cancelURL)
+		}
+		ReportTaskSubmitted(ctx, requestID)
+	}
+
+	envelope, err = f.pollForCompletion(ctx, responseURL, requestID, callbackURL)
+	if requestID != "" && ctx.Err() == nil {
+		// keep the cancel url only when the caller may still cancel the request
+		f.cancelURLs.Delete(requestID)
+	}
+	return envelope, err
+}
+
+// CancelTask cancels a queued fal.ai request.
+func (f *FalAI) CancelTask(ctx context.Context, dbModel entity.DbModel, taskID string) error {
+	if f == nil {
+		return errors.New("fal.ai provider not initialised")
+	}
+	taskID = strings.TrimSpace(taskID)
+	if taskID == "" {
+		return errors.New("fal.ai request id is required")
+	}
+
+	cancelURL := ""
+	if stored, ok := f.cancelURLs.LoadAndDelete(taskID); ok {
+		cancelURL, _ = stored.(string)
+	}
+	if cancelURL == "" {
+		cancelURL = falQueueCancelURL(dbModel, taskID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, cancelURL, nil)
+	if err != nil {
+		return fmt.Errorf("fal.ai create cancel request: %w", err)
+	}
+	req.Header.Set("Authorization", "Key "+f.apiKey)
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fal.ai cancel request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("fal.ai cancel http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// falQueueCancelURL derives the queue cancel url of a request.
+func falQueueCancelURL(dbModel entity.DbModel, requestID string) string {
+	return falQueueRequestURL(dbModel, requestID) + "/cancel"
+}
+
+// falQueueRequestURL derives the queue url of a request (GET returns its result, /status its state);
+// fal.ai addresses queue requests by app id (owner/app).
+func falQueueRequestURL(dbModel entity.DbModel, requestID string) string {
+	path := strings.TrimSpace(dbModel.EndpointPath)
+	if path == "" {
+		path = dbModel.ModelID
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 2 {
+		segments = segments[:2]
+	}
+	return falQueueAPIBaseURL + "/" + strings.Join(segments, "/") + "/requests/" + requestID
+}
+
+// TaskPoller returns a poller for queued requests of dbModel.
+func (f *FalAI) TaskPoller(dbModel entity.DbModel) TaskPoller {
+	return &falTaskPoller{f: f, model: dbModel}
+}
+
+// falTaskPoller polls a queued fal.ai request by its request id.
+type falTaskPoller struct {
+	f     *FalAI
+	model entity.DbModel
+}
+
+func (p *falTaskPoller) Poll(ctx context.Context, taskID string) (*AsyncTask, error) {
+	requestURL := falQueueRequestURL(p.model, strings.TrimSpace(taskID))
+	task := &AsyncTask{ID: taskID, ProviderID: p.f.providerID, ModelID: p.model.ModelID}
+
+	state, done, err := p.f.fetchResponse(ctx, requestURL+"/status")
+	if err != nil {
+		if !done {
+			return nil, err
+		}
+		task.Status = TaskStatusFailed
+		task.Error = err
+		return task, nil
+	}
+	if !done {
+		task.Status = MapTaskStatus(state.Status)
+		return task, nil
+	}
+
+	// the result body carries the model output without a queue status
+	envelope, _, err := p.f.fetchResponse(ctx, requestURL)
+	if err != nil {
+		return nil, err
+	}
+	images, text := p.f.extractImagesAndText(envelope)
+	if len(images) == 0 {
+		task.Status = TaskStatusFailed
+		task.Error = errors.New("fal.ai completed without images")
+		return task, nil
+	}
+
+	task.Status = TaskStatusSucceeded
+	task.Result = &entity.GenerateContentResponse{
+		Outputs:   buildMediaOutputs(images, "image"),
+		Text:      text,
+		TaskID:    taskID,
+		RequestID: taskID,
+	}
+	return task, nil
 }
 
-func (f *FalAI) pollForCompletion(ctx context.Context, responseURL, requestID string) (*falGenerationEnvelope, error) {
+// pollForCompletion waits for a queued request to finish. When a completion callback was
+// requested the result is fetched as soon as it arrives and otherwise only by a sparse safety poll.
+func (f *FalAI) pollForCompletion(ctx context.Context, responseURL, requestID, callbackURL string) (*falGenerationEnvelope, error) {
+	var callbacks <-chan TaskCallback
+	if callbackURL != "" && requestID != "" {
+		var release func()
+		callbacks, release = SubscribeTaskCallback(entity.ProviderDriverFal, requestID)
+		defer release()
+	} else {
+		callbackURL = ""
+	}
+
+	// the attempt limit bounds the total wait, so sparse safety polls get proportionally fewer attempts
+	interval := taskPollInterval(callbackURL, falPollInterval)
+	maxAttempts := max(1, int((falMaxPollAttempts*falPollInterval+interval-1)/interval))
+
 	attempts := 0
-	ticker := time.NewTicker(falPollInterval)
+	var pollErrors pollErrorBudget
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return nil, fmt.Errorf("fal.ai poll cancelled: %w", ctx.Err())
+		case <-callbacks:
+			envelope, done, err := f.fetchResponse(ctx, responseURL)
+			if pollErrors.retry(ctx, err) {
+				// the ticker fetches the result again
+				logrus.WithError(err).WithField("request_id", requestID).Warn("falai_poll_retry")
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				return f.completedEnvelope(envelope)
+			}
 		case <-ticker.C:
 			attempts++
 			envelope, done, err := f.fetchResponse(ctx, responseURL)
+			if pollErrors.retry(ctx, err) {
+				logrus.WithError(err).WithFields(logrus.Fields{
+					"request_id": requestID,
+					"attempt":    attempts,
+				}).Warn("falai_poll_retry")
+				if attempts >= maxAttempts {
+					return nil, err
+				}
+				continue
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -269,22 +468,40 @@ func (f *FalAI) pollForCompletion(ctx context.Context, responseURL, requestID st
 					"status":     envelope.Status,
 					"attempt":    attempts,
 				}).Info("falai_poll_pending")
-				if attempts >= falMaxPollAttempts {
+				ReportTaskProgress(ctx, TaskProgress{
+					TaskID:        requestID,
+					Status:        MapTaskStatus(envelope.Status),
+					QueuePosition: envelope.QueuePosition,
+				})
+				if attempts >= maxAttempts {
 					return nil, errors.New("fal.ai polling exceeded maximum attempts")
 				}
 				continue
 			}
-			if envelope.Error != nil {
-				return nil, fmt.Errorf("fal.ai error: %s", envelope.Error.Message)
-			}
-			if len(f.collectImagePayloads(envelope)) == 0 {
-				return envelope, errors.New("fal.ai completed without images")
-			}
-			return envelope, nil
+			return f.completedEnvelope(envelope)
 		}
 	}
 }
 
+func (f *FalAI) completedEnvelope(envelope *falGenerationEnvelope) (*falGenerationEnvelope, error) {
+	if envelope.Error != nil {
+		return nil, taskFailed(fmt.Errorf("fal.ai error: %s", envelope.Error.Message))
+	}
+	if len(f.collectImagePayloads(envelope)) == 0 {
+		return envelope, taskFailed(errors.New("fal.ai completed without images"))
+	}
+	return envelope, nil
+}
+
+// appendQueryParam adds key=value to the query string of rawURL.
+func appendQueryParam(rawURL, key, value string) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
+
 func (f *FalAI) fetchResponse(ctx context.Context, url string) (*falGenerationEnvelope, bool, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -304,7 +521,7 @@ func (f *FalAI) fetchResponse(ctx context.Context, url string) (*falGenerationEn
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, false, fmt.Errorf("fal.ai poll http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		return nil, false, newHTTPStatusError("fal.ai poll", resp, strings.TrimSpace(string(body)))
 	}
 
 	var envelope falGenerationEnvelope
@@ -320,9 +537,9 @@ func (f *FalAI) fetchResponse(ctx context.Context, url string) (*falGenerationEn
 		return &envelope, true, nil
 	case "FAILED", "CANCELLED", "ERROR":
 		if envelope.Error != nil {
-			return &envelope, true, fmt.Errorf("fal.ai error: %s", envelope.Error.Message)
+			return &envelope, true, taskFailed(fmt.Errorf("fal.ai error: %s", envelope.Error.Message))
 		}
-		return &envelope, true, fmt.Errorf("fal.ai job %s", strings.ToLower(status))
+		return &envelope, true, taskFailed(fmt.Errorf("fal.ai job %s", strings.ToLower(status)))
 	default:
 		return &envelope, false, nil
 	}
@@ -492,19 +709,20 @@ func (p falImagePayload) firstBase64() string {
 }
 
 type falGenerationEnvelope struct {
-	RequestID  string            `json:"request_id"`
-	Status     string            `json:"status"`
-	Images     []falImagePayload `json:"images"`
-	Output     []falImagePayload `json:"output"`
-	Outputs    []falImagePayload `json:"outputs"`
-	Data       []falImagePayload `json:"data"`
-	Result     []falImagePayload `json:"result"`
-	Variants   []falImagePayload `json:"variants"`
-	Text       string            `json:"text"`
-	Message    string            `json:"message"`
-	OutputText string            `json:"output_text"`
-	Error      *falAPIError      `json:"error"`
-	Response   *falInnerResponse `json:"response"`
+	RequestID     string            `json:"request_id"`
+	Status        string            `json:"status"`
+	QueuePosition *int              `json:"queue_position"`
+	Images        []falImagePayload `json:"images"`
+	Output        []falImagePayload `json:"output"`
+	Outputs       []falImagePayload `json:"outputs"`
+	Data          []falImagePayload `json:"data"`
+	Result        []falImagePayload `json:"result"`
+	Variants      []falImagePayload `json:"variants"`
+	Text          string            `json:"text"`
+	Message       string            `json:"message"`
+	OutputText    string            `json:"output_text"`
+	Error         *falAPIError      `json:"error"`
+	Response      *falInnerResponse `json:"response"`
 }
 
 func (e *falGenerationEnvelope) mergeInner() {
@@ -548,6 +766,7 @@ type falSubmissionResponse struct {
 	Status      string            `json:"status"`
 	StatusURL   string            `json:"status_url"`
 	ResponseURL string            `json:"response_url"`
+	CancelURL   string            `json:"cancel_url"`
 	Images      []falImagePayload `json:"images"`
 	Output      []falImagePayload `json:"output"`
 	Outputs     []falImagePayload `json:"outputs"`
@@ -581,6 +800,16 @@ func (s falSubmissionResponse) toEnvelope() *falGenerationEnvelope {
 	return envelope
 }
 
+func (s falSubmissionResponse) cancelURL() string {
+	if cancelURL := strings.TrimSpace(s.CancelURL); cancelURL != "" {
+		return cancelURL
+	}
+	if responseURL := strings.TrimSpace(s.ResponseURL); responseURL != "" {
+		return strings.TrimRight(responseURL, "/") + "/cancel"
+	}
+	return ""
+}
+
 type falAPIError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
@@ -597,9 +826,21 @@ func (f *FalAI) Capabilities(model entity.DbModel) *ModelCapabilities {
 		SupportsStream:     model.SupportsStreaming,
 		SupportsCancel:     model.SupportsCancel,
 		SupportsAsync:      true, // FalAI always uses async polling
+		// num_images
+		SupportsMultipleOutputs: true,
+		AdvancedParams:          falAdvancedParams(resolveFalMode(model)),
 	}
 }
 
+// falAdvancedParams lists the advanced parameters forwarded for a fal.ai mode; strength only applies to image-to-image.
+func falAdvancedParams(mode falMode) []string {
+	params := []string{AdvancedParamSeed, AdvancedParamNegativePrompt, AdvancedParamGuidanceScale, AdvancedParamSteps}
+	if mode == falModeImageToImage {
+		params = append(params, AdvancedParamStrength)
+	}
+	return params
+}
+
 // Validate checks if the request is valid for the model.
 func (f *FalAI) Validate(request entity.GenerateContentRequest, model entity.DbModel) error {
 	if strings.TrimSpace(request.Prompt) == "" {
@@ -625,3 +866,17 @@ func (f *FalAI) Validate(request entity.GenerateContentRequest, model entity.DbM
 
 	return nil
 }
+
+// falAuthHeader returns the Authorization header used by fal APIs.
+func falAuthHeader(apiKey string) http.Header {
+	header := http.Header{}
+	header.Set("Authorization", "Key "+apiKey)
+	return header
+}
+
+// CheckHealth queries model pricing on the platform API, which requires a valid key.
+func (f *FalAI) CheckHealth(ctx context.Context) error {
+	header := falAuthHeader(f.apiKey)
+	target := falPlatformAPIBaseURL + "/models/pricing?endpoint_id=" + url.QueryEscape(falHealthCheckEndpointID)
+	return checkHTTPEndpoint(ctx, "fal", target, header)
+}
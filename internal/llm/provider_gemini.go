@@ -66,3 +66,10 @@ func (p *GeminiService) Validate(request entity.GenerateContentRequest, model en
 	}
 	return nil
 }
+
+// CheckHealth lists a single model, which requires a valid API key.
+func (p *GeminiService) CheckHealth(ctx context.Context) error {
+	header := http.Header{}
+	header.Set("x-goog-api-key", p.apiKey)
+	return checkHTTPEndpoint(ctx, "gemini", resolveGeminiModelsEndpoint(p.endpoint), header)
+}
@@ -68,3 +68,8 @@ func (o *OpenRouter) Validate(request entity.GenerateContentRequest, model entit
 	}
 	return nil
 }
+
+// CheckHealth reads the API key's account info, which requires valid credentials.
+func (o *OpenRouter) CheckHealth(ctx context.Context) error {
+	return checkHTTPEndpoint(ctx, "openrouter", openaiCompatibleBase(o.endpoint)+"/key", bearerHeader(o.apiKey))
+}
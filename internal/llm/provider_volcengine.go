@@ -62,10 +62,23 @@ func (p *Volcengine) GenerateContent(ctx context.Context, request entity.Generat
 	}
 
 	if dbModel.IsVideoModel() {
-		return GenerateVolcengineVideo(ctx, p.apiKey, dbModel, request.Prompt, request.GetSize(), request.GetDuration(), request.GetImages())
+		return GenerateVolcengineVideo(ctx, p.apiKey, dbModel, request.Prompt, request.GetSize(), request.GetDuration(), request.GetImages(), request.Advanced)
 	}
 
-	return GenerateContentByVolcengineProtocol(ctx, p.apiKey, dbModel.ModelID, request.Prompt, requestedSize, request.GetImages())
+	return GenerateContentByVolcengineProtocol(ctx, p.apiKey, dbModel.ModelID, request.Prompt, requestedSize, request.GetNumOutputs(), request.GetImages(), request.Advanced)
+}
+
+// CancelTask cancels a queued volcengine content generation task.
+func (p *Volcengine) CancelTask(ctx context.Context, dbModel entity.DbModel, taskID string) error {
+	if strings.TrimSpace(taskID) == "" {
+		return errors.New("volcengine task id is required")
+	}
+	return cancelVolcengineTask(ctx, p.apiKey, taskID)
+}
+
+// TaskPoller returns a poller for content generation (video) tasks.
+func (p *Volcengine) TaskPoller(dbModel entity.DbModel) TaskPoller {
+	return &volcengineTaskPoller{apiKey: p.apiKey, providerID: p.providerID, modelID: dbModel.ModelID}
 }
 
 // Capabilities returns the capabilities of the model.
@@ -79,9 +92,20 @@ func (p *Volcengine) Capabilities(model entity.DbModel) *ModelCapabilities {
 		SupportsStream:     true, // Volcengine uses streaming
 		SupportsCancel:     model.SupportsCancel,
 		SupportsAsync:      model.IsVideoModel(),
+		// sequential image generation, images only
+		SupportsMultipleOutputs: !model.IsVideoModel(),
+		AdvancedParams:          volcengineAdvancedParams(model),
 	}
 }
 
+// volcengineAdvancedParams lists the advanced parameters forwarded for a model; video tasks only take a seed text command.
+func volcengineAdvancedParams(model entity.DbModel) []string {
+	if model.IsVideoModel() {
+		return []string{AdvancedParamSeed}
+	}
+	return []string{AdvancedParamSeed, AdvancedParamGuidanceScale}
+}
+
 // Validate checks if the request is valid for the model.
 func (p *Volcengine) Validate(request entity.GenerateContentRequest, model entity.DbModel) error {
 	if strings.TrimSpace(request.Prompt) == "" {
@@ -105,3 +129,8 @@ func (p *Volcengine) Validate(request entity.GenerateContentRequest, model entit
 
 	return nil
 }
+
+// CheckHealth lists content generation tasks, which requires a valid API key.
+func (p *Volcengine) CheckHealth(ctx context.Context) error {
+	return checkVolcengineCredentials(ctx, p.apiKey)
+}
@@ -0,0 +1,265 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucket is a token-bucket rate limiter refilled at a fixed number of tokens per minute.
+// It is safe for concurrent use.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a full bucket holding burst tokens (at least one) refilled at perMinute tokens per minute.
+func NewTokenBucket(perMinute float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   perMinute / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// Take removes a token if one is available at now. Otherwise it reports how long until the next token.
+func (b *TokenBucket) Take(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	if b.last.IsZero() || now.After(b.last) {
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if b.rate <= 0 {
+		return false, time.Minute
+	}
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if wait <= 0 {
+		wait = time.Millisecond
+	}
+	return false, wait
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	for {
+		ok, wait := b.Take(time.Now())
+		if ok {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// RateLimit limits requests; zero fields are unlimited.
+type RateLimit struct {
+	RequestsPerMinute float64
+	// Burst is the number of requests allowed back to back, defaulting to one.
+	Burst int
+	// MaxConcurrency caps the requests in flight; only enforced on outbound provider calls.
+	MaxConcurrency int
+}
+
+// IsZero reports whether the limit does not restrict anything.
+func (l RateLimit) IsZero() bool {
+	return l.RequestsPerMinute <= 0 && l.MaxConcurrency <= 0
+}
+
+// ParseRateLimit overrides the fields of base with those present in raw, e.g.
+//
+//	{"requests_per_minute": 60, "burst": 5, "max_concurrency": 4}
+//
+// Missing, invalid or negative fields keep the value of base.
+func ParseRateLimit(raw interface{}, base RateLimit) RateLimit {
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		return base
+	}
+
+	limit := base
+	if value, ok := configNumber(values["requests_per_minute"]); ok && value >= 0 {
+		limit.RequestsPerMinute = value
+	}
+	if value, ok := configNumber(values["burst"]); ok && value >= 0 {
+		limit.Burst = int(value)
+	}
+	if value, ok := configNumber(values["max_concurrency"]); ok && value >= 0 {
+		limit.MaxConcurrency = int(value)
+	}
+	return limit
+}
+
+// ProviderRateLimits are the outbound limits of a provider and of its individual models.
+type ProviderRateLimits struct {
+	Provider RateLimit
+	// Models is keyed by model ID.
+	Models map[string]RateLimit
+}
+
+// IsZero reports whether no limit is configured.
+func (l ProviderRateLimits) IsZero() bool {
+	if !l.Provider.IsZero() {
+		return false
+	}
+	for _, limit := range l.Models {
+		if !limit.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// RateLimitsFromConfig reads the "rate_limit" object of a provider Config, e.g.
+//
+//	{"rate_limit": {"requests_per_minute": 60, "max_concurrency": 4, "models": {"flux-pro": {"requests_per_minute": 10}}}}
+//
+// The provider limit applies to all calls together, a model limit to the calls for that model.
+func RateLimitsFromConfig(config map[string]interface{}) ProviderRateLimits {
+	raw, ok := config["rate_limit"].(map[string]interface{})
+	if !ok {
+		return ProviderRateLimits{}
+	}
+
+	limits := ProviderRateLimits{Provider: ParseRateLimit(raw, RateLimit{})}
+	if models, ok := raw["models"].(map[string]interface{}); ok {
+		limits.Models = make(map[string]RateLimit, len(models))
+		for modelID, value := range models {
+			if limit := ParseRateLimit(value, RateLimit{}); !limit.IsZero() {
+				limits.Models[modelID] = limit
+			}
+		}
+	}
+	return limits
+}
+
+// callLimiter enforces one RateLimit on outbound calls.
+type callLimiter struct {
+	bucket *TokenBucket
+	slots  chan struct{}
+}
+
+func newCallLimiter(limit RateLimit) *callLimiter {
+	limiter := &callLimiter{}
+	if limit.RequestsPerMinute > 0 {
+		limiter.bucket = NewTokenBucket(limit.RequestsPerMinute, limit.Burst)
+	}
+	if limit.MaxConcurrency > 0 {
+		limiter.slots = make(chan struct{}, limit.MaxConcurrency)
+	}
+	return limiter
+}
+
+// acquire waits for a concurrency slot and then for a token; release frees the slot.
+func (l *callLimiter) acquire(ctx context.Context) (func(), error) {
+	release := func() {}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+			release = func() { <-l.slots }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if l.bucket != nil {
+		if err := l.bucket.Wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// rateLimitedService delays GenerateContent calls until the provider and model limits allow them.
+type rateLimitedService struct {
+	AIService
+
+	providerID string
+	limits     ProviderRateLimits
+	provider   *callLimiter
+
+	mu     sync.Mutex
+	models map[string]*callLimiter
+}
+
+// withRateLimits wraps service with the given limits, returning it unchanged when none are configured.
+func withRateLimits(providerID string, service AIService, limits ProviderRateLimits) AIService {
+	if limits.IsZero() {
+		return service
+	}
+	return &rateLimitedService{
+		AIService:  service,
+		providerID: providerID,
+		limits:     limits,
+		provider:   newCallLimiter(limits.Provider),
+		models:     make(map[string]*callLimiter),
+	}
+}
+
+// GenerateContent waits for the model limit and then the provider limit before calling the provider.
+func (s *rateLimitedService) GenerateContent(ctx context.Context, request entity.GenerateContentRequest, dbModel entity.DbModel) (*entity.GenerateContentResponse, error) {
+	releaseModel, err := s.modelLimiter(dbModel.ModelID).acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("waiting for %s/%s rate limit: %w", s.providerID, dbModel.ModelID, err)
+	}
+	defer releaseModel()
+
+	releaseProvider, err := s.provider.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("waiting for %s rate limit: %w", s.providerID, err)
+	}
+	defer releaseProvider()
+
+	return s.AIService.GenerateContent(ctx, request, dbModel)
+}
+
+// Unwrap returns the underlying service.
+func (s *rateLimitedService) Unwrap() AIService {
+	return s.AIService
+}
+
+func (s *rateLimitedService) modelLimiter(modelID string) *callLimiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limiter, ok := s.models[modelID]
+	if !ok {
+		limiter = newCallLimiter(s.limits.Models[modelID])
+		s.models[modelID] = limiter
+	}
+	return limiter
+}
+
+// Unwrap strips the wrappers added by ProviderFactory, so optional interfaces such as TaskCanceller
+// and TaskResumer can be detected on the provider implementation.
+func Unwrap(service AIService) AIService {
+	for {
+		wrapper, ok := service.(interface{ Unwrap() AIService })
+		if !ok {
+			return service
+		}
+		service = wrapper.Unwrap()
+	}
+}
@@ -0,0 +1,171 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketTake(t *testing.T) {
+	now := time.Now()
+	bucket := NewTokenBucket(60, 2)
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := bucket.Take(now); !ok {
+			t.Fatalf("expected token %d within burst", i+1)
+		}
+	}
+	ok, wait := bucket.Take(now)
+	if ok {
+		t.Fatal("expected bucket to be empty")
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("expected wait up to 1s, got %v", wait)
+	}
+
+	if ok, _ := bucket.Take(now.Add(time.Second)); !ok {
+		t.Error("expected a token after one second at 60 rpm")
+	}
+	if ok, _ := bucket.Take(now.Add(time.Second)); ok {
+		t.Error("expected only one token to be refilled")
+	}
+	// 长时间空闲后最多累积 burst 个令牌
+	later := now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if ok, _ := bucket.Take(later); !ok {
+			t.Fatalf("expected token %d after refill", i+1)
+		}
+	}
+	if ok, _ := bucket.Take(later); ok {
+		t.Error("expected refill to be capped at burst")
+	}
+}
+
+func TestRateLimitsFromConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		want   ProviderRateLimits
+	}{
+		{name: "未配置", config: nil, want: ProviderRateLimits{}},
+		{
+			name: "服务商与模型限制",
+			config: map[string]interface{}{"rate_limit": map[string]interface{}{
+				"requests_per_minute": float64(60),
+				"burst":               float64(5),
+				"max_concurrency":     "4",
+				"models": map[string]interface{}{
+					"flux-pro": map[string]interface{}{"max_concurrency": float64(1)},
+					"ignored":  map[string]interface{}{"requests_per_minute": float64(-1)},
+				},
+			}},
+			want: ProviderRateLimits{
+				Provider: RateLimit{RequestsPerMinute: 60, Burst: 5, MaxConcurrency: 4},
+				Models:   map[string]RateLimit{"flux-pro": {MaxConcurrency: 1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RateLimitsFromConfig(tt.config)
+			if got.Provider != tt.want.Provider || len(got.Models) != len(tt.want.Models) {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+			for modelID, limit := range tt.want.Models {
+				if got.Models[modelID] != limit {
+					t.Errorf("model %s: expected %+v, got %+v", modelID, limit, got.Models[modelID])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRateLimitOverridesBase(t *testing.T) {
+	base := RateLimit{RequestsPerMinute: 30, Burst: 10}
+	got := ParseRateLimit(map[string]interface{}{"requests_per_minute": float64(0)}, base)
+	if got.RequestsPerMinute != 0 || got.Burst != 10 {
+		t.Errorf("expected only requests_per_minute to be overridden, got %+v", got)
+	}
+	if got := ParseRateLimit("invalid", base); got != base {
+		t.Errorf("expected base for invalid config, got %+v", got)
+	}
+}
+
+type blockingAIService struct {
+	BaseProvider
+	release  chan struct{}
+	inFlight int32
+	peak     int32
+}
+
+func (s *blockingAIService) GenerateContent(ctx context.Context, request entity.GenerateContentRequest, dbModel entity.DbModel) (*entity.GenerateContentResponse, error) {
+	current := atomic.AddInt32(&s.inFlight, 1)
+	defer atomic.AddInt32(&s.inFlight, -1)
+	for {
+		peak := atomic.LoadInt32(&s.peak)
+		if current <= peak || atomic.CompareAndSwapInt32(&s.peak, peak, current) {
+			break
+		}
+	}
+	<-s.release
+	return &entity.GenerateContentResponse{Text: "ok"}, nil
+}
+
+func TestRateLimitedServiceConcurrency(t *testing.T) {
+	inner := &blockingAIService{release: make(chan struct{})}
+	service := withRateLimits("test", inner, ProviderRateLimits{
+		Provider: RateLimit{MaxConcurrency: 3},
+		Models:   map[string]RateLimit{"slow": {MaxConcurrency: 1}},
+	})
+	if Unwrap(service) != inner {
+		t.Fatal("expected Unwrap to return the provider implementation")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := service.GenerateContent(context.Background(), entity.GenerateContentRequest{}, entity.DbModel{ModelID: "slow"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if got := atomic.LoadInt32(&inner.inFlight); got != 1 {
+		t.Errorf("expected model limit to allow 1 call in flight, got %d", got)
+	}
+	close(inner.release)
+	wg.Wait()
+	if inner.peak != 1 {
+		t.Errorf("expected peak concurrency 1, got %d", inner.peak)
+	}
+}
+
+func TestRateLimitedServiceHonoursContext(t *testing.T) {
+	inner := &stubAIService{errs: []error{nil, nil}}
+	service := withRateLimits("test", inner, ProviderRateLimits{Provider: RateLimit{RequestsPerMinute: 1}})
+
+	if _, err := service.GenerateContent(context.Background(), entity.GenerateContentRequest{}, entity.DbModel{}); err != nil {
+		t.Fatalf("expected first call within burst, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := service.GenerateContent(ctx, entity.GenerateContentRequest{}, entity.DbModel{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded while throttled, got %v", err)
+	}
+	if inner.calls != 1 {
+		t.Errorf("expected throttled call not to reach the provider, got %d calls", inner.calls)
+	}
+	if withRateLimits("test", inner, ProviderRateLimits{}) != AIService(inner) {
+		t.Error("expected service without limits to be returned unwrapped")
+	}
+}
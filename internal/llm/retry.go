@@ -0,0 +1,287 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	volcModel "github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+)
+
+// HTTPStatusError is returned by protocols when a provider answers with a non-success HTTP status.
+type HTTPStatusError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+	// RetryAfter is parsed from the Retry-After header, zero when absent.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%s http %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+func newHTTPStatusError(provider string, resp *http.Response, body string) *HTTPStatusError {
+	return &HTTPStatusError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Body:       body,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter accepts both delay-seconds and HTTP-date forms.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := at.Sub(now); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// ErrorClass describes whether a provider error is worth retrying.
+type ErrorClass struct {
+	Retryable  bool
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+// contentPolicyMarkers identify provider rejections that will never succeed on retry.
+var contentPolicyMarkers = []string{
+	"content_policy",
+	"content policy",
+	"moderation",
+	"safety",
+	"sensitive",
+	"datainspectionfailed",
+}
+
+// ClassifyError classifies a provider error as retryable (timeouts, 5xx, 429) or terminal
+// (content policy, invalid key, bad request and anything unrecognised).
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClass{}
+	}
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return classifyStatus(statusErr.StatusCode, statusErr.RetryAfter, statusErr.Body)
+	}
+
+	var apiErr *volcModel.APIError
+	if errors.As(err, &apiErr) {
+		return classifyStatus(apiErr.HTTPStatusCode, 0, apiErr.Code+" "+apiErr.Message)
+	}
+
+	var reqErr *volcModel.RequestError
+	if errors.As(err, &reqErr) && reqErr.HTTPStatusCode > 0 {
+		return classifyStatus(reqErr.HTTPStatusCode, 0, reqErr.Error())
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return ErrorClass{}
+	}
+
+	// A per-request timeout is transient; the caller stops retrying once its own context expires.
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClass{Retryable: true}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorClass{Retryable: true}
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrorClass{Retryable: true}
+	}
+
+	return ErrorClass{}
+}
+
+func classifyStatus(code int, retryAfter time.Duration, detail string) ErrorClass {
+	class := ErrorClass{StatusCode: code, RetryAfter: retryAfter}
+
+	lower := strings.ToLower(detail)
+	for _, marker := range contentPolicyMarkers {
+		if strings.Contains(lower, marker) {
+			return class
+		}
+	}
+
+	switch {
+	case code == http.StatusTooManyRequests, code == http.StatusRequestTimeout:
+		class.Retryable = true
+	case code >= 500 && code != http.StatusNotImplemented:
+		class.Retryable = true
+	}
+	return class
+}
+
+// RetryPolicy controls how provider calls are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+}
+
+// DefaultRetryPolicy is used when a provider does not configure retries.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 2 * time.Second,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+}
+
+// RetryPolicyFromConfig reads the "retry" object of a provider Config, e.g.
+//
+//	{"retry": {"max_attempts": 5, "initial_backoff_seconds": 1, "max_backoff_seconds": 20, "multiplier": 2}}
+//
+// Missing or invalid fields fall back to DefaultRetryPolicy; max_attempts 1 disables retries.
+func RetryPolicyFromConfig(config map[string]interface{}) RetryPolicy {
+	policy := DefaultRetryPolicy
+
+	raw, ok := config["retry"].(map[string]interface{})
+	if !ok {
+		return policy
+	}
+
+	if value, ok := configNumber(raw["max_attempts"]); ok && value >= 1 {
+		policy.MaxAttempts = int(value)
+	}
+	if value, ok := configNumber(raw["initial_backoff_seconds"]); ok && value >= 0 {
+		policy.InitialBackoff = time.Duration(value * float64(time.Second))
+	}
+	if value, ok := configNumber(raw["max_backoff_seconds"]); ok && value > 0 {
+		policy.MaxBackoff = time.Duration(value * float64(time.Second))
+	}
+	if value, ok := configNumber(raw["multiplier"]); ok && value >= 1 {
+		policy.Multiplier = value
+	}
+	return policy
+}
+
+func configNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return parsed, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// Backoff returns the delay before the attempt following the given (1-based) attempt.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if attempt < 1 || p.InitialBackoff <= 0 {
+		return 0
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := time.Duration(float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1)))
+	if p.MaxBackoff > 0 && (delay > p.MaxBackoff || delay < 0) {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// AttemptResult describes one call made by GenerateWithRetry.
+type AttemptResult struct {
+	Attempt    int
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Err        error
+	Class      ErrorClass
+	// Submitted is true when the attempt created an async task upstream.
+	Submitted bool
+	// Backoff is the delay before the next attempt, zero when no retry follows.
+	Backoff time.Duration
+}
+
+// GenerateWithRetry calls service.GenerateContent, retrying retryable errors according to policy.
+// onAttempt (optional) is invoked after every attempt. A Retry-After longer than MaxBackoff ends retrying.
+// An attempt that reported an async task through ReportTaskSubmitted is never retried: the task may
+// still complete upstream, and a new attempt would submit (and pay for) a second one. Pollers retry
+// their own transient errors instead.
+func GenerateWithRetry(ctx context.Context, service AIService, request entity.GenerateContentRequest, dbModel entity.DbModel, policy RetryPolicy, onAttempt func(AttemptResult)) (*entity.GenerateContentResponse, error) {
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		var submitted atomic.Bool
+		attemptCtx := withSubmissionTracking(withAttempt(ctx, attempt), &submitted)
+
+		startedAt := time.Now()
+		resp, err := service.GenerateContent(attemptCtx, request, dbModel)
+
+		result := AttemptResult{
+			Attempt:    attempt,
+			StartedAt:  startedAt,
+			FinishedAt: time.Now(),
+			Err:        err,
+			Class:      ClassifyError(err),
+			Submitted:  submitted.Load(),
+		}
+
+		retry := err != nil && result.Class.Retryable && !result.Submitted && attempt < maxAttempts && ctx.Err() == nil
+		if retry {
+			result.Backoff = policy.Backoff(attempt)
+			if retryAfter := result.Class.RetryAfter; retryAfter > result.Backoff {
+				if policy.MaxBackoff > 0 && retryAfter > policy.MaxBackoff {
+					retry = false
+					result.Backoff = 0
+				} else {
+					result.Backoff = retryAfter
+				}
+			}
+		}
+
+		if onAttempt != nil {
+			onAttempt(result)
+		}
+		if !retry {
+			return resp, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return resp, err
+		case <-time.After(result.Backoff):
+		}
+	}
+}
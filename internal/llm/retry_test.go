@@ -0,0 +1,244 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	volcModel "github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+		status    int
+	}{
+		{name: "无错误", err: nil, retryable: false},
+		{name: "限流", err: &HTTPStatusError{Provider: "openai", StatusCode: 429}, retryable: true, status: 429},
+		{name: "网关错误", err: fmt.Errorf("wrap: %w", &HTTPStatusError{Provider: "fal.ai", StatusCode: 502}), retryable: true, status: 502},
+		{name: "请求错误", err: &HTTPStatusError{Provider: "openai", StatusCode: 400, Body: "bad request"}, retryable: false, status: 400},
+		{name: "无效密钥", err: &HTTPStatusError{Provider: "gemini", StatusCode: 401}, retryable: false, status: 401},
+		{name: "内容审核", err: &HTTPStatusError{Provider: "openai", StatusCode: 500, Body: `{"error":"content_policy_violation"}`}, retryable: false, status: 500},
+		{name: "火山引擎限流", err: fmt.Errorf("volcengine: %w", &volcModel.APIError{HTTPStatusCode: 429, Code: "RateLimitExceeded"}), retryable: true, status: 429},
+		{name: "火山引擎敏感内容", err: &volcModel.APIError{HTTPStatusCode: 400, Code: "InputTextSensitiveContentDetected"}, retryable: false, status: 400},
+		{name: "请求超时", err: fmt.Errorf("request: %w", context.DeadlineExceeded), retryable: true},
+		{name: "主动取消", err: context.Canceled, retryable: false},
+		{name: "未知错误", err: errors.New("prompt is required"), retryable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class := ClassifyError(tt.err)
+			if class.Retryable != tt.retryable {
+				t.Errorf("expected retryable %v, got %v", tt.retryable, class.Retryable)
+			}
+			if class.StatusCode != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, class.StatusCode)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{name: "空值", value: "", expected: 0},
+		{name: "秒数", value: "7", expected: 7 * time.Second},
+		{name: "HTTP 日期", value: now.Add(30 * time.Second).Format(http.TimeFormat), expected: 30 * time.Second},
+		{name: "无效值", value: "soon", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyFromConfig(t *testing.T) {
+	t.Run("未配置时使用默认策略", func(t *testing.T) {
+		if got := RetryPolicyFromConfig(nil); got != DefaultRetryPolicy {
+			t.Errorf("expected default policy, got %+v", got)
+		}
+	})
+
+	t.Run("读取服务商配置", func(t *testing.T) {
+		policy := RetryPolicyFromConfig(map[string]interface{}{
+			"retry": map[string]interface{}{
+				"max_attempts":            float64(5),
+				"initial_backoff_seconds": 0.5,
+				"max_backoff_seconds":     "10",
+				"multiplier":              float64(3),
+			},
+		})
+		expected := RetryPolicy{MaxAttempts: 5, InitialBackoff: 500 * time.Millisecond, MaxBackoff: 10 * time.Second, Multiplier: 3}
+		if policy != expected {
+			t.Errorf("expected %+v, got %+v", expected, policy)
+		}
+	})
+
+	t.Run("退避时间不超过上限", func(t *testing.T) {
+		policy := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second, Multiplier: 2}
+		expected := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+		for i, want := range expected {
+			if got := policy.Backoff(i + 1); got != want {
+				t.Errorf("attempt %d: expected %v, got %v", i+1, want, got)
+			}
+		}
+	})
+}
+
+type stubAIService struct {
+	BaseProvider
+	errs  []error
+	calls int
+}
+
+func (s *stubAIService) GenerateContent(ctx context.Context, request entity.GenerateContentRequest, dbModel entity.DbModel) (*entity.GenerateContentResponse, error) {
+	err := s.errs[s.calls]
+	s.calls++
+	if err != nil {
+		return nil, err
+	}
+	return &entity.GenerateContentResponse{Text: "ok"}, nil
+}
+
+func TestGenerateWithRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond, Multiplier: 2}
+
+	t.Run("可重试错误后成功", func(t *testing.T) {
+		svc := &stubAIService{errs: []error{&HTTPStatusError{StatusCode: 503}, nil}}
+		var results []AttemptResult
+
+		resp, err := GenerateWithRetry(context.Background(), svc, entity.GenerateContentRequest{}, entity.DbModel{}, policy, func(r AttemptResult) {
+			results = append(results, r)
+		})
+		if err != nil || resp == nil || resp.Text != "ok" {
+			t.Fatalf("unexpected result: %+v, %v", resp, err)
+		}
+		if len(results) != 2 || results[0].Backoff == 0 || results[1].Err != nil {
+			t.Errorf("unexpected attempts: %+v", results)
+		}
+	})
+
+	t.Run("终止错误不重试", func(t *testing.T) {
+		svc := &stubAIService{errs: []error{&HTTPStatusError{StatusCode: 401}, nil}}
+		if _, err := GenerateWithRetry(context.Background(), svc, entity.GenerateContentRequest{}, entity.DbModel{}, policy, nil); err == nil {
+			t.Fatal("expected error")
+		}
+		if svc.calls != 1 {
+			t.Errorf("expected 1 call, got %d", svc.calls)
+		}
+	})
+
+	t.Run("达到最大次数", func(t *testing.T) {
+		retryable := &HTTPStatusError{StatusCode: 502}
+		svc := &stubAIService{errs: []error{retryable, retryable, retryable, nil}}
+		if _, err := GenerateWithRetry(context.Background(), svc, entity.GenerateContentRequest{}, entity.DbModel{}, policy, nil); err == nil {
+			t.Fatal("expected error")
+		}
+		if svc.calls != 3 {
+			t.Errorf("expected 3 calls, got %d", svc.calls)
+		}
+	})
+
+	t.Run("Retry-After 超过上限时放弃", func(t *testing.T) {
+		svc := &stubAIService{errs: []error{&HTTPStatusError{StatusCode: 429, RetryAfter: time.Minute}, nil}}
+		if _, err := GenerateWithRetry(context.Background(), svc, entity.GenerateContentRequest{}, entity.DbModel{}, policy, nil); err == nil {
+			t.Fatal("expected error")
+		}
+		if svc.calls != 1 {
+			t.Errorf("expected 1 call, got %d", svc.calls)
+		}
+	})
+}
+
+// pollingAIService submits an async task on every call and waits for it with poller.
+type pollingAIService struct {
+	BaseProvider
+	poller  TaskPoller
+	submits int
+}
+
+func (s *pollingAIService) GenerateContent(ctx context.Context, request entity.GenerateContentRequest, dbModel entity.DbModel) (*entity.GenerateContentResponse, error) {
+	s.submits++
+	taskID := fmt.Sprintf("task-%d", s.submits)
+	ReportTaskSubmitted(ctx, taskID)
+	return WaitForTask(ctx, s.poller, taskID, PollConfig{Interval: time.Millisecond, MaxAttempts: 20})
+}
+
+// errorPoller fails the first len(errs) polls, then reports success.
+type errorPoller struct {
+	errs  []error
+	calls int
+}
+
+func (p *errorPoller) Poll(ctx context.Context, taskID string) (*AsyncTask, error) {
+	p.calls++
+	if p.calls <= len(p.errs) {
+		return nil, p.errs[p.calls-1]
+	}
+	return &AsyncTask{ID: taskID, Status: TaskStatusSucceeded, Result: &entity.GenerateContentResponse{TaskID: taskID}}, nil
+}
+
+func TestGenerateWithRetryAfterSubmission(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond, Multiplier: 2}
+	pollError := &HTTPStatusError{Provider: "fal.ai poll", StatusCode: 502}
+
+	tests := []struct {
+		name       string
+		pollErrs   []error
+		wantErr    bool
+		wantPolls  int
+		wantSubmit bool
+	}{
+		{name: "轮询 5xx 后在轮询内重试", pollErrs: []error{pollError, pollError}, wantPolls: 3, wantSubmit: true},
+		{name: "轮询持续 5xx 时失败但不重新提交", pollErrs: []error{pollError, pollError, pollError, pollError, pollError, pollError, pollError}, wantErr: true, wantPolls: maxTransientPollErrors + 1, wantSubmit: true},
+		{name: "轮询终止错误不重试", pollErrs: []error{&HTTPStatusError{Provider: "fal.ai poll", StatusCode: 404}}, wantErr: true, wantPolls: 1, wantSubmit: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poller := &errorPoller{errs: tt.pollErrs}
+			svc := &pollingAIService{poller: poller}
+
+			var submitted []string
+			ctx := WithTaskHooks(context.Background(), TaskHooks{
+				OnSubmitted: func(taskID string, slot int) { submitted = append(submitted, taskID) },
+			})
+
+			var results []AttemptResult
+			_, err := GenerateWithRetry(ctx, svc, entity.GenerateContentRequest{}, entity.DbModel{}, policy, func(r AttemptResult) {
+				results = append(results, r)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if svc.submits != 1 {
+				t.Errorf("expected 1 submit, got %d", svc.submits)
+			}
+			if poller.calls != tt.wantPolls {
+				t.Errorf("expected %d polls, got %d", tt.wantPolls, poller.calls)
+			}
+			if len(submitted) != 1 {
+				t.Errorf("expected caller hook to see 1 submission, got %v", submitted)
+			}
+			if len(results) != 1 || results[0].Submitted != tt.wantSubmit || results[0].Backoff != 0 {
+				t.Errorf("unexpected attempts: %+v", results)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStreamedProtocolsReportDeltas(t *testing.T) {
+	tests := []struct {
+		name     string
+		chunks   []string
+		generate func(ctx context.Context, url string) error
+	}{
+		{
+			name: "gemini",
+			chunks: []string{
+				`{"candidates":[{"content":{"parts":[{"text":"先画轮廓"}]}}]}`,
+				`{"candidates":[{"content":{"parts":[{"inlineData":{"mimeType":"image/png","data":"aW1n"}}]}}]}`,
+			},
+			generate: func(ctx context.Context, url string) error {
+				_, err := GenerateContentByGeminiProtocol(ctx, "key", url+"/%s", "gemini-image", "a dress", nil)
+				return err
+			},
+		},
+		{
+			name: "openai",
+			chunks: []string{
+				`{"choices":[{"delta":{"content":"先画轮廓"}}]}`,
+				`{"choices":[{"delta":{"images":[{"type":"image_url","image_url":{"url":"data:image/png;base64,aW1n"}}]}}]}`,
+			},
+			generate: func(ctx context.Context, url string) error {
+				_, err := GenerateContentByOpenaiProtocol(ctx, "key", url, "openai-image", "a dress", nil, nil)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, chunk := range tt.chunks {
+					fmt.Fprintf(w, "data: %s\n\n", chunk)
+				}
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			}))
+			defer server.Close()
+
+			var deltas []GenerationDelta
+			ctx := WithTaskHooks(context.Background(), TaskHooks{
+				OnDelta: func(delta GenerationDelta) { deltas = append(deltas, delta) },
+			})
+
+			if err := tt.generate(ctx, server.URL); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(deltas) != 2 {
+				t.Fatalf("expected 2 deltas, got %+v", deltas)
+			}
+			if deltas[0].Text != "先画轮廓" {
+				t.Errorf("expected text delta first, got %+v", deltas[0])
+			}
+			if deltas[1].Image != "data:image/png;base64,aW1n" {
+				t.Errorf("expected image delta, got %+v", deltas[1])
+			}
+		})
+	}
+}
+
+func TestOpenaiProtocolReportsUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"images\":[{\"type\":\"image_url\",\"image_url\":{\"url\":\"data:image/png;base64,aW1n\"}}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":1290,\"cost\":0.0387}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	resp, err := GenerateContentByOpenaiProtocol(context.Background(), "key", server.URL, "openai-image", "a dress", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Usage == nil {
+		t.Fatal("expected usage to be reported")
+	}
+	if resp.Usage.PromptTokens != 12 || resp.Usage.CompletionTokens != 1290 {
+		t.Errorf("unexpected token usage %+v", resp.Usage)
+	}
+	if resp.Usage.Cost == nil || *resp.Usage.Cost != 0.0387 {
+		t.Errorf("expected cost 0.0387, got %v", resp.Usage.Cost)
+	}
+}
@@ -0,0 +1,219 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// earlyCallbackTTL bounds how long a callback that arrived before its waiter is kept.
+const earlyCallbackTTL = 10 * time.Minute
+
+// callbackSafetyPollInterval is how often a task whose completion callback was requested is still
+// polled, in case the callback is lost.
+const callbackSafetyPollInterval = 45 * time.Second
+
+// taskPollInterval returns how often a task is polled: the provider's own interval when polling is the
+// only way to learn about completion, the sparse callbackSafetyPollInterval when a completion callback
+// was requested (the callback triggers an immediate query).
+func taskPollInterval(callbackURL string, interval time.Duration) time.Duration {
+	if callbackURL == "" {
+		return interval
+	}
+	return max(interval, callbackSafetyPollInterval)
+}
+
+// TaskCallbackConfig configures inbound completion callbacks for async provider tasks.
+type TaskCallbackConfig struct {
+	// BaseURL is the public url the callback route is mounted on, e.g. https://example.com/api/callbacks.
+	BaseURL string
+	// Secret signs the per-driver token embedded in the callback url.
+	Secret string
+}
+
+// Enabled reports whether callbacks can be requested from providers.
+func (c TaskCallbackConfig) Enabled() bool {
+	return strings.TrimSpace(c.BaseURL) != "" && strings.TrimSpace(c.Secret) != ""
+}
+
+// URL returns the callback url for driver, or "" when callbacks are disabled.
+func (c TaskCallbackConfig) URL(driver string) string {
+	if !c.Enabled() {
+		return ""
+	}
+	driver = strings.ToLower(strings.TrimSpace(driver))
+	return strings.TrimRight(strings.TrimSpace(c.BaseURL), "/") + "/" + url.PathEscape(driver) +
+		"?token=" + TaskCallbackToken(c.Secret, driver)
+}
+
+// TaskCallbackToken derives the token a driver's callback url carries: hex(HMAC-SHA256(secret, driver)).
+func TaskCallbackToken(secret, driver string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(driver))))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyTaskCallbackToken checks the token of an inbound callback in constant time.
+func VerifyTaskCallbackToken(secret, driver, token string) bool {
+	if strings.TrimSpace(secret) == "" || strings.TrimSpace(token) == "" {
+		return false
+	}
+	expected := TaskCallbackToken(secret, driver)
+	return hmac.Equal([]byte(expected), []byte(strings.TrimSpace(token)))
+}
+
+type taskCallbackKey struct{}
+
+// WithTaskCallbacks attaches the callback configuration to ctx; providers that support
+// completion callbacks register the url with their async tasks when it is enabled.
+func WithTaskCallbacks(ctx context.Context, cfg TaskCallbackConfig) context.Context {
+	return context.WithValue(ctx, taskCallbackKey{}, cfg)
+}
+
+// TaskCallbackURL returns the callback url for driver carried by ctx, or "" when callbacks are disabled.
+func TaskCallbackURL(ctx context.Context, driver string) string {
+	if ctx == nil {
+		return ""
+	}
+	cfg, _ := ctx.Value(taskCallbackKey{}).(TaskCallbackConfig)
+	return cfg.URL(driver)
+}
+
+// TaskCallback is a completion notification pushed by a provider for an async task.
+// It only signals that the task reached a terminal state; the waiter fetches the result itself.
+type TaskCallback struct {
+	Driver string
+	TaskID string
+	Status TaskStatus
+}
+
+// ParseTaskCallback decodes the callback body sent by driver.
+func ParseTaskCallback(driver string, body []byte) (TaskCallback, error) {
+	driver = strings.ToLower(strings.TrimSpace(driver))
+	callback := TaskCallback{Driver: driver}
+
+	switch driver {
+	case entity.ProviderDriverFal:
+		var payload struct {
+			RequestID string `json:"request_id"`
+			Status    string `json:"status"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return callback, fmt.Errorf("fal.ai decode callback: %w", err)
+		}
+		callback.TaskID = payload.RequestID
+		// fal.ai reports OK or ERROR once the request has finished
+		callback.Status = TaskStatusSucceeded
+		if !strings.EqualFold(strings.TrimSpace(payload.Status), "OK") {
+			callback.Status = TaskStatusFailed
+		}
+	case entity.ProviderDriverDashscope:
+		var payload dashscopeVideoResponse
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return callback, fmt.Errorf("dashscope decode callback: %w", err)
+		}
+		callback.TaskID = payload.Output.TaskID
+		callback.Status = MapTaskStatus(payload.Output.TaskStatus)
+	case entity.ProviderDriverVolcengine:
+		var payload struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return callback, fmt.Errorf("volcengine decode callback: %w", err)
+		}
+		callback.TaskID = payload.ID
+		callback.Status = MapTaskStatus(payload.Status)
+	default:
+		return callback, fmt.Errorf("driver %s does not support callbacks", driver)
+	}
+
+	callback.TaskID = strings.TrimSpace(callback.TaskID)
+	if callback.TaskID == "" {
+		return callback, errors.New("callback missing task id")
+	}
+	return callback, nil
+}
+
+type earlyCallback struct {
+	callback   TaskCallback
+	receivedAt time.Time
+}
+
+// taskCallbackRegistry routes inbound callbacks to the pollers waiting in this process.
+type taskCallbackRegistry struct {
+	mu      sync.Mutex
+	waiters map[string]chan TaskCallback
+	// early keeps callbacks that arrived before the poller subscribed.
+	early map[string]earlyCallback
+}
+
+var taskCallbacks = &taskCallbackRegistry{
+	waiters: make(map[string]chan TaskCallback),
+	early:   make(map[string]earlyCallback),
+}
+
+func taskCallbackRegistryKey(driver, taskID string) string {
+	return strings.ToLower(strings.TrimSpace(driver)) + ":" + strings.TrimSpace(taskID)
+}
+
+// SubscribeTaskCallback registers interest in the completion callback of a task.
+// The returned release function must be called once the task is no longer awaited.
+func SubscribeTaskCallback(driver, taskID string) (<-chan TaskCallback, func()) {
+	key := taskCallbackRegistryKey(driver, taskID)
+	ch := make(chan TaskCallback, 1)
+
+	r := taskCallbacks
+	r.mu.Lock()
+	if early, ok := r.early[key]; ok {
+		delete(r.early, key)
+		ch <- early.callback
+	}
+	r.waiters[key] = ch
+	r.mu.Unlock()
+
+	return ch, func() {
+		r.mu.Lock()
+		if r.waiters[key] == ch {
+			delete(r.waiters, key)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// DeliverTaskCallback hands a callback to the poller waiting for the task.
+// It reports whether a poller in this process was waiting; otherwise the callback is kept
+// briefly in case the poller has not subscribed yet.
+func DeliverTaskCallback(callback TaskCallback) bool {
+	key := taskCallbackRegistryKey(callback.Driver, callback.TaskID)
+
+	r := taskCallbacks
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ch, ok := r.waiters[key]; ok {
+		select {
+		case ch <- callback:
+		default:
+		}
+		return true
+	}
+
+	now := time.Now()
+	for k, early := range r.early {
+		if now.Sub(early.receivedAt) > earlyCallbackTTL {
+			delete(r.early, k)
+		}
+	}
+	r.early[key] = earlyCallback{callback: callback, receivedAt: now}
+	return false
+}
@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaskCallbackURL(t *testing.T) {
+	cfg := TaskCallbackConfig{BaseURL: "https://example.com/api/callbacks/", Secret: "s3cret"}
+	ctx := WithTaskCallbacks(context.Background(), cfg)
+
+	got := TaskCallbackURL(ctx, entity.ProviderDriverFal)
+	want := "https://example.com/api/callbacks/fal?token=" + TaskCallbackToken("s3cret", "fal")
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if url := TaskCallbackURL(context.Background(), entity.ProviderDriverFal); url != "" {
+		t.Errorf("expected no callback url without config, got %q", url)
+	}
+	if url := (TaskCallbackConfig{BaseURL: "https://example.com"}).URL("fal"); url != "" {
+		t.Errorf("expected no callback url without secret, got %q", url)
+	}
+
+	token := TaskCallbackToken("s3cret", "fal")
+	if !VerifyTaskCallbackToken("s3cret", "FAL", token) {
+		t.Error("expected token to verify")
+	}
+	if VerifyTaskCallbackToken("s3cret", "volcengine", token) {
+		t.Error("expected token of another driver to be rejected")
+	}
+	if VerifyTaskCallbackToken("", "fal", TaskCallbackToken("", "fal")) {
+		t.Error("expected tokens to be rejected without a secret")
+	}
+}
+
+func TestParseTaskCallback(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver string
+		body   string
+		taskID string
+		status TaskStatus
+		err    bool
+	}{
+		{name: "fal 成功", driver: entity.ProviderDriverFal, body: `{"request_id":"req-1","status":"OK","payload":{}}`, taskID: "req-1", status: TaskStatusSucceeded},
+		{name: "fal 失败", driver: entity.ProviderDriverFal, body: `{"request_id":"req-1","status":"ERROR","error":"boom"}`, taskID: "req-1", status: TaskStatusFailed},
+		{name: "dashscope", driver: entity.ProviderDriverDashscope, body: `{"output":{"task_id":"t-1","task_status":"SUCCEEDED"}}`, taskID: "t-1", status: TaskStatusSucceeded},
+		{name: "volcengine", driver: entity.ProviderDriverVolcengine, body: `{"id":"cgt-1","status":"failed"}`, taskID: "cgt-1", status: TaskStatusFailed},
+		{name: "缺少任务 ID", driver: entity.ProviderDriverVolcengine, body: `{"status":"succeeded"}`, err: true},
+		{name: "不支持的驱动", driver: entity.ProviderDriverGemini, body: `{}`, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callback, err := ParseTaskCallback(tt.driver, []byte(tt.body))
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if callback.TaskID != tt.taskID || callback.Status != tt.status {
+				t.Errorf("expected %s/%s, got %s/%s", tt.taskID, tt.status, callback.TaskID, callback.Status)
+			}
+		})
+	}
+}
+
+func TestDeliverTaskCallback(t *testing.T) {
+	t.Run("唤醒等待中的任务", func(t *testing.T) {
+		ch, release := SubscribeTaskCallback("fal", "req-wait")
+		defer release()
+
+		if !DeliverTaskCallback(TaskCallback{Driver: "fal", TaskID: "req-wait", Status: TaskStatusSucceeded}) {
+			t.Fatal("expected callback to reach the waiter")
+		}
+		if got := <-ch; got.Status != TaskStatusSucceeded {
+			t.Errorf("unexpected callback: %+v", got)
+		}
+	})
+
+	t.Run("回调早于订阅", func(t *testing.T) {
+		if DeliverTaskCallback(TaskCallback{Driver: "fal", TaskID: "req-early", Status: TaskStatusFailed}) {
+			t.Fatal("expected no waiter")
+		}
+		ch, release := SubscribeTaskCallback("fal", "req-early")
+		defer release()
+
+		select {
+		case got := <-ch:
+			if got.Status != TaskStatusFailed {
+				t.Errorf("unexpected callback: %+v", got)
+			}
+		default:
+			t.Fatal("expected buffered callback")
+		}
+	})
+
+	t.Run("释放后不再投递", func(t *testing.T) {
+		_, release := SubscribeTaskCallback("fal", "req-released")
+		release()
+		if DeliverTaskCallback(TaskCallback{Driver: "fal", TaskID: "req-released"}) {
+			t.Error("expected released waiter to be gone")
+		}
+	})
+}
+
+func TestAppendQueryParam(t *testing.T) {
+	if !strings.HasPrefix(appendQueryParam("https://queue.fal.run/fal-ai/flux?x=1", "fal_webhook", "https://a/b?token=1"), "https://queue.fal.run/fal-ai/flux?x=1&fal_webhook=https%3A%2F%2Fa") {
+		t.Error("unexpected query encoding")
+	}
+}
+
+func TestTaskPollInterval(t *testing.T) {
+	tests := []struct {
+		name        string
+		callbackURL string
+		interval    time.Duration
+		expected    time.Duration
+	}{
+		{name: "未配置回调时按服务商间隔轮询", interval: 3 * time.Second, expected: 3 * time.Second},
+		{name: "配置回调后仅低频兜底轮询", callbackURL: "https://example.com/api/callbacks/dashscope?token=t", interval: 3 * time.Second, expected: callbackSafetyPollInterval},
+		{name: "服务商间隔更长时保持不变", callbackURL: "https://example.com/api/callbacks/fal?token=t", interval: 2 * time.Minute, expected: 2 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := taskPollInterval(tt.callbackURL, tt.interval); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"strings"
+	"sync/atomic"
+)
+
+// TaskHooks are lifecycle callbacks for a single generation, carried via context.
+// Providers invoke them through the Report* helpers; all hooks are optional.
+type TaskHooks struct {
+	// OnSubmitted is called as soon as the provider has accepted an async task,
+	// so the caller can persist the task ID before polling finishes. slot is the output
+	// slot the task generates (see WithOutputSlot).
+	OnSubmitted func(taskID string, slot int)
+
+	// OnProgress is called whenever a poller observes the state of an async task.
+	OnProgress func(progress TaskProgress)
+
+	// OnDelta is called with partial output while a streamed provider response is being read.
+	OnDelta func(delta GenerationDelta)
+}
+
+// GenerationDelta is a piece of partial output received from a streamed response.
+// The complete output is still returned (and persisted) when the call finishes.
+type GenerationDelta struct {
+	// Text is the next chunk of assistant text.
+	Text string
+	// Image is an image (data url or url) that has just been received.
+	Image string
+	// Slot is the output slot of the call that produced the delta (see WithOutputSlot).
+	Slot int
+	// Attempt is the (1-based) GenerateWithRetry attempt that produced the delta; output of an
+	// attempt that is retried is superseded by the next one. 0 outside GenerateWithRetry.
+	Attempt int
+}
+
+// TaskProgress is a snapshot of an async task reported while polling.
+type TaskProgress struct {
+	TaskID string
+	Status TaskStatus
+	// Percent is the completion percentage (0-100), nil when the provider does not report it.
+	Percent *float64
+	// QueuePosition is the position in the provider queue, nil when unknown.
+	QueuePosition *int
+}
+
+type taskHooksKey struct{}
+
+// WithTaskHooks attaches hooks to ctx for the providers to report to.
+func WithTaskHooks(ctx context.Context, hooks TaskHooks) context.Context {
+	return context.WithValue(ctx, taskHooksKey{}, hooks)
+}
+
+func taskHooksFrom(ctx context.Context) TaskHooks {
+	if ctx == nil {
+		return TaskHooks{}
+	}
+	hooks, _ := ctx.Value(taskHooksKey{}).(TaskHooks)
+	return hooks
+}
+
+type outputSlotKey struct{}
+
+// WithOutputSlot marks ctx as the provider call generating output slot (0-based) of a request whose
+// outputs are generated by separate calls; tasks reported under ctx carry the slot.
+func WithOutputSlot(ctx context.Context, slot int) context.Context {
+	return context.WithValue(ctx, outputSlotKey{}, slot)
+}
+
+// OutputSlot returns the output slot of ctx, 0 when all outputs are generated by a single call.
+func OutputSlot(ctx context.Context) int {
+	if ctx == nil {
+		return 0
+	}
+	slot, _ := ctx.Value(outputSlotKey{}).(int)
+	return slot
+}
+
+type attemptKey struct{}
+
+// withAttempt marks ctx as belonging to the given GenerateWithRetry attempt.
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+func attemptFrom(ctx context.Context) int {
+	if ctx == nil {
+		return 0
+	}
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+// withSubmissionTracking returns a ctx whose hooks set submitted once a task has been reported
+// as submitted, in addition to calling the hooks already attached to ctx.
+func withSubmissionTracking(ctx context.Context, submitted *atomic.Bool) context.Context {
+	hooks := taskHooksFrom(ctx)
+	onSubmitted := hooks.OnSubmitted
+	hooks.OnSubmitted = func(taskID string, slot int) {
+		submitted.Store(true)
+		if onSubmitted != nil {
+			onSubmitted(taskID, slot)
+		}
+	}
+	return WithTaskHooks(ctx, hooks)
+}
+
+// ReportTaskSubmitted notifies the caller that an async task has been created upstream.
+func ReportTaskSubmitted(ctx context.Context, taskID string) {
+	taskID = strings.TrimSpace(taskID)
+	if taskID == "" {
+		return
+	}
+	if hooks := taskHooksFrom(ctx); hooks.OnSubmitted != nil {
+		hooks.OnSubmitted(taskID, OutputSlot(ctx))
+	}
+}
+
+// ReportTaskProgress notifies the caller about the current state of an async task.
+func ReportTaskProgress(ctx context.Context, progress TaskProgress) {
+	if hooks := taskHooksFrom(ctx); hooks.OnProgress != nil {
+		hooks.OnProgress(progress)
+	}
+}
+
+// ReportDelta forwards partial output of a streamed response to the caller, tagged with the
+// output slot and attempt of ctx.
+func ReportDelta(ctx context.Context, delta GenerationDelta) {
+	if delta.Text == "" && delta.Image == "" {
+		return
+	}
+	if hooks := taskHooksFrom(ctx); hooks.OnDelta != nil {
+		delta.Slot = OutputSlot(ctx)
+		delta.Attempt = attemptFrom(ctx)
+		hooks.OnDelta(delta)
+	}
+}
+
+// TaskCanceller is implemented by providers that can cancel a submitted task upstream.
+type TaskCanceller interface {
+	// CancelTask asks the provider to stop the task identified by taskID.
+	CancelTask(ctx context.Context, dbModel entity.DbModel, taskID string) error
+}
@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestReportTaskSubmitted(t *testing.T) {
+	var got []string
+	ctx := WithTaskHooks(context.Background(), TaskHooks{
+		OnSubmitted: func(taskID string, slot int) { got = append(got, fmt.Sprintf("%s@%d", taskID, slot)) },
+	})
+
+	ReportTaskSubmitted(ctx, " task-1 ")
+	ReportTaskSubmitted(ctx, "   ")
+	ReportTaskSubmitted(WithOutputSlot(ctx, 2), "task-2")
+	ReportTaskSubmitted(context.Background(), "task-3")
+
+	if len(got) != 2 || got[0] != "task-1@0" || got[1] != "task-2@2" {
+		t.Errorf("expected [task-1@0 task-2@2], got %v", got)
+	}
+}
+
+func TestAsyncProvidersImplementTaskResumer(t *testing.T) {
+	providers := map[string]AIService{
+		entity.ProviderDriverFal:        &FalAI{},
+		entity.ProviderDriverDashscope:  &Dashscope{},
+		entity.ProviderDriverVolcengine: &Volcengine{},
+	}
+	for driver, provider := range providers {
+		if _, ok := provider.(TaskResumer); !ok {
+			t.Errorf("expected %s to implement TaskResumer", driver)
+		}
+	}
+}
+
+func TestFalQueueCancelURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    entity.DbModel
+		expected string
+	}{
+		{
+			name:     "使用模型 ID",
+			model:    entity.DbModel{ModelID: "fal-ai/flux/dev"},
+			expected: "https://queue.fal.run/fal-ai/flux/requests/req-1/cancel",
+		},
+		{
+			name:     "优先使用 EndpointPath",
+			model:    entity.DbModel{ModelID: "flux", EndpointPath: "/fal-ai/nano-banana/edit"},
+			expected: "https://queue.fal.run/fal-ai/nano-banana/requests/req-1/cancel",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := falQueueCancelURL(tt.model, "req-1"); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+type stubPoller struct {
+	tasks []*AsyncTask
+	calls int
+}
+
+func (p *stubPoller) Poll(ctx context.Context, taskID string) (*AsyncTask, error) {
+	task := p.tasks[p.calls]
+	p.calls++
+	return task, nil
+}
+
+func TestWaitForTaskReportsProgress(t *testing.T) {
+	poller := &stubPoller{tasks: []*AsyncTask{
+		{ID: "task-1", Status: TaskStatusPending},
+		{ID: "task-1", Status: TaskStatusRunning, Progress: 40},
+		{ID: "task-1", Status: TaskStatusSucceeded, Result: &entity.GenerateContentResponse{TaskID: "task-1"}},
+	}}
+
+	var reports []TaskProgress
+	ctx := WithTaskHooks(context.Background(), TaskHooks{
+		OnProgress: func(progress TaskProgress) { reports = append(reports, progress) },
+	})
+
+	resp, err := WaitForTask(ctx, poller, "task-1", PollConfig{Interval: time.Millisecond, MaxAttempts: 5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp == nil || resp.TaskID != "task-1" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 progress reports, got %d", len(reports))
+	}
+	if reports[0].Status != TaskStatusPending || reports[0].Percent != nil {
+		t.Errorf("unexpected first report: %+v", reports[0])
+	}
+	if reports[1].Status != TaskStatusRunning || reports[1].Percent == nil || *reports[1].Percent != 40 {
+		t.Errorf("unexpected second report: %+v", reports[1])
+	}
+}
+
+func TestReportDelta(t *testing.T) {
+	var got []GenerationDelta
+	ctx := WithTaskHooks(context.Background(), TaskHooks{
+		OnDelta: func(delta GenerationDelta) { got = append(got, delta) },
+	})
+
+	ReportDelta(ctx, GenerationDelta{})
+	ReportDelta(ctx, GenerationDelta{Text: "a"})
+	ReportDelta(withAttempt(WithOutputSlot(ctx, 1), 2), GenerationDelta{Text: "b"})
+
+	expected := []GenerationDelta{{Text: "a"}, {Text: "b", Slot: 1, Attempt: 2}}
+	if len(got) != len(expected) || got[0] != expected[0] || got[1] != expected[1] {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
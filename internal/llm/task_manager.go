@@ -62,12 +62,67 @@ var VolcenginePollConfig = PollConfig{
 	Backoff:     false,
 }
 
+// TaskFailedError reports that a submitted async task has definitely failed or been cancelled
+// upstream, so no result can arrive for it any more.
+type TaskFailedError struct {
+	Err error
+}
+
+func (e *TaskFailedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TaskFailedError) Unwrap() error {
+	return e.Err
+}
+
+func taskFailed(err error) error {
+	return &TaskFailedError{Err: err}
+}
+
+// IsTaskFailed reports whether err says that a submitted task has definitely failed upstream.
+func IsTaskFailed(err error) bool {
+	var failed *TaskFailedError
+	return errors.As(err, &failed)
+}
+
+// maxTransientPollErrors bounds the consecutive transient errors (timeouts, 5xx, 429, connection
+// resets) tolerated while polling a submitted task. They are retried on the next poll instead of
+// failing the generation, since retrying the whole generation would submit a second paid task.
+const maxTransientPollErrors = 5
+
+// pollErrorBudget counts consecutive transient errors of a poll loop.
+type pollErrorBudget struct {
+	consecutive int
+}
+
+// retry reports whether the poll error err should be retried on the next poll.
+// A successful poll (nil err) resets the budget.
+func (b *pollErrorBudget) retry(ctx context.Context, err error) bool {
+	if err == nil {
+		b.consecutive = 0
+		return false
+	}
+	if ctx.Err() != nil || !ClassifyError(err).Retryable || b.consecutive >= maxTransientPollErrors {
+		return false
+	}
+	b.consecutive++
+	return true
+}
+
 // TaskPoller defines the interface for polling task status.
 type TaskPoller interface {
 	// Poll checks the current status of a task.
 	Poll(ctx context.Context, taskID string) (*AsyncTask, error)
 }
 
+// TaskResumer is implemented by providers that can poll a previously submitted async task by
+// its ID alone, so the result of a task whose poller was lost (e.g. in a restart) can be collected.
+type TaskResumer interface {
+	// TaskPoller returns a poller for async tasks submitted for dbModel.
+	TaskPoller(dbModel entity.DbModel) TaskPoller
+}
+
 // WaitForTask polls a task until completion or timeout.
 func WaitForTask(ctx context.Context, poller TaskPoller, taskID string, config PollConfig) (*entity.GenerateContentResponse, error) {
 	if taskID == "" {
@@ -88,6 +143,7 @@ func WaitForTask(ctx context.Context, poller TaskPoller, taskID string, config P
 	defer ticker.Stop()
 
 	attempts := 0
+	var pollErrors pollErrorBudget
 
 	for {
 		select {
@@ -98,6 +154,14 @@ func WaitForTask(ctx context.Context, poller TaskPoller, taskID string, config P
 			attempts++
 
 			task, err := poller.Poll(ctx, taskID)
+			if pollErrors.retry(ctx, err) {
+				logrus.WithFields(logrus.Fields{
+					"task_id": taskID,
+					"attempt": attempts,
+					"error":   err,
+				}).Warn("task_manager: transient poll error, retrying")
+				continue
+			}
 			if err != nil {
 				logrus.WithFields(logrus.Fields{
 					"task_id": taskID,
@@ -114,18 +178,27 @@ func WaitForTask(ctx context.Context, poller TaskPoller, taskID string, config P
 				"attempt":  attempts,
 			}).Debug("task_manager: poll status")
 
+			if task.Status == TaskStatusPending || task.Status == TaskStatusRunning {
+				progress := TaskProgress{TaskID: taskID, Status: task.Status}
+				if task.Progress > 0 {
+					percent := task.Progress
+					progress.Percent = &percent
+				}
+				ReportTaskProgress(ctx, progress)
+			}
+
 			switch task.Status {
 			case TaskStatusSucceeded:
 				return task.Result, nil
 
 			case TaskStatusFailed:
 				if task.Error != nil {
-					return nil, task.Error
+					return nil, taskFailed(task.Error)
 				}
-				return nil, errors.New("task failed without error message")
+				return nil, taskFailed(errors.New("task failed without error message"))
 
 			case TaskStatusCancelled:
-				return nil, errors.New("task was cancelled")
+				return nil, taskFailed(errors.New("task was cancelled"))
 
 			case TaskStatusPending, TaskStatusRunning:
 				if attempts >= maxAttempts {
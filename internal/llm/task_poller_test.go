@@ -0,0 +1,174 @@
+package llm
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/volcengine/volcengine-go-sdk/service/arkruntime"
+)
+
+// rewriteTransport sends every request to target, keeping its path and query.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newRewriteClient(t *testing.T, server *httptest.Server) *http.Client {
+	t.Helper()
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Transport: rewriteTransport{target: target}}
+}
+
+// pollerCase is the provider response for a task and the state the poller should report for it.
+type pollerCase struct {
+	name       string
+	body       string
+	wantStatus TaskStatus
+	wantErr    bool
+	wantOutput string
+}
+
+func checkPolledTask(t *testing.T, tt pollerCase, task *AsyncTask, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if task.Status != tt.wantStatus {
+		t.Errorf("expected status %s, got %s", tt.wantStatus, task.Status)
+	}
+	if (task.Error != nil) != tt.wantErr {
+		t.Errorf("expected task error %v, got %v", tt.wantErr, task.Error)
+	}
+	if tt.wantOutput == "" {
+		if task.Result != nil {
+			t.Errorf("expected no result, got %+v", task.Result)
+		}
+		return
+	}
+	if task.Result == nil || len(task.Result.Outputs) == 0 || task.Result.Outputs[0].URL != tt.wantOutput {
+		t.Errorf("expected output %s, got %+v", tt.wantOutput, task.Result)
+	}
+}
+
+func TestDashscopeTaskPoller(t *testing.T) {
+	tests := []pollerCase{
+		{name: "排队中", body: `{"output":{"task_id":"t1","task_status":"PENDING"}}`, wantStatus: TaskStatusPending},
+		{name: "执行中", body: `{"output":{"task_id":"t1","task_status":"RUNNING"}}`, wantStatus: TaskStatusRunning},
+		{name: "成功", body: `{"output":{"task_id":"t1","task_status":"SUCCEEDED","video_url":"https://cdn.example.com/v.mp4"}}`, wantStatus: TaskStatusSucceeded, wantOutput: "https://cdn.example.com/v.mp4"},
+		{name: "成功但缺少视频", body: `{"output":{"task_id":"t1","task_status":"SUCCEEDED"}}`, wantStatus: TaskStatusFailed, wantErr: true},
+		{name: "失败", body: `{"output":{"task_id":"t1","task_status":"FAILED","message":"bad input"}}`, wantStatus: TaskStatusFailed, wantErr: true},
+		{name: "已取消", body: `{"output":{"task_id":"t1","task_status":"CANCELED"}}`, wantStatus: TaskStatusCancelled},
+		{name: "任务已过期", body: `{"output":{"task_id":"t1","task_status":"UNKNOWN"}}`, wantStatus: TaskStatusFailed, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/tasks/t1" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			poller := &dashscopeTaskPoller{apiKey: "key", providerID: "dashscope", modelID: "wan", httpClient: newRewriteClient(t, server)}
+			task, err := poller.Poll(context.Background(), "t1")
+			checkPolledTask(t, tt, task, err)
+		})
+	}
+}
+
+func TestVolcengineTaskPoller(t *testing.T) {
+	tests := []pollerCase{
+		{name: "排队中", body: `{"id":"cgt-1","status":"queued"}`, wantStatus: TaskStatusPending},
+		{name: "执行中", body: `{"id":"cgt-1","status":"running"}`, wantStatus: TaskStatusRunning},
+		{name: "成功", body: `{"id":"cgt-1","status":"succeeded","content":{"video_url":"https://cdn.example.com/v.mp4"}}`, wantStatus: TaskStatusSucceeded, wantOutput: "https://cdn.example.com/v.mp4"},
+		{name: "成功但缺少视频", body: `{"id":"cgt-1","status":"succeeded","content":{}}`, wantStatus: TaskStatusFailed, wantErr: true},
+		{name: "失败", body: `{"id":"cgt-1","status":"failed","error":{"code":"InputInvalid","message":"bad input"}}`, wantStatus: TaskStatusFailed, wantErr: true},
+		{name: "已取消", body: `{"id":"cgt-1","status":"cancelled"}`, wantStatus: TaskStatusCancelled},
+		{name: "已过期", body: `{"id":"cgt-1","status":"expired"}`, wantStatus: TaskStatusFailed, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/contents/generations/tasks/cgt-1") {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			poller := &volcengineTaskPoller{
+				apiKey:     "key",
+				providerID: "volcengine",
+				modelID:    "seedance",
+				options:    []arkruntime.ConfigOption{arkruntime.WithBaseUrl(server.URL), arkruntime.WithRetryTimes(0)},
+			}
+			task, err := poller.Poll(context.Background(), "cgt-1")
+			checkPolledTask(t, tt, task, err)
+		})
+	}
+}
+
+func TestFalTaskPoller(t *testing.T) {
+	tests := []struct {
+		pollerCase
+		result string
+	}{
+		{pollerCase: pollerCase{name: "排队中", body: `{"status":"IN_QUEUE","queue_position":3}`, wantStatus: TaskStatusPending}},
+		{pollerCase: pollerCase{name: "执行中", body: `{"status":"IN_PROGRESS"}`, wantStatus: TaskStatusRunning}},
+		{
+			pollerCase: pollerCase{name: "成功", body: `{"status":"COMPLETED"}`, wantStatus: TaskStatusSucceeded, wantOutput: "https://cdn.example.com/a.png"},
+			result:     `{"images":[{"url":"https://cdn.example.com/a.png"}]}`,
+		},
+		{
+			pollerCase: pollerCase{name: "完成但没有图片", body: `{"status":"COMPLETED"}`, wantStatus: TaskStatusFailed, wantErr: true},
+			result:     `{"images":[]}`,
+		},
+		{pollerCase: pollerCase{name: "失败", body: `{"status":"FAILED","error":{"message":"bad input"}}`, wantStatus: TaskStatusFailed, wantErr: true}},
+		{pollerCase: pollerCase{name: "已取消", body: `{"status":"CANCELLED"}`, wantStatus: TaskStatusFailed, wantErr: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/fal-ai/flux/requests/req-1/status":
+					_, _ = w.Write([]byte(tt.body))
+				case "/fal-ai/flux/requests/req-1":
+					_, _ = w.Write([]byte(tt.result))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			f, err := NewFalAI(&entity.DbProvider{ID: "fal", Driver: entity.ProviderDriverFal, APIKey: "key"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.httpClient = newRewriteClient(t, server)
+
+			task, err := f.TaskPoller(entity.DbModel{ModelID: "fal-ai/flux/dev"}).Poll(context.Background(), "req-1")
+			checkPolledTask(t, tt.pollerCase, task, err)
+		})
+	}
+}
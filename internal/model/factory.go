@@ -172,12 +172,67 @@ func (f *RepositoryFactory) openGormDB(dialector gorm.Dialector) (*gorm.DB, erro
 
 // migrateSchema 迁移数据库表结构
 func (f *RepositoryFactory) migrateSchema(db *gorm.DB) error {
-	return db.AutoMigrate(
+	if err := db.AutoMigrate(
 		&entity.DbUser{},
 		&entity.DbUsageRecord{},
 		&entity.DbProvider{},
 		&entity.DbModel{},
 		&entity.DbTag{},
 		&entity.DbUsageRecordTag{},
-	)
+		&entity.DbUsageRecordTask{},
+		&entity.DbGenerationJob{},
+		&entity.DbFailoverChain{},
+		&entity.DbGenerationBatch{},
+		&entity.DbIdempotencyKey{},
+		&entity.DbWebhookEndpoint{},
+		&entity.DbWebhookDelivery{},
+		&entity.DbPromptTemplate{},
+		&entity.DbPipeline{},
+		&entity.DbPipelineRun{},
+		&entity.DbCreditAccount{},
+		&entity.DbCreditLedgerEntry{},
+		&entity.DbCreditQuota{},
+		&entity.DbProviderHealthCheck{},
+	); err != nil {
+		return err
+	}
+
+	return f.backfillUsageRecordStatus(db)
+}
+
+// backfillUsageRecordStatus 为引入状态字段之前的历史记录补齐状态（幂等）
+func (f *RepositoryFactory) backfillUsageRecordStatus(db *gorm.DB) error {
+	missing := "(status IS NULL OR status = '')"
+
+	// 仍在任务队列中的记录
+	pendingJobs := db.Model(&entity.DbGenerationJob{}).
+		Select("record_id").
+		Where("status IN ?", []string{entity.GenerationJobStatusPending, entity.GenerationJobStatusRunning})
+	if err := db.Model(&entity.DbUsageRecord{}).
+		Where(missing).
+		Where("id IN (?)", pendingJobs).
+		UpdateColumn("status", entity.UsageRecordStatusQueued).Error; err != nil {
+		return fmt.Errorf("backfill queued usage records: %w", err)
+	}
+
+	if err := db.Model(&entity.DbUsageRecord{}).
+		Where(missing).
+		Where("error_message IS NOT NULL AND error_message <> ''").
+		UpdateColumns(map[string]interface{}{
+			"status":      entity.UsageRecordStatusFailed,
+			"finished_at": gorm.Expr("updated_at"),
+		}).Error; err != nil {
+		return fmt.Errorf("backfill failed usage records: %w", err)
+	}
+
+	if err := db.Model(&entity.DbUsageRecord{}).
+		Where(missing).
+		UpdateColumns(map[string]interface{}{
+			"status":      entity.UsageRecordStatusSucceeded,
+			"finished_at": gorm.Expr("updated_at"),
+		}).Error; err != nil {
+		return fmt.Errorf("backfill succeeded usage records: %w", err)
+	}
+
+	return nil
 }
@@ -3,6 +3,7 @@ package model
 import (
 	"clothing/internal/entity"
 	"context"
+	"time"
 )
 
 // Repository 定义数据库操作接口
@@ -20,7 +21,11 @@ type Repository interface {
 	CreateUsageRecord(ctx context.Context, record *entity.DbUsageRecord) error
 	UpdateUsageRecord(ctx context.Context, id uint, updates entity.UsageRecordUpdates) error
 	ListUsageRecords(ctx context.Context, params *entity.UsageRecordQuery) ([]entity.DbUsageRecord, *entity.Meta, error)
+	SummariseUsageRecordCost(ctx context.Context, params *entity.UsageRecordQuery) (*entity.UsageCostSummary, error)
 	GetUsageRecord(ctx context.Context, id uint) (*entity.DbUsageRecord, error)
+	SaveUsageRecordTask(ctx context.Context, task *entity.DbUsageRecordTask) error
+	ListUsageRecordTasks(ctx context.Context, recordID uint) ([]entity.DbUsageRecordTask, error)
+	FindUsageRecordTaskByCode(ctx context.Context, driver, taskCode string) (*entity.DbUsageRecordTask, error)
 	DeleteUsageRecord(ctx context.Context, id uint) error
 	SetUsageRecordTags(ctx context.Context, recordID uint, tagIDs []uint) error
 	ListTags(ctx context.Context) ([]entity.DbTag, error)
@@ -29,6 +34,32 @@ type Repository interface {
 	DeleteTag(ctx context.Context, id uint) error
 	FindTagsByIDs(ctx context.Context, ids []uint) ([]entity.DbTag, error)
 
+	// 生成任务队列
+	CreateGenerationJob(ctx context.Context, job *entity.DbGenerationJob) error
+	CreateGeneration(ctx context.Context, record *entity.DbUsageRecord, job *entity.DbGenerationJob, tagIDs []uint, estimate float64, enforceBalance bool) error
+	ClaimGenerationJobs(ctx context.Context, owner string, limit int, lease time.Duration) ([]entity.DbGenerationJob, error)
+	ClaimInterruptedGenerationJobs(ctx context.Context, owner string, limit int, lease time.Duration) ([]entity.DbGenerationJob, error)
+	ClaimInterruptedGenerationJob(ctx context.Context, recordID uint, owner string, lease time.Duration) (*entity.DbGenerationJob, error)
+	ListActiveGenerationJobs(ctx context.Context) ([]entity.DbGenerationJob, error)
+	RenewGenerationJobLease(ctx context.Context, id uint, owner string, lease time.Duration) (bool, error)
+	ReleaseGenerationJobs(ctx context.Context, owner string) (int64, error)
+	FinishGenerationJob(ctx context.Context, id uint, owner string, status string, lastError string) error
+	CancelPendingGenerationJob(ctx context.Context, recordID uint) (*entity.DbGenerationJob, error)
+	RequestGenerationJobCancel(ctx context.Context, recordID uint) (*entity.DbGenerationJob, error)
+	GetGenerationJobByRecord(ctx context.Context, recordID uint) (*entity.DbGenerationJob, error)
+
+	// 批量生成
+	CreateGenerationBatch(ctx context.Context, batch *entity.DbGenerationBatch, records []entity.DbUsageRecord, jobs []entity.DbGenerationJob, estimates []float64, tagIDs []uint, enforceBalance bool) error
+	GetGenerationBatch(ctx context.Context, id uint) (*entity.DbGenerationBatch, error)
+	CountGenerationBatchRecords(ctx context.Context, batchID uint) (map[string]int64, error)
+	CompleteGenerationBatch(ctx context.Context, batchID uint) (bool, error)
+
+	// 幂等键
+	ClaimIdempotencyKey(ctx context.Context, key *entity.DbIdempotencyKey, pendingTTL time.Duration) (*entity.DbIdempotencyKey, bool, error)
+	BindIdempotencyKey(ctx context.Context, id uint, recordID uint) error
+	DeleteIdempotencyKey(ctx context.Context, id uint) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error)
+
 	// 服务商和模型
 	CreateProvider(ctx context.Context, provider *entity.DbProvider) error
 	UpdateProvider(ctx context.Context, id string, updates entity.ProviderUpdates) error
@@ -42,4 +73,62 @@ type Repository interface {
 	UpdateModel(ctx context.Context, providerID, modelID string, updates entity.ModelUpdates) error
 	DeleteModel(ctx context.Context, providerID, modelID string) error
 	ListModels(ctx context.Context, providerID string, includeInactive bool) ([]entity.DbModel, error)
+
+	// 服务商健康检查
+	CreateProviderHealthCheck(ctx context.Context, check *entity.DbProviderHealthCheck) error
+	ListProviderHealthChecks(ctx context.Context, providerID string, limit int) ([]entity.DbProviderHealthCheck, error)
+	DeleteProviderHealthChecksBefore(ctx context.Context, before time.Time) (int64, error)
+	ClaimProviderHealthCheck(ctx context.Context, providerID string, lease time.Duration) (bool, error)
+
+	// 故障转移链
+	ListFailoverChains(ctx context.Context) ([]entity.DbFailoverChain, error)
+	GetFailoverChain(ctx context.Context, id uint) (*entity.DbFailoverChain, error)
+	FindActiveFailoverChain(ctx context.Context, providerID, modelID string) (*entity.DbFailoverChain, error)
+	CreateFailoverChain(ctx context.Context, chain *entity.DbFailoverChain) error
+	UpdateFailoverChain(ctx context.Context, id uint, updates entity.FailoverChainUpdates) error
+	DeleteFailoverChain(ctx context.Context, id uint) error
+
+	// Webhook
+	ListWebhookEndpoints(ctx context.Context, userID uint, includeAll bool) ([]entity.DbWebhookEndpoint, error)
+	GetWebhookEndpoint(ctx context.Context, id uint) (*entity.DbWebhookEndpoint, error)
+	FindWebhookEndpointsForUser(ctx context.Context, userID uint) ([]entity.DbWebhookEndpoint, error)
+	CreateWebhookEndpoint(ctx context.Context, endpoint *entity.DbWebhookEndpoint) error
+	UpdateWebhookEndpoint(ctx context.Context, id uint, updates entity.WebhookEndpointUpdates) error
+	DeleteWebhookEndpoint(ctx context.Context, id uint) error
+	CreateWebhookDeliveries(ctx context.Context, deliveries []entity.DbWebhookDelivery) error
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]entity.DbWebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, id uint, updates entity.WebhookDeliveryUpdates) error
+	ListWebhookDeliveries(ctx context.Context, endpointID uint, limit int) ([]entity.DbWebhookDelivery, error)
+
+	// Prompt template
+	ListPromptTemplates(ctx context.Context, userID uint, includeAll bool) ([]entity.DbPromptTemplate, error)
+	GetPromptTemplate(ctx context.Context, id uint) (*entity.DbPromptTemplate, error)
+	CreatePromptTemplate(ctx context.Context, template *entity.DbPromptTemplate) error
+	UpdatePromptTemplate(ctx context.Context, id uint, updates entity.PromptTemplateUpdates) error
+	DeletePromptTemplate(ctx context.Context, id uint) error
+
+	// Pipeline
+	ListPipelines(ctx context.Context, userID uint, includeAll bool) ([]entity.DbPipeline, error)
+	GetPipeline(ctx context.Context, id uint) (*entity.DbPipeline, error)
+	CreatePipeline(ctx context.Context, pipeline *entity.DbPipeline) error
+	UpdatePipeline(ctx context.Context, id uint, updates entity.PipelineUpdates) error
+	DeletePipeline(ctx context.Context, id uint) error
+	CreatePipelineRun(ctx context.Context, run *entity.DbPipelineRun) error
+	GetPipelineRun(ctx context.Context, id uint) (*entity.DbPipelineRun, error)
+	ListRunningPipelineRuns(ctx context.Context, limit int) ([]entity.DbPipelineRun, error)
+	StartPipelineStep(ctx context.Context, runID uint, step int, record *entity.DbUsageRecord, job *entity.DbGenerationJob, estimate float64, enforceBalance bool) (bool, error)
+	FinishPipelineRun(ctx context.Context, runID uint, status string, errMsg string) (bool, error)
+	ListPipelineRunRecords(ctx context.Context, runID uint) ([]entity.DbUsageRecord, error)
+
+	// 额度与配额
+	GetCreditAccount(ctx context.Context, userID uint) (*entity.DbCreditAccount, error)
+	GrantCredits(ctx context.Context, entry *entity.DbCreditLedgerEntry) error
+	ReserveCredits(ctx context.Context, entry *entity.DbCreditLedgerEntry, enforceBalance bool) error
+	SettleCredits(ctx context.Context, recordID uint, charge *float64) error
+	ListCreditLedger(ctx context.Context, userID uint, limit int) ([]entity.DbCreditLedgerEntry, error)
+	ListCreditQuotas(ctx context.Context) ([]entity.DbCreditQuota, error)
+	GetCreditQuota(ctx context.Context, id uint) (*entity.DbCreditQuota, error)
+	CreateCreditQuota(ctx context.Context, quota *entity.DbCreditQuota) error
+	UpdateCreditQuota(ctx context.Context, id uint, updates entity.CreditQuotaUpdates) error
+	DeleteCreditQuota(ctx context.Context, id uint) error
 }
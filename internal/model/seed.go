@@ -168,18 +168,21 @@ func buildDefaultProviderSeeds(cfg config.Config) []providerSeed {
 					ModelID:  "gemini-2.5-flash-image-preview",
 					Name:     "Gemini 2.5 Flash Image Preview",
 					Price:    "0.03/IMG",
+					Pricing:  entity.ModelPricing{PerImage: 0.03},
 					IsActive: true,
 				},
 				{
 					ModelID:  "imagen-4.0-fast-generate-001",
 					Name:     "Imagen 4.0 Fast Generate",
 					Price:    "0.03/IMG",
+					Pricing:  entity.ModelPricing{PerImage: 0.03},
 					IsActive: true,
 				},
 				{
 					ModelID:  "gpt-4o-image",
 					Name:     "GPT-4o Image",
 					Price:    "0.005/IMG",
+					Pricing:  entity.ModelPricing{PerImage: 0.005},
 					IsActive: true,
 				},
 			},
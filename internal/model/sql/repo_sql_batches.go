@@ -0,0 +1,144 @@
+package sql
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreateGenerationBatch inserts a batch together with its child usage records and generation jobs.
+// records, jobs and estimates are matched by index; their IDs and batch/record references are filled in place.
+// The estimated cost of every record is reserved in the same transaction (see ReserveCredits), so a batch
+// the user's balance or quotas cannot cover is rejected as a whole.
+func (r *GormRepository) CreateGenerationBatch(ctx context.Context, batch *entity.DbGenerationBatch, records []entity.DbUsageRecord, jobs []entity.DbGenerationJob, estimates []float64, tagIDs []uint, enforceBalance bool) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if batch == nil {
+		return fmt.Errorf("batch is nil")
+	}
+	if len(records) == 0 || len(records) != len(jobs) || len(records) != len(estimates) {
+		return fmt.Errorf("batch records, jobs and estimates mismatch")
+	}
+	if batch.Status == "" {
+		batch.Status = entity.GenerationBatchStatusRunning
+	}
+	batch.Total = len(records)
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+
+		batchID := batch.ID
+		for i := range records {
+			records[i].BatchID = &batchID
+			if err := tx.Omit("Tags").Create(&records[i]).Error; err != nil {
+				return err
+			}
+			if err := reserveCredits(tx, recordReservation(records[i], estimates[i]), enforceBalance); err != nil {
+				return err
+			}
+
+			if len(tagIDs) > 0 {
+				links := make([]entity.DbUsageRecordTag, 0, len(tagIDs))
+				for _, tagID := range tagIDs {
+					links = append(links, entity.DbUsageRecordTag{UsageRecordID: records[i].ID, TagID: tagID})
+				}
+				if err := tx.Create(&links).Error; err != nil {
+					return err
+				}
+			}
+
+			jobs[i].RecordID = records[i].ID
+			jobs[i].BatchID = &batchID
+			if jobs[i].Status == "" {
+				jobs[i].Status = entity.GenerationJobStatusPending
+			}
+			if err := tx.Create(&jobs[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetGenerationBatch fetches a batch by id.
+func (r *GormRepository) GetGenerationBatch(ctx context.Context, id uint) (*entity.DbGenerationBatch, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return nil, fmt.Errorf("invalid batch id")
+	}
+
+	var batch entity.DbGenerationBatch
+	if err := r.db.WithContext(ctx).First(&batch, id).Error; err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// CountGenerationBatchRecords returns the number of child usage records per status.
+func (r *GormRepository) CountGenerationBatchRecords(ctx context.Context, batchID uint) (map[string]int64, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&entity.DbUsageRecord{}).
+		Select("status, COUNT(*) AS count").
+		Where("batch_id = ?", batchID).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// CompleteGenerationBatch marks a running batch as completed once none of its jobs are pending or running.
+// It reports whether this call performed the transition, so completion is announced exactly once.
+func (r *GormRepository) CompleteGenerationBatch(ctx context.Context, batchID uint) (bool, error) {
+	if r == nil || r.db == nil {
+		return false, fmt.Errorf("repository not initialised")
+	}
+	if batchID == 0 {
+		return false, fmt.Errorf("invalid batch id")
+	}
+
+	var active int64
+	if err := r.db.WithContext(ctx).
+		Model(&entity.DbGenerationJob{}).
+		Where("batch_id = ? AND status IN ?", batchID, []string{entity.GenerationJobStatusPending, entity.GenerationJobStatusRunning}).
+		Count(&active).Error; err != nil {
+		return false, err
+	}
+	if active > 0 {
+		return false, nil
+	}
+
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&entity.DbGenerationBatch{}).
+		Where("id = ? AND status = ?", batchID, entity.GenerationBatchStatusRunning).
+		Updates(map[string]interface{}{
+			"status":      entity.GenerationBatchStatusCompleted,
+			"finished_at": &now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,330 @@
+package sql
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetCreditAccount returns the credit account of a user; users without an account have a zero balance.
+func (r *GormRepository) GetCreditAccount(ctx context.Context, userID uint) (*entity.DbCreditAccount, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if userID == 0 {
+		return nil, fmt.Errorf("invalid user id")
+	}
+
+	var account entity.DbCreditAccount
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &entity.DbCreditAccount{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// GrantCredits applies a grant (or, with a negative amount, a deduction) to the user's balance
+// and appends it to the ledger. BalanceAfter is filled in on the entry.
+func (r *GormRepository) GrantCredits(ctx context.Context, entry *entity.DbCreditLedgerEntry) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if entry == nil || entry.UserID == 0 {
+		return fmt.Errorf("invalid ledger entry")
+	}
+
+	entry.Type = entity.CreditEntryTypeGrant
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		balance, err := adjustCreditBalance(tx, entry.UserID, entry.Amount, false)
+		if err != nil {
+			return err
+		}
+		entry.BalanceAfter = balance
+		return tx.Create(entry).Error
+	})
+}
+
+// ReserveCredits deducts the estimated cost of a generation (entry.Amount, positive) from the user's balance
+// and appends the reservation to the ledger, where it is recorded as a negative amount.
+// It fails with entity.ErrInsufficientCredits when enforceBalance is set and the balance does not cover
+// the amount, and with *entity.QuotaExceededError when the reservation would exceed one of the user's
+// quotas for the entry's provider and model.
+func (r *GormRepository) ReserveCredits(ctx context.Context, entry *entity.DbCreditLedgerEntry, enforceBalance bool) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if entry == nil || entry.UserID == 0 || entry.RecordID == nil {
+		return fmt.Errorf("invalid ledger entry")
+	}
+	if entry.Amount < 0 {
+		return fmt.Errorf("reservation amount must not be negative")
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return reserveCredits(tx, entry, enforceBalance)
+	})
+}
+
+// reserveCredits records a reservation (see ReserveCredits) inside tx, so that the reservations of several
+// records created in one transaction succeed or fail together.
+func reserveCredits(tx *gorm.DB, entry *entity.DbCreditLedgerEntry, enforceBalance bool) error {
+	amount := entry.Amount
+	entry.Type = entity.CreditEntryTypeReserve
+	entry.Amount = -amount
+
+	// updating the account first locks it, so concurrent reservations of the same user are checked one at a time
+	balance, err := adjustCreditBalance(tx, entry.UserID, -amount, enforceBalance)
+	if err != nil {
+		return err
+	}
+
+	var quotas []entity.DbCreditQuota
+	if err := tx.Where("user_id IN ?", []uint{0, entry.UserID}).
+		Where("provider_id = '' OR provider_id = ?", entry.ProviderID).
+		Where("model_id = '' OR model_id = ?", entry.ModelID).
+		Order("id ASC").
+		Find(&quotas).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, quota := range quotas {
+		spent, err := creditQuotaSpent(tx, entry.UserID, quota, now)
+		if err != nil {
+			return err
+		}
+		if spent+amount > quota.Limit {
+			return &entity.QuotaExceededError{Quota: quota, Spent: spent}
+		}
+	}
+
+	entry.BalanceAfter = balance
+	return tx.Create(entry).Error
+}
+
+// recordReservation builds the reservation of the estimated cost of a newly created usage record.
+func recordReservation(record entity.DbUsageRecord, estimate float64) *entity.DbCreditLedgerEntry {
+	recordID := record.ID
+	return &entity.DbCreditLedgerEntry{
+		UserID:     record.UserID,
+		Amount:     max(estimate, 0),
+		RecordID:   &recordID,
+		ProviderID: record.ProviderID,
+		ModelID:    record.ModelID,
+	}
+}
+
+// SettleCredits settles the reservation of a finished generation: charge is the actual cost (nil keeps the
+// reserved amount) and the difference is returned to the balance. Records without a reservation and records
+// that were already settled are left untouched.
+func (r *GormRepository) SettleCredits(ctx context.Context, recordID uint, charge *float64) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if recordID == 0 {
+		return fmt.Errorf("invalid usage record id")
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var entries []entity.DbCreditLedgerEntry
+		if err := tx.Where("record_id = ?", recordID).Find(&entries).Error; err != nil {
+			return err
+		}
+		var reservation *entity.DbCreditLedgerEntry
+		for i := range entries {
+			switch entries[i].Type {
+			case entity.CreditEntryTypeSettle:
+				return nil
+			case entity.CreditEntryTypeReserve:
+				reservation = &entries[i]
+			}
+		}
+		if reservation == nil {
+			return nil
+		}
+
+		reserved := -reservation.Amount
+		actual := reserved
+		if charge != nil {
+			actual = *charge
+		}
+		refund := reserved - actual
+
+		balance, err := adjustCreditBalance(tx, reservation.UserID, refund, false)
+		if err != nil {
+			return err
+		}
+		// the unique (record_id, type) index rejects a concurrent second settlement
+		return tx.Create(&entity.DbCreditLedgerEntry{
+			UserID:       reservation.UserID,
+			Type:         entity.CreditEntryTypeSettle,
+			Amount:       refund,
+			BalanceAfter: balance,
+			RecordID:     &recordID,
+			ProviderID:   reservation.ProviderID,
+			ModelID:      reservation.ModelID,
+		}).Error
+	})
+}
+
+// ListCreditLedger returns the most recent ledger entries of a user, newest first.
+func (r *GormRepository) ListCreditLedger(ctx context.Context, userID uint, limit int) ([]entity.DbCreditLedgerEntry, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var entries []entity.DbCreditLedgerEntry
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ListCreditQuotas returns all configured quotas.
+func (r *GormRepository) ListCreditQuotas(ctx context.Context) ([]entity.DbCreditQuota, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+
+	var quotas []entity.DbCreditQuota
+	if err := r.db.WithContext(ctx).Order("user_id ASC, id ASC").Find(&quotas).Error; err != nil {
+		return nil, err
+	}
+	return quotas, nil
+}
+
+// GetCreditQuota fetches a quota by id.
+func (r *GormRepository) GetCreditQuota(ctx context.Context, id uint) (*entity.DbCreditQuota, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return nil, fmt.Errorf("invalid quota id")
+	}
+
+	var quota entity.DbCreditQuota
+	if err := r.db.WithContext(ctx).First(&quota, id).Error; err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// CreateCreditQuota inserts a new quota.
+func (r *GormRepository) CreateCreditQuota(ctx context.Context, quota *entity.DbCreditQuota) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if quota == nil {
+		return fmt.Errorf("quota is nil")
+	}
+	return r.db.WithContext(ctx).Create(quota).Error
+}
+
+// UpdateCreditQuota updates the period or limit of a quota.
+func (r *GormRepository) UpdateCreditQuota(ctx context.Context, id uint, updates entity.CreditQuotaUpdates) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return fmt.Errorf("invalid quota id")
+	}
+	m := updates.ToMap()
+	if len(m) == 0 {
+		return nil
+	}
+
+	result := r.db.WithContext(ctx).Model(&entity.DbCreditQuota{}).Where("id = ?", id).Updates(m)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteCreditQuota removes a quota.
+func (r *GormRepository) DeleteCreditQuota(ctx context.Context, id uint) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return fmt.Errorf("invalid quota id")
+	}
+
+	result := r.db.WithContext(ctx).Delete(&entity.DbCreditQuota{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// adjustCreditBalance adds delta to the user's balance, creating the account on first use, and returns the
+// new balance. With requireCover set the update only applies while the balance stays non-negative.
+func adjustCreditBalance(tx *gorm.DB, userID uint, delta float64, requireCover bool) (float64, error) {
+	account := entity.DbCreditAccount{UserID: userID}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error; err != nil {
+		return 0, err
+	}
+
+	query := tx.Model(&entity.DbCreditAccount{}).Where("user_id = ?", userID)
+	if requireCover && delta < 0 {
+		query = query.Where("balance >= ?", -delta)
+	}
+	result := query.Updates(map[string]interface{}{
+		"balance":    gorm.Expr("balance + ?", delta),
+		"updated_at": time.Now(),
+	})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, entity.ErrInsufficientCredits
+	}
+
+	if err := tx.Where("user_id = ?", userID).First(&account).Error; err != nil {
+		return 0, err
+	}
+	return account.Balance, nil
+}
+
+// creditQuotaSpent sums what the user spent within the quota's current period on the quota's scope,
+// counting open reservations at their reserved amount. A settlement counts in the period of its
+// reservation, so a refund settled after the period rolled over never makes the new period negative.
+func creditQuotaSpent(tx *gorm.DB, userID uint, quota entity.DbCreditQuota, now time.Time) (float64, error) {
+	reserved := tx.Model(&entity.DbCreditLedgerEntry{}).
+		Select("record_id").
+		Where("user_id = ? AND type = ? AND created_at >= ?", userID, entity.CreditEntryTypeReserve, quota.PeriodStart(now))
+	if quota.ProviderID != "" {
+		reserved = reserved.Where("provider_id = ?", quota.ProviderID)
+	}
+	if quota.ModelID != "" {
+		reserved = reserved.Where("model_id = ?", quota.ModelID)
+	}
+	query := tx.Model(&entity.DbCreditLedgerEntry{}).
+		Where("user_id = ? AND type IN ? AND record_id IN (?)", userID,
+			[]string{entity.CreditEntryTypeReserve, entity.CreditEntryTypeSettle}, reserved)
+
+	var total float64
+	if err := query.Select("COALESCE(SUM(amount), 0)").Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	return -total, nil
+}
@@ -0,0 +1,112 @@
+package sql
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ListFailoverChains returns all failover chains ordered by their primary provider/model.
+func (r *GormRepository) ListFailoverChains(ctx context.Context) ([]entity.DbFailoverChain, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+
+	var chains []entity.DbFailoverChain
+	if err := r.db.WithContext(ctx).
+		Order("provider_id ASC").
+		Order("model_id ASC").
+		Find(&chains).Error; err != nil {
+		return nil, err
+	}
+	return chains, nil
+}
+
+// GetFailoverChain fetches a failover chain by id.
+func (r *GormRepository) GetFailoverChain(ctx context.Context, id uint) (*entity.DbFailoverChain, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return nil, fmt.Errorf("invalid failover chain id")
+	}
+
+	var chain entity.DbFailoverChain
+	if err := r.db.WithContext(ctx).First(&chain, id).Error; err != nil {
+		return nil, err
+	}
+	return &chain, nil
+}
+
+// FindActiveFailoverChain returns the active chain whose primary is the given provider/model.
+// gorm.ErrRecordNotFound is returned when no active chain is configured.
+func (r *GormRepository) FindActiveFailoverChain(ctx context.Context, providerID, modelID string) (*entity.DbFailoverChain, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+
+	var chain entity.DbFailoverChain
+	err := r.db.WithContext(ctx).
+		Where("provider_id = ? AND model_id = ? AND is_active = ?", strings.TrimSpace(providerID), strings.TrimSpace(modelID), true).
+		First(&chain).Error
+	if err != nil {
+		return nil, err
+	}
+	return &chain, nil
+}
+
+// CreateFailoverChain inserts a new failover chain.
+func (r *GormRepository) CreateFailoverChain(ctx context.Context, chain *entity.DbFailoverChain) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if chain == nil {
+		return fmt.Errorf("failover chain is nil")
+	}
+	return r.db.WithContext(ctx).Create(chain).Error
+}
+
+// UpdateFailoverChain updates failover chain fields.
+func (r *GormRepository) UpdateFailoverChain(ctx context.Context, id uint, updates entity.FailoverChainUpdates) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return fmt.Errorf("invalid failover chain id")
+	}
+	m := updates.ToMap()
+	if len(m) == 0 {
+		return nil
+	}
+
+	result := r.db.WithContext(ctx).Model(&entity.DbFailoverChain{}).Where("id = ?", id).Updates(m)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteFailoverChain removes a failover chain.
+func (r *GormRepository) DeleteFailoverChain(ctx context.Context, id uint) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return fmt.Errorf("invalid failover chain id")
+	}
+
+	result := r.db.WithContext(ctx).Delete(&entity.DbFailoverChain{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
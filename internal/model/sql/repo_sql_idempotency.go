@@ -0,0 +1,104 @@
+package sql
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClaimIdempotencyKey reserves key for its user. Expired keys are replaced, as are keys still
+// unbound to a record pendingTTL after they were claimed (the claiming request died before
+// binding it); pendingTTL <= 0 never reclaims unbound keys.
+// When the key is already held the existing row is returned with claimed=false.
+func (r *GormRepository) ClaimIdempotencyKey(ctx context.Context, key *entity.DbIdempotencyKey, pendingTTL time.Duration) (*entity.DbIdempotencyKey, bool, error) {
+	if r == nil || r.db == nil {
+		return nil, false, fmt.Errorf("repository not initialised")
+	}
+	if key == nil || key.UserID == 0 || strings.TrimSpace(key.Key) == "" {
+		return nil, false, fmt.Errorf("invalid idempotency key")
+	}
+
+	var existing entity.DbIdempotencyKey
+	claimed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		stale := tx.Where("expires_at <= ?", now)
+		if pendingTTL > 0 {
+			stale = stale.Or("record_id = 0 AND created_at <= ?", now.Add(-pendingTTL))
+		}
+		if err := tx.
+			Where("user_id = ? AND idempotency_key = ?", key.UserID, key.Key).
+			Where(stale).
+			Delete(&entity.DbIdempotencyKey{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			claimed = true
+			return nil
+		}
+
+		return tx.Where("user_id = ? AND idempotency_key = ?", key.UserID, key.Key).First(&existing).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if claimed {
+		return key, true, nil
+	}
+	return &existing, false, nil
+}
+
+// BindIdempotencyKey links a claimed key to the usage record created for it.
+func (r *GormRepository) BindIdempotencyKey(ctx context.Context, id uint, recordID uint) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if id == 0 || recordID == 0 {
+		return fmt.Errorf("invalid idempotency key binding")
+	}
+
+	result := r.db.WithContext(ctx).
+		Model(&entity.DbIdempotencyKey{}).
+		Where("id = ?", id).
+		Update("record_id", recordID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteIdempotencyKey releases a key, e.g. when creating its usage record failed.
+func (r *GormRepository) DeleteIdempotencyKey(ctx context.Context, id uint) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return fmt.Errorf("invalid idempotency key id")
+	}
+	return r.db.WithContext(ctx).Delete(&entity.DbIdempotencyKey{}, id).Error
+}
+
+// DeleteExpiredIdempotencyKeys removes keys that expired at or before now.
+func (r *GormRepository) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	if r == nil || r.db == nil {
+		return 0, fmt.Errorf("repository not initialised")
+	}
+
+	result := r.db.WithContext(ctx).
+		Where("expires_at <= ?", now).
+		Delete(&entity.DbIdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
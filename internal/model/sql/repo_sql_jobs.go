@@ -0,0 +1,594 @@
+package sql
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreateGenerationJob inserts a pending generation job.
+func (r *GormRepository) CreateGenerationJob(ctx context.Context, job *entity.DbGenerationJob) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if job == nil {
+		return fmt.Errorf("job is nil")
+	}
+	if job.RecordID == 0 {
+		return fmt.Errorf("invalid usage record id")
+	}
+	if strings.TrimSpace(job.Status) == "" {
+		job.Status = entity.GenerationJobStatusPending
+	}
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+// CreateGeneration inserts a usage record together with its tag links, the reservation of its estimated
+// cost (see ReserveCredits) and its generation job in one transaction, so a record is never left queued
+// without a job. The IDs of record and job are filled in place.
+func (r *GormRepository) CreateGeneration(ctx context.Context, record *entity.DbUsageRecord, job *entity.DbGenerationJob, tagIDs []uint, estimate float64, enforceBalance bool) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if record == nil || job == nil {
+		return fmt.Errorf("record or job is nil")
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tags").Create(record).Error; err != nil {
+			return err
+		}
+		if len(tagIDs) > 0 {
+			links := make([]entity.DbUsageRecordTag, 0, len(tagIDs))
+			for _, tagID := range tagIDs {
+				links = append(links, entity.DbUsageRecordTag{UsageRecordID: record.ID, TagID: tagID})
+			}
+			if err := tx.Create(&links).Error; err != nil {
+				return err
+			}
+		}
+		if err := reserveCredits(tx, recordReservation(*record, estimate), enforceBalance); err != nil {
+			return err
+		}
+
+		job.RecordID = record.ID
+		if strings.TrimSpace(job.Status) == "" {
+			job.Status = entity.GenerationJobStatusPending
+		}
+		return tx.Create(job).Error
+	})
+}
+
+// claimCandidatesPerUser bounds how many claimable jobs of each user and priority lane are considered
+// when scheduling a claim, so one user's large backlog never hides the jobs of other users.
+const claimCandidatesPerUser = 20
+
+// ClaimGenerationJobs leases up to limit jobs that are pending or whose lease has expired.
+// Candidates are claimed in the order given by entity.ScheduleGenerationJobs (priority lanes, then
+// round-robin across users), skipping jobs whose batch or provider has no free concurrency.
+// Each candidate is claimed with a conditional update so concurrent workers never claim the same job.
+func (r *GormRepository) ClaimGenerationJobs(ctx context.Context, owner string, limit int, lease time.Duration) ([]entity.DbGenerationJob, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	owner = strings.TrimSpace(owner)
+	if owner == "" {
+		return nil, fmt.Errorf("lease owner is required")
+	}
+	if limit <= 0 {
+		return []entity.DbGenerationJob{}, nil
+	}
+
+	now := time.Now()
+	batchSlots, err := r.generationBatchSlots(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	providerSlots, err := r.generationProviderSlots(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	running, err := r.runningGenerationJobsByUser(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	candidateQuery := func() *gorm.DB {
+		// 远程任务已提交后被中断的任务由恢复流程继续轮询，不再重新执行
+		query := r.claimableJobs(r.db.WithContext(ctx), now).
+			Where("record_id NOT IN (?)", r.interruptedTaskRecordIDs(ctx))
+		if saturated := saturatedBatches(batchSlots); len(saturated) > 0 {
+			query = query.Where("batch_id IS NULL OR batch_id NOT IN ?", saturated)
+		}
+		if saturated := saturatedProviders(providerSlots); len(saturated) > 0 {
+			query = query.Where("provider_id NOT IN ?", saturated)
+		}
+		return query
+	}
+
+	// the oldest jobs of every user and lane are selected first and then interleaved by the scheduler.
+	// They are loaded with one LIMIT query per lane and user rather than a window function,
+	// which MySQL 5.7 and MariaDB before 10.2 do not support.
+	var lanes []struct {
+		Priority int
+		UserID   uint
+	}
+	if err := candidateQuery().
+		Model(&entity.DbGenerationJob{}).
+		Distinct("priority", "user_id").
+		Find(&lanes).Error; err != nil {
+		return nil, err
+	}
+
+	// payload may hold base64 images, so it is only loaded for the claimed jobs
+	var candidates []entity.DbGenerationJob
+	for _, lane := range lanes {
+		var jobs []entity.DbGenerationJob
+		if err := candidateQuery().
+			Omit("payload").
+			Where("priority = ? AND user_id = ?", lane.Priority, lane.UserID).
+			Order("id ASC").
+			Limit(claimCandidatesPerUser).
+			Find(&jobs).Error; err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, jobs...)
+	}
+
+	claimed := make([]entity.DbGenerationJob, 0, limit)
+	for _, candidate := range entity.ScheduleGenerationJobs(candidates, running) {
+		if len(claimed) >= limit {
+			break
+		}
+		if candidate.BatchID != nil {
+			// 同一批次的候选任务可能超过剩余并发额度
+			if slots, ok := batchSlots[*candidate.BatchID]; ok {
+				if slots <= 0 {
+					continue
+				}
+				batchSlots[*candidate.BatchID] = slots - 1
+			}
+		}
+		if slots, ok := providerSlots[candidate.ProviderID]; ok && slots <= 0 {
+			continue
+		}
+
+		ok, err := r.claimJob(ctx, &candidate, owner, now, lease)
+		if err != nil {
+			return claimed, err
+		}
+		if ok {
+			if slots, limited := providerSlots[candidate.ProviderID]; limited {
+				providerSlots[candidate.ProviderID] = slots - 1
+			}
+			claimed = append(claimed, candidate)
+		}
+	}
+
+	if err := r.loadGenerationJobPayloads(ctx, claimed); err != nil {
+		return claimed, err
+	}
+	return claimed, nil
+}
+
+// ListActiveGenerationJobs returns all pending and running jobs without their payload,
+// ordered by priority lane and submission.
+func (r *GormRepository) ListActiveGenerationJobs(ctx context.Context) ([]entity.DbGenerationJob, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+
+	var jobs []entity.DbGenerationJob
+	if err := r.db.WithContext(ctx).
+		Omit("payload").
+		Where("status IN ?", []string{entity.GenerationJobStatusPending, entity.GenerationJobStatusRunning}).
+		Order("priority DESC, id ASC").
+		Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// ClaimInterruptedGenerationJobs leases up to limit claimable jobs whose generation was interrupted
+// after the provider accepted a remote task, i.e. the record is still running and has an external task code.
+func (r *GormRepository) ClaimInterruptedGenerationJobs(ctx context.Context, owner string, limit int, lease time.Duration) ([]entity.DbGenerationJob, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	owner = strings.TrimSpace(owner)
+	if owner == "" {
+		return nil, fmt.Errorf("lease owner is required")
+	}
+	if limit <= 0 {
+		return []entity.DbGenerationJob{}, nil
+	}
+
+	now := time.Now()
+	var candidates []entity.DbGenerationJob
+	if err := r.claimableJobs(r.db.WithContext(ctx), now).
+		Where("record_id IN (?)", r.interruptedTaskRecordIDs(ctx)).
+		Order("id ASC").
+		Limit(limit).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	claimed := make([]entity.DbGenerationJob, 0, len(candidates))
+	for _, candidate := range candidates {
+		ok, err := r.claimJob(ctx, &candidate, owner, now, lease)
+		if err != nil {
+			return claimed, err
+		}
+		if ok {
+			claimed = append(claimed, candidate)
+		}
+	}
+	return claimed, nil
+}
+
+// ClaimInterruptedGenerationJob leases the job of recordID when its remote task has been submitted
+// but no worker holds a live lease on it. It returns gorm.ErrRecordNotFound when the job is not claimable.
+func (r *GormRepository) ClaimInterruptedGenerationJob(ctx context.Context, recordID uint, owner string, lease time.Duration) (*entity.DbGenerationJob, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	owner = strings.TrimSpace(owner)
+	if owner == "" {
+		return nil, fmt.Errorf("lease owner is required")
+	}
+	if recordID == 0 {
+		return nil, fmt.Errorf("invalid usage record id")
+	}
+
+	now := time.Now()
+	var candidate entity.DbGenerationJob
+	if err := r.claimableJobs(r.db.WithContext(ctx), now).
+		Where("record_id = ? AND record_id IN (?)", recordID, r.interruptedTaskRecordIDs(ctx)).
+		First(&candidate).Error; err != nil {
+		return nil, err
+	}
+
+	ok, err := r.claimJob(ctx, &candidate, owner, now, lease)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &candidate, nil
+}
+
+// claimJob leases a candidate job with a conditional update; it reports false when another worker claimed it first.
+func (r *GormRepository) claimJob(ctx context.Context, candidate *entity.DbGenerationJob, owner string, now time.Time, lease time.Duration) (bool, error) {
+	expiresAt := now.Add(lease)
+	result := r.claimableJobs(r.db.WithContext(ctx).Model(&entity.DbGenerationJob{}), now).
+		Where("id = ?", candidate.ID).
+		Updates(map[string]interface{}{
+			"status":           entity.GenerationJobStatusRunning,
+			"lease_owner":      owner,
+			"lease_expires_at": expiresAt,
+			"attempts":         gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// 已被其他实例认领
+		return false, nil
+	}
+
+	candidate.Status = entity.GenerationJobStatusRunning
+	candidate.LeaseOwner = owner
+	candidate.LeaseExpiresAt = &expiresAt
+	candidate.Attempts++
+	return true, nil
+}
+
+// interruptedTaskRecordIDs selects running usage records that already have an external task code.
+func (r *GormRepository) interruptedTaskRecordIDs(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Model(&entity.DbUsageRecord{}).
+		Select("id").
+		Where("status = ? AND external_task_code <> ''", entity.UsageRecordStatusRunning)
+}
+
+func (r *GormRepository) claimableJobs(query *gorm.DB, now time.Time) *gorm.DB {
+	return query.Where(
+		"status = ? OR (status = ? AND (lease_expires_at IS NULL OR lease_expires_at < ?))",
+		entity.GenerationJobStatusPending,
+		entity.GenerationJobStatusRunning,
+		now,
+	)
+}
+
+// generationBatchSlots returns, for every running batch, how many more of its jobs may be leased.
+func (r *GormRepository) generationBatchSlots(ctx context.Context, now time.Time) (map[uint]int, error) {
+	type batchLoad struct {
+		ID             uint
+		MaxConcurrency int
+		Running        int
+	}
+
+	var loads []batchLoad
+	if err := r.db.WithContext(ctx).
+		Table("generation_batches").
+		Select("generation_batches.id, generation_batches.max_concurrency, COUNT(generation_jobs.id) AS running").
+		Joins("LEFT JOIN generation_jobs ON generation_jobs.batch_id = generation_batches.id AND generation_jobs.status = ? AND generation_jobs.lease_expires_at >= ?",
+			entity.GenerationJobStatusRunning, now).
+		Where("generation_batches.status = ?", entity.GenerationBatchStatusRunning).
+		Group("generation_batches.id, generation_batches.max_concurrency").
+		Scan(&loads).Error; err != nil {
+		return nil, err
+	}
+
+	slots := make(map[uint]int, len(loads))
+	for _, load := range loads {
+		limit := load.MaxConcurrency
+		if limit <= 0 {
+			limit = 1
+		}
+		slots[load.ID] = limit - load.Running
+	}
+	return slots, nil
+}
+
+// generationProviderSlots returns, for every provider with a max_concurrent_jobs limit, how many more of its jobs may be leased.
+func (r *GormRepository) generationProviderSlots(ctx context.Context, now time.Time) (map[string]int, error) {
+	var providers []entity.DbProvider
+	if err := r.db.WithContext(ctx).Select("id", "config").Find(&providers).Error; err != nil {
+		return nil, err
+	}
+
+	slots := make(map[string]int)
+	for i := range providers {
+		if limit := providers[i].MaxConcurrentJobs(); limit > 0 {
+			slots[providers[i].ID] = limit
+		}
+	}
+	if len(slots) == 0 {
+		return slots, nil
+	}
+
+	type providerLoad struct {
+		ProviderID string
+		Running    int
+	}
+	var loads []providerLoad
+	if err := r.db.WithContext(ctx).
+		Model(&entity.DbGenerationJob{}).
+		Select("provider_id, COUNT(id) AS running").
+		Where("status = ? AND lease_expires_at >= ?", entity.GenerationJobStatusRunning, now).
+		Group("provider_id").
+		Scan(&loads).Error; err != nil {
+		return nil, err
+	}
+	for _, load := range loads {
+		if limit, ok := slots[load.ProviderID]; ok {
+			slots[load.ProviderID] = limit - load.Running
+		}
+	}
+	return slots, nil
+}
+
+func saturatedProviders(slots map[string]int) []string {
+	saturated := make([]string, 0)
+	for id, remaining := range slots {
+		if remaining <= 0 {
+			saturated = append(saturated, id)
+		}
+	}
+	return saturated
+}
+
+// runningGenerationJobsByUser counts the jobs currently leased for each user.
+func (r *GormRepository) runningGenerationJobsByUser(ctx context.Context, now time.Time) (map[uint]int, error) {
+	type userLoad struct {
+		UserID  uint
+		Running int
+	}
+	var loads []userLoad
+	if err := r.db.WithContext(ctx).
+		Model(&entity.DbGenerationJob{}).
+		Select("user_id, COUNT(id) AS running").
+		Where("status = ? AND lease_expires_at >= ?", entity.GenerationJobStatusRunning, now).
+		Group("user_id").
+		Scan(&loads).Error; err != nil {
+		return nil, err
+	}
+
+	running := make(map[uint]int, len(loads))
+	for _, load := range loads {
+		running[load.UserID] = load.Running
+	}
+	return running, nil
+}
+
+// loadGenerationJobPayloads fills in the payload of jobs loaded without it.
+func (r *GormRepository) loadGenerationJobPayloads(ctx context.Context, jobs []entity.DbGenerationJob) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(jobs))
+	for _, job := range jobs {
+		ids = append(ids, job.ID)
+	}
+
+	var payloads []entity.DbGenerationJob
+	if err := r.db.WithContext(ctx).Select("id", "payload").Where("id IN ?", ids).Find(&payloads).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]string, len(payloads))
+	for _, payload := range payloads {
+		byID[payload.ID] = payload.Payload
+	}
+	for i := range jobs {
+		jobs[i].Payload = byID[jobs[i].ID]
+	}
+	return nil
+}
+
+func saturatedBatches(slots map[uint]int) []uint {
+	saturated := make([]uint, 0)
+	for id, remaining := range slots {
+		if remaining <= 0 {
+			saturated = append(saturated, id)
+		}
+	}
+	return saturated
+}
+
+// RenewGenerationJobLease extends the lease of a running job held by owner.
+// It reports whether cancellation has been requested for the job.
+func (r *GormRepository) RenewGenerationJobLease(ctx context.Context, id uint, owner string, lease time.Duration) (bool, error) {
+	if r == nil || r.db == nil {
+		return false, fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return false, fmt.Errorf("invalid generation job id")
+	}
+
+	result := r.db.WithContext(ctx).
+		Model(&entity.DbGenerationJob{}).
+		Where("id = ? AND lease_owner = ? AND status = ?", id, owner, entity.GenerationJobStatusRunning).
+		Update("lease_expires_at", time.Now().Add(lease))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, gorm.ErrRecordNotFound
+	}
+
+	var job entity.DbGenerationJob
+	if err := r.db.WithContext(ctx).Select("id", "cancel_requested").First(&job, id).Error; err != nil {
+		return false, err
+	}
+	return job.CancelRequested, nil
+}
+
+// ReleaseGenerationJobs returns running jobs held by owner to the pending state.
+// It is used at startup to immediately resume jobs interrupted by a restart of the same worker.
+func (r *GormRepository) ReleaseGenerationJobs(ctx context.Context, owner string) (int64, error) {
+	if r == nil || r.db == nil {
+		return 0, fmt.Errorf("repository not initialised")
+	}
+	owner = strings.TrimSpace(owner)
+	if owner == "" {
+		return 0, nil
+	}
+
+	result := r.db.WithContext(ctx).
+		Model(&entity.DbGenerationJob{}).
+		Where("lease_owner = ? AND status = ?", owner, entity.GenerationJobStatusRunning).
+		Updates(map[string]interface{}{
+			"status":           entity.GenerationJobStatusPending,
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// FinishGenerationJob marks a job held by owner as finished with the given terminal status.
+// It returns gorm.ErrRecordNotFound when the lease has passed to another owner, so a worker
+// whose lease expired cannot overwrite the result of the worker that reclaimed the job.
+func (r *GormRepository) FinishGenerationJob(ctx context.Context, id uint, owner string, status string, lastError string) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return fmt.Errorf("invalid generation job id")
+	}
+
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&entity.DbGenerationJob{}).
+		Where("id = ? AND lease_owner = ?", id, owner).
+		Updates(map[string]interface{}{
+			"status":           status,
+			"last_error":       lastError,
+			"lease_expires_at": nil,
+			"finished_at":      &now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CancelPendingGenerationJob cancels the job of a record that has not been claimed yet.
+// It returns gorm.ErrRecordNotFound when the record has no pending job.
+func (r *GormRepository) CancelPendingGenerationJob(ctx context.Context, recordID uint) (*entity.DbGenerationJob, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if recordID == 0 {
+		return nil, fmt.Errorf("invalid usage record id")
+	}
+
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&entity.DbGenerationJob{}).
+		Where("record_id = ? AND status = ?", recordID, entity.GenerationJobStatusPending).
+		Updates(map[string]interface{}{
+			"status":           entity.GenerationJobStatusCancelled,
+			"cancel_requested": true,
+			"finished_at":      &now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.getGenerationJobByRecord(ctx, recordID)
+}
+
+// RequestGenerationJobCancel flags the running job of a record for cancellation.
+// The worker holding the lease observes the flag when renewing it.
+// It returns gorm.ErrRecordNotFound when the record has no running job.
+func (r *GormRepository) RequestGenerationJobCancel(ctx context.Context, recordID uint) (*entity.DbGenerationJob, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if recordID == 0 {
+		return nil, fmt.Errorf("invalid usage record id")
+	}
+
+	result := r.db.WithContext(ctx).
+		Model(&entity.DbGenerationJob{}).
+		Where("record_id = ? AND status = ?", recordID, entity.GenerationJobStatusRunning).
+		Update("cancel_requested", true)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.getGenerationJobByRecord(ctx, recordID)
+}
+
+// GetGenerationJobByRecord returns the job of a usage record, including its payload.
+// It returns gorm.ErrRecordNotFound when the record was created without a job.
+func (r *GormRepository) GetGenerationJobByRecord(ctx context.Context, recordID uint) (*entity.DbGenerationJob, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if recordID == 0 {
+		return nil, fmt.Errorf("invalid usage record id")
+	}
+	return r.getGenerationJobByRecord(ctx, recordID)
+}
+
+func (r *GormRepository) getGenerationJobByRecord(ctx context.Context, recordID uint) (*entity.DbGenerationJob, error) {
+	var job entity.DbGenerationJob
+	if err := r.db.WithContext(ctx).Where("record_id = ?", recordID).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
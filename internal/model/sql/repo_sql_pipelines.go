@@ -0,0 +1,245 @@
+package sql
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ListPipelines returns the pipelines owned by userID, or all pipelines when includeAll is set.
+func (r *GormRepository) ListPipelines(ctx context.Context, userID uint, includeAll bool) ([]entity.DbPipeline, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+
+	query := r.db.WithContext(ctx).Model(&entity.DbPipeline{})
+	if !includeAll {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var pipelines []entity.DbPipeline
+	if err := query.Order("id ASC").Find(&pipelines).Error; err != nil {
+		return nil, err
+	}
+	return pipelines, nil
+}
+
+// GetPipeline fetches a pipeline by id.
+func (r *GormRepository) GetPipeline(ctx context.Context, id uint) (*entity.DbPipeline, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return nil, fmt.Errorf("invalid pipeline id")
+	}
+
+	var pipeline entity.DbPipeline
+	if err := r.db.WithContext(ctx).First(&pipeline, id).Error; err != nil {
+		return nil, err
+	}
+	return &pipeline, nil
+}
+
+// CreatePipeline inserts a new pipeline.
+func (r *GormRepository) CreatePipeline(ctx context.Context, pipeline *entity.DbPipeline) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if pipeline == nil {
+		return fmt.Errorf("pipeline is nil")
+	}
+	return r.db.WithContext(ctx).Create(pipeline).Error
+}
+
+// UpdatePipeline updates pipeline fields. Runs already started keep their own step snapshot.
+func (r *GormRepository) UpdatePipeline(ctx context.Context, id uint, updates entity.PipelineUpdates) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return fmt.Errorf("invalid pipeline id")
+	}
+	m := updates.ToMap()
+	if len(m) == 0 {
+		return nil
+	}
+
+	result := r.db.WithContext(ctx).Model(&entity.DbPipeline{}).Where("id = ?", id).Updates(m)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeletePipeline removes a pipeline definition. Its runs and their usage records are kept.
+func (r *GormRepository) DeletePipeline(ctx context.Context, id uint) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return fmt.Errorf("invalid pipeline id")
+	}
+
+	result := r.db.WithContext(ctx).Delete(&entity.DbPipeline{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CreatePipelineRun inserts a new pipeline run; its first step is started with StartPipelineStep.
+func (r *GormRepository) CreatePipelineRun(ctx context.Context, run *entity.DbPipelineRun) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if run == nil {
+		return fmt.Errorf("pipeline run is nil")
+	}
+	if run.Status == "" {
+		run.Status = entity.PipelineRunStatusRunning
+	}
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// GetPipelineRun fetches a pipeline run by id.
+func (r *GormRepository) GetPipelineRun(ctx context.Context, id uint) (*entity.DbPipelineRun, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return nil, fmt.Errorf("invalid pipeline run id")
+	}
+
+	var run entity.DbPipelineRun
+	if err := r.db.WithContext(ctx).First(&run, id).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListRunningPipelineRuns returns up to limit running pipeline runs, least recently advanced first.
+func (r *GormRepository) ListRunningPipelineRuns(ctx context.Context, limit int) ([]entity.DbPipelineRun, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	var runs []entity.DbPipelineRun
+	if err := r.db.WithContext(ctx).
+		Omit("input_media").
+		Where("status = ?", entity.PipelineRunStatusRunning).
+		Order("updated_at ASC").
+		Limit(limit).
+		Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// StartPipelineStep advances a running pipeline run from step-1 to step and inserts the step's usage
+// record and generation job in the same transaction, reserving the step's estimated cost (see ReserveCredits).
+// It reports false when the run is no longer running or another instance already advanced it.
+func (r *GormRepository) StartPipelineStep(ctx context.Context, runID uint, step int, record *entity.DbUsageRecord, job *entity.DbGenerationJob, estimate float64, enforceBalance bool) (bool, error) {
+	if r == nil || r.db == nil {
+		return false, fmt.Errorf("repository not initialised")
+	}
+	if runID == 0 || step <= 0 {
+		return false, fmt.Errorf("invalid pipeline step")
+	}
+	if record == nil || job == nil {
+		return false, fmt.Errorf("pipeline step record or job is nil")
+	}
+
+	started := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.DbPipelineRun{}).
+			Where("id = ? AND status = ? AND current_step = ?", runID, entity.PipelineRunStatusRunning, step-1).
+			Updates(map[string]interface{}{
+				"current_step": step,
+				"updated_at":   time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		record.PipelineRunID = &runID
+		record.PipelineStep = step
+		if err := tx.Omit("Tags").Create(record).Error; err != nil {
+			return err
+		}
+		if err := reserveCredits(tx, recordReservation(*record, estimate), enforceBalance); err != nil {
+			return err
+		}
+
+		job.RecordID = record.ID
+		job.PipelineRunID = &runID
+		if job.Status == "" {
+			job.Status = entity.GenerationJobStatusPending
+		}
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		started = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return started, nil
+}
+
+// FinishPipelineRun moves a running pipeline run to a terminal status.
+// It reports whether this call finished the run.
+func (r *GormRepository) FinishPipelineRun(ctx context.Context, runID uint, status string, errMsg string) (bool, error) {
+	if r == nil || r.db == nil {
+		return false, fmt.Errorf("repository not initialised")
+	}
+	if runID == 0 {
+		return false, fmt.Errorf("invalid pipeline run id")
+	}
+
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&entity.DbPipelineRun{}).
+		Where("id = ? AND status = ?", runID, entity.PipelineRunStatusRunning).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"error_message": errMsg,
+			"finished_at":   now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ListPipelineRunRecords returns the usage records of a pipeline run in step order.
+func (r *GormRepository) ListPipelineRunRecords(ctx context.Context, runID uint) ([]entity.DbUsageRecord, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+
+	var records []entity.DbUsageRecord
+	if err := r.db.WithContext(ctx).
+		Preload("User").
+		Preload("Tags").
+		Where("pipeline_run_id = ?", runID).
+		Order("pipeline_step ASC, id ASC").
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,96 @@
+package sql
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ListPromptTemplates returns the templates owned by userID plus shared ones, or all templates when includeAll is set.
+func (r *GormRepository) ListPromptTemplates(ctx context.Context, userID uint, includeAll bool) ([]entity.DbPromptTemplate, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+
+	query := r.db.WithContext(ctx).Model(&entity.DbPromptTemplate{})
+	if !includeAll {
+		query = query.Where("user_id = ? OR shared = ?", userID, true)
+	}
+
+	var templates []entity.DbPromptTemplate
+	if err := query.Order("id ASC").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// GetPromptTemplate fetches a prompt template by id.
+func (r *GormRepository) GetPromptTemplate(ctx context.Context, id uint) (*entity.DbPromptTemplate, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return nil, fmt.Errorf("invalid prompt template id")
+	}
+
+	var template entity.DbPromptTemplate
+	if err := r.db.WithContext(ctx).First(&template, id).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// CreatePromptTemplate inserts a new prompt template.
+func (r *GormRepository) CreatePromptTemplate(ctx context.Context, template *entity.DbPromptTemplate) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if template == nil {
+		return fmt.Errorf("prompt template is nil")
+	}
+	return r.db.WithContext(ctx).Create(template).Error
+}
+
+// UpdatePromptTemplate updates prompt template fields.
+func (r *GormRepository) UpdatePromptTemplate(ctx context.Context, id uint, updates entity.PromptTemplateUpdates) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return fmt.Errorf("invalid prompt template id")
+	}
+	m := updates.ToMap()
+	if len(m) == 0 {
+		return nil
+	}
+
+	result := r.db.WithContext(ctx).Model(&entity.DbPromptTemplate{}).Where("id = ?", id).Updates(m)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeletePromptTemplate removes a prompt template. Usage records keep their template id and rendered prompt.
+func (r *GormRepository) DeletePromptTemplate(ctx context.Context, id uint) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return fmt.Errorf("invalid prompt template id")
+	}
+
+	result := r.db.WithContext(ctx).Delete(&entity.DbPromptTemplate{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package sql
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CreateProviderHealthCheck appends a provider health check result.
+func (r *GormRepository) CreateProviderHealthCheck(ctx context.Context, check *entity.DbProviderHealthCheck) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if check == nil || strings.TrimSpace(check.ProviderID) == "" {
+		return fmt.Errorf("invalid provider health check")
+	}
+	return r.db.WithContext(ctx).Create(check).Error
+}
+
+// ListProviderHealthChecks returns the most recent health checks of a provider, newest first.
+func (r *GormRepository) ListProviderHealthChecks(ctx context.Context, providerID string, limit int) ([]entity.DbProviderHealthCheck, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	var checks []entity.DbProviderHealthCheck
+	if err := r.db.WithContext(ctx).
+		Where("provider_id = ?", providerID).
+		Order("created_at DESC").
+		Order("id DESC").
+		Limit(limit).
+		Find(&checks).Error; err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// DeleteProviderHealthChecksBefore removes health checks recorded before the cutoff.
+func (r *GormRepository) DeleteProviderHealthChecksBefore(ctx context.Context, before time.Time) (int64, error) {
+	if r == nil || r.db == nil {
+		return 0, fmt.Errorf("repository not initialised")
+	}
+
+	result := r.db.WithContext(ctx).
+		Where("created_at < ?", before).
+		Delete(&entity.DbProviderHealthCheck{})
+	return result.RowsAffected, result.Error
+}
+
+// ClaimProviderHealthCheck leases the scheduled health check of a provider for the given duration.
+// It reports false when another instance holds an unexpired lease, so that only one of several
+// instances checks each provider per interval.
+func (r *GormRepository) ClaimProviderHealthCheck(ctx context.Context, providerID string, lease time.Duration) (bool, error) {
+	if r == nil || r.db == nil {
+		return false, fmt.Errorf("repository not initialised")
+	}
+	providerID = strings.TrimSpace(providerID)
+	if providerID == "" {
+		return false, fmt.Errorf("provider id is required")
+	}
+
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&entity.DbProvider{}).
+		Where("id = ? AND (health_check_lease_until IS NULL OR health_check_lease_until <= ?)", providerID, now).
+		UpdateColumn("health_check_lease_until", now.Add(lease))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
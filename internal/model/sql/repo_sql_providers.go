@@ -29,6 +29,9 @@ func (r *GormRepository) CreateProvider(ctx context.Context, provider *entity.Db
 	if provider.Name == "" {
 		return fmt.Errorf("provider name is required")
 	}
+	if provider.ConfigVersion == 0 {
+		provider.ConfigVersion = 1
+	}
 	return r.db.WithContext(ctx).Create(provider).Error
 }
 
@@ -45,6 +48,8 @@ func (r *GormRepository) UpdateProvider(ctx context.Context, id string, updates
 	if len(m) == 0 {
 		return nil
 	}
+	// Bump the version so every instance rebuilds its cached client on the next lookup.
+	m["config_version"] = gorm.Expr("config_version + ?", 1)
 
 	result := r.db.WithContext(ctx).
 		Model(&entity.DbProvider{}).
@@ -41,39 +41,9 @@ func (r *GormRepository) ListUsageRecords(ctx context.Context, params *entity.Us
 		return nil, nil, fmt.Errorf("repository not initialised")
 	}
 
-	query := r.db.WithContext(ctx).
-		Model(&entity.DbUsageRecord{}).
+	query := r.filterUsageRecords(ctx, params).
 		Preload("User").
 		Preload("Tags")
-	if params != nil {
-		if trimmed := strings.TrimSpace(params.Provider); trimmed != "" {
-			query = query.Where("provider_id = ?", trimmed)
-		}
-		if trimmed := strings.TrimSpace(params.Model); trimmed != "" {
-			query = query.Where("model_id = ?", trimmed)
-		}
-		if !params.IncludeAll && params.UserID > 0 {
-			query = query.Where("user_id = ?", params.UserID)
-		}
-		if trimmed := strings.ToLower(strings.TrimSpace(params.Result)); trimmed != "" && trimmed != "all" {
-			switch trimmed {
-			case "success":
-				query = query.Where("(error_message IS NULL OR error_message = '')")
-			case "failure":
-				query = query.Where("error_message IS NOT NULL AND error_message <> ''")
-			}
-		}
-
-		if len(params.TagIDs) > 0 {
-			query = query.Joins("JOIN usage_record_tags ON usage_record_tags.usage_record_id = usage_records.id").
-				Where("usage_record_tags.tag_id IN ?", params.TagIDs).
-				Group("usage_records.id").
-				Having("COUNT(DISTINCT usage_record_tags.tag_id) >= ?", len(params.TagIDs))
-		}
-		if params.HasOutputImages {
-			query = r.applyHasOutputImagesFilter(query)
-		}
-	}
 
 	var totalCount int64
 	if err := query.Count(&totalCount).Error; err != nil {
@@ -105,6 +75,65 @@ func (r *GormRepository) ListUsageRecords(ctx context.Context, params *entity.Us
 	return records, meta, nil
 }
 
+// filterUsageRecords builds the usage record query for the listing filters.
+func (r *GormRepository) filterUsageRecords(ctx context.Context, params *entity.UsageRecordQuery) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&entity.DbUsageRecord{})
+	if params != nil {
+		if trimmed := strings.TrimSpace(params.Provider); trimmed != "" {
+			query = query.Where("provider_id = ?", trimmed)
+		}
+		if trimmed := strings.TrimSpace(params.Model); trimmed != "" {
+			query = query.Where("model_id = ?", trimmed)
+		}
+		if params.BatchID > 0 {
+			query = query.Where("usage_records.batch_id = ?", params.BatchID)
+		}
+		if params.ParentRecordID > 0 {
+			query = query.Where("usage_records.parent_record_id = ?", params.ParentRecordID)
+		}
+		if !params.IncludeAll && params.UserID > 0 {
+			query = query.Where("user_id = ?", params.UserID)
+		}
+		if statuses, _ := entity.UsageRecordStatusesForResult(params.Result); len(statuses) > 0 {
+			query = query.Where("usage_records.status IN ?", statuses)
+		}
+
+		if len(params.TagIDs) > 0 {
+			query = query.Joins("JOIN usage_record_tags ON usage_record_tags.usage_record_id = usage_records.id").
+				Where("usage_record_tags.tag_id IN ?", params.TagIDs).
+				Group("usage_records.id").
+				Having("COUNT(DISTINCT usage_record_tags.tag_id) >= ?", len(params.TagIDs))
+		}
+		if params.HasOutputImages {
+			query = r.applyHasOutputImagesFilter(query)
+		}
+	}
+	return query
+}
+
+// SummariseUsageRecordCost totals the cost of all usage records matching the listing filters.
+func (r *GormRepository) SummariseUsageRecordCost(ctx context.Context, params *entity.UsageRecordQuery) (*entity.UsageCostSummary, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+
+	// the tag filter groups rows, so aggregate over the matching ids instead of the filtered query itself
+	ids := r.filterUsageRecords(ctx, params).Select("usage_records.id")
+
+	var row struct {
+		TotalCost     float64
+		PricedRecords int64
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&entity.DbUsageRecord{}).
+		Select("COALESCE(SUM(cost), 0) AS total_cost, COUNT(cost) AS priced_records").
+		Where("id IN (?)", ids).
+		Scan(&row).Error; err != nil {
+		return nil, err
+	}
+	return &entity.UsageCostSummary{TotalCost: row.TotalCost, PricedRecords: row.PricedRecords}, nil
+}
+
 func (r *GormRepository) applyHasOutputImagesFilter(query *gorm.DB) *gorm.DB {
 	if query == nil {
 		return query
@@ -0,0 +1,87 @@
+package sql
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveUsageRecordTask stores the remote task submitted for an output slot of a usage record,
+// replacing the task previously stored for the slot (e.g. one submitted before a failover).
+func (r *GormRepository) SaveUsageRecordTask(ctx context.Context, task *entity.DbUsageRecordTask) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if task == nil {
+		return fmt.Errorf("task is nil")
+	}
+	if task.RecordID == 0 {
+		return fmt.Errorf("invalid usage record id")
+	}
+	task.TaskCode = strings.TrimSpace(task.TaskCode)
+	if task.TaskCode == "" {
+		return fmt.Errorf("task code is required")
+	}
+
+	task.UpdatedAt = time.Now()
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "record_id"}, {Name: "slot"}},
+			DoUpdates: clause.AssignmentColumns([]string{"provider_id", "model_id", "task_code", "updated_at"}),
+		}).
+		Create(task).Error
+}
+
+// ListUsageRecordTasks returns the remote tasks of a usage record ordered by output slot.
+func (r *GormRepository) ListUsageRecordTasks(ctx context.Context, recordID uint) ([]entity.DbUsageRecordTask, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if recordID == 0 {
+		return nil, fmt.Errorf("invalid usage record id")
+	}
+
+	var tasks []entity.DbUsageRecordTask
+	if err := r.db.WithContext(ctx).
+		Where("record_id = ?", recordID).
+		Order("slot ASC").
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// FindUsageRecordTaskByCode returns the latest remote task with taskCode submitted to a provider using driver.
+// Task codes are only unique per provider, so the lookup is scoped to the providers of the driver.
+func (r *GormRepository) FindUsageRecordTaskByCode(ctx context.Context, driver, taskCode string) (*entity.DbUsageRecordTask, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	driver = strings.ToLower(strings.TrimSpace(driver))
+	taskCode = strings.TrimSpace(taskCode)
+	if driver == "" || taskCode == "" {
+		return nil, fmt.Errorf("driver and task code are required")
+	}
+
+	providerIDs := r.db.WithContext(ctx).
+		Model(&entity.DbProvider{}).
+		Select("id").
+		Where("LOWER(driver) = ?", driver)
+
+	var task entity.DbUsageRecordTask
+	if err := r.db.WithContext(ctx).
+		Where("task_code = ? AND provider_id IN (?)", taskCode, providerIDs).
+		Order("id DESC").
+		First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to load usage record task: %w", err)
+	}
+	return &task, nil
+}
@@ -0,0 +1,212 @@
+package sql
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ListWebhookEndpoints returns the endpoints owned by userID, or all endpoints when includeAll is set.
+func (r *GormRepository) ListWebhookEndpoints(ctx context.Context, userID uint, includeAll bool) ([]entity.DbWebhookEndpoint, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+
+	query := r.db.WithContext(ctx).Model(&entity.DbWebhookEndpoint{})
+	if !includeAll {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var endpoints []entity.DbWebhookEndpoint
+	if err := query.Order("id ASC").Find(&endpoints).Error; err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// GetWebhookEndpoint fetches a webhook endpoint by id.
+func (r *GormRepository) GetWebhookEndpoint(ctx context.Context, id uint) (*entity.DbWebhookEndpoint, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return nil, fmt.Errorf("invalid webhook endpoint id")
+	}
+
+	var endpoint entity.DbWebhookEndpoint
+	if err := r.db.WithContext(ctx).First(&endpoint, id).Error; err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+// FindWebhookEndpointsForUser returns the active endpoints that receive events of userID's generations:
+// the user's own endpoints plus endpoints subscribed to all users.
+func (r *GormRepository) FindWebhookEndpointsForUser(ctx context.Context, userID uint) ([]entity.DbWebhookEndpoint, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+
+	var endpoints []entity.DbWebhookEndpoint
+	if err := r.db.WithContext(ctx).
+		Where("is_active = ?", true).
+		Where("user_id = ? OR all_users = ?", userID, true).
+		Order("id ASC").
+		Find(&endpoints).Error; err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// CreateWebhookEndpoint inserts a new webhook endpoint.
+func (r *GormRepository) CreateWebhookEndpoint(ctx context.Context, endpoint *entity.DbWebhookEndpoint) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if endpoint == nil {
+		return fmt.Errorf("webhook endpoint is nil")
+	}
+	return r.db.WithContext(ctx).Create(endpoint).Error
+}
+
+// UpdateWebhookEndpoint updates webhook endpoint fields.
+func (r *GormRepository) UpdateWebhookEndpoint(ctx context.Context, id uint, updates entity.WebhookEndpointUpdates) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return fmt.Errorf("invalid webhook endpoint id")
+	}
+	m := updates.ToMap()
+	if len(m) == 0 {
+		return nil
+	}
+
+	result := r.db.WithContext(ctx).Model(&entity.DbWebhookEndpoint{}).Where("id = ?", id).Updates(m)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteWebhookEndpoint removes a webhook endpoint together with its delivery log.
+func (r *GormRepository) DeleteWebhookEndpoint(ctx context.Context, id uint) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return fmt.Errorf("invalid webhook endpoint id")
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&entity.DbWebhookEndpoint{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("endpoint_id = ?", id).Delete(&entity.DbWebhookDelivery{}).Error
+	})
+}
+
+// CreateWebhookDeliveries queues deliveries; pending ones without NextAttemptAt are due immediately.
+func (r *GormRepository) CreateWebhookDeliveries(ctx context.Context, deliveries []entity.DbWebhookDelivery) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for i := range deliveries {
+		if deliveries[i].Status == "" {
+			deliveries[i].Status = entity.WebhookDeliveryStatusPending
+		}
+		if deliveries[i].NextAttemptAt.IsZero() {
+			deliveries[i].NextAttemptAt = now
+		}
+	}
+	return r.db.WithContext(ctx).Create(&deliveries).Error
+}
+
+// ClaimWebhookDeliveries claims up to limit due pending deliveries by pushing their next attempt
+// lease into the future, so that other instances skip them while they are being sent.
+func (r *GormRepository) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]entity.DbWebhookDelivery, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	var candidates []entity.DbWebhookDelivery
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", entity.WebhookDeliveryStatusPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	leaseUntil := now.Add(lease)
+	claimed := make([]entity.DbWebhookDelivery, 0, len(candidates))
+	for _, delivery := range candidates {
+		result := r.db.WithContext(ctx).
+			Model(&entity.DbWebhookDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, entity.WebhookDeliveryStatusPending, now).
+			Update("next_attempt_at", leaseUntil)
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		delivery.NextAttemptAt = leaseUntil
+		claimed = append(claimed, delivery)
+	}
+	return claimed, nil
+}
+
+// UpdateWebhookDelivery records the outcome of a delivery attempt.
+func (r *GormRepository) UpdateWebhookDelivery(ctx context.Context, id uint, updates entity.WebhookDeliveryUpdates) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("repository not initialised")
+	}
+	if id == 0 {
+		return fmt.Errorf("invalid webhook delivery id")
+	}
+	m := updates.ToMap()
+	if len(m) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&entity.DbWebhookDelivery{}).Where("id = ?", id).Updates(m).Error
+}
+
+// ListWebhookDeliveries returns the most recent deliveries of an endpoint.
+func (r *GormRepository) ListWebhookDeliveries(ctx context.Context, endpointID uint, limit int) ([]entity.DbWebhookDelivery, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("repository not initialised")
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var deliveries []entity.DbWebhookDelivery
+	if err := r.db.WithContext(ctx).
+		Where("endpoint_id = ?", endpointID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
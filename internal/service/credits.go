@@ -0,0 +1,47 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetCreditsEnforced 设置余额不足时是否拒绝预留额度（批量生成与流水线步骤由服务预留）
+func (s *GenerationService) SetCreditsEnforced(enforced bool) {
+	s.creditsEnforced = enforced
+}
+
+// settleCredits 生成结束后结算提交时预留的额度：成功时按实际费用扣除并退还差额（未能计算费用时按预留扣除），
+// 失败或取消时全额退还
+func (s *GenerationService) settleCredits(recordID uint) {
+	if s.repo == nil || recordID == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	record, err := s.repo.GetUsageRecord(ctx, recordID)
+	if err != nil {
+		logrus.WithError(err).WithField("record_id", recordID).Warn("failed to load usage record for credit settlement")
+		return
+	}
+
+	charge := settlementCharge(record)
+	if err := s.repo.SettleCredits(ctx, recordID, charge); err != nil {
+		logrus.WithError(err).WithField("record_id", recordID).Error("failed to settle credits")
+	}
+}
+
+// settlementCharge 返回结算时应扣除的费用，nil 表示按预留金额扣除
+func settlementCharge(record *entity.DbUsageRecord) *float64 {
+	switch record.Status {
+	case entity.UsageRecordStatusSucceeded, entity.UsageRecordStatusPartiallySucceeded:
+		return record.Cost
+	default:
+		zero := 0.0
+		return &zero
+	}
+}
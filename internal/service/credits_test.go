@@ -0,0 +1,114 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSettlementCharge(t *testing.T) {
+	cost := 0.12
+
+	tests := []struct {
+		name   string
+		record entity.DbUsageRecord
+		want   *float64
+	}{
+		{
+			name:   "成功时按实际费用扣除",
+			record: entity.DbUsageRecord{Status: entity.UsageRecordStatusSucceeded, Cost: &cost},
+			want:   &cost,
+		},
+		{
+			name:   "部分成功按实际费用扣除",
+			record: entity.DbUsageRecord{Status: entity.UsageRecordStatusPartiallySucceeded, Cost: &cost},
+			want:   &cost,
+		},
+		{
+			name:   "未计算费用时按预留扣除",
+			record: entity.DbUsageRecord{Status: entity.UsageRecordStatusSucceeded},
+		},
+		{
+			name:   "失败时全额退还",
+			record: entity.DbUsageRecord{Status: entity.UsageRecordStatusFailed, Cost: &cost},
+			want:   new(float64),
+		},
+		{
+			name:   "取消时全额退还",
+			record: entity.DbUsageRecord{Status: entity.UsageRecordStatusCancelled},
+			want:   new(float64),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := settlementCharge(&tt.record)
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("charge = %v, want %v", got, tt.want)
+			}
+			if got != nil && *got != *tt.want {
+				t.Fatalf("charge = %v, want %v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestCreditQuotaPeriodStart(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 13, 45, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		period string
+		want   time.Time
+	}{
+		{name: "按日", period: entity.CreditQuotaPeriodDaily, want: time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "按月", period: entity.CreditQuotaPeriodMonthly, want: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := entity.DbCreditQuota{Period: tt.period}.PeriodStart(now)
+			if !got.Equal(tt.want) {
+				t.Fatalf("period start = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReserveCreditsQuotaAcrossPeriods(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+
+	if err := repo.CreateCreditQuota(ctx, &entity.DbCreditQuota{UserID: 1, Period: entity.CreditQuotaPeriodDaily, Limit: 1}); err != nil {
+		t.Fatalf("create quota: %v", err)
+	}
+	reserve := func(recordID uint, amount float64, createdAt time.Time) error {
+		return repo.ReserveCredits(ctx, &entity.DbCreditLedgerEntry{
+			CreatedAt: createdAt,
+			UserID:    1,
+			Amount:    amount,
+			RecordID:  &recordID,
+		}, false)
+	}
+
+	// 昨天预留的生成今天失败并全额退还，退款不应计入今天的花费
+	if err := reserve(1, 1, time.Now().AddDate(0, 0, -1)); err != nil {
+		t.Fatalf("reserve yesterday: %v", err)
+	}
+	if err := repo.SettleCredits(ctx, 1, new(float64)); err != nil {
+		t.Fatalf("settle: %v", err)
+	}
+
+	if err := reserve(2, 1, time.Time{}); err != nil {
+		t.Fatalf("expected reservation within the daily quota, got %v", err)
+	}
+	var quotaErr *entity.QuotaExceededError
+	if err := reserve(3, 0.5, time.Time{}); !errors.As(err, &quotaErr) {
+		t.Fatalf("expected quota exceeded error, got %v", err)
+	}
+	if quotaErr.Spent != 1 {
+		t.Errorf("expected spent %v, got %v", 1.0, quotaErr.Spent)
+	}
+}
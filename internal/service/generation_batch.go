@@ -0,0 +1,118 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetBatchNotifyFunc 设置批次完成通知函数（用于 SSE 推送）
+func (s *GenerationService) SetBatchNotifyFunc(fn func(clientID string, batch entity.DbGenerationBatch, progress entity.GenerationBatchProgress)) {
+	s.batchNotifyFunc = fn
+}
+
+// SubmitBatch 创建批次及其子使用记录，并将每个子生成写入任务队列。
+// records、requests 与 estimates 按下标一一对应，成功后 records 中会填充记录 ID。
+// 每条子记录的预估费用与批次在同一事务中预留，余额不足或超出配额时整个批次被拒绝。
+func (s *GenerationService) SubmitBatch(ctx context.Context, batch *entity.DbGenerationBatch, records []entity.DbUsageRecord, requests []entity.GenerateContentRequest, estimates []float64, tagIDs []uint) error {
+	if s.repo == nil {
+		return errors.New("repository not configured")
+	}
+	if batch == nil || len(records) == 0 || len(records) != len(requests) || len(records) != len(estimates) {
+		return errors.New("invalid batch")
+	}
+
+	jobs := make([]entity.DbGenerationJob, 0, len(records))
+	for i, request := range requests {
+		payload, err := encodeJobPayload(request)
+		if err != nil {
+			return fmt.Errorf("encode job payload: %w", err)
+		}
+		jobs = append(jobs, entity.DbGenerationJob{
+			UserID:     records[i].UserID,
+			ProviderID: records[i].ProviderID,
+			ModelID:    records[i].ModelID,
+			ClientID:   strings.TrimSpace(request.ClientID),
+			Priority:   entity.GenerationJobPriorityBatch,
+			Payload:    payload,
+			Status:     entity.GenerationJobStatusPending,
+		})
+	}
+
+	if err := s.repo.CreateGenerationBatch(ctx, batch, records, jobs, estimates, tagIDs, s.creditsEnforced); err != nil {
+		return err
+	}
+
+	s.wakeWorkers()
+	return nil
+}
+
+// BatchProgress 统计批次下各状态的子记录数量
+func (s *GenerationService) BatchProgress(ctx context.Context, batch entity.DbGenerationBatch) (entity.GenerationBatchProgress, error) {
+	if s.repo == nil {
+		return entity.GenerationBatchProgress{}, errors.New("repository not configured")
+	}
+	counts, err := s.repo.CountGenerationBatchRecords(ctx, batch.ID)
+	if err != nil {
+		return entity.GenerationBatchProgress{}, err
+	}
+	return newBatchProgress(batch.Total, counts), nil
+}
+
+// newBatchProgress 将按状态分组的计数转换为批次进度
+func newBatchProgress(total int, counts map[string]int64) entity.GenerationBatchProgress {
+	progress := entity.GenerationBatchProgress{
+		Total:              int64(total),
+		Queued:             counts[entity.UsageRecordStatusQueued],
+		Running:            counts[entity.UsageRecordStatusRunning],
+		Succeeded:          counts[entity.UsageRecordStatusSucceeded],
+		PartiallySucceeded: counts[entity.UsageRecordStatusPartiallySucceeded],
+		Failed:             counts[entity.UsageRecordStatusFailed],
+		Cancelled:          counts[entity.UsageRecordStatusCancelled],
+	}
+	progress.Finished = progress.Succeeded + progress.PartiallySucceeded + progress.Failed + progress.Cancelled
+	return progress
+}
+
+// completeBatchIfDone 在批次的最后一个子任务结束后将批次标记为完成并推送通知
+func (s *GenerationService) completeBatchIfDone(batchID uint) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	completed, err := s.repo.CompleteGenerationBatch(ctx, batchID)
+	if err != nil {
+		logrus.WithError(err).WithField("batch_id", batchID).Warn("failed to complete generation batch")
+		return
+	}
+	if !completed {
+		return
+	}
+
+	batch, err := s.repo.GetGenerationBatch(ctx, batchID)
+	if err != nil {
+		logrus.WithError(err).WithField("batch_id", batchID).Warn("failed to load completed generation batch")
+		return
+	}
+	progress, err := s.BatchProgress(ctx, *batch)
+	if err != nil {
+		logrus.WithError(err).WithField("batch_id", batchID).Warn("failed to count generation batch records")
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"batch_id":  batchID,
+		"total":     progress.Total,
+		"succeeded": progress.Succeeded + progress.PartiallySucceeded,
+		"failed":    progress.Failed,
+		"cancelled": progress.Cancelled,
+	}).Info("generation batch completed")
+
+	if s.batchNotifyFunc != nil && strings.TrimSpace(batch.ClientID) != "" {
+		s.batchNotifyFunc(batch.ClientID, *batch, progress)
+	}
+}
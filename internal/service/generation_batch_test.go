@@ -0,0 +1,124 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNewBatchProgress(t *testing.T) {
+	progress := newBatchProgress(6, map[string]int64{
+		entity.UsageRecordStatusQueued:             1,
+		entity.UsageRecordStatusRunning:            1,
+		entity.UsageRecordStatusSucceeded:          2,
+		entity.UsageRecordStatusPartiallySucceeded: 1,
+		entity.UsageRecordStatusFailed:             1,
+	})
+
+	if progress.Total != 6 {
+		t.Errorf("expected total 6, got %d", progress.Total)
+	}
+	if progress.Finished != 4 {
+		t.Errorf("expected 4 finished, got %d", progress.Finished)
+	}
+	if progress.Queued != 1 || progress.Running != 1 || progress.Cancelled != 0 {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+}
+
+func TestSubmitBatchReservesCredits(t *testing.T) {
+	ctx := context.Background()
+
+	newBatch := func() ([]entity.DbUsageRecord, []entity.GenerateContentRequest) {
+		records := make([]entity.DbUsageRecord, 2)
+		requests := make([]entity.GenerateContentRequest, 2)
+		for i := range records {
+			records[i] = entity.DbUsageRecord{UserID: 1, ProviderID: "p", ModelID: "m", Prompt: "a", Status: entity.UsageRecordStatusQueued}
+			requests[i] = entity.GenerateContentRequest{ProviderID: "p", ModelID: "m", Prompt: "a"}
+		}
+		return records, requests
+	}
+
+	tests := []struct {
+		name      string
+		estimates []float64
+		quota     float64
+		wantErr   func(error) bool
+		balance   float64
+	}{
+		{
+			name:      "余额不足时整批拒绝",
+			estimates: []float64{0.6, 0.6},
+			wantErr:   func(err error) bool { return errors.Is(err, entity.ErrInsufficientCredits) },
+			balance:   1,
+		},
+		{
+			name:      "超出配额时整批拒绝",
+			estimates: []float64{0.4, 0.4},
+			quota:     0.5,
+			wantErr: func(err error) bool {
+				var quotaErr *entity.QuotaExceededError
+				return errors.As(err, &quotaErr)
+			},
+			balance: 1,
+		},
+		{
+			name:      "为每条子记录预留",
+			estimates: []float64{0.4, 0.4},
+			balance:   0.2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository(t)
+			svc := NewGenerationService(repo, nil)
+			svc.SetCreditsEnforced(true)
+
+			if err := repo.GrantCredits(ctx, &entity.DbCreditLedgerEntry{UserID: 1, Amount: 1}); err != nil {
+				t.Fatalf("grant credits: %v", err)
+			}
+			if tt.quota > 0 {
+				if err := repo.CreateCreditQuota(ctx, &entity.DbCreditQuota{UserID: 1, Period: entity.CreditQuotaPeriodDaily, Limit: tt.quota}); err != nil {
+					t.Fatalf("create quota: %v", err)
+				}
+			}
+
+			records, requests := newBatch()
+			err := svc.SubmitBatch(ctx, &entity.DbGenerationBatch{UserID: 1}, records, requests, tt.estimates, nil)
+			if tt.wantErr != nil {
+				if err == nil || !tt.wantErr(err) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("submit batch: %v", err)
+			}
+
+			account, err := repo.GetCreditAccount(ctx, 1)
+			if err != nil {
+				t.Fatalf("get credit account: %v", err)
+			}
+			if math.Abs(account.Balance-tt.balance) > 1e-9 {
+				t.Errorf("expected balance %.2f, got %.2f", tt.balance, account.Balance)
+			}
+
+			stored, _, err := repo.ListUsageRecords(ctx, &entity.UsageRecordQuery{UserID: 1})
+			if err != nil {
+				t.Fatalf("list usage records: %v", err)
+			}
+			ledger, err := repo.ListCreditLedger(ctx, 1, 10)
+			if err != nil {
+				t.Fatalf("list credit ledger: %v", err)
+			}
+			wantRecords := 0
+			if tt.wantErr == nil {
+				wantRecords = len(records)
+			}
+			if len(stored) != wantRecords || len(ledger) != wantRecords+1 {
+				t.Errorf("expected %d records and reservations, got %d records and %d ledger entries", wantRecords, len(stored), len(ledger))
+			}
+		})
+	}
+}
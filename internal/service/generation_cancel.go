@@ -0,0 +1,130 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/llm"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCancelNotSupported 生成已开始且模型不支持取消
+	ErrCancelNotSupported = errors.New("generation cannot be cancelled once started")
+	// ErrGenerationNotActive 记录没有排队或执行中的生成任务
+	ErrGenerationNotActive = errors.New("generation is not active")
+
+	// errGenerationCancelled 作为取消原因注入生成 context，用于区分用户取消与超时
+	errGenerationCancelled = errors.New("generation cancelled")
+)
+
+const cancelledMessage = "生成已取消"
+
+// CancelGeneration 取消使用记录对应的生成任务。
+// 排队中的任务直接取消；执行中的任务仅在 allowRunning 为 true（模型支持取消）时请求中止，
+// 由执行该任务的实例取消本地 context 并调用服务商的远程取消接口。
+// 返回取消后记录的状态：cancelled 表示已取消，running 表示取消请求已提交、等待执行实例响应。
+func (s *GenerationService) CancelGeneration(ctx context.Context, recordID uint, allowRunning bool) (string, error) {
+	if s.repo == nil {
+		return "", errors.New("repository not configured")
+	}
+
+	job, err := s.repo.CancelPendingGenerationJob(ctx, recordID)
+	if err == nil {
+		s.markRecordCancelled(recordID)
+		s.notifyComplete(job.ClientID, recordID, entity.UsageRecordStatusCancelled, cancelledMessage)
+		logrus.WithField("record_id", recordID).Info("cancelled queued generation")
+		if job.BatchID != nil {
+			s.completeBatchIfDone(*job.BatchID)
+		}
+		if job.PipelineRunID != nil {
+			s.advancePipelineRun(*job.PipelineRunID)
+		}
+		return entity.UsageRecordStatusCancelled, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	if !allowRunning {
+		return "", ErrCancelNotSupported
+	}
+
+	if _, err := s.repo.RequestGenerationJobCancel(ctx, recordID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrGenerationNotActive
+		}
+		return "", err
+	}
+
+	// 任务在本实例执行时立即中止，否则由持有租约的实例在续约时处理
+	s.cancelRunning(recordID)
+	logrus.WithField("record_id", recordID).Info("requested cancellation of running generation")
+	return entity.UsageRecordStatusRunning, nil
+}
+
+// trackRunning 登记执行中生成的取消函数
+func (s *GenerationService) trackRunning(recordID uint, cancel context.CancelCauseFunc) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	if s.running == nil {
+		s.running = make(map[uint]context.CancelCauseFunc)
+	}
+	s.running[recordID] = cancel
+}
+
+func (s *GenerationService) untrackRunning(recordID uint) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	delete(s.running, recordID)
+}
+
+// cancelRunning 取消本实例上执行中的生成，返回是否找到该生成
+func (s *GenerationService) cancelRunning(recordID uint) bool {
+	s.runningMu.Lock()
+	cancel, ok := s.running[recordID]
+	s.runningMu.Unlock()
+	if ok {
+		cancel(errGenerationCancelled)
+	}
+	return ok
+}
+
+// cancelRemoteTask 通知服务商取消已提交的异步任务（尽力而为）
+func (s *GenerationService) cancelRemoteTask(service llm.AIService, dbModel entity.DbModel, recordID uint, taskID string) {
+	canceller, ok := llm.Unwrap(service).(llm.TaskCanceller)
+	if !ok || strings.TrimSpace(taskID) == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	fields := logrus.Fields{
+		"record_id": recordID,
+		"provider":  dbModel.ProviderID,
+		"model":     dbModel.ModelID,
+		"task_id":   taskID,
+	}
+	if err := canceller.CancelTask(ctx, dbModel, taskID); err != nil {
+		logrus.WithError(err).WithFields(fields).Warn("failed to cancel provider task")
+		return
+	}
+	logrus.WithFields(fields).Info("cancelled provider task")
+}
+
+// markRecordCancelled 将使用记录标记为已取消
+func (s *GenerationService) markRecordCancelled(recordID uint) {
+	errMsg := cancelledMessage
+	status := entity.UsageRecordStatusCancelled
+	finishedAt := time.Now()
+	s.updateUsageRecord(recordID, entity.UsageRecordUpdates{
+		ErrorMessage: &errMsg,
+		Status:       &status,
+		FinishedAt:   &finishedAt,
+	})
+}
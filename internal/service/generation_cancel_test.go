@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCancelRunning(t *testing.T) {
+	t.Run("取消执行中的生成", func(t *testing.T) {
+		svc := NewGenerationService(nil, nil)
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+
+		svc.trackRunning(1, cancel)
+		if !svc.cancelRunning(1) {
+			t.Fatal("expected running generation to be found")
+		}
+		if !errors.Is(context.Cause(ctx), errGenerationCancelled) {
+			t.Errorf("expected cause %v, got %v", errGenerationCancelled, context.Cause(ctx))
+		}
+	})
+
+	t.Run("生成结束后不再可取消", func(t *testing.T) {
+		svc := NewGenerationService(nil, nil)
+		_, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+
+		svc.trackRunning(2, cancel)
+		svc.untrackRunning(2)
+		if svc.cancelRunning(2) {
+			t.Error("expected untracked generation not to be cancelled")
+		}
+	})
+
+	t.Run("未初始化的服务", func(t *testing.T) {
+		svc := &GenerationService{}
+		if svc.cancelRunning(3) {
+			t.Error("expected no running generation")
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// computeGenerationCost 计算一次生成的费用（美元）。
+// 服务商报告了实际费用时直接使用；否则按实际服务模型的计费规则，根据产出的图片数、视频时长、
+// 输出像素与 token 用量累加。模型未配置计费规则时返回 nil。
+func computeGenerationCost(model entity.DbModel, request entity.GenerateContentRequest, resp *entity.GenerateContentResponse) (*float64, string, error) {
+	if resp == nil {
+		return nil, "", nil
+	}
+	if resp.Usage != nil && resp.Usage.Cost != nil {
+		cost := *resp.Usage.Cost
+		return &cost, entity.UsageCostSourceProvider, nil
+	}
+
+	pricing := model.Pricing
+	if pricing.IsEmpty() {
+		return nil, "", nil
+	}
+
+	var images, videos int
+	for _, output := range resp.Outputs {
+		switch strings.ToLower(strings.TrimSpace(output.Type)) {
+		case "video":
+			videos++
+		default:
+			images++
+		}
+	}
+
+	cost := pricing.PerImage * float64(images)
+
+	if pricing.PerMegapixel > 0 && images > 0 {
+		size := request.GetSize()
+		if size == "" {
+			size = model.DefaultSize
+		}
+		megapixels, ok := parseMegapixels(size)
+		if !ok {
+			return nil, "", fmt.Errorf("cannot price size %q per megapixel", size)
+		}
+		cost += pricing.PerMegapixel * megapixels * float64(images)
+	}
+
+	if pricing.PerVideoSecond > 0 && videos > 0 {
+		duration := request.GetDuration()
+		if duration <= 0 {
+			duration = model.DefaultDuration
+		}
+		if duration <= 0 {
+			return nil, "", fmt.Errorf("cannot price video without duration")
+		}
+		cost += pricing.PerVideoSecond * float64(duration) * float64(videos)
+	}
+
+	if resp.Usage != nil {
+		cost += pricing.PerThousandInputTokens * float64(resp.Usage.PromptTokens) / 1000
+		cost += pricing.PerThousandOutputTokens * float64(resp.Usage.CompletionTokens) / 1000
+	}
+
+	return &cost, entity.UsageCostSourcePricing, nil
+}
+
+// parseMegapixels 解析输出尺寸的百万像素数，支持 1024x1024、1024*1024 以及 1K/2K/4K（按正方形计算）
+func parseMegapixels(size string) (float64, bool) {
+	size = strings.ToLower(strings.TrimSpace(size))
+	if size == "" {
+		return 0, false
+	}
+
+	if strings.HasSuffix(size, "k") {
+		n, err := strconv.Atoi(strings.TrimSuffix(size, "k"))
+		if err != nil || n <= 0 {
+			return 0, false
+		}
+		side := float64(n * 1024)
+		return side * side / 1e6, true
+	}
+
+	width, height, ok := strings.Cut(strings.ReplaceAll(size, "*", "x"), "x")
+	if !ok {
+		return 0, false
+	}
+	w, err := strconv.Atoi(strings.TrimSpace(width))
+	if err != nil || w <= 0 {
+		return 0, false
+	}
+	h, err := strconv.Atoi(strings.TrimSpace(height))
+	if err != nil || h <= 0 {
+		return 0, false
+	}
+	return float64(w) * float64(h) / 1e6, true
+}
+
+// EstimateGenerationCost 按模型计费规则预估一次生成的费用，用于提交时预留额度。
+// 按请求的输出数量计算，token 用量未知不计入；无法预估时返回 0。
+func EstimateGenerationCost(model entity.DbModel, request entity.GenerateContentRequest) float64 {
+	outputType := "image"
+	if model.IsVideoModel() {
+		outputType = "video"
+	}
+	outputs := make([]entity.MediaOutput, request.GetNumOutputs())
+	for i := range outputs {
+		outputs[i].Type = outputType
+	}
+
+	cost, _, err := computeGenerationCost(model, request, &entity.GenerateContentResponse{Outputs: outputs})
+	if err != nil || cost == nil {
+		return 0
+	}
+	return *cost
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"math"
+	"testing"
+)
+
+func TestComputeGenerationCost(t *testing.T) {
+	floatPtr := func(v float64) *float64 { return &v }
+	image := entity.MediaOutput{Type: "image", URL: "https://example.com/a.png"}
+	video := entity.MediaOutput{Type: "video", URL: "https://example.com/a.mp4"}
+
+	tests := []struct {
+		name       string
+		pricing    entity.ModelPricing
+		request    entity.GenerateContentRequest
+		resp       *entity.GenerateContentResponse
+		want       *float64
+		wantSource string
+		wantErr    bool
+	}{
+		{
+			name:    "未配置计费",
+			request: entity.GenerateContentRequest{},
+			resp:    &entity.GenerateContentResponse{Outputs: []entity.MediaOutput{image}},
+		},
+		{
+			name:       "按图片计费",
+			pricing:    entity.ModelPricing{PerImage: 0.03},
+			resp:       &entity.GenerateContentResponse{Outputs: []entity.MediaOutput{image, image}},
+			want:       floatPtr(0.06),
+			wantSource: entity.UsageCostSourcePricing,
+		},
+		{
+			name:       "按百万像素计费",
+			pricing:    entity.ModelPricing{PerMegapixel: 0.05},
+			request:    entity.GenerateContentRequest{Output: entity.OutputConfig{Size: "2000x1000"}},
+			resp:       &entity.GenerateContentResponse{Outputs: []entity.MediaOutput{image}},
+			want:       floatPtr(0.1),
+			wantSource: entity.UsageCostSourcePricing,
+		},
+		{
+			name:    "无法解析尺寸",
+			pricing: entity.ModelPricing{PerMegapixel: 0.05},
+			request: entity.GenerateContentRequest{Output: entity.OutputConfig{Size: "16:9"}},
+			resp:    &entity.GenerateContentResponse{Outputs: []entity.MediaOutput{image}},
+			wantErr: true,
+		},
+		{
+			name:       "按视频时长计费",
+			pricing:    entity.ModelPricing{PerVideoSecond: 0.1},
+			request:    entity.GenerateContentRequest{Output: entity.OutputConfig{Duration: 5}},
+			resp:       &entity.GenerateContentResponse{Outputs: []entity.MediaOutput{video}},
+			want:       floatPtr(0.5),
+			wantSource: entity.UsageCostSourcePricing,
+		},
+		{
+			name:    "按 token 计费",
+			pricing: entity.ModelPricing{PerThousandInputTokens: 0.001, PerThousandOutputTokens: 0.002},
+			resp: &entity.GenerateContentResponse{
+				Text:  "ok",
+				Usage: &entity.ProviderUsage{PromptTokens: 2000, CompletionTokens: 500},
+			},
+			want:       floatPtr(0.003),
+			wantSource: entity.UsageCostSourcePricing,
+		},
+		{
+			name:    "优先使用服务商报告的费用",
+			pricing: entity.ModelPricing{PerImage: 0.03},
+			resp: &entity.GenerateContentResponse{
+				Outputs: []entity.MediaOutput{image},
+				Usage:   &entity.ProviderUsage{PromptTokens: 10, Cost: floatPtr(0.0391)},
+			},
+			want:       floatPtr(0.0391),
+			wantSource: entity.UsageCostSourceProvider,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, source, err := computeGenerationCost(entity.DbModel{Pricing: tt.pricing}, tt.request, tt.resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("expected no cost, got %v", *got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("expected cost %v, got nil", *tt.want)
+			}
+			if math.Abs(*got-*tt.want) > 1e-9 {
+				t.Errorf("expected cost %v, got %v", *tt.want, *got)
+			}
+			if source != tt.wantSource {
+				t.Errorf("expected source %q, got %q", tt.wantSource, source)
+			}
+		})
+	}
+}
+
+func TestParseMegapixels(t *testing.T) {
+	tests := []struct {
+		size   string
+		want   float64
+		wantOK bool
+	}{
+		{size: "1024x1024", want: 1.048576, wantOK: true},
+		{size: "1280*720", want: 0.9216, wantOK: true},
+		{size: "2K", want: 4.194304, wantOK: true},
+		{size: "16:9"},
+		{size: ""},
+		{size: "0x100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			got, ok := parseMegapixels(tt.size)
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok %v, got %v", tt.wantOK, ok)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEstimateGenerationCost(t *testing.T) {
+	tests := []struct {
+		name    string
+		model   entity.DbModel
+		request entity.GenerateContentRequest
+		want    float64
+	}{
+		{
+			name:    "未配置计费",
+			request: entity.GenerateContentRequest{Output: entity.OutputConfig{NumOutputs: 2}},
+		},
+		{
+			name:    "按请求的输出数量预估",
+			model:   entity.DbModel{Pricing: entity.ModelPricing{PerImage: 0.03}},
+			request: entity.GenerateContentRequest{Output: entity.OutputConfig{NumOutputs: 3}},
+			want:    0.09,
+		},
+		{
+			name: "视频模型使用默认时长",
+			model: entity.DbModel{
+				Pricing:          entity.ModelPricing{PerVideoSecond: 0.1},
+				OutputModalities: entity.StringArray{"video"},
+				DefaultDuration:  4,
+			},
+			want: 0.4,
+		},
+		{
+			name:    "无法解析尺寸时不预留",
+			model:   entity.DbModel{Pricing: entity.ModelPricing{PerImage: 0.01, PerMegapixel: 0.05}},
+			request: entity.GenerateContentRequest{Output: entity.OutputConfig{Size: "16:9"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EstimateGenerationCost(tt.model, tt.request)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("estimate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"clothing/internal/llm"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// deltaFlushInterval 文本片段合并推送的间隔
+const deltaFlushInterval = 200 * time.Millisecond
+
+// maxInlineDeltaImageLength 随增量推送的内联（data URL）图片的最大长度，更大的图片由客户端在生成完成后读取
+const maxInlineDeltaImageLength = 2 << 20
+
+// deltaKey 区分不同输出序号与尝试的流式输出，它们的文本不能合并
+type deltaKey struct {
+	slot    int
+	attempt int
+}
+
+// deltaCoalescer 合并流式输出的文本片段，按 deltaFlushInterval 批量推送，避免逐 token 推送占满 SSE 缓冲。
+// 图片推送可访问的地址，以及不超过 maxInlineDeltaImageLength 的内联 data URL 图片。
+type deltaCoalescer struct {
+	mu      sync.Mutex
+	emit    func(seq int, delta llm.GenerationDelta)
+	seq     int
+	pending map[deltaKey]*strings.Builder
+	timer   *time.Timer
+	closed  bool
+}
+
+func newDeltaCoalescer(emit func(seq int, delta llm.GenerationDelta)) *deltaCoalescer {
+	return &deltaCoalescer{
+		emit:    emit,
+		pending: make(map[deltaKey]*strings.Builder),
+	}
+}
+
+// add 收集一个增量：文本延后合并推送，图片推送前先推送同一输出已收集的文本以保持顺序
+func (c *deltaCoalescer) add(delta llm.GenerationDelta) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+
+	key := deltaKey{slot: delta.Slot, attempt: delta.Attempt}
+	if delta.Text != "" {
+		buf, ok := c.pending[key]
+		if !ok {
+			buf = &strings.Builder{}
+			c.pending[key] = buf
+		}
+		buf.WriteString(delta.Text)
+		if c.timer == nil {
+			c.timer = time.AfterFunc(deltaFlushInterval, c.flush)
+		}
+	}
+
+	if image := strings.TrimSpace(delta.Image); isDeltaImage(image) {
+		c.flushKeyLocked(key)
+		c.seq++
+		c.emit(c.seq, llm.GenerationDelta{Image: image, Slot: delta.Slot, Attempt: delta.Attempt})
+	}
+}
+
+// flush 推送所有已收集的文本
+func (c *deltaCoalescer) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushLocked()
+}
+
+// close 推送剩余文本并停止接收增量，需在推送生成结束事件前调用
+func (c *deltaCoalescer) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushLocked()
+	c.closed = true
+}
+
+func (c *deltaCoalescer) flushLocked() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	keys := make([]deltaKey, 0, len(c.pending))
+	for key := range c.pending {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].slot != keys[j].slot {
+			return keys[i].slot < keys[j].slot
+		}
+		return keys[i].attempt < keys[j].attempt
+	})
+	for _, key := range keys {
+		c.flushKeyLocked(key)
+	}
+}
+
+func (c *deltaCoalescer) flushKeyLocked(key deltaKey) {
+	buf, ok := c.pending[key]
+	if !ok {
+		return
+	}
+	delete(c.pending, key)
+	c.seq++
+	c.emit(c.seq, llm.GenerationDelta{Text: buf.String(), Slot: key.slot, Attempt: key.attempt})
+}
+
+// isDeltaImage 判断图片能否随增量推送：远程地址，或长度在上限内的 data URL
+func isDeltaImage(value string) bool {
+	if isRemoteURL(value) {
+		return true
+	}
+	if !strings.HasPrefix(strings.ToLower(value), "data:image/") {
+		return false
+	}
+	if len(value) > maxInlineDeltaImageLength {
+		logrus.WithField("length", len(value)).Debug("inline image too large for streaming delta")
+		return false
+	}
+	return true
+}
+
+func isRemoteURL(value string) bool {
+	lower := strings.ToLower(value)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"clothing/internal/llm"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeltaCoalescer(t *testing.T) {
+	var got []llm.GenerationDelta
+	var seqs []int
+	c := newDeltaCoalescer(func(seq int, delta llm.GenerationDelta) {
+		seqs = append(seqs, seq)
+		got = append(got, delta)
+	})
+
+	c.add(llm.GenerationDelta{Text: "先画", Attempt: 1})
+	c.add(llm.GenerationDelta{Text: "轮廓", Attempt: 1})
+	c.add(llm.GenerationDelta{Text: "另一张", Slot: 1, Attempt: 1})
+	c.add(llm.GenerationDelta{Image: "data:image/png;base64,aW1n", Attempt: 1})
+	c.add(llm.GenerationDelta{Image: "data:image/png;base64," + strings.Repeat("A", maxInlineDeltaImageLength), Attempt: 1})
+	c.add(llm.GenerationDelta{Image: "https://cdn.example.com/a.png", Attempt: 1})
+	c.add(llm.GenerationDelta{Text: "完成", Attempt: 1})
+	c.close()
+	c.add(llm.GenerationDelta{Text: "结束后忽略", Attempt: 1})
+
+	expected := []llm.GenerationDelta{
+		{Text: "先画轮廓", Attempt: 1},
+		{Image: "data:image/png;base64,aW1n", Attempt: 1},
+		{Image: "https://cdn.example.com/a.png", Attempt: 1},
+		{Text: "完成", Attempt: 1},
+		{Text: "另一张", Slot: 1, Attempt: 1},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if !reflect.DeepEqual(seqs, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("expected consecutive seqs, got %v", seqs)
+	}
+}
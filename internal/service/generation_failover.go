@@ -0,0 +1,119 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/llm"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	// errProviderUnavailable 服务商实例创建失败（如密钥缺失），可由故障转移链接管
+	errProviderUnavailable = errors.New("服务商暂时不可用")
+	// errNoGenerationTarget 主模型与备用模型均不可用
+	errNoGenerationTarget = errors.New("没有可用的服务商")
+)
+
+// GenerationTarget 一次生成可调用的服务商/模型
+type GenerationTarget struct {
+	ProviderID  string
+	Model       entity.DbModel
+	Service     llm.AIService
+	RetryPolicy llm.RetryPolicy
+}
+
+// targets 按顺序返回可调用的目标：主模型在前，故障转移链中的备用模型在后
+func (r GenerateContentRequest) targets() []GenerationTarget {
+	targets := make([]GenerationTarget, 0, 1+len(r.Fallbacks))
+	if r.Service != nil {
+		targets = append(targets, GenerationTarget{
+			ProviderID:  r.Model.ProviderID,
+			Model:       r.Model,
+			Service:     r.Service,
+			RetryPolicy: r.RetryPolicy,
+		})
+	}
+	for _, fallback := range r.Fallbacks {
+		if fallback.Service != nil {
+			targets = append(targets, fallback)
+		}
+	}
+	return targets
+}
+
+// shouldFailover 判断失败后是否切换到下一个目标：生成未被取消或超时，且
+//   - 尚未向服务商提交异步任务时，仅可重试错误切换；
+//   - 已提交异步任务时，仅在该任务确定已在服务商侧失败或取消时切换，避免任务仍可能完成时重复生成（重复计费）。
+func shouldFailover(ctx context.Context, err error, submitted bool) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if submitted {
+		return llm.IsTaskFailed(err)
+	}
+	return llm.ClassifyError(err).Retryable
+}
+
+// loadGenerationTarget 加载并校验服务商与模型，创建对应的 LLM 服务
+func (s *GenerationService) loadGenerationTarget(ctx context.Context, providerID, modelID string) (GenerationTarget, error) {
+	provider, err := s.repo.GetProvider(ctx, providerID)
+	if err != nil {
+		return GenerationTarget{}, fmt.Errorf("服务商不存在: %s", providerID)
+	}
+	if !provider.IsActive {
+		return GenerationTarget{}, fmt.Errorf("服务商已禁用: %s", providerID)
+	}
+
+	dbModel, err := s.repo.GetModel(ctx, providerID, modelID)
+	if err != nil {
+		return GenerationTarget{}, fmt.Errorf("模型不存在: %s", modelID)
+	}
+	if !dbModel.IsActive {
+		return GenerationTarget{}, fmt.Errorf("模型已禁用: %s", modelID)
+	}
+
+	target := GenerationTarget{
+		ProviderID:  providerID,
+		Model:       *dbModel,
+		RetryPolicy: llm.RetryPolicyFromConfig(provider.Config),
+	}
+	llmService, err := llm.GetFactory().Get(provider)
+	if err != nil {
+		return target, fmt.Errorf("%w: %v", errProviderUnavailable, err)
+	}
+	target.Service = llmService
+	return target, nil
+}
+
+// loadFailoverTargets 加载主模型的故障转移链，跳过当前不可用的备用模型
+func (s *GenerationService) loadFailoverTargets(ctx context.Context, providerID, modelID string) []GenerationTarget {
+	chain, err := s.repo.FindActiveFailoverChain(ctx, providerID, modelID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"provider": providerID,
+				"model":    modelID,
+			}).Warn("failed to load failover chain")
+		}
+		return nil
+	}
+
+	targets := make([]GenerationTarget, 0, len(chain.Fallbacks))
+	for _, step := range chain.Fallbacks {
+		target, err := s.loadGenerationTarget(ctx, step.ProviderID, step.ModelID)
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"chain_id": chain.ID,
+				"provider": step.ProviderID,
+				"model":    step.ModelID,
+			}).Warn("skipping unavailable failover target")
+			continue
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/llm"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type stubLLMService struct {
+	llm.BaseProvider
+}
+
+func (*stubLLMService) GenerateContent(ctx context.Context, request entity.GenerateContentRequest, dbModel entity.DbModel) (*entity.GenerateContentResponse, error) {
+	return &entity.GenerateContentResponse{}, nil
+}
+
+func TestGenerateContentRequestTargets(t *testing.T) {
+	svc := &stubLLMService{}
+	fallback := GenerationTarget{ProviderID: "fal", Model: entity.DbModel{ProviderID: "fal", ModelID: "fal-ai/flux"}, Service: svc}
+	unavailable := GenerationTarget{ProviderID: "dashscope", Model: entity.DbModel{ProviderID: "dashscope", ModelID: "wanx"}}
+
+	t.Run("主模型在前", func(t *testing.T) {
+		req := GenerateContentRequest{
+			Model:     entity.DbModel{ProviderID: "openai", ModelID: "gpt-image-1"},
+			Service:   svc,
+			Fallbacks: []GenerationTarget{fallback, unavailable},
+		}
+		targets := req.targets()
+		if len(targets) != 2 {
+			t.Fatalf("expected 2 targets, got %d", len(targets))
+		}
+		if targets[0].ProviderID != "openai" || targets[1].ProviderID != "fal" {
+			t.Errorf("unexpected target order: %+v", targets)
+		}
+	})
+
+	t.Run("主服务商不可用时仅使用备用模型", func(t *testing.T) {
+		req := GenerateContentRequest{
+			Model:     entity.DbModel{ProviderID: "openai", ModelID: "gpt-image-1"},
+			Fallbacks: []GenerationTarget{fallback},
+		}
+		targets := req.targets()
+		if len(targets) != 1 || targets[0].Model.ModelID != "fal-ai/flux" {
+			t.Errorf("unexpected targets: %+v", targets)
+		}
+	})
+}
+
+func TestShouldFailover(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		err       error
+		submitted bool
+		expected  bool
+	}{
+		{name: "成功", ctx: context.Background(), err: nil, expected: false},
+		{name: "服务端错误", ctx: context.Background(), err: &llm.HTTPStatusError{StatusCode: 503}, expected: true},
+		{name: "限流", ctx: context.Background(), err: &llm.HTTPStatusError{StatusCode: 429}, expected: true},
+		{name: "请求错误", ctx: context.Background(), err: &llm.HTTPStatusError{StatusCode: 400}, expected: false},
+		{name: "未知错误", ctx: context.Background(), err: errors.New("boom"), expected: false},
+		{name: "已取消", ctx: cancelled, err: &llm.HTTPStatusError{StatusCode: 503}, expected: false},
+		{name: "已提交任务后轮询失败", ctx: context.Background(), err: &llm.HTTPStatusError{StatusCode: 502}, submitted: true, expected: false},
+		{name: "已提交任务在服务商侧失败", ctx: context.Background(), err: &llm.TaskFailedError{Err: errors.New("fal.ai job failed")}, submitted: true, expected: true},
+		{name: "已提交任务在服务商侧取消", ctx: context.Background(), err: fmt.Errorf("dashscope: %w", &llm.TaskFailedError{Err: errors.New("task was cancelled")}), submitted: true, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldFailover(tt.ctx, tt.err, tt.submitted); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/llm"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const missingOutputMessage = "服务商未返回该输出"
+
+// generateOutputs 按请求的输出数量调用服务商：模型原生支持多输出时单次调用，
+// 否则并行发起 NumOutputs 次单输出调用并按顺序合并结果。
+// 部分调用失败时返回各失败调用的错误（slotErrors），全部失败时返回第一个错误。
+func generateOutputs(ctx context.Context, target GenerationTarget, request entity.GenerateContentRequest, onAttempt func(llm.AttemptResult)) (*entity.GenerateContentResponse, []error, error) {
+	numOutputs := request.GetNumOutputs()
+	if numOutputs <= 1 || target.Service.Capabilities(target.Model).SupportsMultipleOutputs {
+		resp, err := llm.GenerateWithRetry(ctx, target.Service, request, target.Model, target.RetryPolicy, onAttempt)
+		return resp, nil, err
+	}
+
+	single := request
+	single.Output.NumOutputs = 1
+
+	responses := make([]*entity.GenerateContentResponse, numOutputs)
+	errs := make([]error, numOutputs)
+	var wg sync.WaitGroup
+	for i := 0; i < numOutputs; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			slot := single
+			// 指定种子时各输出使用递增的种子，避免逐个调用得到完全相同的结果
+			if seed := single.Advanced.Seed; seed != nil {
+				slotSeed := *seed + int64(idx)
+				slot.Advanced.Seed = &slotSeed
+			}
+			slotCtx := llm.WithOutputSlot(ctx, idx)
+			responses[idx], errs[idx] = llm.GenerateWithRetry(slotCtx, target.Service, slot, target.Model, target.RetryPolicy, onAttempt)
+		}(i)
+	}
+	wg.Wait()
+
+	return mergeOutputResponses(responses, errs)
+}
+
+// mergeOutputResponses 合并并行调用的结果，输出按调用顺序排列
+func mergeOutputResponses(responses []*entity.GenerateContentResponse, errs []error) (*entity.GenerateContentResponse, []error, error) {
+	merged := &entity.GenerateContentResponse{}
+	var slotErrors []error
+	succeeded := 0
+
+	for idx, resp := range responses {
+		if resp != nil {
+			if merged.TaskID == "" {
+				merged.TaskID = resp.TaskID
+			}
+			if merged.RequestID == "" {
+				merged.RequestID = resp.RequestID
+			}
+		}
+		if errs[idx] != nil {
+			slotErrors = append(slotErrors, errs[idx])
+			continue
+		}
+		succeeded++
+		if resp == nil {
+			continue
+		}
+		merged.Outputs = append(merged.Outputs, resp.Outputs...)
+		if merged.Text == "" {
+			merged.Text = resp.Text
+		}
+		if resp.Usage != nil {
+			if merged.Usage == nil {
+				usage := *resp.Usage
+				merged.Usage = &usage
+			} else {
+				merged.Usage.Add(*resp.Usage)
+			}
+		}
+	}
+
+	if succeeded == 0 && len(slotErrors) > 0 {
+		return merged, nil, slotErrors[0]
+	}
+	return merged, slotErrors, nil
+}
+
+// saveOutputs 逐个保存输出媒体文件并记录每个输出的状态；未配置存储时输出仅标记为成功
+func (s *GenerationService) saveOutputs(parentCtx context.Context, payloads []string, modelName string) (entity.GenerationOutputs, []string, error) {
+	if parentCtx == nil {
+		parentCtx = context.Background()
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, 5*time.Minute)
+	defer cancel()
+
+	var (
+		outputs entity.GenerationOutputs
+		paths   []string
+		errs    []string
+	)
+
+	for idx, payload := range payloads {
+		output := entity.GenerationOutput{Index: idx, Status: entity.GenerationOutputStatusSucceeded}
+		if s.storage != nil {
+			relPath, err := s.saveMediaPayload(ctx, "outputs", idx, payload, modelName)
+			if err != nil {
+				output.Status = entity.GenerationOutputStatusFailed
+				output.Error = err.Error()
+				errs = append(errs, fmt.Sprintf("%d: %v", idx, err))
+			} else {
+				output.Path = relPath
+				paths = append(paths, relPath)
+			}
+		}
+		outputs = append(outputs, output)
+	}
+
+	if len(errs) > 0 {
+		return outputs, paths, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return outputs, paths, nil
+}
+
+// appendMissingOutputs 为未生成的输出补充失败状态，使输出列表覆盖请求的全部 numOutputs 个输出
+func appendMissingOutputs(outputs entity.GenerationOutputs, numOutputs int, slotErrors []error) entity.GenerationOutputs {
+	for _, slotErr := range slotErrors {
+		outputs = append(outputs, entity.GenerationOutput{
+			Index:  len(outputs),
+			Status: entity.GenerationOutputStatusFailed,
+			Error:  slotErr.Error(),
+		})
+	}
+	for len(outputs) < numOutputs {
+		outputs = append(outputs, entity.GenerationOutput{
+			Index:  len(outputs),
+			Status: entity.GenerationOutputStatusFailed,
+			Error:  missingOutputMessage,
+		})
+	}
+	return outputs
+}
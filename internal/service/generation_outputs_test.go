@@ -0,0 +1,152 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/llm"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type countingLLMService struct {
+	llm.BaseProvider
+	mu       sync.Mutex
+	calls    int
+	requests []int
+}
+
+func (s *countingLLMService) GenerateContent(ctx context.Context, request entity.GenerateContentRequest, dbModel entity.DbModel) (*entity.GenerateContentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	s.requests = append(s.requests, request.Output.NumOutputs)
+	if s.calls == 2 {
+		return nil, errors.New("prompt rejected")
+	}
+	return &entity.GenerateContentResponse{Outputs: []entity.MediaOutput{{Type: "image", URL: "https://example.com/a.png"}}}, nil
+}
+
+func TestGenerateOutputsFanOut(t *testing.T) {
+	svc := &countingLLMService{}
+	target := GenerationTarget{ProviderID: "openai", Model: entity.DbModel{ProviderID: "openai", ModelID: "gpt-image-1"}, Service: svc, RetryPolicy: llm.RetryPolicy{MaxAttempts: 1}}
+	request := entity.GenerateContentRequest{Prompt: "a red dress", Output: entity.OutputConfig{NumOutputs: 3}}
+
+	resp, slotErrors, err := generateOutputs(context.Background(), target, request, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if svc.calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", svc.calls)
+	}
+	for _, n := range svc.requests {
+		if n != 1 {
+			t.Errorf("expected single output per call, got %d", n)
+		}
+	}
+	if len(resp.Outputs) != 2 || len(slotErrors) != 1 {
+		t.Errorf("expected 2 outputs and 1 failure, got %d and %d", len(resp.Outputs), len(slotErrors))
+	}
+}
+
+type seedRecordingLLMService struct {
+	llm.BaseProvider
+	mu    sync.Mutex
+	seeds []int64
+}
+
+func (s *seedRecordingLLMService) GenerateContent(ctx context.Context, request entity.GenerateContentRequest, dbModel entity.DbModel) (*entity.GenerateContentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if request.Advanced.Seed != nil {
+		s.seeds = append(s.seeds, *request.Advanced.Seed)
+	}
+	return &entity.GenerateContentResponse{Outputs: []entity.MediaOutput{{Type: "image", URL: "https://example.com/a.png"}}}, nil
+}
+
+func TestGenerateOutputsFanOutSeeds(t *testing.T) {
+	svc := &seedRecordingLLMService{}
+	target := GenerationTarget{ProviderID: "openai", Model: entity.DbModel{ProviderID: "openai", ModelID: "gpt-image-1"}, Service: svc, RetryPolicy: llm.RetryPolicy{MaxAttempts: 1}}
+	seed := int64(100)
+	request := entity.GenerateContentRequest{Prompt: "a red dress", Output: entity.OutputConfig{NumOutputs: 3}, Advanced: entity.AdvancedParams{Seed: &seed}}
+
+	if _, _, err := generateOutputs(context.Background(), target, request, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	seen := make(map[int64]bool)
+	for _, s := range svc.seeds {
+		seen[s] = true
+	}
+	for _, want := range []int64{100, 101, 102} {
+		if !seen[want] {
+			t.Errorf("expected seed %d to be used, got %v", want, svc.seeds)
+		}
+	}
+	if *request.Advanced.Seed != 100 {
+		t.Errorf("expected request seed to be unchanged, got %d", *request.Advanced.Seed)
+	}
+}
+
+func TestMergeOutputResponses(t *testing.T) {
+	ok := &entity.GenerateContentResponse{Outputs: []entity.MediaOutput{{URL: "a"}}, RequestID: "req-1"}
+
+	t.Run("部分失败", func(t *testing.T) {
+		resp, slotErrors, err := mergeOutputResponses(
+			[]*entity.GenerateContentResponse{nil, ok},
+			[]error{errors.New("boom"), nil},
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(resp.Outputs) != 1 || resp.RequestID != "req-1" || len(slotErrors) != 1 {
+			t.Errorf("unexpected merge result: %+v, %v", resp, slotErrors)
+		}
+	})
+
+	t.Run("全部失败", func(t *testing.T) {
+		first := errors.New("first")
+		_, _, err := mergeOutputResponses(
+			[]*entity.GenerateContentResponse{nil, nil},
+			[]error{first, errors.New("second")},
+		)
+		if !errors.Is(err, first) {
+			t.Errorf("expected first error, got %v", err)
+		}
+	})
+}
+
+func TestAppendMissingOutputs(t *testing.T) {
+	saved := entity.GenerationOutputs{{Index: 0, Status: entity.GenerationOutputStatusSucceeded, Path: "outputs/a.png"}}
+
+	tests := []struct {
+		name       string
+		numOutputs int
+		slotErrors []error
+		failed     int
+	}{
+		{name: "全部生成", numOutputs: 1, failed: 0},
+		{name: "并行调用失败", numOutputs: 2, slotErrors: []error{errors.New("boom")}, failed: 1},
+		{name: "原生多输出数量不足", numOutputs: 3, failed: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputs := appendMissingOutputs(append(entity.GenerationOutputs(nil), saved...), tt.numOutputs, tt.slotErrors)
+			if len(outputs) != tt.numOutputs {
+				t.Fatalf("expected %d outputs, got %d", tt.numOutputs, len(outputs))
+			}
+			failed := 0
+			for idx, output := range outputs {
+				if output.Index != idx {
+					t.Errorf("expected index %d, got %d", idx, output.Index)
+				}
+				if output.Status == entity.GenerationOutputStatusFailed {
+					failed++
+				}
+			}
+			if failed != tt.failed {
+				t.Errorf("expected %d failed, got %d", tt.failed, failed)
+			}
+		})
+	}
+}
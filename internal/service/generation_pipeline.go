@@ -0,0 +1,456 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/llm"
+	"clothing/internal/storage"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// pipelineInputRoles 步骤输入媒体允许的角色
+var pipelineInputRoles = map[string]struct{}{
+	"reference":   {},
+	"first_frame": {},
+	"last_frame":  {},
+}
+
+// SetPipelineNotifyFunc 设置流水线运行结束通知函数（用于 SSE 推送）
+func (s *GenerationService) SetPipelineNotifyFunc(fn func(clientID string, run entity.DbPipelineRun)) {
+	s.pipelineNotifyFunc = fn
+}
+
+// SetMediaURLBuilder 设置存储路径到公开地址的转换函数，流水线据此将之前步骤的输出传给服务商
+func (s *GenerationService) SetMediaURLBuilder(fn func(path string) string) {
+	s.mediaURLBuilder = fn
+}
+
+// NormalisePipelineSteps 校验并规范化流水线步骤：服务商、模型与提示词必填，
+// 输入只能引用运行输入（0）或之前的步骤
+func NormalisePipelineSteps(steps entity.PipelineSteps) (entity.PipelineSteps, error) {
+	if len(steps) == 0 {
+		return nil, errors.New("流水线至少需要一个步骤")
+	}
+
+	result := make(entity.PipelineSteps, 0, len(steps))
+	for idx, step := range steps {
+		number := idx + 1
+		step.Name = strings.TrimSpace(step.Name)
+		step.ProviderID = strings.TrimSpace(step.ProviderID)
+		step.ModelID = strings.TrimSpace(step.ModelID)
+		step.Prompt = strings.TrimSpace(step.Prompt)
+		step.Size = strings.TrimSpace(step.Size)
+		if step.ProviderID == "" || step.ModelID == "" {
+			return nil, fmt.Errorf("第 %d 步缺少 provider_id 或 model_id", number)
+		}
+		if step.Prompt == "" {
+			return nil, fmt.Errorf("第 %d 步缺少 prompt", number)
+		}
+		if step.Duration < 0 || step.NumOutputs < 0 {
+			return nil, fmt.Errorf("第 %d 步的 duration 与 num_outputs 不能为负数", number)
+		}
+
+		inputs := make([]entity.PipelineStepInput, 0, len(step.Inputs))
+		for _, input := range step.Inputs {
+			if input.FromStep < 0 || input.FromStep >= number {
+				return nil, fmt.Errorf("第 %d 步只能引用运行输入（0）或之前的步骤，不能引用第 %d 步", number, input.FromStep)
+			}
+			if input.Output != nil && *input.Output < 0 {
+				return nil, fmt.Errorf("第 %d 步的输入序号不能为负数", number)
+			}
+			input.Type = strings.ToLower(strings.TrimSpace(input.Type))
+			if input.Type == "" {
+				input.Type = "image"
+			}
+			if input.Type != "image" && input.Type != "video" {
+				return nil, fmt.Errorf("第 %d 步的输入类型无效: %s", number, input.Type)
+			}
+			input.Role = strings.ToLower(strings.TrimSpace(input.Role))
+			if _, ok := pipelineInputRoles[input.Role]; input.Role != "" && !ok {
+				return nil, fmt.Errorf("第 %d 步的输入角色无效: %s", number, input.Role)
+			}
+			inputs = append(inputs, input)
+		}
+		step.Inputs = inputs
+		result = append(result, step)
+	}
+	return result, nil
+}
+
+// ValidatePipelineRun 在创建运行前检查变量能渲染所有步骤的提示词，且引用的运行输入存在
+func ValidatePipelineRun(steps entity.PipelineSteps, variables map[string]string, inputs []entity.MediaInput) error {
+	for idx, step := range steps {
+		if _, err := RenderPromptTemplate(step.Prompt, variables); err != nil {
+			return fmt.Errorf("第 %d 步: %v", idx+1, err)
+		}
+		for _, input := range step.Inputs {
+			if input.FromStep != 0 {
+				continue
+			}
+			if len(inputs) == 0 {
+				return fmt.Errorf("第 %d 步引用了运行输入，但未提交 input_media", idx+1)
+			}
+			if input.Output != nil && *input.Output >= len(inputs) {
+				return fmt.Errorf("第 %d 步引用的运行输入 %d 不存在", idx+1, *input.Output)
+			}
+		}
+	}
+	return nil
+}
+
+// StartPipelineRun 创建流水线运行并启动第一步，之后每一步结束时由任务队列推进到下一步
+func (s *GenerationService) StartPipelineRun(ctx context.Context, pipeline entity.DbPipeline, userID uint, clientID string, variables map[string]string, inputs []entity.MediaInput) (*entity.DbPipelineRun, error) {
+	if s.repo == nil {
+		return nil, errors.New("repository not configured")
+	}
+
+	rawInputs, err := json.Marshal(inputs)
+	if err != nil {
+		return nil, fmt.Errorf("encode pipeline inputs: %w", err)
+	}
+	runVariables := make(entity.JSONMap, len(variables))
+	for name, value := range variables {
+		runVariables[name] = value
+	}
+
+	run := &entity.DbPipelineRun{
+		PipelineID: pipeline.ID,
+		UserID:     userID,
+		ClientID:   strings.TrimSpace(clientID),
+		Steps:      pipeline.Steps,
+		Variables:  runVariables,
+		InputMedia: string(rawInputs),
+		Status:     entity.PipelineRunStatusRunning,
+	}
+	if err := s.repo.CreatePipelineRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	if err := s.startPipelineStep(ctx, run, 1, nil); err != nil {
+		s.finishPipelineRun(run.ID, entity.PipelineRunStatusFailed, fmt.Sprintf("第 1 步启动失败: %v", err))
+		return nil, err
+	}
+	run.CurrentStep = 1
+	return run, nil
+}
+
+// CancelPipelineRun 将运行标记为已取消，之后不再启动新的步骤；当前步骤的生成由调用方取消
+func (s *GenerationService) CancelPipelineRun(runID uint) bool {
+	return s.finishPipelineRun(runID, entity.PipelineRunStatusCancelled, cancelledMessage)
+}
+
+// startPipelineStep 为运行的第 step 步创建使用记录与生成任务。
+// 推进、创建与额度预留在同一事务中完成，多个实例同时推进同一运行时只有一个会成功；
+// 余额不足或超出配额时该步骤不会启动，由调用方将运行标记为失败。
+func (s *GenerationService) startPipelineStep(ctx context.Context, run *entity.DbPipelineRun, step int, records []entity.DbUsageRecord) error {
+	if step <= 0 || step > len(run.Steps) {
+		return fmt.Errorf("invalid pipeline step %d", step)
+	}
+	spec := run.Steps[step-1]
+
+	prompt, err := RenderPromptTemplate(spec.Prompt, pipelineVariables(run.Variables))
+	if err != nil {
+		return err
+	}
+	media, err := s.resolvePipelineInputs(ctx, *run, spec, records)
+	if err != nil {
+		return err
+	}
+
+	request := entity.GenerateContentRequest{
+		ClientID:   run.ClientID,
+		ProviderID: spec.ProviderID,
+		ModelID:    spec.ModelID,
+		Prompt:     prompt,
+		InputMedia: media,
+		Output: entity.OutputConfig{
+			Size:       spec.Size,
+			Duration:   spec.Duration,
+			NumOutputs: spec.NumOutputs,
+		},
+	}
+
+	// 主服务商暂时不可用时由任务执行时的故障转移链接管，其余错误直接失败
+	target, err := s.loadGenerationTarget(ctx, spec.ProviderID, spec.ModelID)
+	if err != nil && !errors.Is(err, errProviderUnavailable) {
+		return err
+	}
+	if target.Service != nil {
+		if err := llm.CheckCapabilities(request, llm.ServiceCapabilities(target.Service, target.Model)); err != nil {
+			return err
+		}
+		if err := target.Service.Validate(request, target.Model); err != nil {
+			return err
+		}
+	}
+
+	payload, err := encodeJobPayload(request)
+	if err != nil {
+		return fmt.Errorf("encode job payload: %w", err)
+	}
+
+	record := entity.DbUsageRecord{
+		UserID:     run.UserID,
+		ProviderID: spec.ProviderID,
+		ModelID:    spec.ModelID,
+		Prompt:     prompt,
+		Size:       spec.Size,
+		NumOutputs: request.GetNumOutputs(),
+		Status:     entity.UsageRecordStatusQueued,
+	}
+	job := entity.DbGenerationJob{
+		UserID:     run.UserID,
+		ProviderID: spec.ProviderID,
+		ModelID:    spec.ModelID,
+		ClientID:   run.ClientID,
+		Priority:   entity.GenerationJobPriorityBatch,
+		Payload:    payload,
+		Status:     entity.GenerationJobStatusPending,
+	}
+
+	started, err := s.repo.StartPipelineStep(ctx, run.ID, step, &record, &job, EstimateGenerationCost(target.Model, request), s.creditsEnforced)
+	if err != nil {
+		return err
+	}
+	if !started {
+		// 运行已结束或已被其他实例推进
+		return nil
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"run_id":    run.ID,
+		"step":      step,
+		"record_id": record.ID,
+		"provider":  spec.ProviderID,
+		"model":     spec.ModelID,
+	}).Info("pipeline step queued")
+
+	s.wakeWorkers()
+	return nil
+}
+
+// advancePipelineRun 在当前步骤的使用记录结束后推进运行：成功则启动下一步，失败或取消则结束运行
+func (s *GenerationService) advancePipelineRun(runID uint) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	fields := logrus.Fields{"run_id": runID}
+
+	run, err := s.repo.GetPipelineRun(ctx, runID)
+	if err != nil {
+		logrus.WithError(err).WithFields(fields).Warn("failed to load pipeline run")
+		return
+	}
+	if run.Status != entity.PipelineRunStatusRunning {
+		return
+	}
+
+	records, err := s.repo.ListPipelineRunRecords(ctx, runID)
+	if err != nil {
+		logrus.WithError(err).WithFields(fields).Warn("failed to load pipeline run records")
+		return
+	}
+
+	// 创建运行后、启动第一步前中断时补齐第一步
+	if run.CurrentStep == 0 {
+		if err := s.startPipelineStep(ctx, run, 1, records); err != nil {
+			s.finishPipelineRun(runID, entity.PipelineRunStatusFailed, fmt.Sprintf("第 1 步启动失败: %v", err))
+		}
+		return
+	}
+
+	current := pipelineStepRecord(records, run.CurrentStep)
+	if current == nil {
+		return
+	}
+
+	switch current.Status {
+	case entity.UsageRecordStatusFailed:
+		s.finishPipelineRun(runID, entity.PipelineRunStatusFailed, fmt.Sprintf("第 %d 步失败: %s", run.CurrentStep, current.ErrorMessage))
+	case entity.UsageRecordStatusCancelled:
+		s.finishPipelineRun(runID, entity.PipelineRunStatusCancelled, fmt.Sprintf("第 %d 步已取消", run.CurrentStep))
+	case entity.UsageRecordStatusSucceeded, entity.UsageRecordStatusPartiallySucceeded:
+		if run.CurrentStep >= len(run.Steps) {
+			s.finishPipelineRun(runID, entity.PipelineRunStatusSucceeded, "")
+			return
+		}
+		next := run.CurrentStep + 1
+		if err := s.startPipelineStep(ctx, run, next, records); err != nil {
+			logrus.WithError(err).WithFields(fields).WithField("step", next).Warn("failed to start pipeline step")
+			s.finishPipelineRun(runID, entity.PipelineRunStatusFailed, fmt.Sprintf("第 %d 步启动失败: %v", next, err))
+		}
+	}
+}
+
+// advancePipelineRuns 推进执行中的流水线运行，兜底处理任务结束后实例中断、未能推进的运行
+func (s *GenerationService) advancePipelineRuns(ctx context.Context) {
+	listCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	runs, err := s.repo.ListRunningPipelineRuns(listCtx, 50)
+	cancel()
+	if err != nil {
+		logrus.WithError(err).Error("failed to list running pipeline runs")
+		return
+	}
+	for _, run := range runs {
+		if ctx.Err() != nil {
+			return
+		}
+		s.advancePipelineRun(run.ID)
+	}
+}
+
+// finishPipelineRun 结束运行并推送通知，返回本次调用是否结束了运行
+func (s *GenerationService) finishPipelineRun(runID uint, status string, errMsg string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fields := logrus.Fields{"run_id": runID, "status": status}
+
+	finished, err := s.repo.FinishPipelineRun(ctx, runID, status, errMsg)
+	if err != nil {
+		logrus.WithError(err).WithFields(fields).Error("failed to finish pipeline run")
+		return false
+	}
+	if !finished {
+		return false
+	}
+	logrus.WithFields(fields).WithField("error", errMsg).Info("pipeline run finished")
+
+	if s.pipelineNotifyFunc == nil {
+		return true
+	}
+	run, err := s.repo.GetPipelineRun(ctx, runID)
+	if err != nil {
+		logrus.WithError(err).WithFields(fields).Warn("failed to load finished pipeline run")
+		return true
+	}
+	if strings.TrimSpace(run.ClientID) != "" {
+		s.pipelineNotifyFunc(run.ClientID, *run)
+	}
+	return true
+}
+
+// resolvePipelineInputs 按步骤的输入映射收集输入媒体
+func (s *GenerationService) resolvePipelineInputs(ctx context.Context, run entity.DbPipelineRun, spec entity.PipelineStep, records []entity.DbUsageRecord) ([]entity.MediaInput, error) {
+	var runInputs []entity.MediaInput
+	media := make([]entity.MediaInput, 0, len(spec.Inputs))
+
+	for _, input := range spec.Inputs {
+		if input.FromStep == 0 {
+			if runInputs == nil && strings.TrimSpace(run.InputMedia) != "" {
+				if err := json.Unmarshal([]byte(run.InputMedia), &runInputs); err != nil {
+					return nil, fmt.Errorf("decode pipeline inputs: %w", err)
+				}
+			}
+			selected, err := selectPipelineOutputs(len(runInputs), input.Output, "运行输入")
+			if err != nil {
+				return nil, err
+			}
+			for _, idx := range selected {
+				item := runInputs[idx]
+				if input.Type != "" {
+					item.Type = input.Type
+				}
+				if input.Role != "" {
+					item.Role = input.Role
+				}
+				media = append(media, item)
+			}
+			continue
+		}
+
+		source := pipelineStepRecord(records, input.FromStep)
+		if source == nil {
+			return nil, fmt.Errorf("第 %d 步尚未执行", input.FromStep)
+		}
+		selected, err := selectPipelineOutputs(len(source.OutputImages), input.Output, fmt.Sprintf("第 %d 步的输出", input.FromStep))
+		if err != nil {
+			return nil, err
+		}
+		for _, idx := range selected {
+			content, err := s.StoredMediaContent(ctx, source.OutputImages[idx])
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 步的输出 %d: %w", input.FromStep, idx, err)
+			}
+			media = append(media, entity.MediaInput{Type: input.Type, Content: content, Role: input.Role})
+		}
+	}
+	return media, nil
+}
+
+// selectPipelineOutputs 返回要使用的输出序号，output 为空时选择全部
+func selectPipelineOutputs(count int, output *int, source string) ([]int, error) {
+	if count == 0 {
+		return nil, fmt.Errorf("%s为空", source)
+	}
+	if output != nil {
+		if *output >= count {
+			return nil, fmt.Errorf("%s只有 %d 个，不存在序号 %d", source, count, *output)
+		}
+		return []int{*output}, nil
+	}
+	selected := make([]int, count)
+	for i := range selected {
+		selected[i] = i
+	}
+	return selected, nil
+}
+
+// StoredMediaContent 将已保存媒体的存储路径转换为服务商可读取的内容：
+// 公开地址可直接访问时使用地址，本地存储则读取文件并编码为 data URL
+func (s *GenerationService) StoredMediaContent(ctx context.Context, path string) (string, error) {
+	path = strings.TrimSpace(path)
+	url := path
+	if s.mediaURLBuilder != nil {
+		url = s.mediaURLBuilder(path)
+	}
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		return url, nil
+	}
+
+	local, ok := s.storage.(storage.LocalBaseDirProvider)
+	if !ok {
+		return "", errors.New("存储没有可公开访问的地址")
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(local.LocalBaseDir(), filepath.FromSlash(path)))
+	if err != nil {
+		return "", fmt.Errorf("read stored file: %w", err)
+	}
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// pipelineStepRecord 返回运行中第 step 步的使用记录
+func pipelineStepRecord(records []entity.DbUsageRecord, step int) *entity.DbUsageRecord {
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].PipelineStep == step {
+			return &records[i]
+		}
+	}
+	return nil
+}
+
+// pipelineVariables 将运行保存的变量转换为模板渲染使用的字符串变量
+func pipelineVariables(values entity.JSONMap) map[string]string {
+	variables := make(map[string]string, len(values))
+	for name, value := range values {
+		variables[name] = fmt.Sprint(value)
+	}
+	return variables
+}
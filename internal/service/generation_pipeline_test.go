@@ -0,0 +1,186 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/storage"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNormalisePipelineSteps(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	tests := []struct {
+		name    string
+		steps   entity.PipelineSteps
+		wantErr bool
+	}{
+		{name: "空流水线", steps: nil, wantErr: true},
+		{name: "缺少模型", steps: entity.PipelineSteps{{ProviderID: "p", Prompt: "x"}}, wantErr: true},
+		{name: "缺少提示词", steps: entity.PipelineSteps{{ProviderID: "p", ModelID: "m", Prompt: "  "}}, wantErr: true},
+		{
+			name: "引用之后的步骤",
+			steps: entity.PipelineSteps{
+				{ProviderID: "p", ModelID: "m", Prompt: "a", Inputs: []entity.PipelineStepInput{{FromStep: 1}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "无效的输入角色",
+			steps: entity.PipelineSteps{
+				{ProviderID: "p", ModelID: "m", Prompt: "a"},
+				{ProviderID: "p", ModelID: "m", Prompt: "b", Inputs: []entity.PipelineStepInput{{FromStep: 1, Role: "middle_frame"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "负数输出序号",
+			steps: entity.PipelineSteps{
+				{ProviderID: "p", ModelID: "m", Prompt: "a"},
+				{ProviderID: "p", ModelID: "m", Prompt: "b", Inputs: []entity.PipelineStepInput{{FromStep: 1, Output: intPtr(-1)}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "图片生成后转视频",
+			steps: entity.PipelineSteps{
+				{ProviderID: "p", ModelID: "image", Prompt: "a"},
+				{ProviderID: "p", ModelID: "video", Prompt: "b", Inputs: []entity.PipelineStepInput{{FromStep: 1, Output: intPtr(0), Role: "First_Frame"}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NormalisePipelineSteps(tt.steps)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("规范化输入类型与角色", func(t *testing.T) {
+		steps, err := NormalisePipelineSteps(entity.PipelineSteps{
+			{ProviderID: " p ", ModelID: " m ", Prompt: " a "},
+			{ProviderID: "p", ModelID: "m", Prompt: "b", Inputs: []entity.PipelineStepInput{{FromStep: 1, Role: " First_Frame "}}},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if steps[0].ProviderID != "p" || steps[0].ModelID != "m" || steps[0].Prompt != "a" {
+			t.Errorf("expected trimmed step, got %+v", steps[0])
+		}
+		input := steps[1].Inputs[0]
+		if input.Type != "image" || input.Role != "first_frame" {
+			t.Errorf("expected image/first_frame, got %s/%s", input.Type, input.Role)
+		}
+	})
+}
+
+func TestValidatePipelineRun(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	steps := entity.PipelineSteps{
+		{ProviderID: "p", ModelID: "m", Prompt: "a {{style}} photo", Inputs: []entity.PipelineStepInput{{FromStep: 0, Output: intPtr(1), Type: "image"}}},
+		{ProviderID: "p", ModelID: "m", Prompt: "animate", Inputs: []entity.PipelineStepInput{{FromStep: 1, Type: "image"}}},
+	}
+	media := entity.MediaInput{Type: "image", Content: "https://example.com/a.png"}
+
+	tests := []struct {
+		name      string
+		variables map[string]string
+		inputs    []entity.MediaInput
+		wantErr   bool
+	}{
+		{name: "变量与输入齐全", variables: map[string]string{"style": "vintage"}, inputs: []entity.MediaInput{media, media}},
+		{name: "缺少变量", inputs: []entity.MediaInput{media, media}, wantErr: true},
+		{name: "缺少运行输入", variables: map[string]string{"style": "vintage"}, wantErr: true},
+		{name: "运行输入序号越界", variables: map[string]string{"style": "vintage"}, inputs: []entity.MediaInput{media}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePipelineRun(steps, tt.variables, tt.inputs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSelectPipelineOutputs(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	tests := []struct {
+		name    string
+		count   int
+		output  *int
+		want    []int
+		wantErr bool
+	}{
+		{name: "选择全部输出", count: 3, want: []int{0, 1, 2}},
+		{name: "选择指定输出", count: 3, output: intPtr(1), want: []int{1}},
+		{name: "序号越界", count: 2, output: intPtr(2), wantErr: true},
+		{name: "没有输出", count: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectPipelineOutputs(tt.count, tt.output, "输出")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStoredMediaContent(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(store.LocalBaseDir(), "outputs"), 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(store.LocalBaseDir(), "outputs", "a.png"), []byte("png"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	t.Run("本地存储编码为 data URL", func(t *testing.T) {
+		svc := NewGenerationService(nil, store)
+		svc.SetMediaURLBuilder(func(path string) string { return "/files/" + path })
+
+		content, err := svc.StoredMediaContent(context.Background(), "outputs/a.png")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if content != "data:image/png;base64,cG5n" {
+			t.Errorf("unexpected content %q", content)
+		}
+	})
+
+	t.Run("公开地址直接使用", func(t *testing.T) {
+		svc := NewGenerationService(nil, store)
+		svc.SetMediaURLBuilder(func(path string) string { return "https://cdn.example.com/" + path })
+
+		content, err := svc.StoredMediaContent(context.Background(), "outputs/a.png")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if content != "https://cdn.example.com/outputs/a.png" {
+			t.Errorf("unexpected content %q", content)
+		}
+	})
+
+	t.Run("文件不存在", func(t *testing.T) {
+		svc := NewGenerationService(nil, store)
+		if _, err := svc.StoredMediaContent(context.Background(), "outputs/missing.png"); err == nil {
+			t.Error("expected error for missing file")
+		}
+	})
+}
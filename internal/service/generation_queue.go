@@ -0,0 +1,406 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// WorkerConfig 生成任务工作池配置
+type WorkerConfig struct {
+	// WorkerID 标识当前实例，用于租约归属；显式配置固定 ID 后，重启时可立即恢复被中断的任务。
+	// 未配置时使用主机名加进程随机后缀，避免主机名相同的副本互相释放租约。
+	WorkerID string
+	// Workers 并发执行的任务数上限。
+	Workers int
+	// PollInterval 轮询任务表的间隔。
+	PollInterval time.Duration
+	// LeaseDuration 任务租约时长，执行期间会定期续约。
+	LeaseDuration time.Duration
+	// MaxAttempts 任务被认领的最大次数，超过后直接判定失败（防止崩溃循环）。
+	MaxAttempts int
+}
+
+// normaliseWorkerConfig 填充工作池配置的默认值
+func normaliseWorkerConfig(cfg WorkerConfig) WorkerConfig {
+	cfg.WorkerID = strings.TrimSpace(cfg.WorkerID)
+	if cfg.WorkerID == "" {
+		cfg.WorkerID = defaultWorkerID
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 2 * time.Second
+	}
+	if cfg.LeaseDuration <= 0 {
+		cfg.LeaseDuration = time.Minute
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	return cfg
+}
+
+// defaultWorkerID 未配置 WorkerID 时使用的实例标识，在进程内保持不变
+var defaultWorkerID = newDefaultWorkerID()
+
+func newDefaultWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil || strings.TrimSpace(hostname) == "" {
+		hostname = "worker"
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
+	}
+	return hostname + "-" + hex.EncodeToString(suffix)
+}
+
+// generationJobPayload 任务表中持久化的请求内容
+type generationJobPayload struct {
+	Request entity.GenerateContentRequest `json:"request"`
+}
+
+func encodeJobPayload(request entity.GenerateContentRequest) (string, error) {
+	raw, err := json.Marshal(generationJobPayload{Request: request})
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func decodeJobPayload(payload string) (entity.GenerateContentRequest, error) {
+	var decoded generationJobPayload
+	if strings.TrimSpace(payload) == "" {
+		return decoded.Request, errors.New("empty job payload")
+	}
+	if err := json.Unmarshal([]byte(payload), &decoded); err != nil {
+		return decoded.Request, err
+	}
+	return decoded.Request, nil
+}
+
+// QueuedGenerationRequest 返回使用记录入队时提交的原始生成请求，包含输入媒体的类型与角色及输出时长；
+// 记录没有对应任务时返回 gorm.ErrRecordNotFound
+func (s *GenerationService) QueuedGenerationRequest(ctx context.Context, recordID uint) (entity.GenerateContentRequest, error) {
+	if s.repo == nil {
+		return entity.GenerateContentRequest{}, errors.New("repository not configured")
+	}
+	job, err := s.repo.GetGenerationJobByRecord(ctx, recordID)
+	if err != nil {
+		return entity.GenerateContentRequest{}, err
+	}
+	request, err := decodeJobPayload(job.Payload)
+	if err != nil {
+		return request, fmt.Errorf("decode job payload: %w", err)
+	}
+	return request, nil
+}
+
+// EnqueueGeneration 在同一事务中创建使用记录、关联标签、按 estimate 预留额度并将生成请求写入持久化任务队列的
+// priority 通道，由工作池异步执行；成功后 record 中会填充记录 ID。余额不足或超出配额时什么都不会创建
+func (s *GenerationService) EnqueueGeneration(ctx context.Context, record *entity.DbUsageRecord, request entity.GenerateContentRequest, priority int, estimate float64, tagIDs []uint) error {
+	if s.repo == nil {
+		return errors.New("repository not configured")
+	}
+
+	payload, err := encodeJobPayload(request)
+	if err != nil {
+		return fmt.Errorf("encode job payload: %w", err)
+	}
+
+	job := entity.DbGenerationJob{
+		UserID:     record.UserID,
+		ProviderID: record.ProviderID,
+		ModelID:    record.ModelID,
+		ClientID:   strings.TrimSpace(request.ClientID),
+		Priority:   priority,
+		Payload:    payload,
+		Status:     entity.GenerationJobStatusPending,
+	}
+	if err := s.repo.CreateGeneration(ctx, record, &job, tagIDs, estimate, s.creditsEnforced); err != nil {
+		return err
+	}
+
+	s.wakeWorkers()
+	return nil
+}
+
+// wakeWorkers 通知工作池立即尝试认领任务
+func (s *GenerationService) wakeWorkers() {
+	if s.wakeCh == nil {
+		return
+	}
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// StartWorkers 启动生成任务工作池，持续认领待处理及租约过期（被中断）的任务
+func (s *GenerationService) StartWorkers(ctx context.Context, cfg WorkerConfig) {
+	if s.repo == nil {
+		return
+	}
+	cfg = normaliseWorkerConfig(cfg)
+	s.workerCfg = cfg
+
+	// 同一实例重启后，之前持有的任务不可能仍在执行，直接放回队列
+	releaseCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	released, err := s.repo.ReleaseGenerationJobs(releaseCtx, cfg.WorkerID)
+	cancel()
+	if err != nil {
+		logrus.WithError(err).WithField("worker_id", cfg.WorkerID).Warn("failed to release interrupted generation jobs")
+	} else if released > 0 {
+		logrus.WithFields(logrus.Fields{
+			"worker_id": cfg.WorkerID,
+			"released":  released,
+		}).Info("released interrupted generation jobs")
+	}
+
+	go s.runWorkerLoop(ctx, cfg)
+}
+
+func (s *GenerationService) runWorkerLoop(ctx context.Context, cfg WorkerConfig) {
+	slots := make(chan struct{}, cfg.Workers)
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	logrus.WithFields(logrus.Fields{
+		"worker_id": cfg.WorkerID,
+		"workers":   cfg.Workers,
+	}).Info("generation worker started")
+
+	for {
+		s.claimAndDispatch(ctx, cfg, slots)
+
+		select {
+		case <-ctx.Done():
+			logrus.WithField("worker_id", cfg.WorkerID).Info("generation worker stopped")
+			return
+		case <-ticker.C:
+		case <-s.wakeCh:
+		}
+	}
+}
+
+func (s *GenerationService) claimAndDispatch(ctx context.Context, cfg WorkerConfig, slots chan struct{}) {
+	free := cap(slots) - len(slots)
+	if free <= 0 {
+		return
+	}
+
+	claimCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	jobs, err := s.repo.ClaimGenerationJobs(claimCtx, cfg.WorkerID, free, cfg.LeaseDuration)
+	if err != nil {
+		logrus.WithError(err).WithField("worker_id", cfg.WorkerID).Error("failed to claim generation jobs")
+	}
+
+	for _, job := range jobs {
+		slots <- struct{}{}
+		go func(job entity.DbGenerationJob) {
+			defer func() { <-slots }()
+			s.runJob(ctx, cfg, job)
+		}(job)
+	}
+}
+
+// runJob 执行单个已认领的任务，并在执行期间维持租约
+func (s *GenerationService) runJob(ctx context.Context, cfg WorkerConfig, job entity.DbGenerationJob) {
+	fields := jobLogFields(job)
+	if !s.checkJobRunnable(cfg, job, fields) {
+		return
+	}
+
+	req, err := s.buildJobRequest(ctx, job)
+	if err != nil {
+		logrus.WithError(err).WithFields(fields).Error("failed to prepare generation job")
+		s.failJob(job, err.Error())
+		return
+	}
+
+	logrus.WithFields(fields).Info("generation job started")
+	s.runLeasedJob(ctx, cfg, job, func() error {
+		return s.handleGeneration(req)
+	})
+}
+
+func jobLogFields(job entity.DbGenerationJob) logrus.Fields {
+	return logrus.Fields{
+		"job_id":    job.ID,
+		"record_id": job.RecordID,
+		"provider":  job.ProviderID,
+		"model":     job.ModelID,
+		"attempt":   job.Attempts,
+	}
+}
+
+// checkJobRunnable 处理认领前已请求取消或中断次数过多的任务，返回任务是否仍需执行
+func (s *GenerationService) checkJobRunnable(cfg WorkerConfig, job entity.DbGenerationJob, fields logrus.Fields) bool {
+	// 执行实例崩溃前已收到取消请求，无需重新执行
+	if job.CancelRequested {
+		logrus.WithFields(fields).Info("generation job cancelled before resume")
+		s.markRecordCancelled(job.RecordID)
+		s.finishJob(job, entity.GenerationJobStatusCancelled, cancelledMessage)
+		s.notifyComplete(job.ClientID, job.RecordID, entity.UsageRecordStatusCancelled, cancelledMessage)
+		return false
+	}
+
+	if job.Attempts > cfg.MaxAttempts {
+		errMsg := fmt.Sprintf("生成任务被中断次数过多（%d 次），已放弃", job.Attempts-1)
+		logrus.WithFields(fields).Warn("generation job exceeded max attempts")
+		s.failJob(job, errMsg)
+		return false
+	}
+	return true
+}
+
+// runLeasedJob 在维持租约的同时执行 run，并根据其结果结束任务
+func (s *GenerationService) runLeasedJob(ctx context.Context, cfg WorkerConfig, job entity.DbGenerationJob, run func() error) {
+	leaseCtx, stopLease := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.keepJobLease(leaseCtx, cfg, job)
+	}()
+
+	genErr := run()
+
+	stopLease()
+	wg.Wait()
+
+	status := entity.GenerationJobStatusSucceeded
+	lastError := ""
+	switch {
+	case errors.Is(genErr, errGenerationCancelled):
+		status = entity.GenerationJobStatusCancelled
+		lastError = cancelledMessage
+	case genErr != nil:
+		status = entity.GenerationJobStatusFailed
+		lastError = genErr.Error()
+	}
+	s.finishJob(job, status, lastError)
+}
+
+// buildJobRequest 根据任务表内容重建生成请求
+func (s *GenerationService) buildJobRequest(ctx context.Context, job entity.DbGenerationJob) (GenerateContentRequest, error) {
+	request, err := decodeJobPayload(job.Payload)
+	if err != nil {
+		return GenerateContentRequest{}, fmt.Errorf("decode job payload: %w", err)
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	record, err := s.repo.GetUsageRecord(loadCtx, job.RecordID)
+	if err != nil {
+		return GenerateContentRequest{}, fmt.Errorf("load usage record: %w", err)
+	}
+
+	primary, err := s.loadGenerationTarget(loadCtx, job.ProviderID, job.ModelID)
+	fallbacks := s.loadFailoverTargets(loadCtx, job.ProviderID, job.ModelID)
+	if err != nil {
+		// 主服务商实例不可用时由故障转移链接管，其余错误（不存在、已禁用）直接失败
+		if !errors.Is(err, errProviderUnavailable) || len(fallbacks) == 0 {
+			return GenerateContentRequest{}, err
+		}
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"record_id": job.RecordID,
+			"provider":  job.ProviderID,
+			"model":     job.ModelID,
+		}).Warn("primary provider unavailable, using failover chain")
+	}
+
+	return GenerateContentRequest{
+		Record:   *record,
+		Request:  request,
+		Model:    primary.Model,
+		Service:  primary.Service,
+		ClientID: job.ClientID,
+
+		RetryPolicy: primary.RetryPolicy,
+		Fallbacks:   fallbacks,
+	}, nil
+}
+
+// keepJobLease 定期续约，直到任务执行结束
+func (s *GenerationService) keepJobLease(ctx context.Context, cfg WorkerConfig, job entity.DbGenerationJob) {
+	interval := cfg.LeaseDuration / 3
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			renewCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			cancelRequested, err := s.repo.RenewGenerationJobLease(renewCtx, job.ID, cfg.WorkerID, cfg.LeaseDuration)
+			cancel()
+			if err != nil && ctx.Err() == nil {
+				logrus.WithError(err).WithFields(logrus.Fields{
+					"job_id":    job.ID,
+					"record_id": job.RecordID,
+				}).Warn("failed to renew generation job lease")
+			}
+			if cancelRequested {
+				// 取消请求可能来自其他实例
+				s.cancelRunning(job.RecordID)
+			}
+		}
+	}
+}
+
+// failJob 在生成开始前失败时，同时更新使用记录与任务状态
+func (s *GenerationService) failJob(job entity.DbGenerationJob, errMsg string) {
+	failedStatus := entity.UsageRecordStatusFailed
+	finishedAt := time.Now()
+	s.updateUsageRecord(job.RecordID, entity.UsageRecordUpdates{
+		ErrorMessage: &errMsg,
+		Status:       &failedStatus,
+		FinishedAt:   &finishedAt,
+	})
+	s.finishJob(job, entity.GenerationJobStatusFailed, errMsg)
+	s.notifyComplete(job.ClientID, job.RecordID, "failure", errMsg)
+}
+
+func (s *GenerationService) finishJob(job entity.DbGenerationJob, status string, lastError string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.repo.FinishGenerationJob(ctx, job.ID, job.LeaseOwner, status, lastError); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 租约已过期并被其他实例重新认领，结果以新的持有者为准
+			logrus.WithField("job_id", job.ID).Warn("generation job lease lost before finishing")
+			return
+		}
+		logrus.WithError(err).WithField("job_id", job.ID).Error("failed to finish generation job")
+	}
+
+	if job.BatchID != nil {
+		// 批次释放了一个并发额度，唤醒工作池认领同批次的下一个任务
+		s.wakeWorkers()
+		s.completeBatchIfDone(*job.BatchID)
+	}
+	if job.PipelineRunID != nil {
+		s.advancePipelineRun(*job.PipelineRunID)
+	}
+}
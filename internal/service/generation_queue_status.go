@@ -0,0 +1,144 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// QueueStatus 统计生成任务队列各优先级通道与服务商的深度，userID 非 0 时同时统计该用户自己的任务
+func (s *GenerationService) QueueStatus(ctx context.Context, userID uint) (*entity.GenerationQueueStatus, error) {
+	if s.repo == nil {
+		return nil, errors.New("repository not configured")
+	}
+
+	jobs, err := s.repo.ListActiveGenerationJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	providers, err := s.repo.ListProviders(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	return buildQueueStatus(jobs, providers, userID, time.Now()), nil
+}
+
+// QueuePosition 估算使用记录对应任务在认领顺序中的位置
+func (s *GenerationService) QueuePosition(ctx context.Context, recordID uint) (*entity.GenerationQueuePosition, error) {
+	if s.repo == nil {
+		return nil, errors.New("repository not configured")
+	}
+
+	jobs, err := s.repo.ListActiveGenerationJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return queuePosition(jobs, recordID, time.Now()), nil
+}
+
+// splitActiveJobs 将未结束的任务分为待认领与执行中；租约已过期的任务可被重新认领，计为待认领
+func splitActiveJobs(jobs []entity.DbGenerationJob, now time.Time) ([]entity.DbGenerationJob, []entity.DbGenerationJob) {
+	var pending, running []entity.DbGenerationJob
+	for _, job := range jobs {
+		if job.Status == entity.GenerationJobStatusRunning && job.LeaseExpiresAt != nil && !job.LeaseExpiresAt.Before(now) {
+			running = append(running, job)
+			continue
+		}
+		pending = append(pending, job)
+	}
+	return pending, running
+}
+
+func buildQueueStatus(jobs []entity.DbGenerationJob, providers []entity.DbProvider, userID uint, now time.Time) *entity.GenerationQueueStatus {
+	pending, running := splitActiveJobs(jobs, now)
+	status := &entity.GenerationQueueStatus{
+		Pending:   len(pending),
+		Running:   len(running),
+		Lanes:     make([]entity.GenerationQueueLane, 0, 3),
+		Providers: make([]entity.GenerationQueueProvider, 0),
+	}
+
+	lanes := make(map[int]*entity.GenerationQueueLane)
+	for _, priority := range []int{entity.GenerationJobPriorityAdmin, entity.GenerationJobPriorityInteractive, entity.GenerationJobPriorityBatch} {
+		lanes[priority] = &entity.GenerationQueueLane{Name: entity.GenerationJobLaneName(priority), Priority: priority}
+	}
+	byProvider := make(map[string]*entity.GenerationQueueProvider)
+	for i := range providers {
+		if limit := providers[i].MaxConcurrentJobs(); limit > 0 {
+			byProvider[providers[i].ID] = &entity.GenerationQueueProvider{ProviderID: providers[i].ID, MaxConcurrentJobs: limit}
+		}
+	}
+
+	count := func(job entity.DbGenerationJob, isRunning bool) {
+		lane, ok := lanes[job.Priority]
+		if !ok {
+			lane = &entity.GenerationQueueLane{Name: entity.GenerationJobLaneName(job.Priority), Priority: job.Priority}
+			lanes[job.Priority] = lane
+		}
+		provider, ok := byProvider[job.ProviderID]
+		if !ok {
+			provider = &entity.GenerationQueueProvider{ProviderID: job.ProviderID}
+			byProvider[job.ProviderID] = provider
+		}
+		if isRunning {
+			lane.Running++
+			provider.Running++
+			if userID != 0 && job.UserID == userID {
+				status.UserRunning++
+			}
+			return
+		}
+		lane.Pending++
+		provider.Pending++
+		if userID != 0 && job.UserID == userID {
+			status.UserPending++
+		}
+	}
+	for _, job := range pending {
+		count(job, false)
+	}
+	for _, job := range running {
+		count(job, true)
+	}
+
+	for _, lane := range lanes {
+		status.Lanes = append(status.Lanes, *lane)
+	}
+	sort.Slice(status.Lanes, func(i, j int) bool { return status.Lanes[i].Priority > status.Lanes[j].Priority })
+	for _, provider := range byProvider {
+		status.Providers = append(status.Providers, *provider)
+	}
+	sort.Slice(status.Providers, func(i, j int) bool { return status.Providers[i].ProviderID < status.Providers[j].ProviderID })
+	return status
+}
+
+func queuePosition(jobs []entity.DbGenerationJob, recordID uint, now time.Time) *entity.GenerationQueuePosition {
+	pending, running := splitActiveJobs(jobs, now)
+	position := &entity.GenerationQueuePosition{RecordID: recordID, Pending: len(pending)}
+
+	for _, job := range running {
+		if job.RecordID == recordID {
+			position.Status = entity.GenerationJobStatusRunning
+			position.Priority = job.Priority
+			position.Lane = entity.GenerationJobLaneName(job.Priority)
+			return position
+		}
+	}
+
+	runningByUser := make(map[uint]int)
+	for _, job := range running {
+		runningByUser[job.UserID]++
+	}
+	for i, job := range entity.ScheduleGenerationJobs(pending, runningByUser) {
+		if job.RecordID == recordID {
+			position.Status = entity.GenerationJobStatusPending
+			position.Priority = job.Priority
+			position.Lane = entity.GenerationJobLaneName(job.Priority)
+			position.Position = i + 1
+			break
+		}
+	}
+	return position
+}
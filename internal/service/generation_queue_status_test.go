@@ -0,0 +1,140 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScheduleGenerationJobs(t *testing.T) {
+	job := func(id, userID uint, priority int) entity.DbGenerationJob {
+		return entity.DbGenerationJob{ID: id, RecordID: id, UserID: userID, Priority: priority}
+	}
+
+	tests := []struct {
+		name    string
+		pending []entity.DbGenerationJob
+		running map[uint]int
+		want    []uint
+	}{
+		{
+			name: "同一通道按用户轮转",
+			pending: []entity.DbGenerationJob{
+				job(1, 1, entity.GenerationJobPriorityInteractive),
+				job(2, 1, entity.GenerationJobPriorityInteractive),
+				job(3, 1, entity.GenerationJobPriorityInteractive),
+				job(4, 2, entity.GenerationJobPriorityInteractive),
+				job(5, 3, entity.GenerationJobPriorityInteractive),
+				job(6, 2, entity.GenerationJobPriorityInteractive),
+			},
+			want: []uint{1, 4, 5, 2, 6, 3},
+		},
+		{
+			name: "高优先级通道优先",
+			pending: []entity.DbGenerationJob{
+				job(1, 1, entity.GenerationJobPriorityBatch),
+				job(2, 2, entity.GenerationJobPriorityInteractive),
+				job(3, 3, entity.GenerationJobPriorityAdmin),
+			},
+			want: []uint{3, 2, 1},
+		},
+		{
+			name: "执行中任务较多的用户靠后",
+			pending: []entity.DbGenerationJob{
+				job(1, 1, entity.GenerationJobPriorityInteractive),
+				job(2, 2, entity.GenerationJobPriorityInteractive),
+				job(3, 2, entity.GenerationJobPriorityInteractive),
+			},
+			running: map[uint]int{1: 2},
+			want:    []uint{2, 3, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]uint, 0, len(tt.pending))
+			for _, job := range entity.ScheduleGenerationJobs(tt.pending, tt.running) {
+				got = append(got, job.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected order %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestQueuePosition(t *testing.T) {
+	now := time.Now()
+	lease := now.Add(time.Minute)
+	expired := now.Add(-time.Minute)
+	jobs := []entity.DbGenerationJob{
+		{ID: 1, RecordID: 11, UserID: 1, Priority: entity.GenerationJobPriorityInteractive, Status: entity.GenerationJobStatusRunning, LeaseExpiresAt: &lease},
+		{ID: 2, RecordID: 12, UserID: 1, Priority: entity.GenerationJobPriorityInteractive, Status: entity.GenerationJobStatusPending},
+		{ID: 3, RecordID: 13, UserID: 2, Priority: entity.GenerationJobPriorityInteractive, Status: entity.GenerationJobStatusPending},
+		{ID: 4, RecordID: 14, UserID: 3, Priority: entity.GenerationJobPriorityBatch, Status: entity.GenerationJobStatusRunning, LeaseExpiresAt: &expired},
+	}
+
+	tests := []struct {
+		name         string
+		recordID     uint
+		wantStatus   string
+		wantPosition int
+	}{
+		{name: "执行中", recordID: 11, wantStatus: entity.GenerationJobStatusRunning},
+		{name: "其他用户先轮到", recordID: 12, wantStatus: entity.GenerationJobStatusPending, wantPosition: 2},
+		{name: "无执行中任务的用户优先", recordID: 13, wantStatus: entity.GenerationJobStatusPending, wantPosition: 1},
+		{name: "租约过期计为待认领", recordID: 14, wantStatus: entity.GenerationJobStatusPending, wantPosition: 3},
+		{name: "不在队列中", recordID: 99},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := queuePosition(jobs, tt.recordID, now)
+			if got.Status != tt.wantStatus || got.Position != tt.wantPosition {
+				t.Errorf("expected %s at %d, got %s at %d", tt.wantStatus, tt.wantPosition, got.Status, got.Position)
+			}
+			if got.Pending != 3 {
+				t.Errorf("expected 3 pending jobs, got %d", got.Pending)
+			}
+		})
+	}
+}
+
+func TestBuildQueueStatus(t *testing.T) {
+	now := time.Now()
+	lease := now.Add(time.Minute)
+	jobs := []entity.DbGenerationJob{
+		{ID: 1, UserID: 1, ProviderID: "fal", Priority: entity.GenerationJobPriorityInteractive, Status: entity.GenerationJobStatusRunning, LeaseExpiresAt: &lease},
+		{ID: 2, UserID: 1, ProviderID: "fal", Priority: entity.GenerationJobPriorityInteractive, Status: entity.GenerationJobStatusPending},
+		{ID: 3, UserID: 2, ProviderID: "openrouter", Priority: entity.GenerationJobPriorityBatch, Status: entity.GenerationJobStatusPending},
+	}
+	providers := []entity.DbProvider{
+		{ID: "fal", Config: entity.JSONMap{"max_concurrent_jobs": float64(2)}},
+		{ID: "gemini", Config: entity.JSONMap{"max_concurrent_jobs": "1"}},
+		{ID: "openrouter"},
+	}
+
+	status := buildQueueStatus(jobs, providers, 1, now)
+	if status.Pending != 2 || status.Running != 1 || status.UserPending != 1 || status.UserRunning != 1 {
+		t.Fatalf("unexpected totals: %+v", status)
+	}
+
+	wantLanes := []entity.GenerationQueueLane{
+		{Name: "admin", Priority: entity.GenerationJobPriorityAdmin},
+		{Name: "interactive", Priority: entity.GenerationJobPriorityInteractive, Pending: 1, Running: 1},
+		{Name: "batch", Priority: entity.GenerationJobPriorityBatch, Pending: 1},
+	}
+	if !reflect.DeepEqual(status.Lanes, wantLanes) {
+		t.Errorf("expected lanes %+v, got %+v", wantLanes, status.Lanes)
+	}
+
+	wantProviders := []entity.GenerationQueueProvider{
+		{ProviderID: "fal", Pending: 1, Running: 1, MaxConcurrentJobs: 2},
+		{ProviderID: "gemini", MaxConcurrentJobs: 1},
+		{ProviderID: "openrouter", Pending: 1},
+	}
+	if !reflect.DeepEqual(status.Providers, wantProviders) {
+		t.Errorf("expected providers %+v, got %+v", wantProviders, status.Providers)
+	}
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNormaliseWorkerConfig(t *testing.T) {
+	t.Run("填充默认值", func(t *testing.T) {
+		cfg := normaliseWorkerConfig(WorkerConfig{})
+
+		if cfg.WorkerID == "" {
+			t.Error("expected worker id to be filled")
+		}
+		if hostname, err := os.Hostname(); err == nil && cfg.WorkerID == hostname {
+			t.Errorf("expected default worker id %q to carry a per-process suffix", cfg.WorkerID)
+		}
+		if again := normaliseWorkerConfig(WorkerConfig{}); again.WorkerID != cfg.WorkerID {
+			t.Errorf("expected default worker id to be stable within the process, got %q and %q", cfg.WorkerID, again.WorkerID)
+		}
+		if cfg.Workers != 4 {
+			t.Errorf("expected workers %d, got %d", 4, cfg.Workers)
+		}
+		if cfg.PollInterval != 2*time.Second {
+			t.Errorf("expected poll interval %v, got %v", 2*time.Second, cfg.PollInterval)
+		}
+		if cfg.LeaseDuration != time.Minute {
+			t.Errorf("expected lease %v, got %v", time.Minute, cfg.LeaseDuration)
+		}
+		if cfg.MaxAttempts != 3 {
+			t.Errorf("expected max attempts %d, got %d", 3, cfg.MaxAttempts)
+		}
+	})
+
+	t.Run("保留显式配置", func(t *testing.T) {
+		cfg := normaliseWorkerConfig(WorkerConfig{
+			WorkerID:      "  node-1 ",
+			Workers:       8,
+			PollInterval:  time.Second,
+			LeaseDuration: 30 * time.Second,
+			MaxAttempts:   5,
+		})
+
+		if cfg.WorkerID != "node-1" {
+			t.Errorf("expected worker id %q, got %q", "node-1", cfg.WorkerID)
+		}
+		if cfg.Workers != 8 || cfg.PollInterval != time.Second || cfg.LeaseDuration != 30*time.Second || cfg.MaxAttempts != 5 {
+			t.Errorf("unexpected config %+v", cfg)
+		}
+	})
+}
+
+func TestJobPayloadRoundTrip(t *testing.T) {
+	request := entity.GenerateContentRequest{
+		ClientID:   "client-1",
+		ProviderID: "fal",
+		ModelID:    "fal-ai/flux",
+		Prompt:     "a red dress",
+		InputMedia: []entity.MediaInput{{Type: "image", Content: "data:image/png;base64,AAAA", Role: "reference"}},
+		Output:     entity.OutputConfig{Size: "1024x1024", Duration: 5},
+		TagIDs:     []uint{1, 2},
+	}
+
+	payload, err := encodeJobPayload(request)
+	if err != nil {
+		t.Fatalf("encode payload: %v", err)
+	}
+
+	decoded, err := decodeJobPayload(payload)
+	if err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+
+	if decoded.Prompt != request.Prompt || decoded.ModelID != request.ModelID || decoded.Output != request.Output {
+		t.Errorf("decoded request mismatch: %+v", decoded)
+	}
+	if len(decoded.InputMedia) != 1 || decoded.InputMedia[0] != request.InputMedia[0] {
+		t.Errorf("decoded input media mismatch: %+v", decoded.InputMedia)
+	}
+
+	if _, err := decodeJobPayload("  "); err == nil {
+		t.Error("expected error for empty payload")
+	}
+}
+
+func TestClaimGenerationJobsAcrossUsers(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+
+	seed := func(t *testing.T, userID uint) uint {
+		t.Helper()
+		record := entity.DbUsageRecord{UserID: userID, ProviderID: "fal", ModelID: "fal-ai/flux", Status: entity.UsageRecordStatusQueued}
+		if err := repo.CreateUsageRecord(ctx, &record); err != nil {
+			t.Fatalf("create usage record: %v", err)
+		}
+		job := entity.DbGenerationJob{RecordID: record.ID, UserID: userID, ProviderID: "fal", ModelID: "fal-ai/flux", Priority: entity.GenerationJobPriorityBatch}
+		if err := repo.CreateGenerationJob(ctx, &job); err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+		return job.ID
+	}
+
+	// 第一个用户积压的任务远多于单个用户的候选数
+	first := seed(t, 1)
+	for i := 0; i < 60; i++ {
+		seed(t, 1)
+	}
+	other := seed(t, 2)
+
+	jobs, err := repo.ClaimGenerationJobs(ctx, "worker-a", 2, time.Minute)
+	if err != nil {
+		t.Fatalf("claim jobs: %v", err)
+	}
+	claimed := make(map[uint]bool, len(jobs))
+	for _, job := range jobs {
+		claimed[job.ID] = true
+	}
+	if len(jobs) != 2 || !claimed[first] || !claimed[other] {
+		t.Errorf("expected the oldest job of each user, got %+v", jobs)
+	}
+}
+
+func TestEnqueueGeneration(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		estimate float64
+		wantErr  bool
+	}{
+		{name: "余额不足时不创建记录与任务", estimate: 2, wantErr: true},
+		{name: "记录、标签、预留与任务一并创建", estimate: 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository(t)
+			svc := NewGenerationService(repo, nil)
+			svc.SetCreditsEnforced(true)
+
+			if err := repo.GrantCredits(ctx, &entity.DbCreditLedgerEntry{UserID: 1, Amount: 1}); err != nil {
+				t.Fatalf("grant credits: %v", err)
+			}
+			tag := entity.DbTag{Name: "夏装"}
+			if err := repo.CreateTag(ctx, &tag); err != nil {
+				t.Fatalf("create tag: %v", err)
+			}
+
+			record := entity.DbUsageRecord{UserID: 1, ProviderID: "fal", ModelID: "fal-ai/flux", Prompt: "a red dress", Status: entity.UsageRecordStatusQueued}
+			request := entity.GenerateContentRequest{ProviderID: "fal", ModelID: "fal-ai/flux", Prompt: "a red dress"}
+			err := svc.EnqueueGeneration(ctx, &record, request, entity.GenerationJobPriorityInteractive, tt.estimate, []uint{tag.ID})
+			if tt.wantErr {
+				if !errors.Is(err, entity.ErrInsufficientCredits) {
+					t.Fatalf("expected ErrInsufficientCredits, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("enqueue generation: %v", err)
+			}
+
+			stored, _, err := repo.ListUsageRecords(ctx, &entity.UsageRecordQuery{UserID: 1})
+			if err != nil {
+				t.Fatalf("list usage records: %v", err)
+			}
+			jobs, err := repo.ListActiveGenerationJobs(ctx)
+			if err != nil {
+				t.Fatalf("list jobs: %v", err)
+			}
+			if tt.wantErr {
+				if len(stored) != 0 || len(jobs) != 0 {
+					t.Errorf("expected nothing created, got %d records and %d jobs", len(stored), len(jobs))
+				}
+				return
+			}
+			if len(stored) != 1 || len(stored[0].Tags) != 1 || len(jobs) != 1 || jobs[0].RecordID != record.ID {
+				t.Fatalf("expected one tagged record with its job, got %+v and %+v", stored, jobs)
+			}
+			account, err := repo.GetCreditAccount(ctx, 1)
+			if err != nil {
+				t.Fatalf("get credit account: %v", err)
+			}
+			if math.Abs(account.Balance-0.5) > 1e-9 {
+				t.Errorf("expected balance 0.50, got %.2f", account.Balance)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/llm"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RecoveryConfig 远程任务恢复配置
+type RecoveryConfig struct {
+	// Interval 扫描被中断远程任务的间隔。
+	Interval time.Duration
+	// Concurrency 同时恢复的任务数上限。
+	Concurrency int
+}
+
+// normaliseRecoveryConfig 填充远程任务恢复配置的默认值
+func normaliseRecoveryConfig(cfg RecoveryConfig) RecoveryConfig {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	return cfg
+}
+
+// StartRecovery 启动远程任务恢复：启动时及之后定期认领「服务商已接受远程任务、但执行实例已中断」的生成任务，
+// 通过服务商的 TaskPoller 继续轮询并保存结果，而不是重新提交。需在 StartWorkers 之后调用，以沿用其租约配置。
+// 每轮扫描同时推进步骤已结束、但因实例中断未能进入下一步的流水线运行。
+func (s *GenerationService) StartRecovery(ctx context.Context, cfg RecoveryConfig) {
+	if s.repo == nil {
+		return
+	}
+	cfg = normaliseRecoveryConfig(cfg)
+	workerCfg := normaliseWorkerConfig(s.workerCfg)
+
+	go s.runRecoveryLoop(ctx, cfg, workerCfg)
+}
+
+func (s *GenerationService) runRecoveryLoop(ctx context.Context, cfg RecoveryConfig, workerCfg WorkerConfig) {
+	slots := make(chan struct{}, cfg.Concurrency)
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	logrus.WithField("worker_id", workerCfg.WorkerID).Info("generation recovery started")
+
+	for {
+		s.recoverInterruptedJobs(ctx, workerCfg, slots)
+		s.advancePipelineRuns(ctx)
+
+		select {
+		case <-ctx.Done():
+			logrus.WithField("worker_id", workerCfg.WorkerID).Info("generation recovery stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recoverInterruptedJobs 认领被中断的远程任务并逐个恢复
+func (s *GenerationService) recoverInterruptedJobs(ctx context.Context, cfg WorkerConfig, slots chan struct{}) {
+	free := cap(slots) - len(slots)
+	if free <= 0 {
+		return
+	}
+
+	claimCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	jobs, err := s.repo.ClaimInterruptedGenerationJobs(claimCtx, cfg.WorkerID, free, cfg.LeaseDuration)
+	if err != nil {
+		logrus.WithError(err).WithField("worker_id", cfg.WorkerID).Error("failed to claim interrupted generation jobs")
+	}
+
+	for _, job := range jobs {
+		slots <- struct{}{}
+		go func(job entity.DbGenerationJob) {
+			defer func() { <-slots }()
+			s.resumeJob(ctx, cfg, job)
+		}(job)
+	}
+}
+
+// interruptedTask 恢复远程任务所需的上下文
+type interruptedTask struct {
+	Record  entity.DbUsageRecord
+	Request entity.GenerateContentRequest
+	Target  GenerationTarget
+	Resumer llm.TaskResumer
+	// TaskIDs 实际服务商的全部远程任务 ID，按输出序号排列
+	TaskIDs []string
+}
+
+// resumeJob 继续轮询被中断任务的远程任务，并在维持租约的同时完成使用记录
+func (s *GenerationService) resumeJob(ctx context.Context, cfg WorkerConfig, job entity.DbGenerationJob) {
+	fields := jobLogFields(job)
+	if !s.checkJobRunnable(cfg, job, fields) {
+		return
+	}
+
+	task, err := s.loadInterruptedTask(ctx, job)
+	if err != nil {
+		logrus.WithError(err).WithFields(fields).Error("failed to prepare interrupted remote task")
+		s.failJob(job, fmt.Sprintf("远程任务恢复失败: %v", err))
+		return
+	}
+
+	fields["task_id"] = task.Record.ExternalTaskCode
+	fields["task_count"] = len(task.TaskIDs)
+	fields["served_provider"] = task.Target.ProviderID
+	logrus.WithFields(fields).Info("resuming interrupted remote task")
+	s.runLeasedJob(ctx, cfg, job, func() error {
+		return s.resumeGeneration(task, job.ClientID)
+	})
+}
+
+// loadInterruptedTask 加载使用记录，并通过 ProviderFactory 重新创建提交远程任务的服务商实例
+func (s *GenerationService) loadInterruptedTask(ctx context.Context, job entity.DbGenerationJob) (interruptedTask, error) {
+	request, err := decodeJobPayload(job.Payload)
+	if err != nil {
+		return interruptedTask{}, fmt.Errorf("decode job payload: %w", err)
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	record, err := s.repo.GetUsageRecord(loadCtx, job.RecordID)
+	if err != nil {
+		return interruptedTask{}, fmt.Errorf("load usage record: %w", err)
+	}
+	if record.ExternalTaskCode == "" {
+		return interruptedTask{}, errors.New("记录没有远程任务编号")
+	}
+
+	// 提交远程任务时会记录实际调用的服务商/模型（可能是故障转移的备用模型）
+	providerID, modelID := record.ServedProviderID, record.ServedModelID
+	if providerID == "" || modelID == "" {
+		providerID, modelID = record.ProviderID, record.ModelID
+	}
+
+	target, err := s.loadGenerationTarget(loadCtx, providerID, modelID)
+	if err != nil {
+		return interruptedTask{}, err
+	}
+	resumer, ok := llm.Unwrap(target.Service).(llm.TaskResumer)
+	if !ok {
+		return interruptedTask{}, fmt.Errorf("服务商 %s 不支持恢复远程任务", providerID)
+	}
+
+	taskIDs, err := s.loadRecordTaskIDs(loadCtx, *record, providerID, modelID)
+	if err != nil {
+		return interruptedTask{}, err
+	}
+
+	return interruptedTask{
+		Record:  *record,
+		Request: request,
+		Target:  target,
+		Resumer: resumer,
+		TaskIDs: taskIDs,
+	}, nil
+}
+
+// loadRecordTaskIDs 返回使用记录在实际服务商/模型上提交的全部远程任务 ID（按输出序号），
+// 早于按输出保存任务的记录只有 ExternalTaskCode
+func (s *GenerationService) loadRecordTaskIDs(ctx context.Context, record entity.DbUsageRecord, providerID, modelID string) ([]string, error) {
+	tasks, err := s.repo.ListUsageRecordTasks(ctx, record.ID)
+	if err != nil {
+		return nil, fmt.Errorf("load remote tasks: %w", err)
+	}
+
+	taskIDs := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		// 故障转移前的目标提交的任务已结束，不再轮询
+		if task.ProviderID != providerID || task.ModelID != modelID {
+			continue
+		}
+		taskIDs = append(taskIDs, task.TaskCode)
+	}
+	if len(taskIDs) == 0 {
+		taskIDs = append(taskIDs, record.ExternalTaskCode)
+	}
+	return taskIDs, nil
+}
+
+// resumeGeneration 并发轮询全部远程任务直至结束，保存输出并完成使用记录，返回生成失败的原因（成功时为 nil）
+func (s *GenerationService) resumeGeneration(task interruptedTask, clientID string) error {
+	record := task.Record
+
+	timeoutCtx, cancelTimeout := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancelTimeout()
+	genCtx, cancelGen := context.WithCancelCause(timeoutCtx)
+	defer cancelGen(nil)
+
+	s.trackRunning(record.ID, cancelGen)
+	defer s.untrackRunning(record.ID)
+
+	genCtx = llm.WithTaskHooks(genCtx, llm.TaskHooks{
+		OnProgress: func(progress llm.TaskProgress) {
+			s.notifyProgress(clientID, record.ID, progress)
+		},
+	})
+
+	poller := task.Resumer.TaskPoller(task.Target.Model)
+	responses := make([]*entity.GenerateContentResponse, len(task.TaskIDs))
+	errs := make([]error, len(task.TaskIDs))
+
+	var wg sync.WaitGroup
+	for idx, taskID := range task.TaskIDs {
+		wg.Add(1)
+		go func(idx int, taskID string) {
+			defer wg.Done()
+			responses[idx], errs[idx] = llm.WaitForTask(llm.WithOutputSlot(genCtx, idx), poller, taskID, llm.DefaultPollConfig)
+		}(idx, taskID)
+	}
+	wg.Wait()
+
+	resp, slotErrors, err := mergeOutputResponses(responses, errs)
+
+	return s.finishGeneration(genCtx, generationOutcome{
+		Record:           record,
+		Request:          task.Request,
+		ClientID:         clientID,
+		Served:           task.Target,
+		Response:         resp,
+		SlotErrors:       slotErrors,
+		Err:              err,
+		SubmittedTaskIDs: task.TaskIDs,
+	})
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormaliseRecoveryConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      RecoveryConfig
+		expected RecoveryConfig
+	}{
+		{name: "填充默认值", cfg: RecoveryConfig{}, expected: RecoveryConfig{Interval: time.Minute, Concurrency: 4}},
+		{name: "保留显式配置", cfg: RecoveryConfig{Interval: 10 * time.Second, Concurrency: 2}, expected: RecoveryConfig{Interval: 10 * time.Second, Concurrency: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normaliseRecoveryConfig(tt.cfg); got != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoadRecordTaskIDs(t *testing.T) {
+	repo := newTestRepository(t)
+	svc := NewGenerationService(repo, nil)
+	ctx := context.Background()
+
+	tasks := []entity.DbUsageRecordTask{
+		{RecordID: 1, Slot: 1, ProviderID: "fal", ModelID: "fal-ai/flux", TaskCode: "fal-1"},
+		{RecordID: 1, Slot: 0, ProviderID: "dashscope", ModelID: "wanx", TaskCode: "wanx-0"},
+		// 故障转移后同一输出序号的任务被覆盖
+		{RecordID: 1, Slot: 0, ProviderID: "fal", ModelID: "fal-ai/flux", TaskCode: "fal-0"},
+		{RecordID: 2, Slot: 0, ProviderID: "dashscope", ModelID: "wanx", TaskCode: "wanx-0"},
+	}
+	for i := range tasks {
+		if err := repo.SaveUsageRecordTask(ctx, &tasks[i]); err != nil {
+			t.Fatalf("save task: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		record   entity.DbUsageRecord
+		provider string
+		model    string
+		expected []string
+	}{
+		{name: "按输出序号返回全部任务", record: entity.DbUsageRecord{ID: 1, ExternalTaskCode: "fal-0"}, provider: "fal", model: "fal-ai/flux", expected: []string{"fal-0", "fal-1"}},
+		{name: "忽略其他服务商的任务", record: entity.DbUsageRecord{ID: 2, ExternalTaskCode: "legacy"}, provider: "fal", model: "fal-ai/flux", expected: []string{"legacy"}},
+		{name: "没有保存任务时使用记录的任务编号", record: entity.DbUsageRecord{ID: 3, ExternalTaskCode: "legacy"}, provider: "fal", model: "fal-ai/flux", expected: []string{"legacy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.loadRecordTaskIDs(ctx, tt.record, tt.provider, tt.model)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestClaimInterruptedGenerationJobs(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+
+	expired := time.Now().Add(-time.Minute)
+	live := time.Now().Add(time.Minute)
+	seed := func(t *testing.T, status, taskCode, jobStatus string, leaseExpiresAt *time.Time) uint {
+		t.Helper()
+		record := entity.DbUsageRecord{UserID: 1, ProviderID: "fal", ModelID: "fal-ai/flux", Status: status, ExternalTaskCode: taskCode}
+		if err := repo.CreateUsageRecord(ctx, &record); err != nil {
+			t.Fatalf("create usage record: %v", err)
+		}
+		job := entity.DbGenerationJob{RecordID: record.ID, UserID: 1, ProviderID: "fal", ModelID: "fal-ai/flux", Status: jobStatus, LeaseOwner: "crashed-worker", LeaseExpiresAt: leaseExpiresAt}
+		if err := repo.CreateGenerationJob(ctx, &job); err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+		return record.ID
+	}
+
+	submitted := seed(t, entity.UsageRecordStatusRunning, "req-1", entity.GenerationJobStatusRunning, &expired)
+	notSubmitted := seed(t, entity.UsageRecordStatusRunning, "", entity.GenerationJobStatusRunning, &expired)
+	pending := seed(t, entity.UsageRecordStatusQueued, "", entity.GenerationJobStatusPending, nil)
+	seed(t, entity.UsageRecordStatusRunning, "req-live", entity.GenerationJobStatusRunning, &live)
+
+	recordIDs := func(jobs []entity.DbGenerationJob) map[uint]bool {
+		ids := make(map[uint]bool, len(jobs))
+		for _, job := range jobs {
+			ids[job.RecordID] = true
+		}
+		return ids
+	}
+
+	t.Run("执行队列不认领已提交远程任务的中断任务", func(t *testing.T) {
+		jobs, err := repo.ClaimGenerationJobs(ctx, "worker-a", 10, time.Minute)
+		if err != nil {
+			t.Fatalf("claim jobs: %v", err)
+		}
+		expected := map[uint]bool{notSubmitted: true, pending: true}
+		if got := recordIDs(jobs); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected records %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("恢复流程认领已提交远程任务的中断任务", func(t *testing.T) {
+		jobs, err := repo.ClaimInterruptedGenerationJobs(ctx, "worker-b", 10, time.Minute)
+		if err != nil {
+			t.Fatalf("claim interrupted jobs: %v", err)
+		}
+		expected := map[uint]bool{submitted: true}
+		if got := recordIDs(jobs); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected records %v, got %v", expected, got)
+		}
+		if len(jobs) == 1 && jobs[0].LeaseOwner != "worker-b" {
+			t.Errorf("expected lease owner worker-b, got %q", jobs[0].LeaseOwner)
+		}
+	})
+
+	t.Run("已认领的任务不再被认领", func(t *testing.T) {
+		jobs, err := repo.ClaimInterruptedGenerationJobs(ctx, "worker-c", 10, time.Minute)
+		if err != nil {
+			t.Fatalf("claim interrupted jobs: %v", err)
+		}
+		if len(jobs) != 0 {
+			t.Errorf("expected no jobs, got %d", len(jobs))
+		}
+	})
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"clothing/internal/entity"
+	"clothing/internal/entity/converter"
 	"clothing/internal/llm"
 	"clothing/internal/model"
 	"clothing/internal/storage"
@@ -9,10 +10,13 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -25,6 +29,30 @@ type GenerationService struct {
 
 	// notifyFunc 用于通知生成完成事件（由调用方设置）
 	notifyFunc func(clientID string, recordID uint, status string, errMsg string)
+	// progressFunc 用于推送异步任务进度（由调用方设置）
+	progressFunc func(clientID string, recordID uint, progress llm.TaskProgress)
+	// deltaFunc 用于推送流式输出的增量内容（由调用方设置），seq 为记录内从 1 开始的序号
+	deltaFunc func(clientID string, recordID uint, seq int, delta llm.GenerationDelta)
+	// batchNotifyFunc 用于通知批次完成事件（由调用方设置）
+	batchNotifyFunc func(clientID string, batch entity.DbGenerationBatch, progress entity.GenerationBatchProgress)
+	// pipelineNotifyFunc 用于通知流水线运行结束事件（由调用方设置）
+	pipelineNotifyFunc func(clientID string, run entity.DbPipelineRun)
+	// mediaURLBuilder 将存储路径转换为公开地址，流水线据此把之前步骤的输出传给服务商
+	mediaURLBuilder func(path string) string
+	// webhooks 生成结束时投递 Webhook 事件（可选）
+	webhooks *WebhookService
+	// taskCallbacks 服务商异步任务完成回调配置，未启用时服务商轮询任务状态
+	taskCallbacks llm.TaskCallbackConfig
+	// creditsEnforced 余额不足时拒绝为批量子任务与流水线步骤预留额度
+	creditsEnforced bool
+
+	// 任务队列工作池
+	workerCfg WorkerConfig
+	wakeCh    chan struct{}
+
+	// running 本实例上执行中生成的取消函数，按使用记录 ID 索引
+	running   map[uint]context.CancelCauseFunc
+	runningMu sync.Mutex
 }
 
 // NewGenerationService 创建生成服务实例
@@ -32,6 +60,8 @@ func NewGenerationService(repo model.Repository, store storage.Storage) *Generat
 	return &GenerationService{
 		repo:    repo,
 		storage: store,
+		wakeCh:  make(chan struct{}, 1),
+		running: make(map[uint]context.CancelCauseFunc),
 	}
 }
 
@@ -40,6 +70,16 @@ func (s *GenerationService) SetNotifyFunc(fn func(clientID string, recordID uint
 	s.notifyFunc = fn
 }
 
+// SetProgressFunc 设置进度通知函数（用于 SSE 推送）
+func (s *GenerationService) SetProgressFunc(fn func(clientID string, recordID uint, progress llm.TaskProgress)) {
+	s.progressFunc = fn
+}
+
+// SetDeltaFunc 设置流式增量通知函数（用于 SSE 推送）
+func (s *GenerationService) SetDeltaFunc(fn func(clientID string, recordID uint, seq int, delta llm.GenerationDelta)) {
+	s.deltaFunc = fn
+}
+
 // GenerateContentRequest 生成内容请求参数
 type GenerateContentRequest struct {
 	Record   entity.DbUsageRecord
@@ -47,30 +87,83 @@ type GenerateContentRequest struct {
 	Model    entity.DbModel
 	Service  llm.AIService
 	ClientID string
+	// RetryPolicy 服务商调用的重试策略（来自服务商 Config）
+	RetryPolicy llm.RetryPolicy
+	// Fallbacks 主模型失败（可重试错误）后依次尝试的备用模型
+	Fallbacks []GenerationTarget
 }
 
-// GenerateContentAsync 异步生成内容
-func (s *GenerationService) GenerateContentAsync(req GenerateContentRequest) {
-	go s.handleGeneration(req)
-}
-
-// handleGeneration 处理内容生成的核心逻辑
-func (s *GenerationService) handleGeneration(req GenerateContentRequest) {
+// handleGeneration 处理内容生成的核心逻辑，返回生成失败的原因（成功时为 nil）
+func (s *GenerationService) handleGeneration(req GenerateContentRequest) error {
 	if s.repo == nil {
-		return
+		return errors.New("repository not configured")
 	}
 
 	record := req.Record
 	request := req.Request
-	dbModel := req.Model
-	service := req.Service
 	clientID := strings.TrimSpace(req.ClientID)
 
-	genCtx, cancelGen := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancelGen()
+	timeoutCtx, cancelTimeout := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancelTimeout()
+	genCtx, cancelGen := context.WithCancelCause(timeoutCtx)
+	defer cancelGen(nil)
+
+	s.trackRunning(record.ID, cancelGen)
+	defer s.untrackRunning(record.ID)
+
+	// 服务商提交异步任务后立即记录任务 ID 及提交任务的服务商/模型，便于取消、排查与重启后恢复轮询；
+	// 多输出分多次调用时按输出序号分别记录每个远程任务
+	submittedTasks := make(map[int]string)
+	var submittingTarget GenerationTarget
+	var submittedMu sync.Mutex
+	// 支持流式输出的模型边生成边推送文本片段与图片；attemptBase 为切换到当前目标前已进行的尝试数，
+	// 使故障转移后推送的尝试序号继续递增，客户端据此丢弃被重试取代的输出
+	var attemptBase int
+	deltas := newDeltaCoalescer(func(seq int, delta llm.GenerationDelta) {
+		s.notifyDelta(clientID, record.ID, seq, delta)
+	})
+	genCtx = llm.WithTaskHooks(genCtx, llm.TaskHooks{
+		OnSubmitted: func(taskID string, slot int) {
+			submittedMu.Lock()
+			submittedTasks[slot] = taskID
+			providerID := submittingTarget.ProviderID
+			modelID := submittingTarget.Model.ModelID
+			submittedMu.Unlock()
+			s.saveRecordTask(entity.DbUsageRecordTask{
+				RecordID:   record.ID,
+				Slot:       slot,
+				ProviderID: providerID,
+				ModelID:    modelID,
+				TaskCode:   taskID,
+			})
+			s.updateUsageRecord(record.ID, entity.UsageRecordUpdates{
+				TaskID:           &taskID,
+				ServedProviderID: &providerID,
+				ServedModelID:    &modelID,
+			})
+		},
+		OnProgress: func(progress llm.TaskProgress) {
+			s.notifyProgress(clientID, record.ID, progress)
+		},
+		OnDelta: func(delta llm.GenerationDelta) {
+			submittedMu.Lock()
+			streaming := submittingTarget.Model.SupportsStreaming
+			delta.Attempt += attemptBase
+			submittedMu.Unlock()
+			if !streaming {
+				return
+			}
+			deltas.add(delta)
+		},
+	})
+	genCtx = llm.WithTaskCallbacks(genCtx, s.taskCallbacks)
+	s.notifyProgress(clientID, record.ID, llm.TaskProgress{Status: llm.TaskStatusRunning})
+
+	runningStatus := entity.UsageRecordStatusRunning
+	startedAt := time.Now()
+	s.updateUsageRecord(record.ID, entity.UsageRecordUpdates{Status: &runningStatus, StartedAt: &startedAt})
 
 	var updates entity.UsageRecordUpdates
-	completionError := ""
 	var storageIssues []string
 
 	// 保存输入图片
@@ -91,8 +184,147 @@ func (s *GenerationService) handleGeneration(req GenerateContentRequest) {
 		}
 	}
 
-	// 调用 LLM 服务生成内容
-	resp, err := service.GenerateContent(genCtx, request, dbModel)
+	// 调用 LLM 服务生成内容，可重试的错误按服务商策略重试，每次尝试都记录到使用记录；
+	// 重试用尽后仍为可重试错误时，按故障转移链切换到下一个服务商/模型
+	var attempts entity.GenerationAttempts
+	var attemptsMu sync.Mutex
+	var resp *entity.GenerateContentResponse
+	var slotErrors []error
+	var served GenerationTarget
+	err := errNoGenerationTarget
+
+	targets := req.targets()
+	for idx, target := range targets {
+		served = target
+		targetRequest := request
+		targetRequest.ProviderID = target.ProviderID
+		targetRequest.ModelID = target.Model.ModelID
+
+		// 剔除目标模型不支持的高级参数，实际生效的参数写入记录以便复现
+		if !request.Advanced.IsEmpty() {
+			effective, dropped := llm.ApplyAdvancedParams(request.Advanced, llm.ServiceCapabilities(target.Service, target.Model))
+			if len(dropped) > 0 {
+				logrus.WithFields(logrus.Fields{
+					"record_id": record.ID,
+					"provider":  target.ProviderID,
+					"model":     target.Model.ModelID,
+					"dropped":   dropped,
+				}).Warn("dropping advanced params unsupported by model")
+			}
+			targetRequest.Advanced = effective
+			params := converter.AdvancedParamsFromDTO(effective)
+			s.updateUsageRecord(record.ID, entity.UsageRecordUpdates{AdvancedParams: &params})
+		}
+
+		attemptsMu.Lock()
+		previousAttempts := len(attempts)
+		attemptsMu.Unlock()
+
+		submittedMu.Lock()
+		submittedTasks = make(map[int]string)
+		submittingTarget = target
+		attemptBase = previousAttempts
+		submittedMu.Unlock()
+
+		resp, slotErrors, err = generateOutputs(genCtx, target, targetRequest, func(result llm.AttemptResult) {
+			attemptsMu.Lock()
+			defer attemptsMu.Unlock()
+			attempt := newGenerationAttempt(result)
+			attempt.Attempt = len(attempts) + 1
+			attempt.ProviderID = target.ProviderID
+			attempt.ModelID = target.Model.ModelID
+			attempts = append(attempts, attempt)
+			recorded := append(entity.GenerationAttempts(nil), attempts...)
+			s.updateUsageRecord(record.ID, entity.UsageRecordUpdates{Attempts: &recorded})
+
+			if result.Backoff > 0 {
+				logrus.WithError(result.Err).WithFields(logrus.Fields{
+					"record_id": record.ID,
+					"provider":  target.ProviderID,
+					"model":     target.Model.ModelID,
+					"attempt":   result.Attempt,
+					"backoff":   result.Backoff.String(),
+				}).Warn("retrying provider call")
+			}
+		})
+
+		submittedMu.Lock()
+		submitted := len(submittedTasks) > 0
+		submittedMu.Unlock()
+		if idx == len(targets)-1 || !shouldFailover(genCtx, err, submitted) {
+			break
+		}
+		next := targets[idx+1]
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"record_id":     record.ID,
+			"provider":      target.ProviderID,
+			"model":         target.Model.ModelID,
+			"next_provider": next.ProviderID,
+			"next_model":    next.Model.ModelID,
+		}).Warn("failing over to next provider")
+	}
+
+	deltas.close()
+
+	submittedMu.Lock()
+	taskIDs := sortedTaskIDs(submittedTasks)
+	submittedMu.Unlock()
+
+	return s.finishGeneration(genCtx, generationOutcome{
+		Record:           record,
+		Request:          request,
+		ClientID:         clientID,
+		Served:           served,
+		Response:         resp,
+		SlotErrors:       slotErrors,
+		Err:              err,
+		SubmittedTaskIDs: taskIDs,
+		Updates:          updates,
+		StorageIssues:    storageIssues,
+	})
+}
+
+// sortedTaskIDs 按输出序号返回已提交的远程任务 ID
+func sortedTaskIDs(tasks map[int]string) []string {
+	slots := make([]int, 0, len(tasks))
+	for slot := range tasks {
+		slots = append(slots, slot)
+	}
+	sort.Ints(slots)
+	ids := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		ids = append(ids, tasks[slot])
+	}
+	return ids
+}
+
+// generationOutcome 服务商调用（或恢复的远程任务）的结果，用于完成使用记录
+type generationOutcome struct {
+	Record   entity.DbUsageRecord
+	Request  entity.GenerateContentRequest
+	ClientID string
+	// Served 产生结果的服务商/模型
+	Served     GenerationTarget
+	Response   *entity.GenerateContentResponse
+	SlotErrors []error
+	Err        error
+	// SubmittedTaskIDs 已提交的全部远程任务 ID（按输出序号），取消时逐个取消；响应中没有任务 ID 时记录第一个
+	SubmittedTaskIDs []string
+	// Updates 已累积的记录更新（如输入图片），StorageIssues 为已发生的存储问题
+	Updates       entity.UsageRecordUpdates
+	StorageIssues []string
+}
+
+// finishGeneration 保存生成结果、更新使用记录并发送完成通知，返回生成失败的原因（成功时为 nil）
+func (s *GenerationService) finishGeneration(ctx context.Context, outcome generationOutcome) error {
+	record := outcome.Record
+	clientID := outcome.ClientID
+	served := outcome.Served
+	resp := outcome.Response
+	err := outcome.Err
+	updates := outcome.Updates
+	storageIssues := outcome.StorageIssues
+	completionError := ""
 
 	var taskID, requestID string
 	var outputs []string
@@ -105,9 +337,34 @@ func (s *GenerationService) handleGeneration(req GenerateContentRequest) {
 		text = resp.Text
 	}
 
+	if taskID == "" && len(outcome.SubmittedTaskIDs) > 0 {
+		taskID = outcome.SubmittedTaskIDs[0]
+	}
 	if taskID != "" {
 		updates.TaskID = &taskID
 	}
+
+	// 用户取消：通知服务商取消远程任务，并将记录标记为已取消
+	if err != nil && errors.Is(context.Cause(ctx), errGenerationCancelled) {
+		logrus.WithFields(logrus.Fields{
+			"record_id": record.ID,
+			"provider":  record.ProviderID,
+			"model":     record.ModelID,
+			"task_id":   taskID,
+		}).Info("generation cancelled")
+
+		cancelIDs := outcome.SubmittedTaskIDs
+		if len(cancelIDs) == 0 {
+			cancelIDs = []string{taskID}
+		}
+		for _, id := range cancelIDs {
+			s.cancelRemoteTask(served.Service, served.Model, record.ID, id)
+		}
+		s.updateUsageRecord(record.ID, updates)
+		s.markRecordCancelled(record.ID)
+		s.notifyComplete(clientID, record.ID, entity.UsageRecordStatusCancelled, cancelledMessage)
+		return errGenerationCancelled
+	}
 	if requestID != "" {
 		updates.RequestID = &requestID
 	}
@@ -125,26 +382,54 @@ func (s *GenerationService) handleGeneration(req GenerateContentRequest) {
 			errMsg = appendStorageNotes(errMsg, storageIssues)
 		}
 
+		failedStatus := entity.UsageRecordStatusFailed
+		finishedAt := time.Now()
 		updates.ErrorMessage = &errMsg
+		updates.Status = &failedStatus
+		updates.FinishedAt = &finishedAt
 		s.updateUsageRecord(record.ID, updates)
 		s.notifyComplete(clientID, record.ID, "failure", errMsg)
-		return
+		return err
 	}
 
 	logrus.WithFields(logrus.Fields{
-		"record_id": record.ID,
-		"provider":  record.ProviderID,
-		"model":     record.ModelID,
+		"record_id":       record.ID,
+		"provider":        record.ProviderID,
+		"model":           record.ModelID,
+		"served_provider": served.ProviderID,
+		"served_model":    served.Model.ModelID,
 	}).Info("generated content")
 
+	// 记录实际完成生成的服务商与模型
+	servedProviderID := served.ProviderID
+	servedModelID := served.Model.ModelID
+	updates.ServedProviderID = &servedProviderID
+	updates.ServedModelID = &servedModelID
+
+	// 按实际服务的模型计算费用
+	cost, costSource, costErr := computeGenerationCost(served.Model, outcome.Request, resp)
+	if costErr != nil {
+		logrus.WithError(costErr).WithFields(logrus.Fields{
+			"record_id": record.ID,
+			"provider":  servedProviderID,
+			"model":     servedModelID,
+		}).Warn("failed to compute generation cost")
+	}
+	if cost != nil {
+		updates.Cost = cost
+		updates.CostSource = &costSource
+	}
+
 	// 保存文本内容
 	if text != "" {
 		updates.OutputText = &text
 	}
 
-	// 保存输出媒体文件
+	// 保存输出媒体文件，并记录每个输出的状态
+	var outputStatuses entity.GenerationOutputs
 	if len(outputs) > 0 {
-		outputPaths, err := s.saveMediaToStorage(genCtx, "outputs", outputs, request.ModelID)
+		statuses, outputPaths, err := s.saveOutputs(ctx, outputs, servedModelID)
+		outputStatuses = statuses
 		if len(outputPaths) > 0 {
 			outputImages := entity.StringArray(outputPaths)
 			updates.OutputImages = &outputImages
@@ -159,8 +444,22 @@ func (s *GenerationService) handleGeneration(req GenerateContentRequest) {
 		}
 	}
 
-	// 合并存储问题到错误信息
+	// 请求多个输出时，未生成的输出记为失败
+	numOutputs := outcome.Request.GetNumOutputs()
+	if numOutputs > 1 {
+		outputStatuses = appendMissingOutputs(outputStatuses, numOutputs, outcome.SlotErrors)
+		if missing := numOutputs - len(outputs); missing > 0 {
+			storageIssues = append(storageIssues, fmt.Sprintf("outputs: %d of %d not generated", missing, numOutputs))
+		}
+	}
+	if len(outputStatuses) > 0 {
+		updates.Outputs = &outputStatuses
+	}
+
+	// 合并存储问题到错误信息，部分结果未能保存或生成时标记为部分成功
+	finalStatus := entity.UsageRecordStatusSucceeded
 	if len(storageIssues) > 0 {
+		finalStatus = entity.UsageRecordStatusPartiallySucceeded
 		existingError := ""
 		if updates.ErrorMessage != nil {
 			existingError = *updates.ErrorMessage
@@ -170,8 +469,12 @@ func (s *GenerationService) handleGeneration(req GenerateContentRequest) {
 		completionError = combined
 	}
 
+	finishedAt := time.Now()
+	updates.Status = &finalStatus
+	updates.FinishedAt = &finishedAt
 	s.updateUsageRecord(record.ID, updates)
 	s.notifyComplete(clientID, record.ID, "success", completionError)
+	return nil
 }
 
 // saveMediaToStorage 保存媒体文件到存储
@@ -193,29 +496,11 @@ func (s *GenerationService) saveMediaToStorage(parentCtx context.Context, catego
 	)
 
 	for idx, payload := range payloads {
-		trimmed := strings.TrimSpace(payload)
-		if trimmed == "" {
-			continue
-		}
-
-		data, ext, err := s.resolveMediaPayload(ctx, trimmed)
-		if err != nil {
-			errs = append(errs, fmt.Sprintf("%d: %v", idx, err))
+		if strings.TrimSpace(payload) == "" {
 			continue
 		}
 
-		saveOpts := storage.SaveOptions{Category: category, Extension: ext}
-		switch strings.ToLower(strings.TrimSpace(category)) {
-		case "inputs":
-			saveOpts.SkipIfExists = true
-			saveOpts.BaseName = computeInputBaseName(data)
-		case "outputs":
-			saveOpts.BaseName = buildOutputBaseName(modelName, idx)
-		default:
-			saveOpts.BaseName = ""
-		}
-
-		relPath, err := s.storage.Save(ctx, data, saveOpts)
+		relPath, err := s.saveMediaPayload(ctx, category, idx, payload, modelName)
 		if err != nil {
 			errs = append(errs, fmt.Sprintf("%d: %v", idx, err))
 			continue
@@ -230,6 +515,27 @@ func (s *GenerationService) saveMediaToStorage(parentCtx context.Context, catego
 	return paths, nil
 }
 
+// saveMediaPayload 保存单个媒体文件，返回存储相对路径
+func (s *GenerationService) saveMediaPayload(ctx context.Context, category string, idx int, payload string, modelName string) (string, error) {
+	data, ext, err := s.resolveMediaPayload(ctx, payload)
+	if err != nil {
+		return "", err
+	}
+
+	saveOpts := storage.SaveOptions{Category: category, Extension: ext}
+	switch strings.ToLower(strings.TrimSpace(category)) {
+	case "inputs":
+		saveOpts.SkipIfExists = true
+		saveOpts.BaseName = computeInputBaseName(data)
+	case "outputs":
+		saveOpts.BaseName = buildOutputBaseName(modelName, idx)
+	default:
+		saveOpts.BaseName = ""
+	}
+
+	return s.storage.Save(ctx, data, saveOpts)
+}
+
 // resolveMediaPayload 解析媒体数据（URL 或 base64）
 func (s *GenerationService) resolveMediaPayload(ctx context.Context, payload string) ([]byte, string, error) {
 	trimmed := strings.TrimSpace(payload)
@@ -302,13 +608,62 @@ func (s *GenerationService) updateUsageRecord(recordID uint, updates entity.Usag
 	}
 }
 
-// notifyComplete 通知生成完成
+// saveRecordTask 记录使用记录某个输出提交的远程任务
+func (s *GenerationService) saveRecordTask(task entity.DbUsageRecordTask) {
+	if s.repo == nil || task.RecordID == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.repo.SaveUsageRecordTask(ctx, &task); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"record_id": task.RecordID,
+			"slot":      task.Slot,
+			"task_id":   task.TaskCode,
+		}).Error("failed to save usage record task")
+	}
+}
+
+// notifyComplete 通知生成完成，结算预留的额度并投递 Webhook 事件
 func (s *GenerationService) notifyComplete(clientID string, recordID uint, status string, errMsg string) {
+	s.settleCredits(recordID)
+	s.webhooks.EnqueueGenerationEvent(recordID)
 	if s.notifyFunc != nil && strings.TrimSpace(clientID) != "" {
 		s.notifyFunc(clientID, recordID, status, errMsg)
 	}
 }
 
+// newGenerationAttempt 将一次调用结果转换为使用记录中的尝试记录
+func newGenerationAttempt(result llm.AttemptResult) entity.GenerationAttempt {
+	attempt := entity.GenerationAttempt{
+		Attempt:    result.Attempt,
+		StartedAt:  result.StartedAt,
+		FinishedAt: result.FinishedAt,
+		StatusCode: result.Class.StatusCode,
+		Retryable:  result.Class.Retryable,
+		BackoffMs:  result.Backoff.Milliseconds(),
+	}
+	if result.Err != nil {
+		attempt.Error = result.Err.Error()
+	}
+	return attempt
+}
+
+// notifyDelta 通知流式输出的增量内容
+func (s *GenerationService) notifyDelta(clientID string, recordID uint, seq int, delta llm.GenerationDelta) {
+	if s.deltaFunc != nil && strings.TrimSpace(clientID) != "" {
+		s.deltaFunc(clientID, recordID, seq, delta)
+	}
+}
+
+// notifyProgress 通知生成进度
+func (s *GenerationService) notifyProgress(clientID string, recordID uint, progress llm.TaskProgress) {
+	if s.progressFunc != nil && strings.TrimSpace(clientID) != "" {
+		s.progressFunc(clientID, recordID, progress)
+	}
+}
+
 // appendStorageNotes 合并存储问题说明
 func appendStorageNotes(existing string, notes []string) string {
 	if len(notes) == 0 {
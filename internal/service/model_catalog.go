@@ -0,0 +1,377 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/entity/converter"
+	"clothing/internal/llm"
+	"clothing/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 模型目录同步动作
+const (
+	CatalogActionCreate     = "create"
+	CatalogActionUpdate     = "update"
+	CatalogActionDeactivate = "deactivate"
+)
+
+// 目录更新可写入的模型字段
+const (
+	CatalogFieldName             = "name"
+	CatalogFieldDescription      = "description"
+	CatalogFieldInputModalities  = "input_modalities"
+	CatalogFieldOutputModalities = "output_modalities"
+	CatalogFieldGenerationMode   = "generation_mode"
+	CatalogFieldPricing          = "pricing"
+)
+
+// catalogFieldDefaults 各字段是否默认随目录更新；名称与描述常由管理员自行维护，需显式指定才会覆盖
+var catalogFieldDefaults = map[string]bool{
+	CatalogFieldName:             false,
+	CatalogFieldDescription:      false,
+	CatalogFieldInputModalities:  true,
+	CatalogFieldOutputModalities: true,
+	CatalogFieldGenerationMode:   true,
+	CatalogFieldPricing:          true,
+}
+
+// IsCatalogField 判断是否为目录更新可写入的字段
+func IsCatalogField(field string) bool {
+	_, ok := catalogFieldDefaults[field]
+	return ok
+}
+
+// catalogUpdateFields 返回更新时写入的字段，fields 为空时使用默认字段
+func catalogUpdateFields(fields []string) map[string]bool {
+	selected := make(map[string]bool, len(catalogFieldDefaults))
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); IsCatalogField(field) {
+			selected[field] = true
+		}
+	}
+	if len(selected) == 0 {
+		for field, enabled := range catalogFieldDefaults {
+			selected[field] = enabled
+		}
+	}
+	return selected
+}
+
+// ErrCatalogFetchFailed 拉取服务商上游模型目录失败
+var ErrCatalogFetchFailed = errors.New("failed to fetch model catalog")
+
+// catalogActionOrder 预览与应用时各动作的先后顺序
+var catalogActionOrder = map[string]int{
+	CatalogActionCreate:     0,
+	CatalogActionUpdate:     1,
+	CatalogActionDeactivate: 2,
+}
+
+// IsCatalogAction 判断是否为有效的目录同步动作
+func IsCatalogAction(action string) bool {
+	_, ok := catalogActionOrder[action]
+	return ok
+}
+
+// catalogChange 上游目录与已保存模型之间的一项差异
+type catalogChange struct {
+	action  string
+	modelID string
+	fields  []string
+	// current 为已保存的模型（新建时为空），proposed 为应用后的模型
+	current  *entity.DbModel
+	proposed *entity.DbModel
+	// updates 为更新与停用时写入的字段
+	updates entity.ModelUpdates
+}
+
+// ModelCatalogService 从服务商上游拉取模型目录，与 llm_models 对比后预览或应用新建、更新与停用
+type ModelCatalogService struct {
+	repo  model.Repository
+	fetch func(ctx context.Context, provider *entity.DbProvider) ([]llm.CatalogModel, error)
+}
+
+// NewModelCatalogService 创建模型目录同步服务
+func NewModelCatalogService(repo model.Repository) *ModelCatalogService {
+	return &ModelCatalogService{
+		repo:  repo,
+		fetch: llm.GetFactory().FetchCatalog,
+	}
+}
+
+// Preview 拉取上游目录并返回与已保存模型的差异，不做任何修改。fields 为更新时比较的字段，为空时使用默认字段
+func (s *ModelCatalogService) Preview(ctx context.Context, provider *entity.DbProvider, fields []string) (*entity.ModelCatalogDiff, error) {
+	changes, catalogSize, err := s.diff(ctx, provider, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &entity.ModelCatalogDiff{
+		ProviderID:  provider.ID,
+		CatalogSize: catalogSize,
+		Changes:     make([]entity.ModelCatalogChange, 0, len(changes)),
+	}
+	for _, change := range changes {
+		switch change.action {
+		case CatalogActionCreate:
+			diff.Creates++
+		case CatalogActionUpdate:
+			diff.Updates++
+		case CatalogActionDeactivate:
+			diff.Deactivations++
+		}
+		diff.Changes = append(diff.Changes, catalogChangeToDTO(change))
+	}
+	return diff, nil
+}
+
+// Apply 重新拉取上游目录并应用所选差异。actions 为空时只应用新建与更新，modelIDs 为空时应用所选动作的全部差异，
+// fields 为空时更新默认字段（不含名称与描述）；单个模型失败不影响其他模型，失败原因记录在返回结果中。
+func (s *ModelCatalogService) Apply(ctx context.Context, provider *entity.DbProvider, actions []string, modelIDs []string, fields []string) (*entity.ModelCatalogSyncResponse, error) {
+	changes, _, err := s.diff(ctx, provider, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	selectedActions := make(map[string]bool)
+	for _, action := range actions {
+		selectedActions[strings.TrimSpace(action)] = true
+	}
+	if len(selectedActions) == 0 {
+		selectedActions[CatalogActionCreate] = true
+		selectedActions[CatalogActionUpdate] = true
+	}
+	selectedModels := make(map[string]bool)
+	for _, modelID := range modelIDs {
+		if trimmed := strings.TrimSpace(modelID); trimmed != "" {
+			selectedModels[trimmed] = true
+		}
+	}
+
+	result := &entity.ModelCatalogSyncResponse{
+		ProviderID: provider.ID,
+		Applied:    make([]entity.ModelCatalogChange, 0),
+		Failed:     make([]entity.ModelCatalogChange, 0),
+	}
+	for _, change := range changes {
+		if !selectedActions[change.action] || (len(selectedModels) > 0 && !selectedModels[change.modelID]) {
+			continue
+		}
+
+		item := catalogChangeToDTO(change)
+		if err := s.applyChange(ctx, provider.ID, change); err != nil {
+			item.Error = err.Error()
+			result.Failed = append(result.Failed, item)
+			continue
+		}
+		result.Applied = append(result.Applied, item)
+	}
+	return result, nil
+}
+
+func (s *ModelCatalogService) applyChange(ctx context.Context, providerID string, change catalogChange) error {
+	if change.action == CatalogActionCreate {
+		created := *change.proposed
+		return s.repo.CreateModel(ctx, &created)
+	}
+	return s.repo.UpdateModel(ctx, providerID, change.modelID, change.updates)
+}
+
+func (s *ModelCatalogService) diff(ctx context.Context, provider *entity.DbProvider, fields []string) ([]catalogChange, int, error) {
+	if s.repo == nil {
+		return nil, 0, errors.New("repository not configured")
+	}
+
+	catalog, err := s.fetch(ctx, provider)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", ErrCatalogFetchFailed, err)
+	}
+	existing, err := s.repo.ListModels(ctx, provider.ID, true)
+	if err != nil {
+		return nil, 0, err
+	}
+	return diffModelCatalog(provider.ID, catalog, existing, catalogUpdateFields(fields)), len(catalog), nil
+}
+
+// diffModelCatalog 对比上游目录与已保存的模型：目录中新增的模型新建，fields 中字段变化的模型更新，
+// 已启用但不再出现在目录中的模型停用。已停用的模型不会被重新启用。
+func diffModelCatalog(providerID string, catalog []llm.CatalogModel, existing []entity.DbModel, fields map[string]bool) []catalogChange {
+	stored := make(map[string]*entity.DbModel, len(existing))
+	for i := range existing {
+		stored[existing[i].ModelID] = &existing[i]
+	}
+
+	changes := make([]catalogChange, 0)
+	listed := make(map[string]bool, len(catalog))
+	for _, item := range catalog {
+		modelID := strings.TrimSpace(item.ModelID)
+		if modelID == "" || listed[modelID] {
+			continue
+		}
+		listed[modelID] = true
+
+		current, ok := stored[modelID]
+		if !ok {
+			changes = append(changes, catalogChange{
+				action:   CatalogActionCreate,
+				modelID:  modelID,
+				proposed: catalogModelToDb(providerID, item),
+			})
+			continue
+		}
+		if change, ok := catalogUpdate(current, item, fields); ok {
+			changes = append(changes, change)
+		}
+	}
+
+	for i := range existing {
+		current := &existing[i]
+		if !current.IsActive || listed[current.ModelID] {
+			continue
+		}
+		proposed := *current
+		proposed.IsActive = false
+		inactive := false
+		changes = append(changes, catalogChange{
+			action:   CatalogActionDeactivate,
+			modelID:  current.ModelID,
+			fields:   []string{"is_active"},
+			current:  current,
+			proposed: &proposed,
+			updates:  entity.ModelUpdates{IsActive: &inactive},
+		})
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].action != changes[j].action {
+			return catalogActionOrder[changes[i].action] < catalogActionOrder[changes[j].action]
+		}
+		return changes[i].modelID < changes[j].modelID
+	})
+	return changes
+}
+
+// catalogModelToDb 将目录条目转换为待新建的模型，预填名称、模态、生成方式与计费
+func catalogModelToDb(providerID string, item llm.CatalogModel) *entity.DbModel {
+	name := strings.TrimSpace(item.Name)
+	if name == "" {
+		name = strings.TrimSpace(item.ModelID)
+	}
+	return &entity.DbModel{
+		ProviderID:       providerID,
+		ModelID:          strings.TrimSpace(item.ModelID),
+		Name:             name,
+		Description:      strings.TrimSpace(item.Description),
+		Pricing:          item.Pricing,
+		InputModalities:  entity.StringArray(item.InputModalities),
+		OutputModalities: entity.StringArray(item.OutputModalities),
+		GenerationMode:   item.GenerationMode,
+		IsActive:         true,
+	}
+}
+
+// catalogUpdate 计算目录条目相对已保存模型在 fields 中字段上的变化；目录未提供的字段（空值）保持不变
+func catalogUpdate(current *entity.DbModel, item llm.CatalogModel, fields map[string]bool) (catalogChange, bool) {
+	proposed := *current
+	change := catalogChange{action: CatalogActionUpdate, modelID: current.ModelID, current: current}
+
+	if name := strings.TrimSpace(item.Name); fields[CatalogFieldName] && name != "" && name != current.Name {
+		proposed.Name = name
+		change.updates.Name = &name
+		change.fields = append(change.fields, CatalogFieldName)
+	}
+	if description := strings.TrimSpace(item.Description); fields[CatalogFieldDescription] && description != "" && description != current.Description {
+		proposed.Description = description
+		change.updates.Description = &description
+		change.fields = append(change.fields, CatalogFieldDescription)
+	}
+	if fields[CatalogFieldInputModalities] && len(item.InputModalities) > 0 && !sameModalities(current.InputModalities, item.InputModalities) {
+		inputs := entity.StringArray(item.InputModalities)
+		proposed.InputModalities = inputs
+		change.updates.InputModalities = &inputs
+		change.fields = append(change.fields, CatalogFieldInputModalities)
+	}
+	if fields[CatalogFieldOutputModalities] && len(item.OutputModalities) > 0 && !sameModalities(current.OutputModalities, item.OutputModalities) {
+		outputs := entity.StringArray(item.OutputModalities)
+		proposed.OutputModalities = outputs
+		change.updates.OutputModalities = &outputs
+		change.fields = append(change.fields, CatalogFieldOutputModalities)
+	}
+	if mode := item.GenerationMode; fields[CatalogFieldGenerationMode] && mode != "" && mode != current.GenerationMode {
+		proposed.GenerationMode = mode
+		change.updates.GenerationMode = &mode
+		change.fields = append(change.fields, CatalogFieldGenerationMode)
+	}
+	if pricing := mergePricing(current.Pricing, item.Pricing); fields[CatalogFieldPricing] && pricing != current.Pricing {
+		proposed.Pricing = pricing
+		change.updates.Pricing = &pricing
+		change.fields = append(change.fields, CatalogFieldPricing)
+	}
+
+	if len(change.fields) == 0 {
+		return catalogChange{}, false
+	}
+	change.proposed = &proposed
+	return change, true
+}
+
+// mergePricing 用目录提供的非零计费项覆盖已保存的计费，目录未提供的计费项（如手工配置的按 token 计费）保持不变
+func mergePricing(current, catalog entity.ModelPricing) entity.ModelPricing {
+	merged := current
+	if catalog.PerImage > 0 {
+		merged.PerImage = catalog.PerImage
+	}
+	if catalog.PerVideoSecond > 0 {
+		merged.PerVideoSecond = catalog.PerVideoSecond
+	}
+	if catalog.PerMegapixel > 0 {
+		merged.PerMegapixel = catalog.PerMegapixel
+	}
+	if catalog.PerThousandInputTokens > 0 {
+		merged.PerThousandInputTokens = catalog.PerThousandInputTokens
+	}
+	if catalog.PerThousandOutputTokens > 0 {
+		merged.PerThousandOutputTokens = catalog.PerThousandOutputTokens
+	}
+	return merged
+}
+
+// sameModalities 忽略顺序与大小写比较两组模态
+func sameModalities(a entity.StringArray, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int, len(a))
+	for _, value := range a {
+		counts[strings.ToLower(strings.TrimSpace(value))]++
+	}
+	for _, value := range b {
+		key := strings.ToLower(strings.TrimSpace(value))
+		if counts[key] == 0 {
+			return false
+		}
+		counts[key]--
+	}
+	return true
+}
+
+func catalogChangeToDTO(change catalogChange) entity.ModelCatalogChange {
+	item := entity.ModelCatalogChange{
+		Action:  change.action,
+		ModelID: change.modelID,
+		Fields:  change.fields,
+	}
+	if change.current != nil {
+		current := converter.ModelToSummary(change.current)
+		item.Current = &current
+	}
+	if change.proposed != nil {
+		proposed := converter.ModelToSummary(change.proposed)
+		item.Proposed = &proposed
+	}
+	return item
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/llm"
+	"reflect"
+	"testing"
+)
+
+func TestDiffModelCatalog(t *testing.T) {
+	catalog := []llm.CatalogModel{
+		{ModelID: "fal-ai/flux/dev", Name: "FLUX.1 [dev]", InputModalities: []string{"text"}, OutputModalities: []string{"image"}, GenerationMode: "text_to_image", Pricing: entity.ModelPricing{PerMegapixel: 0.025}},
+		{ModelID: "fal-ai/flux/schnell", Name: "FLUX.1 [schnell]", InputModalities: []string{"text"}, OutputModalities: []string{"image"}},
+		{ModelID: "fal-ai/kling-video", Name: "Kling", InputModalities: []string{"text", "image"}, OutputModalities: []string{"video"}, GenerationMode: "image_to_video"},
+		{ModelID: "fal-ai/kling-video", Name: "Kling duplicate"},
+	}
+	existing := []entity.DbModel{
+		// 名称由管理员修改过（默认不覆盖），目录计费与手工配置的计费合并
+		{ModelID: "fal-ai/flux/dev", Name: "Flux Dev", InputModalities: entity.StringArray{"text"}, OutputModalities: entity.StringArray{"image"}, GenerationMode: "text_to_image", Pricing: entity.ModelPricing{PerImage: 0.02}, IsActive: true},
+		// 模态顺序不同视为相同，目录未提供计费时保留已有计费
+		{ModelID: "fal-ai/flux/schnell", Name: "FLUX.1 [schnell]", InputModalities: entity.StringArray{"TEXT"}, OutputModalities: entity.StringArray{"image"}, Pricing: entity.ModelPricing{PerImage: 0.003}, IsActive: true},
+		{ModelID: "fal-ai/legacy", Name: "Legacy", IsActive: true},
+		{ModelID: "fal-ai/disabled", Name: "Disabled", IsActive: false},
+	}
+
+	changes := diffModelCatalog("fal", catalog, existing, catalogUpdateFields(nil))
+
+	type summary struct {
+		action  string
+		modelID string
+		fields  []string
+	}
+	got := make([]summary, 0, len(changes))
+	for _, change := range changes {
+		got = append(got, summary{change.action, change.modelID, change.fields})
+	}
+	want := []summary{
+		{CatalogActionCreate, "fal-ai/kling-video", nil},
+		{CatalogActionUpdate, "fal-ai/flux/dev", []string{"pricing"}},
+		{CatalogActionDeactivate, "fal-ai/legacy", []string{"is_active"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	created := changes[0].proposed
+	if created.ProviderID != "fal" || created.Name != "Kling" || created.GenerationMode != "image_to_video" || !created.IsActive {
+		t.Errorf("unexpected created model %+v", created)
+	}
+	update := changes[1]
+	wantPricing := entity.ModelPricing{PerImage: 0.02, PerMegapixel: 0.025}
+	if update.updates.Name != nil || update.updates.Pricing == nil || *update.updates.Pricing != wantPricing || update.updates.InputModalities != nil {
+		t.Errorf("unexpected updates %+v", update.updates)
+	}
+	if deactivate := changes[2]; deactivate.updates.IsActive == nil || *deactivate.updates.IsActive || deactivate.proposed.IsActive {
+		t.Errorf("expected legacy model to be deactivated, got %+v", deactivate.updates)
+	}
+
+	t.Run("显式指定名称时覆盖", func(t *testing.T) {
+		changes := diffModelCatalog("fal", catalog, existing, catalogUpdateFields([]string{CatalogFieldName}))
+		var update *catalogChange
+		for i := range changes {
+			if changes[i].action == CatalogActionUpdate {
+				update = &changes[i]
+			}
+		}
+		if update == nil || !reflect.DeepEqual(update.fields, []string{CatalogFieldName}) || *update.updates.Name != "FLUX.1 [dev]" {
+			t.Fatalf("expected name-only update, got %+v", update)
+		}
+
+		item := catalogChangeToDTO(*update)
+		if item.Current == nil || item.Proposed == nil || item.Proposed.Name != "FLUX.1 [dev]" || item.Current.Name != "Flux Dev" {
+			t.Errorf("unexpected change DTO %+v", item)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// promptVariablePattern 匹配 {{name}} 形式的占位符，名称两侧允许空白
+var promptVariablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+// RenderPromptTemplate 使用变量替换提示词模板中的 {{name}} 占位符，缺少变量时返回错误
+func RenderPromptTemplate(template string, variables map[string]string) (string, error) {
+	missing := make(map[string]struct{})
+	rendered := promptVariablePattern.ReplaceAllStringFunc(template, func(match string) string {
+		name := promptVariablePattern.FindStringSubmatch(match)[1]
+		value, ok := variables[name]
+		if !ok {
+			missing[name] = struct{}{}
+			return match
+		}
+		return value
+	})
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("缺少模板变量: %s", strings.Join(names, ", "))
+	}
+	return strings.TrimSpace(rendered), nil
+}
+
+// promptVariableNamePattern 变量名允许的字符，与占位符保持一致
+var promptVariableNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+
+// PromptTemplatePlaceholders 按首次出现的顺序返回模板中的占位符名称
+func PromptTemplatePlaceholders(template string) []string {
+	var names []string
+	seen := make(map[string]struct{})
+	for _, match := range promptVariablePattern.FindAllStringSubmatch(template, -1) {
+		name := match[1]
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	return names
+}
+
+// NormalisePromptTemplateVariables 校验模板的变量定义：类型、枚举可选值与默认值必须合法，
+// 定义的变量必须出现在模板中；模板中未定义的占位符补充为必填的文本变量
+func NormalisePromptTemplateVariables(template string, variables entity.PromptTemplateVariables) (entity.PromptTemplateVariables, error) {
+	placeholders := PromptTemplatePlaceholders(template)
+	used := make(map[string]struct{}, len(placeholders))
+	for _, name := range placeholders {
+		used[name] = struct{}{}
+	}
+
+	result := make(entity.PromptTemplateVariables, 0, len(placeholders))
+	declared := make(map[string]struct{}, len(variables))
+	for _, variable := range variables {
+		variable.Name = strings.TrimSpace(variable.Name)
+		if !promptVariableNamePattern.MatchString(variable.Name) {
+			return nil, fmt.Errorf("无效的变量名: %q", variable.Name)
+		}
+		if _, ok := declared[variable.Name]; ok {
+			return nil, fmt.Errorf("变量 %s 重复定义", variable.Name)
+		}
+		if _, ok := used[variable.Name]; !ok {
+			return nil, fmt.Errorf("变量 %s 未在模板中使用", variable.Name)
+		}
+		declared[variable.Name] = struct{}{}
+
+		variable.Type = strings.ToLower(strings.TrimSpace(variable.Type))
+		variable.Description = strings.TrimSpace(variable.Description)
+		switch variable.Type {
+		case "", entity.PromptVariableTypeText:
+			variable.Type = entity.PromptVariableTypeText
+			variable.Choices = nil
+		case entity.PromptVariableTypeEnum:
+			choices := make([]string, 0, len(variable.Choices))
+			for _, choice := range variable.Choices {
+				if trimmed := strings.TrimSpace(choice); trimmed != "" {
+					choices = append(choices, trimmed)
+				}
+			}
+			if len(choices) == 0 {
+				return nil, fmt.Errorf("枚举变量 %s 至少需要一个可选值", variable.Name)
+			}
+			variable.Choices = choices
+			if variable.Default != nil {
+				choice, ok := matchPromptChoice(choices, *variable.Default)
+				if !ok {
+					return nil, fmt.Errorf("变量 %s 的默认值不在可选值中", variable.Name)
+				}
+				variable.Default = &choice
+			}
+		default:
+			return nil, fmt.Errorf("变量 %s 的类型无效: %s", variable.Name, variable.Type)
+		}
+		result = append(result, variable)
+	}
+
+	for _, name := range placeholders {
+		if _, ok := declared[name]; !ok {
+			result = append(result, entity.PromptTemplateVariable{Name: name, Type: entity.PromptVariableTypeText})
+		}
+	}
+	return result, nil
+}
+
+// ResolvePromptTemplate 按模板的变量定义校验传入的变量并填充默认值，返回渲染后的提示词与实际使用的变量
+func ResolvePromptTemplate(template *entity.DbPromptTemplate, values map[string]string) (string, map[string]string, error) {
+	if template == nil {
+		return "", nil, fmt.Errorf("模板不存在")
+	}
+
+	declared := make(map[string]struct{}, len(template.Variables))
+	for _, variable := range template.Variables {
+		declared[variable.Name] = struct{}{}
+	}
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			return "", nil, fmt.Errorf("模板未定义变量: %s", name)
+		}
+	}
+
+	resolved := make(map[string]string, len(template.Variables))
+	for _, variable := range template.Variables {
+		value, ok := values[variable.Name]
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			if variable.Default == nil {
+				// 交给渲染统一报告缺少的变量
+				continue
+			}
+			value = *variable.Default
+		}
+		if variable.Type == entity.PromptVariableTypeEnum {
+			choice, ok := matchPromptChoice(variable.Choices, value)
+			if !ok {
+				return "", nil, fmt.Errorf("变量 %s 的取值必须是: %s", variable.Name, strings.Join(variable.Choices, ", "))
+			}
+			value = choice
+		}
+		resolved[variable.Name] = value
+	}
+
+	rendered, err := RenderPromptTemplate(template.Template, resolved)
+	if err != nil {
+		return "", nil, err
+	}
+	return rendered, resolved, nil
+}
+
+// matchPromptChoice 忽略大小写匹配枚举可选值，返回定义中的写法
+func matchPromptChoice(choices []string, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, choice := range choices {
+		if strings.EqualFold(choice, value) {
+			return choice, true
+		}
+	}
+	return "", false
+}
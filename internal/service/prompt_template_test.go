@@ -0,0 +1,131 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"strings"
+	"testing"
+)
+
+func TestRenderPromptTemplate(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  string
+		variables map[string]string
+		expected  string
+		wantErr   bool
+	}{
+		{name: "替换变量", template: "a {{color}} {{garment}}", variables: map[string]string{"color": "red", "garment": "dress"}, expected: "a red dress"},
+		{name: "允许空白", template: "a {{ color }} coat", variables: map[string]string{"color": "blue"}, expected: "a blue coat"},
+		{name: "重复变量", template: "{{c}} and {{c}}", variables: map[string]string{"c": "x"}, expected: "x and x"},
+		{name: "无占位符", template: "plain prompt", variables: nil, expected: "plain prompt"},
+		{name: "缺少变量", template: "a {{color}} {{garment}}", variables: map[string]string{"color": "red"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderPromptTemplate(tt.template, tt.variables)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNormalisePromptTemplateVariables(t *testing.T) {
+	white := "White"
+	tests := []struct {
+		name      string
+		template  string
+		variables entity.PromptTemplateVariables
+		expected  []string
+		wantErr   bool
+	}{
+		{name: "补充未定义的占位符", template: "a {{color}} {{garment}}", variables: entity.PromptTemplateVariables{{Name: "garment", Type: "enum", Choices: []string{"dress", "coat"}}}, expected: []string{"garment", "color"}},
+		{name: "枚举默认值规范为可选值写法", template: "on {{background}}", variables: entity.PromptTemplateVariables{{Name: "background", Type: "enum", Choices: []string{"white", "grey"}, Default: &white}}, expected: []string{"background"}},
+		{name: "枚举缺少可选值", template: "{{pose}}", variables: entity.PromptTemplateVariables{{Name: "pose", Type: "enum"}}, wantErr: true},
+		{name: "默认值不在可选值中", template: "{{pose}}", variables: entity.PromptTemplateVariables{{Name: "pose", Type: "enum", Choices: []string{"standing"}, Default: &white}}, wantErr: true},
+		{name: "变量未在模板中使用", template: "{{color}}", variables: entity.PromptTemplateVariables{{Name: "size"}}, wantErr: true},
+		{name: "重复定义", template: "{{color}}", variables: entity.PromptTemplateVariables{{Name: "color"}, {Name: "color"}}, wantErr: true},
+		{name: "无效类型", template: "{{color}}", variables: entity.PromptTemplateVariables{{Name: "color", Type: "number"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalisePromptTemplateVariables(tt.template, tt.variables)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			names := make([]string, 0, len(got))
+			for _, variable := range got {
+				names = append(names, variable.Name)
+				if variable.Default != nil && variable.Type == entity.PromptVariableTypeEnum && *variable.Default != variable.Choices[0] {
+					t.Errorf("expected default to match choice spelling, got %q", *variable.Default)
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected variables %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestResolvePromptTemplate(t *testing.T) {
+	white := "white"
+	template := &entity.DbPromptTemplate{
+		Template: "a {{color}} {{garment}} on a {{background}} background",
+		Variables: entity.PromptTemplateVariables{
+			{Name: "color", Type: entity.PromptVariableTypeText},
+			{Name: "garment", Type: entity.PromptVariableTypeEnum, Choices: []string{"dress", "coat"}},
+			{Name: "background", Type: entity.PromptVariableTypeText, Default: &white},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		values   map[string]string
+		expected string
+		wantErr  bool
+	}{
+		{name: "使用默认值", values: map[string]string{"color": "red", "garment": "Dress"}, expected: "a red dress on a white background"},
+		{name: "覆盖默认值", values: map[string]string{"color": "red", "garment": "coat", "background": "grey"}, expected: "a red coat on a grey background"},
+		{name: "缺少必填变量", values: map[string]string{"garment": "coat"}, wantErr: true},
+		{name: "枚举值无效", values: map[string]string{"color": "red", "garment": "hat"}, wantErr: true},
+		{name: "未定义的变量", values: map[string]string{"color": "red", "garment": "coat", "pose": "sitting"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, resolved, err := ResolvePromptTemplate(template, tt.values)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+			if len(resolved) != len(template.Variables) {
+				t.Errorf("expected all variables to be resolved, got %v", resolved)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/llm"
+	"clothing/internal/model"
+	"context"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxHealthCheckErrorLength 健康检查记录中错误信息的最大长度（字符）
+const maxHealthCheckErrorLength = 1000
+
+// ProviderHealthConfig 服务商后台健康检查配置
+type ProviderHealthConfig struct {
+	// Interval 检查所有启用服务商的间隔，不大于 0 时不启动后台检查。
+	Interval time.Duration
+	// Timeout 单个服务商检查的超时。
+	Timeout time.Duration
+	// Retention 健康检查记录的保留时长，过期记录在每轮检查后清理。
+	Retention time.Duration
+}
+
+// normaliseProviderHealthConfig 填充健康检查配置的默认值
+func normaliseProviderHealthConfig(cfg ProviderHealthConfig) ProviderHealthConfig {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 15 * time.Second
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 7 * 24 * time.Hour
+	}
+	return cfg
+}
+
+// ProviderHealthService 通过各驱动的低成本凭证检查（列出模型、查询账户等）探测服务商连通性，并记录检查历史
+type ProviderHealthService struct {
+	repo  model.Repository
+	check func(ctx context.Context, provider *entity.DbProvider) (time.Duration, error)
+}
+
+// NewProviderHealthService 创建服务商健康检查服务
+func NewProviderHealthService(repo model.Repository) *ProviderHealthService {
+	return &ProviderHealthService{
+		repo:  repo,
+		check: llm.GetFactory().CheckHealth,
+	}
+}
+
+// Check 使用服务商当前保存的配置执行一次凭证检查并记录结果。
+// 检查失败记录在返回结果中，仅保存记录失败时返回错误；超时由调用方通过 ctx 控制。
+func (s *ProviderHealthService) Check(ctx context.Context, provider *entity.DbProvider, source string) (*entity.DbProviderHealthCheck, error) {
+	latency, err := s.check(ctx, provider)
+	result := newProviderHealthCheck(provider.ID, source, latency, err)
+	if s.repo == nil {
+		return result, nil
+	}
+
+	// 检查超时后 ctx 已结束，记录结果使用独立的超时
+	saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.repo.CreateProviderHealthCheck(saveCtx, result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// newProviderHealthCheck 根据检查耗时与错误构造健康检查记录
+func newProviderHealthCheck(providerID, source string, latency time.Duration, err error) *entity.DbProviderHealthCheck {
+	result := &entity.DbProviderHealthCheck{
+		ProviderID: providerID,
+		Source:     source,
+		Status:     entity.ProviderHealthStatusOK,
+		LatencyMs:  latency.Milliseconds(),
+	}
+	if err == nil {
+		return result
+	}
+
+	result.Status = entity.ProviderHealthStatusError
+	result.StatusCode = llm.ClassifyError(err).StatusCode
+	message := err.Error()
+	if utf8.RuneCountInString(message) > maxHealthCheckErrorLength {
+		message = string([]rune(message)[:maxHealthCheckErrorLength]) + "..."
+	}
+	result.Error = message
+	return result
+}
+
+// Start 启动后台健康检查：启动时及之后每隔 Interval 检查所有启用的服务商，并清理过期的检查记录
+func (s *ProviderHealthService) Start(ctx context.Context, cfg ProviderHealthConfig) {
+	if s.repo == nil || cfg.Interval <= 0 {
+		return
+	}
+	cfg = normaliseProviderHealthConfig(cfg)
+
+	go s.runHealthLoop(ctx, cfg)
+}
+
+func (s *ProviderHealthService) runHealthLoop(ctx context.Context, cfg ProviderHealthConfig) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	logrus.WithField("interval", cfg.Interval.String()).Info("provider health checker started")
+
+	for {
+		s.checkActiveProviders(ctx, cfg)
+		s.pruneHealthChecks(ctx, cfg)
+
+		select {
+		case <-ctx.Done():
+			logrus.Info("provider health checker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkActiveProviders 并发检查所有启用的服务商。
+// 多个实例共用数据库时，每个服务商每个周期只由认领到租约的实例检查一次；
+// 租约略短于检查间隔，避免本实例下一轮因计时误差被自己上一轮的租约挡住。
+func (s *ProviderHealthService) checkActiveProviders(ctx context.Context, cfg ProviderHealthConfig) {
+	listCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	providers, err := s.repo.ListProviders(listCtx, false)
+	cancel()
+	if err != nil {
+		logrus.WithError(err).Error("failed to list providers for health check")
+		return
+	}
+
+	lease := cfg.Interval - cfg.Interval/10
+	var wg sync.WaitGroup
+	for i := range providers {
+		provider := providers[i]
+
+		claimCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		claimed, err := s.repo.ClaimProviderHealthCheck(claimCtx, provider.ID, lease)
+		cancel()
+		if err != nil {
+			logrus.WithError(err).WithField("provider_id", provider.ID).Error("failed to claim provider health check")
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+			defer cancel()
+
+			result, err := s.Check(checkCtx, &provider, entity.ProviderHealthSourceScheduled)
+			fields := logrus.Fields{"provider_id": provider.ID}
+			if err != nil {
+				logrus.WithError(err).WithFields(fields).Error("failed to record provider health check")
+			}
+			if !result.IsHealthy() {
+				fields["status_code"] = result.StatusCode
+				logrus.WithFields(fields).WithField("error", result.Error).Warn("provider health check failed")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// pruneHealthChecks 删除超过保留期的检查记录
+func (s *ProviderHealthService) pruneHealthChecks(ctx context.Context, cfg ProviderHealthConfig) {
+	pruneCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	deleted, err := s.repo.DeleteProviderHealthChecksBefore(pruneCtx, time.Now().Add(-cfg.Retention))
+	if err != nil {
+		logrus.WithError(err).Error("failed to prune provider health checks")
+		return
+	}
+	if deleted > 0 {
+		logrus.WithField("deleted", deleted).Debug("pruned provider health checks")
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/llm"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewProviderHealthCheck(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantStatus     string
+		wantStatusCode int
+	}{
+		{name: "检查成功", wantStatus: entity.ProviderHealthStatusOK},
+		{
+			name:           "凭证无效",
+			err:            &llm.HTTPStatusError{Provider: "fal", StatusCode: 401, Body: "unauthorized"},
+			wantStatus:     entity.ProviderHealthStatusError,
+			wantStatusCode: 401,
+		},
+		{name: "请求超时", err: context.DeadlineExceeded, wantStatus: entity.ProviderHealthStatusError},
+		{name: "不支持检查", err: llm.ErrHealthCheckUnsupported, wantStatus: entity.ProviderHealthStatusError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newProviderHealthCheck("fal", entity.ProviderHealthSourceManual, 1500*time.Millisecond, tt.err)
+			if got.Status != tt.wantStatus || got.StatusCode != tt.wantStatusCode {
+				t.Fatalf("expected %s/%d, got %s/%d", tt.wantStatus, tt.wantStatusCode, got.Status, got.StatusCode)
+			}
+			if got.LatencyMs != 1500 {
+				t.Errorf("expected latency 1500ms, got %d", got.LatencyMs)
+			}
+			if tt.err != nil && got.Error != tt.err.Error() {
+				t.Errorf("expected error %q, got %q", tt.err.Error(), got.Error)
+			}
+		})
+	}
+}
+
+func TestNewProviderHealthCheckTruncatesError(t *testing.T) {
+	got := newProviderHealthCheck("fal", entity.ProviderHealthSourceScheduled, 0, errors.New(strings.Repeat("错", 2*maxHealthCheckErrorLength)))
+	if n := len([]rune(got.Error)); n != maxHealthCheckErrorLength+3 {
+		t.Errorf("expected error truncated to %d runes, got %d", maxHealthCheckErrorLength+3, n)
+	}
+}
+
+func TestProviderHealthServiceCheck(t *testing.T) {
+	svc := &ProviderHealthService{check: func(ctx context.Context, provider *entity.DbProvider) (time.Duration, error) {
+		return 20 * time.Millisecond, nil
+	}}
+	result, err := svc.Check(context.Background(), &entity.DbProvider{ID: "gemini"}, entity.ProviderHealthSourceManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsHealthy() || result.ProviderID != "gemini" || result.Source != entity.ProviderHealthSourceManual {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestCheckActiveProvidersAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	if err := repo.CreateProvider(ctx, &entity.DbProvider{ID: "fal", Name: "fal", Driver: entity.ProviderDriverFal, IsActive: true}); err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+
+	var checks atomic.Int32
+	newInstance := func() *ProviderHealthService {
+		return &ProviderHealthService{repo: repo, check: func(ctx context.Context, provider *entity.DbProvider) (time.Duration, error) {
+			checks.Add(1)
+			return 0, nil
+		}}
+	}
+	cfg := normaliseProviderHealthConfig(ProviderHealthConfig{Interval: time.Minute})
+
+	// 两个实例在同一周期内检查，只有认领到租约的实例执行
+	newInstance().checkActiveProviders(ctx, cfg)
+	newInstance().checkActiveProviders(ctx, cfg)
+
+	if got := checks.Load(); got != 1 {
+		t.Errorf("expected 1 check, got %d", got)
+	}
+	recorded, err := repo.ListProviderHealthChecks(ctx, "fal", 10)
+	if err != nil {
+		t.Fatalf("list health checks: %v", err)
+	}
+	if len(recorded) != 1 {
+		t.Errorf("expected 1 recorded check, got %d", len(recorded))
+	}
+}
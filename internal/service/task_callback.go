@@ -0,0 +1,84 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"clothing/internal/llm"
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// SetTaskCallbacks 设置服务商异步任务完成回调配置
+func (s *GenerationService) SetTaskCallbacks(cfg llm.TaskCallbackConfig) {
+	s.taskCallbacks = cfg
+}
+
+// HandleTaskCallback 处理服务商推送的任务完成回调：按驱动与外部任务编号匹配使用记录。
+// 本实例有等待该任务的生成时将回调交给它，由其立即拉取结果并完成记录；
+// 否则若提交任务的实例已中断（租约过期），认领生成任务，通过服务商的 TaskPoller 拉取结果并完成记录；
+// 执行实例仍存活时由其按正常间隔轮询完成。
+// 返回匹配的使用记录；记录不存在且本实例没有等待该任务时返回 gorm.ErrRecordNotFound。
+func (s *GenerationService) HandleTaskCallback(ctx context.Context, callback llm.TaskCallback) (*entity.DbUsageRecord, error) {
+	if s.repo == nil {
+		return nil, errors.New("repository not configured")
+	}
+
+	fields := logrus.Fields{
+		"driver":  callback.Driver,
+		"task_id": callback.TaskID,
+		"status":  callback.Status,
+	}
+
+	task, err := s.repo.FindUsageRecordTaskByCode(ctx, callback.Driver, callback.TaskID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		// 回调可能早于提交回执写入，仍尝试唤醒本实例的等待
+		if !llm.DeliverTaskCallback(callback) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		logrus.WithFields(fields).Info("delivered callback for unrecorded task")
+		return nil, nil
+	}
+
+	record, err := s.repo.GetUsageRecord(ctx, task.RecordID)
+	if err != nil {
+		return nil, err
+	}
+	fields["record_id"] = record.ID
+	fields["slot"] = task.Slot
+
+	// 已结束的记录无需处理（例如轮询先完成了任务）
+	if record.Status != entity.UsageRecordStatusQueued && record.Status != entity.UsageRecordStatusRunning {
+		logrus.WithFields(fields).Info("ignored callback for finished task")
+		return record, nil
+	}
+
+	if llm.DeliverTaskCallback(callback) {
+		logrus.WithFields(fields).Info("delivered task callback")
+		return record, nil
+	}
+
+	s.resumeCallbackTask(ctx, *record, fields)
+	return record, nil
+}
+
+// resumeCallbackTask 认领执行实例已中断的生成任务并在后台恢复；租约仍有效时交由执行实例完成
+func (s *GenerationService) resumeCallbackTask(ctx context.Context, record entity.DbUsageRecord, fields logrus.Fields) {
+	cfg := normaliseWorkerConfig(s.workerCfg)
+	job, err := s.repo.ClaimInterruptedGenerationJob(ctx, record.ID, cfg.WorkerID, cfg.LeaseDuration)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.WithFields(fields).Info("task callback left to the worker holding the job")
+			return
+		}
+		logrus.WithError(err).WithFields(fields).Warn("failed to claim job for task callback")
+		return
+	}
+
+	logrus.WithFields(fields).Info("resuming interrupted remote task after callback")
+	go s.resumeJob(context.WithoutCancel(ctx), cfg, *job)
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"clothing/internal/config"
+	"clothing/internal/entity"
+	"clothing/internal/llm"
+	"clothing/internal/model"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// newTestRepository 创建基于临时 SQLite 文件的仓库
+func newTestRepository(t *testing.T) model.Repository {
+	t.Helper()
+
+	repo, err := model.InitRepository(&config.Config{
+		DBType: model.DBTypeSQLite,
+		DBPath: filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatalf("init repository: %v", err)
+	}
+	return repo
+}
+
+func TestHandleTaskCallback(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	svc := NewGenerationService(repo, nil)
+
+	for _, provider := range []entity.DbProvider{
+		{ID: "fal-main", Name: "fal", Driver: entity.ProviderDriverFal, IsActive: true},
+		{ID: "wanx", Name: "dashscope", Driver: entity.ProviderDriverDashscope, IsActive: true},
+	} {
+		if err := repo.CreateProvider(ctx, &provider); err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+	}
+
+	// createTask 创建执行中的使用记录及其远程任务，任务的租约由其他实例持有
+	createTask := func(t *testing.T, status, providerID, taskCode string) entity.DbUsageRecord {
+		t.Helper()
+		record := entity.DbUsageRecord{UserID: 1, ProviderID: providerID, ModelID: "model", Status: status, ExternalTaskCode: taskCode}
+		if err := repo.CreateUsageRecord(ctx, &record); err != nil {
+			t.Fatalf("create usage record: %v", err)
+		}
+		if err := repo.SaveUsageRecordTask(ctx, &entity.DbUsageRecordTask{RecordID: record.ID, ProviderID: providerID, ModelID: "model", TaskCode: taskCode}); err != nil {
+			t.Fatalf("save task: %v", err)
+		}
+		leaseExpiresAt := time.Now().Add(time.Minute)
+		job := entity.DbGenerationJob{RecordID: record.ID, ProviderID: providerID, ModelID: "model", Status: entity.GenerationJobStatusRunning, LeaseOwner: "other-worker", LeaseExpiresAt: &leaseExpiresAt}
+		if err := repo.CreateGenerationJob(ctx, &job); err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+		return record
+	}
+
+	t.Run("未知任务", func(t *testing.T) {
+		_, err := svc.HandleTaskCallback(ctx, llm.TaskCallback{Driver: entity.ProviderDriverFal, TaskID: "unknown"})
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("expected record not found, got %v", err)
+		}
+	})
+
+	t.Run("任务编号按驱动区分", func(t *testing.T) {
+		createTask(t, entity.UsageRecordStatusRunning, "wanx", "shared-id")
+		_, err := svc.HandleTaskCallback(ctx, llm.TaskCallback{Driver: entity.ProviderDriverFal, TaskID: "shared-id"})
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("expected record not found, got %v", err)
+		}
+	})
+
+	t.Run("交给本实例等待的生成", func(t *testing.T) {
+		record := createTask(t, entity.UsageRecordStatusRunning, "fal-main", "req-local")
+		callbacks, release := llm.SubscribeTaskCallback(entity.ProviderDriverFal, "req-local")
+		defer release()
+
+		got, err := svc.HandleTaskCallback(ctx, llm.TaskCallback{Driver: entity.ProviderDriverFal, TaskID: "req-local", Status: llm.TaskStatusSucceeded})
+		if err != nil || got == nil || got.ID != record.ID {
+			t.Fatalf("unexpected result: %+v, %v", got, err)
+		}
+		select {
+		case <-callbacks:
+		default:
+			t.Error("expected callback to be delivered to the local waiter")
+		}
+	})
+
+	t.Run("执行实例存活时不认领任务", func(t *testing.T) {
+		record := createTask(t, entity.UsageRecordStatusRunning, "fal-main", "req-remote")
+		got, err := svc.HandleTaskCallback(ctx, llm.TaskCallback{Driver: entity.ProviderDriverFal, TaskID: "req-remote", Status: llm.TaskStatusSucceeded})
+		if err != nil || got == nil || got.ID != record.ID {
+			t.Fatalf("unexpected result: %+v, %v", got, err)
+		}
+
+		jobs, err := repo.ListActiveGenerationJobs(ctx)
+		if err != nil {
+			t.Fatalf("list jobs: %v", err)
+		}
+		for _, job := range jobs {
+			if job.RecordID == record.ID && job.LeaseOwner != "other-worker" {
+				t.Errorf("expected job to stay with its worker, got owner %q", job.LeaseOwner)
+			}
+		}
+	})
+
+	t.Run("忽略已结束的记录", func(t *testing.T) {
+		record := createTask(t, entity.UsageRecordStatusSucceeded, "fal-main", "req-finished")
+		got, err := svc.HandleTaskCallback(ctx, llm.TaskCallback{Driver: entity.ProviderDriverFal, TaskID: "req-finished"})
+		if err != nil || got == nil || got.ID != record.ID {
+			t.Errorf("unexpected result: %+v, %v", got, err)
+		}
+	})
+}
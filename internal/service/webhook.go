@@ -0,0 +1,383 @@
+package service
+
+import (
+	"bytes"
+	"clothing/internal/entity"
+	"clothing/internal/entity/converter"
+	"clothing/internal/llm"
+	"clothing/internal/model"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Webhook 请求头
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// WebhookConfig Webhook 投递配置
+type WebhookConfig struct {
+	// PollInterval 轮询待投递记录的间隔。
+	PollInterval time.Duration
+	// Timeout 单次投递的 HTTP 超时。
+	Timeout time.Duration
+	// MaxAttempts 单个事件的最大投递次数（含首次），之后标记为失败。
+	MaxAttempts int
+	// Concurrency 同时进行的投递数上限。
+	Concurrency int
+}
+
+// normaliseWebhookConfig 填充 Webhook 投递配置的默认值
+func normaliseWebhookConfig(cfg WebhookConfig) WebhookConfig {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 6
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	return cfg
+}
+
+// webhookBackoff 投递失败后的重试间隔：30s 起指数增长，最长 1 小时
+var webhookBackoff = llm.RetryPolicy{
+	InitialBackoff: 30 * time.Second,
+	MaxBackoff:     time.Hour,
+	Multiplier:     2,
+}
+
+// WebhookService 在生成结束时向注册的端点投递带 HMAC 签名的事件。
+// 投递记录持久化在仓储中，由轮询循环认领并按退避策略重试，多实例部署时不会重复投递。
+type WebhookService struct {
+	repo model.Repository
+	// imageURLBuilder 将存储路径转换为对外可访问的地址
+	imageURLBuilder func(path string) entity.UsageImage
+
+	cfg    WebhookConfig
+	client *http.Client
+	wakeCh chan struct{}
+}
+
+// NewWebhookService 创建 Webhook 服务实例
+func NewWebhookService(repo model.Repository, imageURLBuilder func(path string) entity.UsageImage) *WebhookService {
+	cfg := normaliseWebhookConfig(WebhookConfig{})
+	return &WebhookService{
+		repo:            repo,
+		imageURLBuilder: imageURLBuilder,
+		cfg:             cfg,
+		client:          newWebhookClient(cfg.Timeout),
+		wakeCh:          make(chan struct{}, 1),
+	}
+}
+
+// errWebhookAddressBlocked 端点解析到内网、回环或链路本地地址
+var errWebhookAddressBlocked = errors.New("webhook endpoint resolves to a non-public address")
+
+// WebhookAddressAllowed 判断 Webhook 是否可以投递到 ip：拒绝回环、内网（RFC1918 / ULA）、
+// 链路本地（含云厂商元数据地址 169.254.169.254）、组播及未指定地址
+func WebhookAddressAllowed(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// newWebhookClient 创建投递用的 HTTP 客户端。端点地址由用户填写，为防止 SSRF：
+// 在建立连接时校验实际解析出的 IP（避免 DNS 重绑定绕过注册时的校验），不使用环境代理，也不跟随重定向
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !WebhookAddressAllowed(net.ParseIP(host)) {
+				return fmt.Errorf("%w: %s", errWebhookAddressBlocked, host)
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// 3xx 按非 2xx 处理，避免被重定向到内网地址
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// SetWebhookService 设置生成结束时使用的 Webhook 服务
+func (s *GenerationService) SetWebhookService(webhooks *WebhookService) {
+	s.webhooks = webhooks
+}
+
+// webhookEventForStatus 根据使用记录的最终状态确定事件类型
+func webhookEventForStatus(status string) string {
+	switch status {
+	case entity.UsageRecordStatusSucceeded, entity.UsageRecordStatusPartiallySucceeded:
+		return entity.WebhookEventGenerationCompleted
+	default:
+		return entity.WebhookEventGenerationFailed
+	}
+}
+
+// EnqueueGenerationEvent 为已结束的使用记录生成事件，写入所有订阅端点的投递队列
+func (w *WebhookService) EnqueueGenerationEvent(recordID uint) {
+	if w == nil || w.repo == nil || recordID == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fields := logrus.Fields{"record_id": recordID}
+
+	record, err := w.repo.GetUsageRecord(ctx, recordID)
+	if err != nil {
+		logrus.WithError(err).WithFields(fields).Warn("failed to load usage record for webhooks")
+		return
+	}
+
+	endpoints, err := w.repo.FindWebhookEndpointsForUser(ctx, record.UserID)
+	if err != nil {
+		logrus.WithError(err).WithFields(fields).Warn("failed to load webhook endpoints")
+		return
+	}
+
+	event := webhookEventForStatus(record.Status)
+	var subscribed []entity.DbWebhookEndpoint
+	for _, endpoint := range endpoints {
+		if endpoint.Subscribes(event) {
+			subscribed = append(subscribed, endpoint)
+		}
+	}
+	if len(subscribed) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(entity.WebhookEvent{
+		Type:      event,
+		CreatedAt: time.Now().UTC(),
+		Data:      converter.UsageRecordToWebhookData(record, w.imageURLBuilder),
+	})
+	if err != nil {
+		logrus.WithError(err).WithFields(fields).Error("failed to encode webhook payload")
+		return
+	}
+
+	deliveries := make([]entity.DbWebhookDelivery, 0, len(subscribed))
+	for _, endpoint := range subscribed {
+		deliveries = append(deliveries, entity.DbWebhookDelivery{
+			EndpointID: endpoint.ID,
+			RecordID:   record.ID,
+			Event:      event,
+			Payload:    string(payload),
+		})
+	}
+
+	if err := w.repo.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		logrus.WithError(err).WithFields(fields).Error("failed to queue webhook deliveries")
+		return
+	}
+	w.wake()
+}
+
+func (w *WebhookService) wake() {
+	select {
+	case w.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// Start 启动投递循环，随 ctx 取消而停止
+func (w *WebhookService) Start(ctx context.Context, cfg WebhookConfig) {
+	if w == nil || w.repo == nil {
+		return
+	}
+	w.cfg = normaliseWebhookConfig(cfg)
+	w.client = newWebhookClient(w.cfg.Timeout)
+
+	go w.runLoop(ctx)
+}
+
+func (w *WebhookService) runLoop(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	logrus.WithField("concurrency", w.cfg.Concurrency).Info("webhook dispatcher started")
+
+	for {
+		w.dispatchDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			logrus.Info("webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+		case <-w.wakeCh:
+		}
+	}
+}
+
+// dispatchDue 认领到期的投递并并发发送，全部发送完成后返回
+func (w *WebhookService) dispatchDue(ctx context.Context) {
+	// 认领租约需覆盖一次投递的最长耗时，避免其他实例重复认领
+	lease := w.cfg.Timeout + 30*time.Second
+
+	claimCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	deliveries, err := w.repo.ClaimWebhookDeliveries(claimCtx, w.cfg.Concurrency, lease)
+	cancel()
+	if err != nil {
+		logrus.WithError(err).Error("failed to claim webhook deliveries")
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+
+	done := make(chan struct{}, len(deliveries))
+	for _, delivery := range deliveries {
+		go func(delivery entity.DbWebhookDelivery) {
+			defer func() { done <- struct{}{} }()
+			w.deliver(ctx, delivery)
+		}(delivery)
+	}
+	for range deliveries {
+		<-done
+	}
+
+	// 仍可能有更多到期的投递
+	if len(deliveries) == w.cfg.Concurrency {
+		w.wake()
+	}
+}
+
+// deliver 发送一次投递并记录结果，失败时按退避策略安排重试
+func (w *WebhookService) deliver(ctx context.Context, delivery entity.DbWebhookDelivery) {
+	fields := logrus.Fields{
+		"delivery_id": delivery.ID,
+		"endpoint_id": delivery.EndpointID,
+		"record_id":   delivery.RecordID,
+		"event":       delivery.Event,
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	endpoint, err := w.repo.GetWebhookEndpoint(loadCtx, delivery.EndpointID)
+	cancel()
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		// 租约到期后重新认领
+		logrus.WithError(err).WithFields(fields).Warn("failed to load webhook endpoint")
+		return
+	}
+	if endpoint == nil || !endpoint.IsActive {
+		status := entity.WebhookDeliveryStatusFailed
+		lastError := "webhook endpoint removed or disabled"
+		w.updateDelivery(delivery.ID, entity.WebhookDeliveryUpdates{Status: &status, LastError: &lastError})
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	statusCode, sendErr := w.send(ctx, *endpoint, delivery)
+
+	updates := entity.WebhookDeliveryUpdates{Attempts: &attempts, LastStatusCode: &statusCode}
+	if sendErr == nil {
+		status := entity.WebhookDeliveryStatusSucceeded
+		deliveredAt := time.Now()
+		lastError := ""
+		updates.Status = &status
+		updates.DeliveredAt = &deliveredAt
+		updates.LastError = &lastError
+		w.updateDelivery(delivery.ID, updates)
+		logrus.WithFields(fields).Info("delivered webhook")
+		return
+	}
+
+	lastError := sendErr.Error()
+	updates.LastError = &lastError
+	if attempts >= w.cfg.MaxAttempts {
+		status := entity.WebhookDeliveryStatusFailed
+		updates.Status = &status
+		logrus.WithError(sendErr).WithFields(fields).Warn("webhook delivery failed permanently")
+	} else {
+		nextAttemptAt := time.Now().Add(webhookBackoff.Backoff(attempts))
+		updates.NextAttemptAt = &nextAttemptAt
+		logrus.WithError(sendErr).WithFields(fields).WithField("next_attempt_at", nextAttemptAt).Warn("webhook delivery failed, will retry")
+	}
+	w.updateDelivery(delivery.ID, updates)
+}
+
+// send POST 事件到端点，2xx 视为成功。失败原因只记录状态码，不保存端点返回的内容
+func (w *WebhookService) send(ctx context.Context, endpoint entity.DbWebhookEndpoint, delivery entity.DbWebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(endpoint.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded http %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (w *WebhookService) updateDelivery(id uint, updates entity.WebhookDeliveryUpdates) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.repo.UpdateWebhookDelivery(ctx, id, updates); err != nil {
+		logrus.WithError(err).WithField("delivery_id", id).Error("failed to update webhook delivery")
+	}
+}
+
+// SignWebhookPayload 计算签名头的值：t=<unix 时间戳>,v1=<hex(HMAC-SHA256(secret, "<时间戳>.<body>"))>。
+// 接收方用相同方式计算并比较 v1，同时校验时间戳以防重放。
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
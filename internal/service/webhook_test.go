@@ -0,0 +1,166 @@
+package service
+
+import (
+	"clothing/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"type":"generation.completed"}`)
+
+	signature := SignWebhookPayload("whsec_test", 1700000000, body)
+	if !strings.HasPrefix(signature, "t=1700000000,v1=") {
+		t.Fatalf("unexpected signature format: %s", signature)
+	}
+	if signature != SignWebhookPayload("whsec_test", 1700000000, body) {
+		t.Error("expected signature to be deterministic")
+	}
+	if signature == SignWebhookPayload("whsec_other", 1700000000, body) {
+		t.Error("expected signature to depend on secret")
+	}
+	if signature == SignWebhookPayload("whsec_test", 1700000001, body) {
+		t.Error("expected signature to depend on timestamp")
+	}
+}
+
+func TestWebhookEventForStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		expected string
+	}{
+		{name: "成功", status: entity.UsageRecordStatusSucceeded, expected: entity.WebhookEventGenerationCompleted},
+		{name: "部分成功", status: entity.UsageRecordStatusPartiallySucceeded, expected: entity.WebhookEventGenerationCompleted},
+		{name: "失败", status: entity.UsageRecordStatusFailed, expected: entity.WebhookEventGenerationFailed},
+		{name: "取消", status: entity.UsageRecordStatusCancelled, expected: entity.WebhookEventGenerationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := webhookEventForStatus(tt.status); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	var gotSignature, gotEvent, gotBody string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(WebhookSignatureHeader)
+		gotEvent = r.Header.Get(WebhookEventHeader)
+		raw, _ := io.ReadAll(r.Body)
+		gotBody = string(raw)
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, "internal details")
+	}))
+	defer server.Close()
+
+	w := NewWebhookService(nil, nil)
+	// 默认客户端拒绝连接回环地址，测试服务器需使用其自带的客户端
+	w.client = server.Client()
+	endpoint := entity.DbWebhookEndpoint{URL: server.URL, Secret: "whsec_test"}
+	delivery := entity.DbWebhookDelivery{ID: 7, Event: entity.WebhookEventGenerationCompleted, Payload: `{"type":"generation.completed"}`}
+
+	t.Run("投递成功并携带签名", func(t *testing.T) {
+		code, err := w.send(context.Background(), endpoint, delivery)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("unexpected result: %d, %v", code, err)
+		}
+		if gotBody != delivery.Payload || gotEvent != delivery.Event {
+			t.Errorf("unexpected request: event %q body %q", gotEvent, gotBody)
+		}
+		var timestamp int64
+		if _, err := fmt.Sscanf(gotSignature, "t=%d,", &timestamp); err != nil {
+			t.Fatalf("failed to parse signature %q: %v", gotSignature, err)
+		}
+		if gotSignature != SignWebhookPayload(endpoint.Secret, timestamp, []byte(delivery.Payload)) {
+			t.Errorf("signature mismatch: %s", gotSignature)
+		}
+	})
+
+	t.Run("非 2xx 视为失败", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		code, err := w.send(context.Background(), endpoint, delivery)
+		if err == nil || code != http.StatusInternalServerError {
+			t.Fatalf("expected failure, got %d, %v", code, err)
+		}
+		if strings.Contains(err.Error(), "internal details") {
+			t.Errorf("expected response body not to be recorded: %v", err)
+		}
+	})
+}
+
+func TestWebhookAddressAllowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		ip       string
+		expected bool
+	}{
+		{name: "公网地址", ip: "93.184.216.34", expected: true},
+		{name: "公网 IPv6", ip: "2606:2800:220:1:248:1893:25c8:1946", expected: true},
+		{name: "回环地址", ip: "127.0.0.1", expected: false},
+		{name: "IPv6 回环", ip: "::1", expected: false},
+		{name: "内网地址", ip: "10.1.2.3", expected: false},
+		{name: "内网地址 172.16", ip: "172.16.0.1", expected: false},
+		{name: "内网地址 192.168", ip: "192.168.1.1", expected: false},
+		{name: "元数据地址", ip: "169.254.169.254", expected: false},
+		{name: "映射的内网地址", ip: "::ffff:10.0.0.1", expected: false},
+		{name: "未指定地址", ip: "0.0.0.0", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WebhookAddressAllowed(net.ParseIP(tt.ip)); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWebhookClientBlocksPrivateAddresses(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL, http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	w := NewWebhookService(nil, nil)
+	delivery := entity.DbWebhookDelivery{ID: 1, Event: entity.WebhookEventGenerationCompleted, Payload: `{}`}
+
+	t.Run("拒绝连接回环地址", func(t *testing.T) {
+		_, err := w.send(context.Background(), entity.DbWebhookEndpoint{URL: server.URL}, delivery)
+		if !errors.Is(err, errWebhookAddressBlocked) {
+			t.Errorf("expected blocked address error, got %v", err)
+		}
+	})
+
+	t.Run("不跟随重定向", func(t *testing.T) {
+		w.client = redirect.Client()
+		w.client.CheckRedirect = newWebhookClient(time.Second).CheckRedirect
+		code, err := w.send(context.Background(), entity.DbWebhookEndpoint{URL: redirect.URL}, delivery)
+		if err == nil || code != http.StatusFound {
+			t.Errorf("expected redirect to fail delivery, got %d, %v", code, err)
+		}
+	})
+
+	if hits.Load() != 0 {
+		t.Errorf("expected blocked endpoint not to be reached, got %d requests", hits.Load())
+	}
+}
@@ -0,0 +1,39 @@
+-- Migration: Add lifecycle status to usage_records and backfill existing rows
+-- GORM AutoMigrate creates the columns and indexes, and migrateSchema runs the same
+-- backfill on startup. The SQL below is for reference if manual migration is needed.
+
+-- SQLite version (default):
+ALTER TABLE usage_records ADD COLUMN status VARCHAR(32);
+ALTER TABLE usage_records ADD COLUMN started_at DATETIME;
+ALTER TABLE usage_records ADD COLUMN finished_at DATETIME;
+CREATE INDEX IF NOT EXISTS idx_usage_records_status ON usage_records (status);
+CREATE INDEX IF NOT EXISTS idx_usage_record_user_status ON usage_records (user_id, status);
+
+-- For MySQL/MariaDB:
+-- ALTER TABLE usage_records
+--     ADD COLUMN status VARCHAR(32),
+--     ADD COLUMN started_at DATETIME(3) NULL,
+--     ADD COLUMN finished_at DATETIME(3) NULL;
+-- CREATE INDEX idx_usage_records_status ON usage_records (status);
+-- CREATE INDEX idx_usage_record_user_status ON usage_records (user_id, status);
+
+-- For PostgreSQL:
+-- ALTER TABLE usage_records
+--     ADD COLUMN IF NOT EXISTS status VARCHAR(32),
+--     ADD COLUMN IF NOT EXISTS started_at TIMESTAMPTZ,
+--     ADD COLUMN IF NOT EXISTS finished_at TIMESTAMPTZ;
+-- CREATE INDEX IF NOT EXISTS idx_usage_records_status ON usage_records (status);
+-- CREATE INDEX IF NOT EXISTS idx_usage_record_user_status ON usage_records (user_id, status);
+
+-- Backfill (all dialects). Records still waiting in generation_jobs become queued,
+-- the rest are inferred from error_message as before.
+UPDATE usage_records SET status = 'queued'
+WHERE (status IS NULL OR status = '')
+  AND id IN (SELECT record_id FROM generation_jobs WHERE status IN ('pending', 'running'));
+
+UPDATE usage_records SET status = 'failed', finished_at = updated_at
+WHERE (status IS NULL OR status = '')
+  AND error_message IS NOT NULL AND error_message <> '';
+
+UPDATE usage_records SET status = 'succeeded', finished_at = updated_at
+WHERE (status IS NULL OR status = '');